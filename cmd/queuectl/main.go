@@ -0,0 +1,67 @@
+// Команда queuectl — инструмент администратора: миграции базы данных, создание администратора,
+// импорт расписания, управление очередями и ручной запуск фоновых задач. Настройки читаются так же,
+// как сервером (см. internal/config).
+//
+//	go run ./cmd/queuectl <команда> [аргументы]
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"test_hack/internal/app"
+	"test_hack/internal/config"
+	"test_hack/internal/queuectl"
+)
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "help" {
+		fmt.Fprintln(os.Stderr, queuectl.Usage)
+		os.Exit(2)
+	}
+	if !queuectl.Known(os.Args[1]) {
+		fmt.Fprintf(os.Stderr, "неизвестная команда %q\n\n%s\n", os.Args[1], queuectl.Usage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		fatal(err)
+	}
+	a, err := app.Open(cfg)
+	if err != nil {
+		fatal(fmt.Errorf("подключение к базе данных: %w", err))
+	}
+	// У queuectl нет клиентов WebSocket: события очередей передаются серверу через Redis.
+	a.ForwardEvents()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cli := &queuectl.CLI{App: a, In: os.Stdin, Out: os.Stdout, Err: os.Stderr}
+	err = cli.Run(ctx, os.Args[1], os.Args[2:])
+
+	// Дожидаемся записи событий и закрываем подключения до выхода: os.Exit не выполняет отложенные вызовы.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	if serr := a.Shutdown(shutdownCtx); serr != nil {
+		fmt.Fprintln(os.Stderr, "queuectl: остановка:", serr)
+	}
+	cancel()
+
+	if err != nil {
+		if errors.Is(err, queuectl.ErrUsage) {
+			fmt.Fprintf(os.Stderr, "%v\n\n%s\n", err, queuectl.Usage)
+			os.Exit(2)
+		}
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "queuectl:", err)
+	os.Exit(1)
+}
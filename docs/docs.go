@@ -15,48 +15,107 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/queues/{id}/join": {
-            "post": {
+        "/admin/audit-events": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Добавляет пользователя в очередь и уведомляет других участников",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Возвращает события журнала аудита от новых к старым. Фильтр action принимает несколько типов через запятую; значение с точкой на конце (например, queue.) выбирает все события раздела. Для следующей страницы передайте before_id — ID последней полученной записи",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "queue"
+                    "admin"
                 ],
-                "summary": "Вступление в очередь",
+                "summary": "Журнал аудита",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID очереди",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Типы событий через запятую, например queue.joined,queue.left или queue.",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Кто выполнил действие",
+                        "name": "actor_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Пользователь, к которому относится событие",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Тип объекта: queue, user, webhook, job",
+                        "name": "target_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID объекта",
+                        "name": "target_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID запроса (заголовок X-Request-ID)",
+                        "name": "request_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IP-адрес",
+                        "name": "ip",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Начало периода (RFC 3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Конец периода (RFC 3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Вернуть записи с ID меньше указанного",
+                        "name": "before_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество записей (по умолчанию 50, максимум 500)",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Успешное вступление в очередь с указанием позиции",
+                        "description": "События",
                         "schema": {
-                            "$ref": "#/definitions/response.MessageResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.AuditEventResponse"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Ошибка валидации (INVALID_QUEUE_ID, ALREADY_IN_QUEUE, QUEUE_INACTIVE)",
+                        "description": "Ошибка валидации (VALIDATION_ERROR)",
                         "schema": {
                             "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Очередь не найдена (QUEUE_NOT_FOUND)",
+                    "403": {
+                        "description": "Недостаточно прав (FORBIDDEN)",
                         "schema": {
                             "$ref": "#/definitions/response.ErrorResponse"
                         }
@@ -70,42 +129,33 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/queues/{id}/leave": {
-            "post": {
+        "/admin/jobs": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Удаляет пользователя из очереди и уведомляет других участников",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Возвращает зарегистрированные задачи планировщика, их расписание и результат последнего запуска",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "queue"
-                ],
-                "summary": "Выход из очереди",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "ID очереди",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "admin"
                 ],
+                "summary": "Список фоновых задач",
                 "responses": {
                     "200": {
-                        "description": "Успешный выход из очереди",
+                        "description": "Список задач",
                         "schema": {
-                            "$ref": "#/definitions/response.SuccessResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.JobStatusResponse"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "Ошибка валидации (INVALID_QUEUE_ID, NOT_IN_QUEUE)",
+                    "403": {
+                        "description": "Недостаточно прав (FORBIDDEN)",
                         "schema": {
                             "$ref": "#/definitions/response.ErrorResponse"
                         }
@@ -119,54 +169,51 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/queues/{id}/status": {
-            "get": {
+        "/admin/jobs/{name}/run": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Возвращает информацию о состоянии очереди и списке участников",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Синхронно выполняет задачу планировщика и возвращает результат запуска",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "queue"
+                    "admin"
                 ],
-                "summary": "Получение статуса очереди",
+                "summary": "Ручной запуск задачи",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID очереди",
-                        "name": "id",
+                        "description": "Имя задачи",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Успешное получение статуса очереди",
+                        "description": "Результат запуска",
                         "schema": {
-                            "$ref": "#/definitions/response.SwaggerQueueStatusResponse"
+                            "$ref": "#/definitions/handlers.JobRunResponse"
                         }
                     },
-                    "400": {
-                        "description": "Ошибка валидации (INVALID_QUEUE_ID)",
+                    "403": {
+                        "description": "Недостаточно прав (FORBIDDEN)",
                         "schema": {
                             "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "Очередь не найдена (QUEUE_NOT_FOUND)",
+                        "description": "Задача не найдена (JOB_NOT_FOUND)",
                         "schema": {
                             "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Ошибка сервера (DB_ERROR)",
+                    "409": {
+                        "description": "Задача уже выполняется (JOB_RUNNING)",
                         "schema": {
                             "$ref": "#/definitions/response.ErrorResponse"
                         }
@@ -174,48 +221,54 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/queues/{id}/ws": {
+        "/admin/jobs/{name}/runs": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Устанавливает WebSocket соединение для получения обновлений очереди в реальном времени",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Возвращает последние запуски задачи: время начала и окончания, длительность, ошибку и число затронутых записей",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "websocket"
+                    "admin"
                 ],
-                "summary": "Подключение к WebSocket очереди",
+                "summary": "История запусков задачи",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID очереди",
-                        "name": "id",
+                        "description": "Имя задачи",
+                        "name": "name",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество записей (по умолчанию 50, максимум 500)",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "101": {
-                        "description": "Переключение протокола на WebSocket",
+                    "200": {
+                        "description": "История запусков",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.JobRunResponse"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "Ошибка валидации (INVALID_QUEUE_ID)",
+                    "403": {
+                        "description": "Недостаточно прав (FORBIDDEN)",
                         "schema": {
                             "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Ошибка сервера (WEBSOCKET_ERROR)",
+                        "description": "Ошибка сервера (DB_ERROR)",
                         "schema": {
                             "$ref": "#/definitions/response.ErrorResponse"
                         }
@@ -223,9 +276,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/login": {
-            "post": {
-                "description": "Авторизация пользователя и получение токенов",
+        "/admin/users/{id}/role": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Назначает пользователю роль student, teacher или admin",
                 "consumes": [
                     "application/json"
                 ],
@@ -233,41 +291,54 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Авторизация пользователя",
+                "summary": "Изменение роли пользователя",
                 "parameters": [
                     {
-                        "description": "Данные для авторизации",
-                        "name": "user",
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Новая роль",
+                        "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.LoginRequest"
+                            "$ref": "#/definitions/handlers.UpdateRoleRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Успешная авторизация",
+                        "description": "Роль обновлена",
                         "schema": {
-                            "$ref": "#/definitions/response.TokenResponse"
+                            "$ref": "#/definitions/response.ProfileResponse"
                         }
                     },
                     "400": {
-                        "description": "Ошибка валидации данных (VALIDATION_ERROR)",
+                        "description": "Ошибка валидации (INVALID_USER_ID, VALIDATION_ERROR)",
                         "schema": {
                             "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
-                    "401": {
-                        "description": "Неверные учетные данные (INVALID_CREDENTIALS)",
+                    "403": {
+                        "description": "Недостаточно прав (FORBIDDEN)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Пользователь не найден (USER_NOT_FOUND)",
                         "schema": {
                             "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Ошибка сервера (TOKEN_GENERATION_ERROR)",
+                        "description": "Ошибка сервера (DB_ERROR)",
                         "schema": {
                             "$ref": "#/definitions/response.ErrorResponse"
                         }
@@ -275,61 +346,52 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/refresh": {
-            "post": {
-                "description": "Обновление access токена с помощью refresh токена",
-                "consumes": [
-                    "application/json"
+        "/admin/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Возвращает зарегистрированные вебхуки и их фильтры событий",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
-                ],
-                "summary": "Обновление access токена",
-                "parameters": [
-                    {
-                        "description": "Refresh токен",
-                        "name": "refresh_token",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.RefreshTokenRequest"
-                        }
-                    }
+                    "admin"
                 ],
+                "summary": "Список вебхуков",
                 "responses": {
                     "200": {
-                        "description": "Успешное обновление access токена",
-                        "schema": {
-                            "$ref": "#/definitions/response.TokenResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Ошибка валидации данных (VALIDATION_ERROR)",
+                        "description": "Список вебхуков",
                         "schema": {
-                            "$ref": "#/definitions/response.ErrorResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.WebhookResponse"
+                            }
                         }
                     },
-                    "401": {
-                        "description": "Неверный или просроченный refresh токен (INVALID_REFRESH_TOKEN) или пользователь не найден (USER_NOT_FOUND)",
+                    "403": {
+                        "description": "Недостаточно прав (FORBIDDEN)",
                         "schema": {
                             "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Ошибка сервера (TOKEN_GENERATION_ERROR)",
+                        "description": "Ошибка сервера (DB_ERROR)",
                         "schema": {
                             "$ref": "#/definitions/response.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/auth/register": {
+            },
             "post": {
-                "description": "Регистрация нового пользователя",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Регистрирует адрес для получения событий очередей. События отправляются POST-запросом с JSON в формате сообщений WebSocket и заголовками X-Webhook-Event, X-Webhook-Delivery, X-Webhook-Timestamp и X-Webhook-Signature (sha256=hex(HMAC-SHA256(secret, \"\u003ctimestamp\u003e.\u003cbody\u003e\"))). Секрет возвращается только в этом ответе",
                 "consumes": [
                     "application/json"
                 ],
@@ -337,35 +399,41 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Регистрация пользователя",
+                "summary": "Регистрация вебхука",
                 "parameters": [
                     {
-                        "description": "Данные пользователя",
-                        "name": "user",
+                        "description": "Параметры вебхука",
+                        "name": "webhook",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.RegisterRequest"
+                            "$ref": "#/definitions/handlers.WebhookRequest"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Успешная регистрация",
+                        "description": "Вебхук создан",
                         "schema": {
-                            "$ref": "#/definitions/response.SuccessResponse"
+                            "$ref": "#/definitions/handlers.WebhookResponse"
                         }
                     },
                     "400": {
-                        "description": "Ошибка валидации (VALIDATION_ERROR) или пользователь уже существует (EMAIL_EXISTS)",
+                        "description": "Ошибка валидации (VALIDATION_ERROR, UNKNOWN_EVENT)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав (FORBIDDEN)",
                         "schema": {
                             "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Ошибка сервера (PASSWORD_HASH_ERROR, DB_ERROR)",
+                        "description": "Ошибка сервера (SECRET_GENERATION_ERROR, DB_ERROR)",
                         "schema": {
                             "$ref": "#/definitions/response.ErrorResponse"
                         }
@@ -373,28 +441,53 @@ const docTemplate = `{
                 }
             }
         },
-        "/groups": {
+        "/admin/webhooks/dead-letters": {
             "get": {
-                "description": "Получает список всех групп, кэширует результат в Redis",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Возвращает события, которые не удалось доставить после всех попыток",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "groups"
+                    "admin"
+                ],
+                "summary": "Недоставленные события",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Фильтр по вебхуку",
+                        "name": "webhook_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество записей (по умолчанию 50, максимум 500)",
+                        "name": "limit",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Получение списка групп",
                 "responses": {
                     "200": {
-                        "description": "Успешный ответ с данными групп",
+                        "description": "Недоставленные события",
                         "schema": {
-                            "$ref": "#/definitions/handlers.GroupResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.WebhookDeadLetterResponse"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав (FORBIDDEN)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Ошибка сервера (API_ERROR, CACHE_ERROR, DECODE_ERROR)",
+                        "description": "Ошибка сервера (DB_ERROR)",
                         "schema": {
                             "$ref": "#/definitions/response.ErrorResponse"
                         }
@@ -402,33 +495,51 @@ const docTemplate = `{
                 }
             }
         },
-        "/profile": {
-            "get": {
+        "/admin/webhooks/dead-letters/{id}/retry": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Получение данных пользователя по токену",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Создаёт новую доставку для недоставленного события и сразу выполняет первую попытку",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "profile"
+                    "admin"
+                ],
+                "summary": "Повторная отправка события",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID недоставленного события",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Получение данных пользователя",
                 "responses": {
                     "200": {
-                        "description": "Успешное получение данных пользователя",
+                        "description": "Новая доставка",
                         "schema": {
-                            "$ref": "#/definitions/response.ProfileResponse"
+                            "$ref": "#/definitions/handlers.WebhookDeliveryResponse"
                         }
                     },
-                    "401": {
-                        "description": "Ошибка авторизации (UNAUTHORIZED)",
+                    "403": {
+                        "description": "Недостаточно прав (FORBIDDEN)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Событие не найдено (DEAD_LETTER_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Событие уже отправлено повторно (ALREADY_RETRIED)",
                         "schema": {
                             "$ref": "#/definitions/response.ErrorResponse"
                         }
@@ -442,14 +553,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/profile/queues": {
-            "get": {
+        "/admin/webhooks/{id}": {
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Получение списка очередей, в которых пользователь участвует",
+                "description": "Изменяет адрес, фильтр событий, интервал queue_update, описание или активность вебхука",
                 "consumes": [
                     "application/json"
                 ],
@@ -457,21 +568,183 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "profile"
+                    "admin"
+                ],
+                "summary": "Изменение вебхука",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID вебхука",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Параметры вебхука",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WebhookRequest"
+                        }
+                    }
                 ],
-                "summary": "Получение списка своих очередей",
                 "responses": {
                     "200": {
-                        "description": "List of queues the user is part of",
+                        "description": "Вебхук изменён",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WebhookResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации (INVALID_WEBHOOK_ID, VALIDATION_ERROR, UNKNOWN_EVENT)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав (FORBIDDEN)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Вебхук не найден (WEBHOOK_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (DB_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет вебхук; оставшиеся в очереди доставки события будут отменены",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Удаление вебхука",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID вебхука",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Вебхук удалён",
+                        "schema": {
+                            "$ref": "#/definitions/response.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации (INVALID_WEBHOOK_ID)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав (FORBIDDEN)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Вебхук не найден (WEBHOOK_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (DB_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks/{id}/deliveries": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает последние попытки доставки событий: статус, число попыток, код ответа и ошибку",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Журнал доставки вебхука",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID вебхука",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по статусу (pending, success, dead)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество записей (по умолчанию 50, максимум 500)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Журнал доставки",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/handlers.UserQueueItem"
+                                "$ref": "#/definitions/handlers.WebhookDeliveryResponse"
                             }
                         }
                     },
+                    "400": {
+                        "description": "Ошибка валидации (INVALID_WEBHOOK_ID)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав (FORBIDDEN)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Вебхук не найден (WEBHOOK_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
                     "500": {
-                        "description": "Server error (DB_ERROR)",
+                        "description": "Ошибка сервера (DB_ERROR)",
                         "schema": {
                             "$ref": "#/definitions/response.ErrorResponse"
                         }
@@ -479,9 +752,72 @@ const docTemplate = `{
                 }
             }
         },
-        "/schedule": {
+        "/api/queues/{id}/history": {
             "get": {
-                "description": "Получает расписание по заданным параметрам (group_id), кэширует результат в Redis",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает все записи очереди (включая вышедших и принятых) с временем входа, выхода, итоговым статусом и тем, кто принял участника. Доступно и для закрытых очередей",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "История очереди",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID очереди",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "История очереди",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.QueueHistoryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации (INVALID_QUEUE_ID)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав (FORBIDDEN)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Очередь не найдена (QUEUE_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (DB_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/queues/{id}/join": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Добавляет пользователя в очередь и уведомляет других участников",
                 "consumes": [
                     "application/json"
                 ],
@@ -489,164 +825,3111 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "schedule"
+                    "queue"
                 ],
-                "summary": "Получение расписания",
+                "summary": "Вступление в очередь",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID группы",
-                        "name": "group_id",
-                        "in": "query",
+                        "description": "ID очереди",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Успешный ответ с данными расписания и связанными очередями",
+                        "description": "Успешное вступление в очередь с указанием позиции",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/response.SwaggerScheduleWithQueue"
-                            }
+                            "$ref": "#/definitions/response.MessageResponse"
                         }
                     },
                     "400": {
-                        "description": "Ошибка валидации данных (MISSING_GROUP_ID)",
+                        "description": "Ошибка валидации (INVALID_QUEUE_ID, ALREADY_IN_QUEUE, QUEUE_INACTIVE)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Email не подтверждён (EMAIL_NOT_VERIFIED)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Очередь не найдена (QUEUE_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много запросов (RATE_LIMITED)",
                         "schema": {
                             "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Ошибка сервера (DB_ERROR, API_ERROR, DECODE_ERROR)",
+                        "description": "Ошибка сервера (DB_ERROR)",
                         "schema": {
                             "$ref": "#/definitions/response.ErrorResponse"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "handlers.Group": {
+        },
+        "/api/queues/{id}/leave": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет пользователя из очереди и уведомляет других участников",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "queue"
+                ],
+                "summary": "Выход из очереди",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID очереди",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешный выход из очереди",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации (INVALID_QUEUE_ID, NOT_IN_QUEUE)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много запросов (RATE_LIMITED)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (DB_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/queues/{id}/serve": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Преподаватель отмечает участника как принятого (по умолчанию — первого в очереди). Позиции остальных участников сдвигаются",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "queue"
+                ],
+                "summary": "Приём участника очереди",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID очереди",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Кого принять",
+                        "name": "body",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ServeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Участник принят",
+                        "schema": {
+                            "$ref": "#/definitions/response.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации (INVALID_QUEUE_ID, VALIDATION_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав (FORBIDDEN)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Участник не найден (NOT_IN_QUEUE, QUEUE_EMPTY)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (DB_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/queues/{id}/status": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает информацию о состоянии очереди и списке участников",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "queue"
+                ],
+                "summary": "Получение статуса очереди",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID очереди",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешное получение статуса очереди",
+                        "schema": {
+                            "$ref": "#/definitions/response.SwaggerQueueStatusResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации (INVALID_QUEUE_ID)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Очередь не найдена (QUEUE_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много запросов (RATE_LIMITED)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (DB_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/queues/{id}/ws": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Устанавливает WebSocket соединение для получения обновлений очереди в реальном времени",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "websocket"
+                ],
+                "summary": "Подключение к WebSocket очереди",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID очереди",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": "Переключение протокола на WebSocket",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации (INVALID_QUEUE_ID)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (WEBSOCKET_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/reports/attendance": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отчёт по группе и/или событию расписания за период: кто вставал в очередь, кого приняли, кто отсутствовал. Поддерживается выгрузка в CSV и XLSX",
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Отчёт о посещаемости",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID группы (обязателен, если не указан schedule_id)",
+                        "name": "group_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID события расписания",
+                        "name": "schedule_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Начало периода (YYYY-MM-DD), по умолчанию 30 дней назад",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Конец периода включительно (YYYY-MM-DD), по умолчанию сегодня; не раньше from",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "csv",
+                            "xlsx"
+                        ],
+                        "type": "string",
+                        "description": "Формат: json, csv или xlsx",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Отчёт о посещаемости",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AttendanceReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации (MISSING_FILTER, INVALID_DATE, INVALID_SCHEDULE_ID, INVALID_FORMAT)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав (FORBIDDEN)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (DB_ERROR, EXPORT_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/forgot-password": {
+            "post": {
+                "description": "Отправляет на email ссылку для сброса пароля. Ответ не зависит от того, зарегистрирован ли email. Не больше 3 запросов на один email в час",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Запрос сброса пароля",
+                "parameters": [
+                    {
+                        "description": "Email пользователя",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ForgotPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Запрос принят",
+                        "schema": {
+                            "$ref": "#/definitions/response.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации (VALIDATION_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много запросов (TOO_MANY_REQUESTS)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (CACHE_ERROR, TOKEN_GENERATION_ERROR, DB_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/login": {
+            "post": {
+                "description": "Авторизация пользователя и получение токенов. Если у пользователя подключена 2FA, вместо токенов возвращается токен второго шага (202). После 3 неудачных попыток для email следующие возможны с нарастающей паузой, после 5 — вход по email блокируется на 15 минут; после 20 неудач с одного IP блокируется IP",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Авторизация пользователя",
+                "parameters": [
+                    {
+                        "description": "Данные для авторизации",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешная авторизация",
+                        "schema": {
+                            "$ref": "#/definitions/response.TokenResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Пароль верный, требуется код 2FA через /auth/login/mfa",
+                        "schema": {
+                            "$ref": "#/definitions/response.MFAChallengeResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации данных (VALIDATION_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверные учетные данные (INVALID_CREDENTIALS)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Вход временно заблокирован (LOCKED_OUT) или слишком частые попытки (TOO_MANY_ATTEMPTS); заголовок Retry-After содержит паузу в секундах",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (TOKEN_GENERATION_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/login/mfa": {
+            "post": {
+                "description": "Завершает вход пользователя с подключённой 2FA: принимает токен из ответа /auth/login и код из приложения или код восстановления. Токен действует 5 минут и допускает не больше 5 попыток",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Второй шаг входа",
+                "parameters": [
+                    {
+                        "description": "Токен второго шага и код",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MFALoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешная авторизация",
+                        "schema": {
+                            "$ref": "#/definitions/response.TokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации данных (VALIDATION_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный или просроченный токен (INVALID_MFA_TOKEN) или неверный код (INVALID_MFA_CODE)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышено число попыток для токена (TOO_MANY_ATTEMPTS)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (CACHE_ERROR, DB_ERROR, TOKEN_GENERATION_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/oidc/callback": {
+            "get": {
+                "description": "Принимает код авторизации от провайдера, проверяет ID токен и выдаёт токены. Пользователь находится по идентификатору SSO или по подтверждённому провайдером email; если пользователя нет, он создаётся. Если задан OIDC_SUCCESS_URL, вместо JSON выполняется перенаправление на него с токенами во фрагменте URL",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Завершение входа через SSO",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Код авторизации",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Состояние из /auth/oidc/login",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешная авторизация",
+                        "schema": {
+                            "$ref": "#/definitions/response.TokenResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Требуется код 2FA через /auth/login/mfa",
+                        "schema": {
+                            "$ref": "#/definitions/response.MFAChallengeResponse"
+                        }
+                    },
+                    "302": {
+                        "description": "Перенаправление на OIDC_SUCCESS_URL"
+                    },
+                    "400": {
+                        "description": "Ошибка провайдера (OIDC_PROVIDER_ERROR), неверное состояние (INVALID_OIDC_STATE) или в токене нет email (OIDC_EMAIL_MISSING)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный ID токен (INVALID_ID_TOKEN)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Email не подтверждён провайдером (OIDC_EMAIL_NOT_VERIFIED), запрещённый домен (EMAIL_DOMAIN_NOT_ALLOWED) или регистрация отключена (OIDC_SIGNUP_DISABLED)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "SSO не настроен (OIDC_DISABLED)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Email привязан к другому аккаунту SSO (OIDC_ACCOUNT_MISMATCH)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (DB_ERROR, TOKEN_GENERATION_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Ошибка обмена кода у провайдера (OIDC_EXCHANGE_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/oidc/login": {
+            "get": {
+                "description": "Перенаправляет на страницу входа провайдера OpenID Connect (authorization code flow с PKCE). Ссылка действует 10 минут",
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Вход через SSO",
+                "responses": {
+                    "302": {
+                        "description": "Перенаправление к провайдеру"
+                    },
+                    "404": {
+                        "description": "SSO не настроен (OIDC_DISABLED)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (TOKEN_GENERATION_ERROR, CACHE_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/refresh": {
+            "post": {
+                "description": "Обновление access токена с помощью refresh токена. Продлевает сессию, к которой относится токен; токены завершённой сессии отклоняются",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Обновление access токена",
+                "parameters": [
+                    {
+                        "description": "Refresh токен",
+                        "name": "refresh_token",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RefreshTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешное обновление access токена",
+                        "schema": {
+                            "$ref": "#/definitions/response.TokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации данных (VALIDATION_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный или просроченный refresh токен (INVALID_REFRESH_TOKEN) или пользователь не найден (USER_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (TOKEN_GENERATION_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/register": {
+            "post": {
+                "description": "Регистрация нового пользователя. На email отправляется ссылка для подтверждения адреса",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Регистрация пользователя",
+                "parameters": [
+                    {
+                        "description": "Данные пользователя",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RegisterRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Успешная регистрация",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации (VALIDATION_ERROR), запрещённый домен (EMAIL_DOMAIN_NOT_ALLOWED) или пользователь уже существует (EMAIL_EXISTS)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (PASSWORD_HASH_ERROR, DB_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/resend-verification": {
+            "post": {
+                "description": "Отправляет новое письмо для подтверждения email; ссылки из предыдущих писем перестают действовать. Ответ не зависит от того, зарегистрирован ли email. Не больше 3 писем на один email в час",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Повторная отправка письма подтверждения",
+                "parameters": [
+                    {
+                        "description": "Email пользователя",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ResendVerificationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Запрос принят",
+                        "schema": {
+                            "$ref": "#/definitions/response.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации (VALIDATION_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много запросов (TOO_MANY_REQUESTS)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (CACHE_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/reset-password": {
+            "post": {
+                "description": "Устанавливает новый пароль по токену из письма. Токен одноразовый и действует 1 час; после сброса все сессии завершаются и выданные токены перестают действовать",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Сброс пароля",
+                "parameters": [
+                    {
+                        "description": "Токен из письма и новый пароль",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ResetPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Пароль изменён",
+                        "schema": {
+                            "$ref": "#/definitions/response.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации (VALIDATION_ERROR) или недействительный токен (INVALID_RESET_TOKEN)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (PASSWORD_HASH_ERROR, DB_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/verify": {
+            "get": {
+                "description": "Подтверждает email по одноразовому токену из письма. Токен действует 24 часа",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Подтверждение email",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Токен из письма",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Email подтверждён",
+                        "schema": {
+                            "$ref": "#/definitions/response.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Недействительный токен (INVALID_VERIFICATION_TOKEN)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (DB_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/groups": {
+            "get": {
+                "description": "Получает список всех групп, кэширует результат в Redis",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Получение списка групп",
+                "responses": {
+                    "200": {
+                        "description": "Успешный ответ с данными групп",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.GroupResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (API_ERROR, CACHE_ERROR, DECODE_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Возвращает 200, пока процесс обрабатывает HTTP-запросы. Зависимости не проверяются — для этого есть /readyz.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Проверка живости",
+                "responses": {
+                    "200": {
+                        "description": "Сервис работает",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
+                }
+            }
+        },
+        "/profile": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получение данных пользователя по токену",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Получение данных пользователя",
+                "responses": {
+                    "200": {
+                        "description": "Успешное получение данных пользователя",
+                        "schema": {
+                            "$ref": "#/definitions/response.ProfileResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Ошибка авторизации (UNAUTHORIZED)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (DB_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Изменяет имя, фамилию, группу и язык пользователя. Незаданные поля не меняются",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Изменение профиля",
+                "parameters": [
+                    {
+                        "description": "Новые значения полей",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateProfileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Обновлённый профиль",
+                        "schema": {
+                            "$ref": "#/definitions/response.ProfileResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации (VALIDATION_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Ошибка авторизации (UNAUTHORIZED)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (DB_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Выводит пользователя из всех активных очередей (позиции остальных участников сдвигаются, участники получают событие user_left) и обезличивает аккаунт: имя, email, пароль, группа и привязки удаляются, история очередей сохраняется без персональных данных, а из журнала аудита удаляются email, IP и User-Agent пользователя. Удаление подтверждается паролем, кодом 2FA (если она подключена) или входом через SSO не раньше 10 минут назад — так аккаунт без пароля удаляется после повторного входа через SSO",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Удаление аккаунта",
+                "parameters": [
+                    {
+                        "description": "Пароль или код 2FA для подтверждения",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DeleteProfileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Аккаунт удалён",
+                        "schema": {
+                            "$ref": "#/definitions/response.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации (VALIDATION_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Ошибка авторизации (UNAUTHORIZED), неверный пароль (INVALID_CREDENTIALS), неверный код (INVALID_MFA_CODE) или нужен повторный вход (REAUTHENTICATION_REQUIRED)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много попыток (RATE_LIMITED)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (DB_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/profile/2fa/disable": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отключает 2FA после проверки пароля и кода из приложения (или кода восстановления). Недоступно для ролей из MFA_REQUIRED_ROLES",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Отключение 2FA",
+                "parameters": [
+                    {
+                        "description": "Пароль и код",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DisableMFARequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "2FA отключена",
+                        "schema": {
+                            "$ref": "#/definitions/response.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации (VALIDATION_ERROR), 2FA не подключена (MFA_NOT_ENABLED) или неверный код (INVALID_MFA_CODE)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Ошибка авторизации (UNAUTHORIZED) или неверный пароль (INVALID_CREDENTIALS)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "2FA обязательна для роли (MFA_REQUIRED)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (DB_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/profile/2fa/enable": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Включает 2FA после проверки кода из приложения и возвращает 10 одноразовых кодов восстановления. Коды показываются только один раз",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Включение 2FA",
+                "parameters": [
+                    {
+                        "description": "Код из приложения",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MFACodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "2FA включена",
+                        "schema": {
+                            "$ref": "#/definitions/response.RecoveryCodesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации (VALIDATION_ERROR), 2FA не настроена (MFA_NOT_SETUP) или неверный код (INVALID_MFA_CODE)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Ошибка авторизации (UNAUTHORIZED)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "2FA уже подключена (MFA_ALREADY_ENABLED)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (DB_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/profile/2fa/recovery-codes": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Выпускает 10 новых кодов восстановления; прежние коды перестают действовать. Требует код из приложения",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Новые коды восстановления",
+                "parameters": [
+                    {
+                        "description": "Код из приложения",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MFACodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Новые коды восстановления",
+                        "schema": {
+                            "$ref": "#/definitions/response.RecoveryCodesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации (VALIDATION_ERROR), 2FA не подключена (MFA_NOT_ENABLED) или неверный код (INVALID_MFA_CODE)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Ошибка авторизации (UNAUTHORIZED)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (DB_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/profile/2fa/setup": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Генерирует новый секрет TOTP и возвращает URI otpauth:// и QR-код для приложения-аутентификатора. 2FA включается только после подтверждения кодом через /profile/2fa/enable",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Начало подключения 2FA",
+                "responses": {
+                    "200": {
+                        "description": "Данные для приложения-аутентификатора",
+                        "schema": {
+                            "$ref": "#/definitions/response.MFASetupResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Ошибка авторизации (UNAUTHORIZED)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "2FA уже подключена (MFA_ALREADY_ENABLED)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (MFA_SETUP_ERROR, DB_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/profile/export": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Выгружает профиль, историю очередей с названиями событий, настройки и историю напоминаний, push-подписки и активные сессии. Небольшая выгрузка отдаётся сразу файлом; если записей в очередях больше EXPORT_ASYNC_THRESHOLD (по умолчанию 500) или передан async=true, выгрузка формируется в фоне, а ответ 202 содержит адрес для проверки состояния. Готовая фоновая выгрузка хранится 24 часа",
+                "produces": [
+                    "application/json",
+                    "application/zip"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Выгрузка персональных данных",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Формат: json (по умолчанию) или zip",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Сформировать выгрузку в фоне",
+                        "name": "async",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Файл выгрузки (JSON или ZIP)",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserDataExport"
+                        }
+                    },
+                    "202": {
+                        "description": "Выгрузка формируется в фоне",
+                        "schema": {
+                            "$ref": "#/definitions/response.DataExportResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный формат (VALIDATION_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Ошибка авторизации (UNAUTHORIZED)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много запросов (RATE_LIMITED)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (DB_ERROR, EXPORT_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/profile/export/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает готовый файл выгрузки или её состояние, пока она формируется",
+                "produces": [
+                    "application/json",
+                    "application/zip"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Состояние фоновой выгрузки",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID выгрузки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Состояние выгрузки (pending, failed) или файл, если status=ready",
+                        "schema": {
+                            "$ref": "#/definitions/response.DataExportResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID (VALIDATION_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Ошибка авторизации (UNAUTHORIZED)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Выгрузка не найдена или истекла (EXPORT_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/profile/notifications": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает настройки напоминаний пользователя (или настройки по умолчанию) и доступные каналы доставки",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Настройки напоминаний",
+                "responses": {
+                    "200": {
+                        "description": "Настройки напоминаний",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.NotificationSettingsResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (DB_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Сохраняет, о чём и по каким каналам напоминать пользователю",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Изменение настроек напоминаний",
+                "parameters": [
+                    {
+                        "description": "Настройки напоминаний",
+                        "name": "settings",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.NotificationSettingsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Настройки сохранены",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.NotificationSettingsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации (VALIDATION_ERROR, UNKNOWN_CHANNEL)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (DB_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/profile/password": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Меняет пароль после проверки текущего. Все сессии завершаются, выданные ранее токены перестают действовать; в ответе — новая пара токенов для текущего клиента",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Смена пароля",
+                "parameters": [
+                    {
+                        "description": "Текущий и новый пароль",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ChangePasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Пароль изменён",
+                        "schema": {
+                            "$ref": "#/definitions/response.TokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации (VALIDATION_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Ошибка авторизации (UNAUTHORIZED) или неверный текущий пароль (INVALID_CREDENTIALS)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (PASSWORD_HASH_ERROR, DB_ERROR, TOKEN_GENERATION_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/profile/push/subscriptions": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Сохраняет подписку браузера и включает канал webpush в настройках напоминаний. Повторная регистрация того же endpoint обновляет ключи",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Подписка на Web Push",
+                "parameters": [
+                    {
+                        "description": "Объект PushSubscription",
+                        "name": "subscription",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PushSubscriptionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Подписка сохранена",
+                        "schema": {
+                            "$ref": "#/definitions/response.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации (VALIDATION_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (DB_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Web Push не настроен (PUSH_DISABLED)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет подписку браузера пользователя по endpoint",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Отписка от Web Push",
+                "parameters": [
+                    {
+                        "description": "Адрес подписки",
+                        "name": "subscription",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DeletePushSubscriptionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Подписка удалена",
+                        "schema": {
+                            "$ref": "#/definitions/response.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации (VALIDATION_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписка не найдена (SUBSCRIPTION_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (DB_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/profile/queues": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получение списка очередей, в которых пользователь участвует",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Получение списка своих очередей",
+                "responses": {
+                    "200": {
+                        "description": "List of queues the user is part of",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.UserQueueItem"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Server error (DB_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/profile/sessions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Список устройств, на которых выполнен вход: браузер, IP, время входа и последнего использования. Сессия, к которой относится текущий токен, помечена current=true",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Активные сессии",
+                "responses": {
+                    "200": {
+                        "description": "Активные сессии",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/response.SessionResponse"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Ошибка авторизации (UNAUTHORIZED)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (DB_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/profile/sessions/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Завершает сессию: её access и refresh токены перестают приниматься. Завершение текущей сессии равносильно выходу из аккаунта",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Завершение сессии",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID сессии",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Сессия завершена",
+                        "schema": {
+                            "$ref": "#/definitions/response.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID (VALIDATION_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Ошибка авторизации (UNAUTHORIZED)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Сессия не найдена или уже завершена (SESSION_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (DB_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/profile/telegram": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отвязывает чат Telegram от аккаунта; бот перестаёт присылать уведомления",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Отвязка Telegram",
+                "responses": {
+                    "200": {
+                        "description": "Telegram отвязан",
+                        "schema": {
+                            "$ref": "#/definitions/response.MessageResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (DB_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/profile/telegram/link": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Выдаёт одноразовый код, который нужно отправить боту командой /start \u003cкод\u003e. Код действует 10 минут",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Код привязки Telegram",
+                "responses": {
+                    "200": {
+                        "description": "Код привязки",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TelegramLinkResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (CODE_GENERATION_ERROR, CACHE_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/push/vapid-public-key": {
+            "get": {
+                "description": "Возвращает ключ applicationServerKey для подписки браузера на Web Push",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "push"
+                ],
+                "summary": "Публичный ключ VAPID",
+                "responses": {
+                    "200": {
+                        "description": "Публичный ключ",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.VAPIDPublicKeyResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Web Push не настроен (PUSH_DISABLED)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Проверяет базу данных, Redis, хаб WebSocket и планировщик фоновых задач. Возвращает статус каждого компонента; если хотя бы один недоступен — код 503.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Проверка готовности",
+                "responses": {
+                    "200": {
+                        "description": "Все компоненты работают",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    },
+                    "503": {
+                        "description": "Хотя бы один компонент недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
+                }
+            }
+        },
+        "/schedule": {
+            "get": {
+                "description": "Получает расписание по заданным параметрам (group_id), кэширует результат в Redis",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedule"
+                ],
+                "summary": "Получение расписания",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID группы",
+                        "name": "group_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешный ответ с данными расписания и связанными очередями",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/response.SwaggerScheduleWithQueue"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации данных (MISSING_GROUP_ID)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера (DB_ERROR, API_ERROR, DECODE_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/telegram/webhook": {
+            "post": {
+                "description": "Принимает обновления от Telegram Bot API. Запрос должен содержать заголовок X-Telegram-Bot-Api-Secret-Token, если задан TELEGRAM_WEBHOOK_SECRET",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "telegram"
+                ],
+                "summary": "Webhook Telegram-бота",
+                "responses": {
+                    "200": {
+                        "description": "Обновление принято",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректное обновление (VALIDATION_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный секрет (INVALID_WEBHOOK_SECRET)",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "handlers.AttendanceReport": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "group_id": {
+                    "type": "string"
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.AttendanceRow"
+                    }
+                },
+                "schedule_id": {
+                    "type": "integer"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.AttendanceRow": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "group_id": {
+                    "type": "string"
+                },
+                "joined_at": {
+                    "type": "string"
+                },
+                "left_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "queue_id": {
+                    "type": "integer"
+                },
+                "schedule_id": {
+                    "type": "integer"
+                },
+                "schedule_name": {
+                    "type": "string"
+                },
+                "served_by": {
+                    "type": "string"
+                },
+                "start_time": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "surname": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.AuditEventResponse": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "example": "queue.left"
+                },
+                "actor_id": {
+                    "type": "integer"
+                },
+                "after": {
+                    "type": "object"
+                },
+                "before": {
+                    "type": "object"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "details": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "target_id": {
+                    "type": "string",
+                    "example": "15"
+                },
+                "target_type": {
+                    "type": "string",
+                    "example": "queue"
+                },
+                "user_agent": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.ChangePasswordRequest": {
+            "type": "object",
+            "required": [
+                "new_password",
+                "old_password"
+            ],
+            "properties": {
+                "new_password": {
+                    "type": "string",
+                    "minLength": 6
+                },
+                "old_password": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.DeleteProfileRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Код из приложения-аутентификатора или код восстановления",
+                    "type": "string"
+                },
+                "password": {
+                    "description": "Текущий пароль",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.DeletePushSubscriptionRequest": {
+            "type": "object",
+            "required": [
+                "endpoint"
+            ],
+            "properties": {
+                "endpoint": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.DisableMFARequest": {
+            "type": "object",
+            "required": [
+                "code",
+                "password"
+            ],
+            "properties": {
+                "code": {
+                    "description": "Код из приложения или код восстановления",
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ExportNotification": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "type": "string"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "queue_id": {
+                    "type": "integer"
+                },
+                "sent_at": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ExportNotificationSettings": {
+            "type": "object",
+            "properties": {
+                "before_event": {
+                    "type": "boolean"
+                },
+                "before_event_minutes": {
+                    "type": "integer"
+                },
+                "channels": {
+                    "type": "string"
+                },
+                "position_reached": {
+                    "type": "boolean"
+                },
+                "position_threshold": {
+                    "type": "integer"
+                },
+                "queue_opened": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "handlers.ExportPushSubscription": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "endpoint": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ExportQueueEntry": {
+            "type": "object",
+            "properties": {
+                "event_start": {
+                    "type": "string"
+                },
+                "exited_at": {
+                    "type": "string"
+                },
+                "joined_at": {
+                    "type": "string"
+                },
+                "position": {
+                    "type": "integer"
+                },
+                "queue_id": {
+                    "type": "integer"
+                },
+                "schedule_id": {
+                    "type": "integer"
+                },
+                "schedule_name": {
+                    "type": "string"
+                },
+                "served_at": {
+                    "type": "string"
+                },
+                "served_by": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "still_in_queue": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "handlers.ExportSession": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ExportUser": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "email_verified": {
+                    "type": "boolean"
+                },
+                "email_verified_at": {
+                    "type": "string"
+                },
+                "group_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "language": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "sso_linked": {
+                    "type": "boolean"
+                },
+                "surname": {
+                    "type": "string"
+                },
+                "telegram_linked": {
+                    "type": "boolean"
+                },
+                "two_factor_enabled": {
+                    "type": "boolean"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ForgotPasswordRequest": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.Group": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "number": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.GroupResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.Group"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.JobRunResponse": {
+            "type": "object",
+            "properties": {
+                "affected_rows": {
+                    "type": "integer"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "job_name": {
+                    "type": "string"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "trigger": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.JobStatusResponse": {
+            "type": "object",
+            "properties": {
+                "last_run": {
+                    "$ref": "#/definitions/handlers.JobRunResponse"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "running": {
+                    "type": "boolean"
+                },
+                "schedule": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.LoginRequest": {
+            "type": "object",
+            "required": [
+                "email",
+                "password"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.MFACodeRequest": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "description": "Код из приложения-аутентификатора",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.MFALoginRequest": {
+            "type": "object",
+            "required": [
+                "code",
+                "mfa_token"
+            ],
+            "properties": {
+                "code": {
+                    "description": "Код из приложения или код восстановления",
+                    "type": "string"
+                },
+                "mfa_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.NotificationSettingsRequest": {
+            "type": "object",
+            "properties": {
+                "before_event": {
+                    "type": "boolean"
+                },
+                "before_event_minutes": {
+                    "type": "integer",
+                    "maximum": 1440,
+                    "minimum": 1
+                },
+                "channels": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "position_reached": {
+                    "type": "boolean"
+                },
+                "position_threshold": {
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 1
+                },
+                "queue_opened": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "handlers.NotificationSettingsResponse": {
+            "type": "object",
+            "properties": {
+                "available_channels": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "before_event": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "before_event_minutes": {
+                    "type": "integer",
+                    "example": 30
+                },
+                "channels": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "position_reached": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "position_threshold": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "queue_opened": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "handlers.PushSubscriptionRequest": {
+            "type": "object",
+            "required": [
+                "endpoint",
+                "keys"
+            ],
+            "properties": {
+                "endpoint": {
+                    "type": "string",
+                    "example": "https://fcm.googleapis.com/fcm/send/abc123"
+                },
+                "keys": {
+                    "type": "object",
+                    "required": [
+                        "auth",
+                        "p256dh"
+                    ],
+                    "properties": {
+                        "auth": {
+                            "type": "string"
+                        },
+                        "p256dh": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "handlers.QueueHistoryEntry": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "entry_id": {
+                    "type": "integer"
+                },
+                "joined_at": {
+                    "type": "string"
+                },
+                "left_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "position": {
+                    "type": "integer"
+                },
+                "served_at": {
+                    "type": "string"
+                },
+                "served_by_id": {
+                    "type": "integer"
+                },
+                "served_by_name": {
+                    "type": "string"
+                },
+                "served_by_surname": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "surname": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.QueueHistoryResponse": {
+            "type": "object",
+            "properties": {
+                "closes_at": {
+                    "type": "string"
+                },
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.QueueHistoryEntry"
+                    }
+                },
+                "is_active": {
+                    "type": "boolean"
+                },
+                "opens_at": {
+                    "type": "string"
+                },
+                "queue_id": {
+                    "type": "integer"
+                },
+                "schedule_id": {
+                    "type": "integer"
+                },
+                "schedule_name": {
+                    "type": "string"
+                },
+                "start_time": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.RefreshTokenRequest": {
+            "type": "object",
+            "required": [
+                "refresh_token"
+            ],
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.RegisterRequest": {
+            "type": "object",
+            "required": [
+                "email",
+                "name",
+                "password",
+                "surname"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "group_id": {
+                    "description": "ID учебной группы из /groups (необязательно)",
+                    "type": "string"
+                },
+                "language": {
+                    "description": "Язык писем и уведомлений, по умолчанию ru",
+                    "type": "string",
+                    "enum": [
+                        "ru",
+                        "en"
+                    ]
+                },
+                "name": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string",
+                    "minLength": 6
+                },
+                "surname": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ResendVerificationRequest": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ResetPasswordRequest": {
+            "type": "object",
+            "required": [
+                "password",
+                "token"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "minLength": 6
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ServeRequest": {
+            "type": "object",
+            "properties": {
+                "user_id": {
+                    "description": "ID пользователя, которого нужно принять. Если не указан — принимается первый в очереди.",
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.TelegramLinkResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "K7M2QX9P"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "link": {
+                    "description": "Ссылка для открытия бота с кодом (если задан TELEGRAM_BOT_USERNAME)",
+                    "type": "string",
+                    "example": "https://t.me/queue_bot?start=K7M2QX9P"
+                }
+            }
+        },
+        "handlers.UpdateProfileRequest": {
+            "type": "object",
+            "properties": {
+                "group_id": {
+                    "description": "Пустая строка — убрать группу",
+                    "type": "string",
+                    "maxLength": 50
+                },
+                "language": {
+                    "description": "Язык писем и уведомлений",
+                    "type": "string",
+                    "enum": [
+                        "ru",
+                        "en"
+                    ]
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "minLength": 1
+                },
+                "surname": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "minLength": 1
+                }
+            }
+        },
+        "handlers.UpdateRoleRequest": {
+            "type": "object",
+            "required": [
+                "role"
+            ],
+            "properties": {
+                "role": {
+                    "type": "string",
+                    "enum": [
+                        "student",
+                        "teacher",
+                        "admin"
+                    ]
+                }
+            }
+        },
+        "handlers.UserDataExport": {
             "type": "object",
             "properties": {
-                "id": {
-                    "type": "integer"
-                },
-                "name": {
+                "exported_at": {
                     "type": "string"
                 },
-                "number": {
-                    "type": "string"
+                "notification_settings": {
+                    "$ref": "#/definitions/handlers.ExportNotificationSettings"
+                },
+                "notifications": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.ExportNotification"
+                    }
+                },
+                "push_subscriptions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.ExportPushSubscription"
+                    }
+                },
+                "queue_history": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.ExportQueueEntry"
+                    }
+                },
+                "sessions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.ExportSession"
+                    }
+                },
+                "user": {
+                    "$ref": "#/definitions/handlers.ExportUser"
                 }
             }
         },
-        "handlers.GroupResponse": {
+        "handlers.UserQueueItem": {
             "type": "object",
             "properties": {
-                "items": {
+                "closes_at": {
+                    "type": "string"
+                },
+                "end_time": {
+                    "type": "string"
+                },
+                "group_numbers": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/handlers.Group"
+                        "type": "string"
                     }
                 },
-                "limit": {
+                "is_active": {
+                    "type": "boolean"
+                },
+                "opens_at": {
+                    "type": "string"
+                },
+                "position": {
                     "type": "integer"
                 },
-                "offset": {
+                "queue_id": {
                     "type": "integer"
                 },
-                "total": {
+                "schedule_id": {
                     "type": "integer"
+                },
+                "schedule_name": {
+                    "type": "string"
+                },
+                "start_time": {
+                    "type": "string"
                 }
             }
         },
-        "handlers.LoginRequest": {
+        "handlers.VAPIDPublicKeyResponse": {
             "type": "object",
-            "required": [
-                "email",
-                "password"
-            ],
             "properties": {
-                "email": {
+                "public_key": {
+                    "type": "string",
+                    "example": "BNcRdreALRFXTkOOUHK1EtK2wtaz5Ry4YfYCA_0QTpQtUbVlUls0VJXg7A8u-Ts1XbjhazAkj7I99e8QcYP7DkM"
+                }
+            }
+        },
+        "handlers.WebhookDeadLetterResponse": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
                     "type": "string"
                 },
-                "password": {
+                "delivery_id": {
+                    "type": "integer"
+                },
+                "event_type": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "string"
+                },
+                "retried_at": {
                     "type": "string"
+                },
+                "webhook_id": {
+                    "type": "integer"
                 }
             }
         },
-        "handlers.RefreshTokenRequest": {
+        "handlers.WebhookDeliveryResponse": {
             "type": "object",
-            "required": [
-                "refresh_token"
-            ],
             "properties": {
-                "refresh_token": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "event_type": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_status_code": {
+                    "type": "integer"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "payload": {
                     "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "pending"
+                },
+                "webhook_id": {
+                    "type": "integer"
                 }
             }
         },
-        "handlers.RegisterRequest": {
+        "handlers.WebhookRequest": {
             "type": "object",
             "required": [
-                "email",
-                "name",
-                "password",
-                "surname"
+                "url"
             ],
             "properties": {
-                "email": {
-                    "type": "string"
+                "description": {
+                    "type": "string",
+                    "example": "Дашборд кафедры"
                 },
-                "name": {
-                    "type": "string"
+                "events": {
+                    "description": "Типы событий: user_joined, user_left, user_served, queue_closed, queue_update. Пустой список — все события",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "password": {
-                    "type": "string",
-                    "minLength": 6
+                "is_active": {
+                    "type": "boolean"
                 },
-                "surname": {
-                    "type": "string"
+                "queue_update_interval": {
+                    "description": "Не чаще одного события queue_update каждой очереди за столько секунд; 0 — каждое обновление",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 300
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://lms.example.com/hooks/queue"
                 }
             }
         },
-        "handlers.UserQueueItem": {
+        "handlers.WebhookResponse": {
             "type": "object",
             "properties": {
-                "closes_at": {
+                "created_at": {
                     "type": "string"
                 },
-                "end_time": {
+                "description": {
                     "type": "string"
                 },
-                "group_numbers": {
+                "events": {
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
                 },
+                "id": {
+                    "type": "integer"
+                },
                 "is_active": {
                     "type": "boolean"
                 },
-                "opens_at": {
+                "queue_update_interval": {
+                    "description": "Минимальный интервал между событиями queue_update одной очереди в секундах",
+                    "type": "integer"
+                },
+                "secret": {
                     "type": "string"
                 },
-                "position": {
-                    "type": "integer"
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "health.Component": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "dial tcp 127.0.0.1:6379: connect: connection refused"
                 },
-                "queue_id": {
-                    "type": "integer"
+                "latency_ms": {
+                    "type": "integer",
+                    "example": 3
                 },
-                "schedule_id": {
-                    "type": "integer"
+                "status": {
+                    "type": "string",
+                    "example": "up"
+                }
+            }
+        },
+        "health.Report": {
+            "type": "object",
+            "properties": {
+                "components": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/health.Component"
+                    }
                 },
-                "schedule_name": {
-                    "type": "string"
+                "status": {
+                    "type": "string",
+                    "example": "up"
+                }
+            }
+        },
+        "response.DataExportResponse": {
+            "type": "object",
+            "properties": {
+                "completed_at": {
+                    "type": "string",
+                    "example": "2023-01-01T12:00:05Z"
                 },
-                "start_time": {
+                "created_at": {
+                    "type": "string",
+                    "example": "2023-01-01T12:00:00Z"
+                },
+                "download_url": {
+                    "description": "Адрес для проверки состояния и скачивания готового файла",
+                    "type": "string",
+                    "example": "/profile/export/12"
+                },
+                "error": {
                     "type": "string"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "example": "2023-01-02T12:00:00Z"
+                },
+                "format": {
+                    "description": "json или zip",
+                    "type": "string",
+                    "example": "zip"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "status": {
+                    "description": "pending, ready или failed",
+                    "type": "string",
+                    "example": "pending"
                 }
             }
         },
@@ -667,6 +3950,43 @@ const docTemplate = `{
                 }
             }
         },
+        "response.MFAChallengeResponse": {
+            "type": "object",
+            "properties": {
+                "expires_in": {
+                    "description": "Время жизни токена в секундах",
+                    "type": "integer",
+                    "example": 300
+                },
+                "mfa_required": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "mfa_token": {
+                    "description": "Короткоживущий токен для второго шага входа\nexample: eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9...",
+                    "type": "string"
+                }
+            }
+        },
+        "response.MFASetupResponse": {
+            "type": "object",
+            "properties": {
+                "otpauth_url": {
+                    "description": "URI otpauth:// для QR-кода",
+                    "type": "string",
+                    "example": "otpauth://totp/PracticeQueue:ivan@example.com?issuer=PracticeQueue\u0026secret=JBSWY3DPEHPK3PXP"
+                },
+                "qr_code": {
+                    "description": "QR-код в формате data:image/png;base64",
+                    "type": "string"
+                },
+                "secret": {
+                    "description": "Секрет в base32 для ручного ввода",
+                    "type": "string",
+                    "example": "JBSWY3DPEHPK3PXP"
+                }
+            }
+        },
         "response.MessageResponse": {
             "type": "object",
             "properties": {
@@ -682,14 +4002,86 @@ const docTemplate = `{
                 "email": {
                     "type": "string"
                 },
+                "email_verified": {
+                    "description": "Подтверждён ли email по ссылке из письма",
+                    "type": "boolean",
+                    "example": true
+                },
+                "group_id": {
+                    "type": "string",
+                    "example": "67"
+                },
                 "id": {
                     "type": "integer"
                 },
+                "language": {
+                    "type": "string",
+                    "example": "ru"
+                },
                 "name": {
                     "type": "string"
                 },
+                "role": {
+                    "type": "string",
+                    "example": "student"
+                },
                 "surname": {
                     "type": "string"
+                },
+                "two_factor_enabled": {
+                    "description": "Подключена ли двухфакторная аутентификация",
+                    "type": "boolean",
+                    "example": false
+                },
+                "two_factor_required": {
+                    "description": "Требует ли роль пользователя 2FA для доступа к привилегированным эндпоинтам",
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "response.RecoveryCodesResponse": {
+            "type": "object",
+            "properties": {
+                "recovery_codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "k7d2m-q9x4a",
+                        "p3n8r-w6t1c"
+                    ]
+                }
+            }
+        },
+        "response.SessionResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "example": "2023-01-01T12:00:00Z"
+                },
+                "current": {
+                    "description": "Сессия, к которой относится токен запроса",
+                    "type": "boolean",
+                    "example": true
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "ip": {
+                    "type": "string",
+                    "example": "192.0.2.10"
+                },
+                "last_used_at": {
+                    "type": "string",
+                    "example": "2023-01-01T14:30:00Z"
+                },
+                "user_agent": {
+                    "type": "string",
+                    "example": "Mozilla/5.0 (Windows NT 10.0; Win64; x64)"
                 }
             }
         },
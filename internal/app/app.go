@@ -0,0 +1,262 @@
+// Package app собирает приложение из конфигурации: подключения к базе и Redis, хаб WebSocket,
+// обработчики, планировщик фоновых задач, Telegram-бот и маршруты HTTP. Всё состояние принадлежит
+// экземпляру App, поэтому в одном процессе можно поднять несколько независимых экземпляров.
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync/atomic"
+
+	"test_hack/internal/auth"
+	"test_hack/internal/config"
+	"test_hack/internal/handlers"
+	"test_hack/internal/health"
+	"test_hack/internal/jobs"
+	"test_hack/internal/metrics"
+	"test_hack/internal/migrations"
+	"test_hack/internal/notify"
+	"test_hack/internal/ratelimit"
+	"test_hack/internal/storage"
+	"test_hack/internal/tasks"
+	"test_hack/internal/telegram"
+	"test_hack/internal/webhooks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+// App — собранное приложение.
+type App struct {
+	Config    config.Config
+	DB        *gorm.DB
+	Redis     *redis.Client
+	Hub       *handlers.Hub
+	Jobs      *jobs.Registry
+	Webhooks  *webhooks.Dispatcher
+	Handler   *handlers.Handler
+	Auth      *auth.Authenticator
+	Scheduler *cron.Cron
+	// Bot — Telegram-бот; nil, если TELEGRAM_BOT_TOKEN не задан.
+	Bot    *telegram.Bot
+	Router *gin.Engine
+	// Health — проверки готовности для /readyz.
+	Health *health.Checker
+	// Notify — каналы напоминаний и почтовый сервис, подключаются в InitIntegrations.
+	Notify *notify.Notifier
+	// RateLimiter ограничивает частоту запросов к API.
+	RateLimiter *ratelimit.Limiter
+	// Metrics — метрики Prometheus этого экземпляра, отдаются на /metrics.
+	Metrics *metrics.Metrics
+
+	// server — HTTP-сервер, запущенный Run.
+	server *http.Server
+	// schedulerRunning — запущен ли Scheduler: cron не сообщает об этом сам.
+	schedulerRunning atomic.Bool
+	// events — подписка на события других процессов, см. ListenEvents.
+	events *redis.PubSub
+}
+
+// Open подключается к базе данных и Redis по конфигурации и собирает приложение.
+func Open(cfg config.Config) (*App, error) {
+	db, err := storage.Open(cfg.Database.DSN())
+	if err != nil {
+		return nil, err
+	}
+	return New(cfg, db, storage.NewRedis(cfg.Redis.Addr, cfg.Redis.Password, cfg.Redis.DB)), nil
+}
+
+// New собирает приложение поверх готовых подключений. Фоновые процессы не запускаются — см. Start.
+func New(cfg config.Config, db *gorm.DB, rdb *redis.Client) *App {
+	m := metrics.New()
+	a := &App{
+		Config:   cfg,
+		DB:       db,
+		Redis:    rdb,
+		Jobs:     jobs.NewRegistry(db, m),
+		Webhooks: webhooks.NewDispatcher(db, m),
+		Notify:   notify.NewNotifier(),
+		Metrics:  m,
+	}
+	a.RateLimiter = ratelimit.NewLimiter(rdb, cfg.RateLimit)
+	a.Hub = handlers.NewHub(a.Webhooks, m)
+	a.Handler = &handlers.Handler{
+		DB:       db,
+		Redis:    rdb,
+		Hub:      a.Hub,
+		Jobs:     a.Jobs,
+		Webhooks: a.Webhooks,
+		Notify:   a.Notify,
+		Config:   cfg,
+		Metrics:  m,
+	}
+	a.Handler.TimetableClient = &http.Client{Transport: m.Transport("timetable", nil)}
+	a.Auth = auth.New([]byte(cfg.JWT.AccessSecret), db, a.Handler)
+	a.Auth.MFA = cfg.MFA
+	a.Scheduler = tasks.NewScheduler(a.Jobs, tasks.NewPlanner(a.Handler).Jobs())
+	a.Bot = telegram.New(a.Handler)
+	a.Health = a.healthChecks()
+	a.Router = a.routes()
+	return a
+}
+
+// Migrator возвращает Migrator со встроенными миграциями для базы приложения.
+func (a *App) Migrator() (*migrations.Migrator, error) {
+	sqlDB, err := a.DB.DB()
+	if err != nil {
+		return nil, err
+	}
+	return migrations.New(sqlDB)
+}
+
+// Migrate применяет к базе данных новые миграции из internal/migrations.
+func (a *App) Migrate(ctx context.Context) error {
+	m, err := a.Migrator()
+	if err != nil {
+		return err
+	}
+	applied, err := m.Up(ctx)
+	for _, mig := range applied {
+		log.Println("Применена миграция", mig)
+	}
+	return err
+}
+
+// InitIntegrations подключает внешние сервисы: SSO, почту, Web Push и канал уведомлений Telegram.
+// Ошибки необязательных интеграций только логируются.
+func (a *App) InitIntegrations(ctx context.Context) {
+	if err := a.Handler.InitOIDC(ctx); err != nil {
+		log.Println("Вход через SSO отключён:", err)
+	}
+	a.Notify.SetMailer(notify.NewMailer(a.Config.Mail))
+	if sender, err := notify.NewPushSender(a.DB, a.Config.WebPush, a.Config.Mail.From, a.Metrics); err != nil {
+		log.Println("Web Push отключён:", err)
+	} else {
+		a.Notify.SetPushSender(sender, a.DB)
+	}
+	if a.Bot != nil {
+		a.Notify.Register(telegram.Channel{Client: a.Bot.Client})
+	}
+}
+
+// ForwardEvents настраивает приложение без хаба WebSocket (queuectl): события очередей передаются
+// запущенным серверам через Redis, а на вебхуки ставятся в очередь доставки (см. handlers.NewRedisPublisher).
+func (a *App) ForwardEvents() {
+	a.Handler.Events = handlers.NewRedisPublisher(a.Redis, a.Webhooks)
+}
+
+// ListenEvents подписывается на события очередей, которые публикуют другие процессы (см. ForwardEvents),
+// и рассылает их клиентам WebSocket хаба. Возвращает ошибку, если подписку не удалось подтвердить;
+// при этом подписка остаётся и восстанавливается, когда Redis станет доступен. Закрывается в Shutdown.
+func (a *App) ListenEvents(ctx context.Context) error {
+	a.events = a.Redis.Subscribe(ctx, handlers.EventsChannel)
+	_, err := a.events.Receive(ctx)
+	go func(ch <-chan *redis.Message) {
+		for msg := range ch {
+			a.Hub.Relay([]byte(msg.Payload))
+		}
+	}(a.events.Channel())
+	return err
+}
+
+// Start запускает хаб WebSocket, приём событий queuectl, планировщик фоновых задач и получение обновлений Telegram.
+func (a *App) Start(ctx context.Context) {
+	go a.Hub.Run()
+	if err := a.ListenEvents(ctx); err != nil {
+		log.Println("Ошибка подписки на события queuectl:", err)
+	}
+
+	a.Scheduler.Start()
+	a.schedulerRunning.Store(true)
+	log.Println("Cron-планировщик запущен.")
+
+	if a.Bot != nil {
+		if a.Bot.WebhookURL != "" {
+			// Validate не пропускает webhook без секрета; при чтении настроек без проверки webhook не регистрируется.
+			if a.Bot.WebhookSecret == "" {
+				log.Println("Webhook Telegram не зарегистрирован: не задан TELEGRAM_WEBHOOK_SECRET")
+			} else if err := a.Bot.Client.SetWebhook(ctx, a.Bot.WebhookURL, a.Bot.WebhookSecret); err != nil {
+				log.Println("Ошибка регистрации webhook Telegram:", err)
+			}
+		} else {
+			go a.Bot.Poll(ctx)
+		}
+	}
+}
+
+// Run запускает фоновые процессы и HTTP-сервер на порту Config.Server.Port и работает до отмены ctx,
+// после чего останавливает приложение (см. Shutdown) с таймаутом Config.Server.ShutdownTimeout.
+func (a *App) Run(ctx context.Context) error {
+	a.Start(ctx)
+
+	a.server = &http.Server{Addr: a.Config.Addr(), Handler: a.Router}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- a.server.ListenAndServe()
+	}()
+	log.Println("HTTP-сервер запущен на", a.Config.Addr())
+
+	select {
+	case err := <-serveErr:
+		// Сервер не смог запуститься (например, порт занят) — останавливаем остальное и возвращаем ошибку.
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), a.Config.Server.ShutdownTimeout)
+		defer cancel()
+		return errors.Join(err, a.Shutdown(shutdownCtx))
+	case <-ctx.Done():
+	}
+
+	log.Println("Получен сигнал остановки.")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.Config.Server.ShutdownTimeout)
+	defer cancel()
+	return a.Shutdown(shutdownCtx)
+}
+
+// Shutdown останавливает приложение по шагам: дожидается завершения HTTP-запросов, останавливает
+// планировщик и ждёт выполняющиеся задачи, отправляет клиентам WebSocket кадр закрытия с подсказкой
+// переподключиться, дожидается отправки событий на вебхуки и закрывает подключения к базе данных и Redis.
+// Шаги, не успевшие завершиться до отмены ctx, прерываются, а их ошибки возвращаются вместе.
+func (a *App) Shutdown(ctx context.Context) error {
+	var errs []error
+
+	if a.server != nil {
+		log.Println("Остановка HTTP-сервера: ожидание завершения запросов...")
+		if err := a.server.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("HTTP-сервер: %w", err))
+		}
+	}
+
+	log.Println("Остановка планировщика: ожидание выполняющихся задач...")
+	a.schedulerRunning.Store(false)
+	select {
+	case <-a.Scheduler.Stop().Done():
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("планировщик: задачи не завершились: %w", ctx.Err()))
+	}
+
+	if a.events != nil {
+		a.events.Close()
+	}
+	a.Hub.Shutdown(ctx, a.Config.Server.WSReconnectAfter)
+
+	log.Println("Остановка вебхуков: ожидание отправки событий...")
+	if err := a.Webhooks.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("вебхуки: %w", err))
+	}
+
+	if sqlDB, err := a.DB.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("база данных: %w", err))
+		}
+	}
+	if err := a.Redis.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("Redis: %w", err))
+	}
+
+	log.Println("Приложение остановлено.")
+	return errors.Join(errs...)
+}
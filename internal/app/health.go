@@ -0,0 +1,37 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"test_hack/internal/health"
+)
+
+// healthChecks собирает проверки готовности: доступность базы данных и Redis, работу хаба WebSocket
+// и планировщика фоновых задач.
+func (a *App) healthChecks() *health.Checker {
+	c := health.New()
+	c.Add("database", func(ctx context.Context) error {
+		sqlDB, err := a.DB.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	})
+	c.Add("redis", func(ctx context.Context) error {
+		return a.Redis.Ping(ctx).Err()
+	})
+	c.Add("websocket_hub", func(context.Context) error {
+		if !a.Hub.Alive() {
+			return errors.New("хаб WebSocket не запущен")
+		}
+		return nil
+	})
+	c.Add("scheduler", func(context.Context) error {
+		if !a.schedulerRunning.Load() {
+			return errors.New("планировщик не запущен")
+		}
+		return nil
+	})
+	return c
+}
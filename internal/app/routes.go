@@ -0,0 +1,140 @@
+package app
+
+import (
+	"log"
+	"time"
+
+	"test_hack/internal/health"
+	"test_hack/internal/metrics"
+	"test_hack/internal/models"
+	"test_hack/internal/ratelimit"
+	"test_hack/internal/repository"
+	"test_hack/internal/requestid"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+)
+
+// routes регистрирует маршруты HTTP API.
+func (a *App) routes() *gin.Engine {
+	h := a.Handler
+	limit := func(name string, def ratelimit.Limit) gin.HandlerFunc {
+		return a.RateLimiter.Middleware(name, def)
+	}
+
+	r := gin.Default()
+	// По умолчанию gin доверяет X-Forwarded-For от любого клиента, и ограничения по IP обходятся подменой заголовка.
+	if err := r.SetTrustedProxies(a.Config.Server.TrustedProxies); err != nil {
+		log.Println("Ошибка настройки доверенных прокси, X-Forwarded-For не учитывается:", err)
+		r.SetTrustedProxies(nil)
+	}
+
+	r.Use(a.Metrics.Middleware())
+	r.Use(requestid.Middleware())
+	r.Use(cors.New(cors.Config{
+		AllowOrigins:     a.Config.Server.CORSOrigins,
+		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Authorization", "Content-Type", requestid.Header},
+		ExposeHeaders:    []string{"Content-Length", "Retry-After", requestid.Header, "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"},
+		AllowCredentials: true,
+	}))
+
+	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	r.GET("/healthz", health.Liveness)
+	r.GET("/readyz", a.Health.Readiness)
+	if a.Config.Metrics.Token != "" {
+		r.GET("/metrics", metrics.RequireToken(a.Config.Metrics.Token),
+			a.Metrics.Handler(metrics.QueueLengths(repository.NewQueueRepository(a.DB).ActiveLengths)))
+	} else {
+		log.Println("Маршрут /metrics не подключён: не задан METRICS_TOKEN")
+	}
+
+	authGroup := r.Group("/auth", limit("auth", ratelimit.Per(30, time.Minute)))
+	{
+		authGroup.POST("/login", h.Login)
+		authGroup.POST("/login/mfa", h.LoginMFA)
+		authGroup.GET("/oidc/login", h.OIDCLogin)
+		authGroup.GET("/oidc/callback", h.OIDCCallback)
+		authGroup.POST("/register", h.Register)
+		authGroup.POST("/refresh", h.RefreshToken)
+		authGroup.POST("/forgot-password", h.ForgotPassword)
+		authGroup.POST("/reset-password", h.ResetPassword)
+		authGroup.GET("/verify", h.VerifyEmail)
+		authGroup.POST("/resend-verification", h.ResendVerification)
+	}
+
+	profileGroup := r.Group("/profile", a.Auth.AuthMiddleware())
+	{
+		profileGroup.GET("/", h.GetMyProfileHandler)
+		profileGroup.PUT("/", h.UpdateProfileHandler)
+		// Удаление подтверждается паролем или кодом 2FA: лимит не даёт их подбирать.
+		profileGroup.DELETE("/", limit("profile_delete", ratelimit.Per(5, time.Hour)), h.DeleteProfileHandler)
+		profileGroup.POST("/password", h.ChangePasswordHandler)
+		profileGroup.GET("/export", limit("profile_export", ratelimit.Per(5, time.Hour)), h.ExportProfileDataHandler)
+		profileGroup.GET("/export/:id", h.GetProfileDataExportHandler)
+		profileGroup.GET("/sessions", h.ListSessionsHandler)
+		profileGroup.DELETE("/sessions/:id", h.RevokeSessionHandler)
+		profileGroup.GET("/queues", h.GetUserQueuesHandler)
+		profileGroup.GET("/notifications", h.GetNotificationSettingsHandler)
+		profileGroup.PUT("/notifications", h.UpdateNotificationSettingsHandler)
+		profileGroup.POST("/telegram/link", h.CreateTelegramLinkCodeHandler)
+		profileGroup.DELETE("/telegram", h.UnlinkTelegramHandler)
+		profileGroup.POST("/push/subscriptions", h.SubscribePushHandler)
+		profileGroup.DELETE("/push/subscriptions", h.UnsubscribePushHandler)
+		profileGroup.POST("/2fa/setup", h.SetupMFAHandler)
+		profileGroup.POST("/2fa/enable", h.EnableMFAHandler)
+		profileGroup.POST("/2fa/disable", h.DisableMFAHandler)
+		profileGroup.POST("/2fa/recovery-codes", h.RegenerateRecoveryCodesHandler)
+	}
+
+	apiGroup := r.Group("")
+	{
+		apiGroup.GET("/groups", h.GetGroupsHandler)
+		apiGroup.GET("/schedule", h.GetFullScheduleHandler)
+		apiGroup.GET("/push/vapid-public-key", h.GetVAPIDPublicKeyHandler)
+	}
+
+	r.GET("/api/queues/:id/status", limit("queue_status", ratelimit.Per(60, time.Minute)), h.GetQueueStatusHandler)
+	r.GET("/api/queues/:id/ws", h.QueueWebSocketHandler)
+	// У вступления и выхода отдельные корзины: исчерпав лимит вступлений, студент всё равно может выйти из очереди.
+	queues := r.Group("/api/queues", a.Auth.AuthMiddleware())
+	{
+		queues.POST("/:id/join", limit("queue_join", ratelimit.Per(10, time.Minute)), h.JoinQueueHandler)
+		queues.POST("/:id/leave", limit("queue_leave", ratelimit.Per(10, time.Minute)), h.LeaveQueueHandler)
+	}
+
+	teacherQueues := r.Group("/api/queues", a.Auth.AuthMiddleware(), a.Auth.RequireRole(models.RoleTeacher, models.RoleAdmin))
+	{
+		teacherQueues.POST("/:id/serve", h.ServeQueueHandler)
+		teacherQueues.GET("/:id/history", h.GetQueueHistoryHandler)
+	}
+
+	reports := r.Group("/api/reports", a.Auth.AuthMiddleware(), a.Auth.RequireRole(models.RoleTeacher, models.RoleAdmin))
+	{
+		reports.GET("/attendance", h.GetAttendanceReportHandler)
+	}
+
+	adminGroup := r.Group("/admin", a.Auth.AuthMiddleware(), a.Auth.RequireRole(models.RoleAdmin))
+	{
+		adminGroup.PUT("/users/:id/role", h.UpdateUserRoleHandler)
+		adminGroup.GET("/audit-events", h.ListAuditEventsHandler)
+		adminGroup.GET("/jobs", h.ListJobsHandler)
+		adminGroup.GET("/jobs/:name/runs", h.ListJobRunsHandler)
+		adminGroup.POST("/jobs/:name/run", h.RunJobHandler)
+		adminGroup.GET("/webhooks", h.ListWebhooksHandler)
+		adminGroup.POST("/webhooks", h.CreateWebhookHandler)
+		adminGroup.PUT("/webhooks/:id", h.UpdateWebhookHandler)
+		adminGroup.DELETE("/webhooks/:id", h.DeleteWebhookHandler)
+		adminGroup.GET("/webhooks/:id/deliveries", h.ListWebhookDeliveriesHandler)
+		adminGroup.GET("/webhooks/dead-letters", h.ListWebhookDeadLettersHandler)
+		adminGroup.POST("/webhooks/dead-letters/:id/retry", h.RetryWebhookDeadLetterHandler)
+	}
+
+	if a.Bot != nil && a.Bot.WebhookURL != "" && a.Bot.WebhookSecret != "" {
+		r.POST("/telegram/webhook", a.Bot.WebhookHandler)
+	}
+
+	return r
+}
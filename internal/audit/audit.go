@@ -0,0 +1,132 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"test_hack/internal/models"
+	"test_hack/internal/requestid"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Типы событий аудита
+const (
+	ActionLoginFailed      = "auth.login_failed"       // Неверный email или пароль
+	ActionLoginLocked      = "auth.login_locked"       // Попытка входа отклонена из-за блокировки или слишком частых попыток
+	ActionMFAFailed        = "auth.mfa_failed"         // Неверный код второго фактора при входе
+	ActionMFAEnabled       = "auth.mfa_enabled"        // Пользователь подключил 2FA
+	ActionMFADisabled      = "auth.mfa_disabled"       // Пользователь отключил 2FA
+	ActionRecoveryCodeUsed = "auth.recovery_code_used" // Вход выполнен по коду восстановления
+	ActionOIDCLogin        = "auth.oidc_login"         // Вход через SSO
+	ActionOIDCLinked       = "auth.oidc_linked"        // Аккаунт SSO привязан к существующему пользователю по email
+	ActionPasswordChanged  = "auth.password_changed"   // Пользователь сменил пароль в профиле
+	ActionAccountDeleted   = "user.deleted"            // Пользователь удалил аккаунт; персональные данные обезличены
+	ActionSessionRevoked   = "auth.session_revoked"    // Пользователь завершил сессию на другом устройстве
+
+	ActionQueueJoined   = "queue.joined"   // Пользователь встал в очередь
+	ActionQueueLeft     = "queue.left"     // Пользователь вышел из очереди
+	ActionQueueServed   = "queue.served"   // Преподаватель принял участника очереди
+	ActionQueueClosed   = "queue.closed"   // Очередь закрыта
+	ActionQueueReopened = "queue.reopened" // Закрытая очередь снова открыта
+
+	ActionRoleChanged    = "admin.role_changed"    // Администратор изменил роль пользователя
+	ActionAdminCreated   = "admin.admin_created"   // Администратор создан командой queuectl
+	ActionJobRun         = "admin.job_run"         // Администратор вручную запустил фоновую задачу
+	ActionWebhookCreated = "admin.webhook_created" // Администратор создал вебхук
+	ActionWebhookUpdated = "admin.webhook_updated" // Администратор изменил вебхук
+	ActionWebhookDeleted = "admin.webhook_deleted" // Администратор удалил вебхук
+	ActionWebhookRetried = "admin.webhook_retried" // Администратор повторил доставку из dead letter
+)
+
+// Типы объектов, к которым относятся события
+const (
+	TargetQueue   = "queue"
+	TargetUser    = "user"
+	TargetWebhook = "webhook"
+	TargetJob     = "job"
+)
+
+// Event описывает событие для записи в журнал аудита.
+type Event struct {
+	Action     string
+	ActorID    *uint // Кто выполнил действие; nil — система
+	UserID     *uint // Пользователь, к которому относится событие
+	Email      string
+	TargetType string
+	TargetID   interface{} // ID объекта; сохраняется строкой
+	Before     interface{} // Состояние до изменения, сохраняется в JSON
+	After      interface{} // Состояние после изменения, сохраняется в JSON
+	IP         string
+	UserAgent  string
+	RequestID  string
+	Details    map[string]interface{}
+}
+
+// RecordRequest сохраняет событие, заполняя по запросу незаданные поля: автора (userID из AuthMiddleware),
+// IP, User-Agent и ID запроса.
+func RecordRequest(db *gorm.DB, c *gin.Context, e Event) {
+	if e.ActorID == nil {
+		if id := c.GetUint("userID"); id != 0 {
+			e.ActorID = &id
+		}
+	}
+	if e.IP == "" {
+		e.IP = c.ClientIP()
+	}
+	if e.UserAgent == "" {
+		e.UserAgent = c.Request.UserAgent()
+	}
+	if e.RequestID == "" {
+		e.RequestID = requestid.Get(c)
+	}
+	Record(db, e)
+}
+
+// Record сохраняет событие в таблицу audit_events. Ошибки записи только логируются,
+// чтобы сбой журнала не ломал основной запрос.
+func Record(db *gorm.DB, e Event) {
+	record := models.AuditEvent{
+		Action:     e.Action,
+		ActorID:    e.ActorID,
+		UserID:     e.UserID,
+		Email:      e.Email,
+		TargetType: e.TargetType,
+		IP:         e.IP,
+		UserAgent:  e.UserAgent,
+		RequestID:  e.RequestID,
+		Before:     toJSON(e.Before),
+		After:      toJSON(e.After),
+	}
+	if e.TargetID != nil {
+		record.TargetID = fmt.Sprint(e.TargetID)
+	}
+	if len(e.Details) > 0 {
+		record.Details = toJSON(e.Details)
+	}
+	if err := db.Create(&record).Error; err != nil {
+		log.Printf("Ошибка записи события аудита %s: %v", e.Action, err)
+	}
+}
+
+// ScrubUser удаляет из журнала персональные данные удалённого пользователя: email, IP и User-Agent событий,
+// где он автор или субъект, и событий с его email (например, неудачных входов). Это единственное разрешённое
+// изменение журнала, поэтому запрос выполняется в обход хуков модели AuditEvent; остальные поля не меняются.
+func ScrubUser(db *gorm.DB, userID uint, email string) error {
+	return db.Exec(`UPDATE audit_events SET email = '', ip = '', user_agent = ''
+		WHERE user_id = ? OR actor_id = ? OR (email <> '' AND LOWER(email) = LOWER(?))`,
+		userID, userID, email).Error
+}
+
+func toJSON(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
@@ -1,17 +1,43 @@
 package auth
 
 import (
+	"errors"
 	"net/http"
 	"strings"
-	"test_hack/internal/handlers"
+	"test_hack/internal/config"
 	"test_hack/internal/response"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 )
 
+// ErrSessionRevoked возвращается, если сессия токена завершена или истекла.
+var ErrSessionRevoked = errors.New("сессия завершена")
+
+// SessionChecker проверяет, что сессия, для которой выпущен токен, ещё действует.
+// sessionID равен 0 для токенов, выпущенных до появления сессий.
+type SessionChecker interface {
+	CheckSession(userID, sessionID uint) error
+}
+
+// Authenticator проверяет access токены и роли пользователей.
+type Authenticator struct {
+	// AccessSecret — ключ подписи access токенов.
+	AccessSecret []byte
+	DB           *gorm.DB
+	Sessions     SessionChecker
+	// MFA — роли, которым RequireRole не даёт доступа без подключённой 2FA.
+	MFA config.MFA
+}
+
+// New создаёт Authenticator.
+func New(accessSecret []byte, db *gorm.DB, sessions SessionChecker) *Authenticator {
+	return &Authenticator{AccessSecret: accessSecret, DB: db, Sessions: sessions}
+}
+
 // AuthMiddleware проверяет валидность access токена
-func AuthMiddleware() gin.HandlerFunc {
+func (a *Authenticator) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -25,7 +51,7 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return handlers.AccessSecret, nil
+			return a.AccessSecret, nil
 		})
 
 		if err != nil || !token.Valid {
@@ -57,7 +83,27 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Токены без sid выпущены до появления сессий.
+		sessionID, _ := claims["sid"].(float64)
+		if err := a.Sessions.CheckSession(uint(userID), uint(sessionID)); err != nil {
+			if errors.Is(err, ErrSessionRevoked) {
+				c.JSON(http.StatusUnauthorized, response.ErrorResponse{
+					Code:    "SESSION_REVOKED",
+					Message: "Сессия завершена, войдите заново",
+				})
+			} else {
+				c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+					Code:    "DB_ERROR",
+					Message: "Ошибка при проверке сессии",
+					Details: err.Error(),
+				})
+			}
+			c.Abort()
+			return
+		}
+
 		c.Set("userID", uint(userID))
+		c.Set("sessionID", uint(sessionID))
 		c.Next()
 	}
 }
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"net/http"
+	"test_hack/internal/models"
+	"test_hack/internal/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole пропускает запрос только если роль пользователя входит в список разрешённых.
+// Если роль входит в MFA.RequiredRoles, пользователь без подключённой 2FA получает 403 MFA_ENROLLMENT_REQUIRED.
+// Должен подключаться после AuthMiddleware.
+func (a *Authenticator) RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetUint("userID")
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, response.ErrorResponse{
+				Code:    "UNAUTHORIZED",
+				Message: "Ошибка авторизации",
+			})
+			c.Abort()
+			return
+		}
+
+		var user models.User
+		if err := a.DB.Select("id", "role", "totp_enabled").First(&user, userID).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, response.ErrorResponse{
+				Code:    "USER_NOT_FOUND",
+				Message: "Пользователь не найден",
+			})
+			c.Abort()
+			return
+		}
+
+		for _, role := range roles {
+			if user.Role == role {
+				if a.MFA.Required(user.Role) && !user.TOTPEnabled {
+					c.JSON(http.StatusForbidden, response.ErrorResponse{
+						Code:    "MFA_ENROLLMENT_REQUIRED",
+						Message: "Для вашей роли нужно подключить двухфакторную аутентификацию в профиле",
+					})
+					c.Abort()
+					return
+				}
+				c.Set("userRole", user.Role)
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, response.ErrorResponse{
+			Code:    "FORBIDDEN",
+			Message: "Недостаточно прав для выполнения операции",
+		})
+		c.Abort()
+	}
+}
@@ -0,0 +1,436 @@
+// Package config загружает настройки приложения. Источники применяются по порядку, каждый следующий
+// переопределяет предыдущий: значения по умолчанию, YAML-файл (если задан), переменные окружения
+// и файл .env. После загрузки настройки проверяются, и приложение не стартует с некорректной конфигурацией.
+//
+// Имена переменных окружения составляются из тегов env: у вложенной структуры тег задаёт префикс,
+// у поля — окончание, например DB_ + HOST = DB_HOST.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config — настройки приложения.
+type Config struct {
+	Server   Server   `yaml:"server" env:""`
+	Database Database `yaml:"database" env:"DB_"`
+	// TestDatabase и TestRedis — база и Redis для интеграционных тестов; при запуске сервера не используются
+	// и не проверяются. Пустой адрес TestRedis — сервер из REDIS_ADDR.
+	TestDatabase Database   `yaml:"test_database" env:"TEST_DB_"`
+	TestRedis    Redis      `yaml:"test_redis" env:"TEST_REDIS_"`
+	Migrations   Migrations `yaml:"migrations" env:"MIGRATE_"`
+	Redis        Redis      `yaml:"redis" env:"REDIS_"`
+	JWT          JWT        `yaml:"jwt" env:"JWT_"`
+	Cache        Cache      `yaml:"cache" env:"CACHE_"`
+	Timetable    Timetable  `yaml:"timetable" env:"TIMETABLE_"`
+	Mail         Mail       `yaml:"mail" env:""`
+	Accounts     Accounts   `yaml:"accounts" env:""`
+	MFA          MFA        `yaml:"mfa" env:"MFA_"`
+	OIDC         OIDC       `yaml:"oidc" env:"OIDC_"`
+	Telegram     Telegram   `yaml:"telegram" env:"TELEGRAM_"`
+	WebPush      WebPush    `yaml:"webpush" env:"VAPID_"`
+	RateLimit    RateLimit  `yaml:"rate_limit" env:"RATE_LIMIT_"`
+	Export       Export     `yaml:"export" env:"EXPORT_"`
+	Metrics      Metrics    `yaml:"metrics" env:"METRICS_"`
+}
+
+// Server — настройки HTTP-сервера.
+type Server struct {
+	// Port — порт HTTP-сервера (PORT).
+	Port int `yaml:"port" env:"PORT"`
+	// CORSOrigins — источники, которым разрешены запросы из браузера, через запятую (CORS_ORIGINS).
+	// "*" разрешает любые источники.
+	CORSOrigins []string `yaml:"cors_origins" env:"CORS_ORIGINS"`
+	// ShutdownTimeout — сколько при остановке ждать завершения запросов и фоновых задач (SHUTDOWN_TIMEOUT).
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	// WSReconnectAfter — через сколько клиентам WebSocket предлагается переподключиться после остановки
+	// сервера (WS_RECONNECT_AFTER).
+	WSReconnectAfter time.Duration `yaml:"ws_reconnect_after" env:"WS_RECONNECT_AFTER"`
+	// PublicURL — публичный адрес API для ссылок в письмах и адреса возврата SSO (PUBLIC_URL).
+	PublicURL string `yaml:"public_url" env:"PUBLIC_URL"`
+	// TrustedProxies — адреса и подсети обратных прокси через запятую, которым разрешено передавать
+	// адрес клиента в X-Forwarded-For (TRUSTED_PROXIES). Пусто — заголовок не учитывается,
+	// адресом клиента считается адрес соединения.
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+}
+
+// Database — параметры подключения к PostgreSQL.
+type Database struct {
+	Host     string `yaml:"host" env:"HOST"`
+	Port     string `yaml:"port" env:"PORT"`
+	User     string `yaml:"user" env:"USER"`
+	Password string `yaml:"password" env:"PASSWORD"`
+	Name     string `yaml:"name" env:"NAME"`
+	// Schema — схема PostgreSQL для таблиц приложения (DB_SCHEMA); пусто — search_path сервера.
+	// Тесты создают отдельную схему для каждого экземпляра приложения.
+	Schema string `yaml:"schema" env:"SCHEMA"`
+}
+
+// DSN возвращает строку подключения для драйвера postgres.
+func (d Database) DSN() string {
+	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		d.Host, d.Port, d.User, d.Password, d.Name)
+	if d.Schema != "" {
+		dsn += " search_path=" + d.Schema
+	}
+	return dsn
+}
+
+// Migrations — применение миграций схемы базы данных.
+type Migrations struct {
+	// OnStart — применять новые миграции при запуске сервера (MIGRATE_ON_START). Если выключено,
+	// миграции применяются командой migrate up.
+	OnStart bool `yaml:"on_start" env:"ON_START"`
+}
+
+// Redis — параметры подключения к Redis.
+type Redis struct {
+	Addr     string `yaml:"addr" env:"ADDR"`
+	Password string `yaml:"password" env:"PASS"`
+	// DB — номер базы Redis (REDIS_DB).
+	DB int `yaml:"db" env:"DB"`
+}
+
+// JWT — ключи подписи и время жизни токенов.
+type JWT struct {
+	AccessSecret  string        `yaml:"access_secret" env:"ACCESS_SECRET"`
+	RefreshSecret string        `yaml:"refresh_secret" env:"REFRESH_SECRET"`
+	AccessTTL     time.Duration `yaml:"access_ttl" env:"ACCESS_TTL"`
+	// RefreshTTL — время жизни refresh токена и сессии без использования.
+	RefreshTTL time.Duration `yaml:"refresh_ttl" env:"REFRESH_TTL"`
+}
+
+// Cache — время хранения ответов внешнего API в Redis.
+type Cache struct {
+	// GroupsTTL — список групп (CACHE_GROUPS_TTL).
+	GroupsTTL time.Duration `yaml:"groups_ttl" env:"GROUPS_TTL"`
+	// EmptyScheduleTTL — отметка о том, что у группы нет событий на неделю (CACHE_EMPTY_SCHEDULE_TTL).
+	EmptyScheduleTTL time.Duration `yaml:"empty_schedule_ttl" env:"EMPTY_SCHEDULE_TTL"`
+}
+
+// Timetable — внешний API расписания.
+type Timetable struct {
+	// APIURL — базовый адрес API; группы запрашиваются по APIURL/group/, события — по APIURL/event/.
+	APIURL string `yaml:"api_url" env:"API_URL"`
+}
+
+// Mail — отправка писем.
+type Mail struct {
+	// Backend — smtp, file или memory (MAIL_BACKEND). Пустое значение — smtp, если задан SMTP_HOST, иначе file.
+	Backend string `yaml:"backend" env:"MAIL_BACKEND"`
+	// From — адрес отправителя (MAIL_FROM).
+	From string `yaml:"from" env:"MAIL_FROM"`
+	// CaptureDir — каталог для писем при MAIL_BACKEND=file (MAIL_CAPTURE_DIR).
+	CaptureDir string `yaml:"capture_dir" env:"MAIL_CAPTURE_DIR"`
+	SMTP       SMTP   `yaml:"smtp" env:"SMTP_"`
+}
+
+// SMTP — параметры SMTP-сервера. Порт 465 — неявный TLS, остальные — STARTTLS.
+type SMTP struct {
+	Host     string `yaml:"host" env:"HOST"`
+	Port     string `yaml:"port" env:"PORT"`
+	User     string `yaml:"user" env:"USER"`
+	Password string `yaml:"password" env:"PASS"`
+	// Timeout — ограничение на подключение и отправку одного письма (SMTP_TIMEOUT).
+	Timeout time.Duration `yaml:"timeout" env:"TIMEOUT"`
+}
+
+// BackendName возвращает способ отправки писем с учётом значения по умолчанию.
+func (m Mail) BackendName() string {
+	switch {
+	case m.Backend != "":
+		return m.Backend
+	case m.SMTP.Host != "":
+		return "smtp"
+	}
+	return "file"
+}
+
+// Accounts — регистрация и восстановление доступа.
+type Accounts struct {
+	// PasswordResetURL — страница фронтенда, принимающая ?token=... из письма сброса пароля (PASSWORD_RESET_URL).
+	PasswordResetURL string `yaml:"password_reset_url" env:"PASSWORD_RESET_URL"`
+	// RequireEmailVerification — не пускать в очереди пользователей с неподтверждённым email
+	// (REQUIRE_EMAIL_VERIFICATION).
+	RequireEmailVerification bool `yaml:"require_email_verification" env:"REQUIRE_EMAIL_VERIFICATION"`
+	// EmailAllowedDomains — домены, с которых разрешена регистрация, через запятую; поддомены тоже
+	// разрешены. Пустой список — любые домены (EMAIL_ALLOWED_DOMAINS).
+	EmailAllowedDomains []string `yaml:"email_allowed_domains" env:"EMAIL_ALLOWED_DOMAINS"`
+}
+
+// MFA — двухфакторная аутентификация.
+type MFA struct {
+	// Issuer — название сервиса в приложении-аутентификаторе (MFA_ISSUER).
+	Issuer string `yaml:"issuer" env:"ISSUER"`
+	// RequiredRoles — роли, которые обязаны подключить 2FA, через запятую (MFA_REQUIRED_ROLES).
+	RequiredRoles []string `yaml:"required_roles" env:"REQUIRED_ROLES"`
+}
+
+// Required сообщает, обязана ли роль использовать 2FA.
+func (m MFA) Required(role string) bool {
+	for _, r := range m.RequiredRoles {
+		if r == role && role != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// OIDC — вход через провайдера OpenID Connect. Пустой IssuerURL отключает SSO.
+type OIDC struct {
+	IssuerURL    string `yaml:"issuer_url" env:"ISSUER_URL"`
+	ClientID     string `yaml:"client_id" env:"CLIENT_ID"`
+	ClientSecret string `yaml:"client_secret" env:"CLIENT_SECRET"`
+	// RedirectURL — адрес возврата; по умолчанию PUBLIC_URL/auth/oidc/callback (OIDC_REDIRECT_URL).
+	RedirectURL string `yaml:"redirect_url" env:"REDIRECT_URL"`
+	// Scopes — запрашиваемые scope через запятую (OIDC_SCOPES).
+	Scopes []string `yaml:"scopes" env:"SCOPES"`
+	// SuccessURL — страница фронтенда, получающая токены во фрагменте URL; пусто — ответ в JSON (OIDC_SUCCESS_URL).
+	SuccessURL string `yaml:"success_url" env:"SUCCESS_URL"`
+	// AllowSignup — создавать аккаунт при первом входе через SSO (OIDC_ALLOW_SIGNUP).
+	AllowSignup bool `yaml:"allow_signup" env:"ALLOW_SIGNUP"`
+}
+
+// Telegram — бот для уведомлений. Пустой BotToken отключает бота.
+type Telegram struct {
+	BotToken    string `yaml:"bot_token" env:"BOT_TOKEN"`
+	BotUsername string `yaml:"bot_username" env:"BOT_USERNAME"`
+	// APIURL — адрес Bot API; пусто — api.telegram.org (TELEGRAM_API_URL).
+	APIURL string `yaml:"api_url" env:"API_URL"`
+	// WebhookURL — публичный адрес /telegram/webhook; пусто — long polling (TELEGRAM_WEBHOOK_URL).
+	WebhookURL string `yaml:"webhook_url" env:"WEBHOOK_URL"`
+	// WebhookSecret — обязателен в режиме webhook: без него обновления не принимаются (TELEGRAM_WEBHOOK_SECRET).
+	WebhookSecret string `yaml:"webhook_secret" env:"WEBHOOK_SECRET"`
+}
+
+// WebPush — ключи VAPID. Если ключи не заданы, они берутся из базы или генерируются при первом запуске.
+type WebPush struct {
+	PublicKey  string `yaml:"public_key" env:"PUBLIC_KEY"`
+	PrivateKey string `yaml:"private_key" env:"PRIVATE_KEY"`
+	// Subject — контакт администратора для push-сервисов; пусто — mailto:MAIL_FROM (VAPID_SUBJECT).
+	Subject string `yaml:"subject" env:"SUBJECT"`
+}
+
+// Metrics — доступ к метрикам Prometheus.
+type Metrics struct {
+	// Token — токен, который сборщик метрик передаёт в заголовке Authorization: Bearer (METRICS_TOKEN).
+	// Без токена маршрут /metrics не подключается.
+	Token string `yaml:"token" env:"TOKEN"`
+}
+
+// RateLimit — ограничение частоты запросов. Лимиты задаются в формате N/период, например 10/1m;
+// пустое значение — лимит маршрута по умолчанию.
+type RateLimit struct {
+	// Enabled — false отключает все ограничения (RATE_LIMIT_ENABLED).
+	Enabled       bool   `yaml:"enabled" env:"ENABLED"`
+	Auth          string `yaml:"auth" env:"AUTH"`
+	QueueJoin     string `yaml:"queue_join" env:"QUEUE_JOIN"`
+	QueueLeave    string `yaml:"queue_leave" env:"QUEUE_LEAVE"`
+	QueueStatus   string `yaml:"queue_status" env:"QUEUE_STATUS"`
+	ProfileExport string `yaml:"profile_export" env:"PROFILE_EXPORT"`
+	ProfileDelete string `yaml:"profile_delete" env:"PROFILE_DELETE"`
+}
+
+// Limits возвращает заданные лимиты по именам маршрутов.
+func (r RateLimit) Limits() map[string]string {
+	limits := map[string]string{}
+	for name, value := range map[string]string{
+		"auth":           r.Auth,
+		"queue_join":     r.QueueJoin,
+		"queue_leave":    r.QueueLeave,
+		"queue_status":   r.QueueStatus,
+		"profile_export": r.ProfileExport,
+		"profile_delete": r.ProfileDelete,
+	} {
+		if value != "" {
+			limits[name] = value
+		}
+	}
+	return limits
+}
+
+// Export — выгрузка персональных данных.
+type Export struct {
+	// AsyncThreshold — число записей в очередях, начиная с которого выгрузка формируется в фоне
+	// (EXPORT_ASYNC_THRESHOLD).
+	AsyncThreshold int `yaml:"async_threshold" env:"ASYNC_THRESHOLD"`
+}
+
+// Default возвращает настройки по умолчанию.
+func Default() Config {
+	return Config{
+		Server: Server{
+			Port:             8080,
+			CORSOrigins:      []string{"*"},
+			ShutdownTimeout:  30 * time.Second,
+			WSReconnectAfter: 5 * time.Second,
+			PublicURL:        "http://localhost:8080",
+		},
+		Database:   Database{Port: "5432"},
+		Migrations: Migrations{OnStart: true},
+		TestRedis:  Redis{DB: 15},
+		JWT: JWT{
+			AccessTTL:  15 * time.Minute,
+			RefreshTTL: 7 * 24 * time.Hour,
+		},
+		Cache: Cache{
+			GroupsTTL:        6 * time.Hour,
+			EmptyScheduleTTL: 15 * time.Minute,
+		},
+		Timetable: Timetable{APIURL: "https://api.profcomff.com/timetable"},
+		Mail: Mail{
+			From:       "noreply@localhost",
+			CaptureDir: "mail",
+			SMTP:       SMTP{Port: "587", Timeout: 30 * time.Second},
+		},
+		Accounts:  Accounts{PasswordResetURL: "http://localhost:3000/reset-password"},
+		MFA:       MFA{Issuer: "PracticeQueue"},
+		OIDC:      OIDC{AllowSignup: true},
+		RateLimit: RateLimit{Enabled: true},
+		Export:    Export{AsyncThreshold: 500},
+	}
+}
+
+// Addr возвращает адрес, который слушает HTTP-сервер.
+func (c Config) Addr() string {
+	return fmt.Sprintf(":%d", c.Server.Port)
+}
+
+// Validate проверяет обязательные значения и допустимые диапазоны. Возвращает все найденные ошибки сразу.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "PORT: недопустимый порт %d", c.Server.Port)
+	check(len(c.Server.CORSOrigins) > 0, "CORS_ORIGINS: не задан ни один источник")
+	check(c.Server.ShutdownTimeout > 0, "SHUTDOWN_TIMEOUT: должен быть больше нуля")
+	check(c.Server.WSReconnectAfter >= 0, "WS_RECONNECT_AFTER: не может быть отрицательным")
+	for _, proxy := range c.Server.TrustedProxies {
+		check(isIPOrCIDR(proxy), "TRUSTED_PROXIES: ожидается IP-адрес или подсеть, получено %q", proxy)
+	}
+
+	check(c.Database.Host != "", "DB_HOST: не задан")
+	check(c.Database.User != "", "DB_USER: не задан")
+	check(c.Database.Name != "", "DB_NAME: не задан")
+	check(c.Database.Schema == "" || isIdentifier(c.Database.Schema),
+		"DB_SCHEMA: ожидается имя из строчных латинских букв, цифр и _, получено %q", c.Database.Schema)
+
+	check(c.Redis.Addr != "", "REDIS_ADDR: не задан")
+
+	check(c.JWT.AccessSecret != "", "JWT_ACCESS_SECRET: не задан")
+	check(c.JWT.RefreshSecret != "", "JWT_REFRESH_SECRET: не задан")
+	check(c.JWT.AccessSecret == "" || c.JWT.AccessSecret != c.JWT.RefreshSecret,
+		"JWT_REFRESH_SECRET: должен отличаться от JWT_ACCESS_SECRET")
+	check(c.JWT.AccessTTL > 0, "JWT_ACCESS_TTL: должен быть больше нуля")
+	check(c.JWT.RefreshTTL > c.JWT.AccessTTL, "JWT_REFRESH_TTL: должен быть больше JWT_ACCESS_TTL")
+
+	check(c.Cache.GroupsTTL > 0, "CACHE_GROUPS_TTL: должен быть больше нуля")
+	check(c.Cache.EmptyScheduleTTL > 0, "CACHE_EMPTY_SCHEDULE_TTL: должен быть больше нуля")
+
+	checkURL := func(name, value string) {
+		check(isHTTPURL(value), "%s: ожидается адрес http(s), получено %q", name, value)
+	}
+	checkOptionalURL := func(name, value string) {
+		if value != "" {
+			checkURL(name, value)
+		}
+	}
+
+	checkURL("TIMETABLE_API_URL", c.Timetable.APIURL)
+	checkURL("PUBLIC_URL", c.Server.PublicURL)
+	checkURL("PASSWORD_RESET_URL", c.Accounts.PasswordResetURL)
+
+	switch backend := c.Mail.BackendName(); backend {
+	case "smtp":
+		check(c.Mail.SMTP.Host != "", "SMTP_HOST: не задан при MAIL_BACKEND=smtp")
+		port, err := strconv.Atoi(c.Mail.SMTP.Port)
+		check(err == nil && port > 0 && port <= 65535, "SMTP_PORT: недопустимый порт %q", c.Mail.SMTP.Port)
+		check(c.Mail.SMTP.Timeout > 0, "SMTP_TIMEOUT: должен быть больше нуля")
+	case "file":
+		check(c.Mail.CaptureDir != "", "MAIL_CAPTURE_DIR: не задан при MAIL_BACKEND=file")
+	case "memory":
+	default:
+		check(false, "MAIL_BACKEND: ожидается smtp, file или memory, получено %q", backend)
+	}
+	check(strings.Contains(c.Mail.From, "@"), "MAIL_FROM: ожидается адрес email, получено %q", c.Mail.From)
+
+	check(c.MFA.Issuer != "", "MFA_ISSUER: не задан")
+
+	if c.OIDC.IssuerURL != "" {
+		checkURL("OIDC_ISSUER_URL", c.OIDC.IssuerURL)
+		check(c.OIDC.ClientID != "", "OIDC_CLIENT_ID: не задан при заданном OIDC_ISSUER_URL")
+		checkOptionalURL("OIDC_REDIRECT_URL", c.OIDC.RedirectURL)
+		checkOptionalURL("OIDC_SUCCESS_URL", c.OIDC.SuccessURL)
+	}
+
+	if c.Telegram.BotToken != "" {
+		checkOptionalURL("TELEGRAM_API_URL", c.Telegram.APIURL)
+		checkOptionalURL("TELEGRAM_WEBHOOK_URL", c.Telegram.WebhookURL)
+		check(c.Telegram.WebhookURL == "" || c.Telegram.WebhookSecret != "",
+			"TELEGRAM_WEBHOOK_SECRET: не задан при заданном TELEGRAM_WEBHOOK_URL")
+	}
+
+	check((c.WebPush.PublicKey == "") == (c.WebPush.PrivateKey == ""),
+		"VAPID_PUBLIC_KEY, VAPID_PRIVATE_KEY: задаются вместе")
+
+	limits := c.RateLimit.Limits()
+	names := make([]string, 0, len(limits))
+	for name := range limits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		check(isLimit(limits[name]), "RATE_LIMIT_%s: ожидается формат N/период, получено %q", strings.ToUpper(name), limits[name])
+	}
+
+	check(c.Export.AsyncThreshold >= 0, "EXPORT_ASYNC_THRESHOLD: не может быть отрицательным")
+
+	return errors.Join(errs...)
+}
+
+// isIdentifier проверяет, что value можно подставить в SQL как имя без кавычек.
+func isIdentifier(value string) bool {
+	for i, r := range value {
+		if !(r == '_' || r >= 'a' && r <= 'z' || i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return value != ""
+}
+
+func isIPOrCIDR(value string) bool {
+	if _, _, err := net.ParseCIDR(value); err == nil {
+		return true
+	}
+	return net.ParseIP(value) != nil
+}
+
+func isHTTPURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// isLimit проверяет лимит в формате N/период, например 10/1m.
+func isLimit(value string) bool {
+	n, period, ok := strings.Cut(value, "/")
+	if !ok {
+		return false
+	}
+	count, err := strconv.Atoi(n)
+	if err != nil || count <= 0 {
+		return false
+	}
+	d, err := time.ParseDuration(period)
+	return err == nil && d > 0
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Load загружает и проверяет настройки. yamlPath — путь к YAML-файлу; пустая строка — без файла.
+// envFiles — файлы в формате .env (по умолчанию ".env"); отсутствующие файлы пропускаются.
+// Переменные из .env не заменяют уже заданные переменные окружения.
+func Load(yamlPath string, envFiles ...string) (Config, error) {
+	cfg, err := Read(yamlPath, envFiles...)
+	if err != nil {
+		return cfg, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("некорректная конфигурация:\n%w", err)
+	}
+	return cfg, nil
+}
+
+// Read загружает настройки так же, как Load, но не проверяет их.
+func Read(yamlPath string, envFiles ...string) (Config, error) {
+	cfg := Default()
+
+	if yamlPath != "" {
+		data, err := os.ReadFile(yamlPath)
+		if err != nil {
+			return cfg, fmt.Errorf("чтение %s: %w", yamlPath, err)
+		}
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return cfg, fmt.Errorf("разбор %s: %w", yamlPath, err)
+		}
+	}
+
+	if len(envFiles) == 0 {
+		envFiles = []string{".env"}
+	}
+	// Файлы .env читаются в карту, а не в окружение процесса: чтение настроек не должно менять
+	// состояние, общее для всех экземпляров приложения в процессе.
+	fileEnv := map[string]string{}
+	for _, name := range envFiles {
+		values, err := godotenv.Read(name)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return cfg, fmt.Errorf("чтение %s: %w", name, err)
+		}
+		for key, value := range values {
+			// Как и godotenv.Load, первый файл имеет приоритет над следующими.
+			if _, ok := fileEnv[key]; !ok {
+				fileEnv[key] = value
+			}
+		}
+	}
+	lookup := func(name string) (string, bool) {
+		if value, ok := os.LookupEnv(name); ok {
+			return value, true
+		}
+		value, ok := fileEnv[name]
+		return value, ok
+	}
+
+	if err := applyEnv(reflect.ValueOf(&cfg).Elem(), "", lookup); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// applyEnv записывает в поля структуры v значения переменных, которые находит lookup.
+func applyEnv(v reflect.Value, prefix string, lookup func(string) (string, bool)) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag, ok := t.Field(i).Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			if err := applyEnv(field, prefix+tag, lookup); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := prefix + tag
+		raw, ok := lookup(name)
+		if !ok {
+			continue
+		}
+		if err := setField(field, strings.TrimSpace(raw)); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func setField(field reflect.Value, raw string) error {
+	switch {
+	case field.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("ожидается длительность вида 15m или 24h, получено %q", raw)
+		}
+		field.SetInt(int64(d))
+	case field.Kind() == reflect.String:
+		field.SetString(raw)
+	case field.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("ожидается целое число, получено %q", raw)
+		}
+		field.SetInt(int64(n))
+	case field.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("ожидается true или false, получено %q", raw)
+		}
+		field.SetBool(b)
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("неподдерживаемый тип поля %s", field.Type())
+	}
+	return nil
+}
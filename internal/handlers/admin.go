@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"test_hack/internal/audit"
+	"test_hack/internal/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UpdateRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=student teacher admin"`
+}
+
+// UpdateUserRoleHandler изменяет роль пользователя
+// @Summary		Изменение роли пользователя
+// @Description	Назначает пользователю роль student, teacher или admin
+// @Tags			admin
+// @Accept			json
+// @Produce		json
+// @Param			id		path	int					true	"ID пользователя"
+// @Param			body	body	UpdateRoleRequest	true	"Новая роль"
+// @Security		BearerAuth
+// @Success		200	{object}	response.ProfileResponse	"Роль обновлена"
+// @Failure		400	{object}	response.ErrorResponse	"Ошибка валидации (INVALID_USER_ID, VALIDATION_ERROR)"
+// @Failure		403	{object}	response.ErrorResponse	"Недостаточно прав (FORBIDDEN)"
+// @Failure		404	{object}	response.ErrorResponse	"Пользователь не найден (USER_NOT_FOUND)"
+// @Failure		500	{object}	response.ErrorResponse	"Ошибка сервера (DB_ERROR)"
+// @Router			/admin/users/{id}/role [put]
+func (h *Handler) UpdateUserRoleHandler(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "INVALID_USER_ID",
+			Message: "Неверный идентификатор пользователя",
+		})
+		return
+	}
+
+	var req UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "VALIDATION_ERROR",
+			Message: "Ошибка валидации данных",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	user, err := h.Users().FindByID(uint(userID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, response.ErrorResponse{
+			Code:    "USER_NOT_FOUND",
+			Message: "Пользователь не найден",
+		})
+		return
+	}
+
+	previousRole := user.Role
+	if err := h.Users().Update(user.ID, map[string]interface{}{"role": req.Role}); err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка при изменении роли",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	user.Role = req.Role
+
+	audit.RecordRequest(h.DB, c, audit.Event{
+		Action:     audit.ActionRoleChanged,
+		UserID:     &user.ID,
+		Email:      user.Email,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+		Before:     map[string]interface{}{"role": previousRole},
+		After:      map[string]interface{}{"role": req.Role},
+	})
+	c.JSON(http.StatusOK, h.profileResponse(user))
+}
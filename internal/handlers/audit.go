@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"test_hack/internal/models"
+	"test_hack/internal/response"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditEventResponse — запись журнала аудита.
+type AuditEventResponse struct {
+	ID         uint                   `json:"id"`
+	CreatedAt  time.Time              `json:"created_at"`
+	Action     string                 `json:"action" example:"queue.left"`
+	ActorID    *uint                  `json:"actor_id,omitempty"`
+	UserID     *uint                  `json:"user_id,omitempty"`
+	Email      string                 `json:"email,omitempty"`
+	TargetType string                 `json:"target_type,omitempty" example:"queue"`
+	TargetID   string                 `json:"target_id,omitempty" example:"15"`
+	Before     interface{}            `json:"before,omitempty" swaggertype:"object"`
+	After      interface{}            `json:"after,omitempty" swaggertype:"object"`
+	IP         string                 `json:"ip,omitempty"`
+	UserAgent  string                 `json:"user_agent,omitempty"`
+	RequestID  string                 `json:"request_id,omitempty"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+}
+
+func toAuditEventResponse(e models.AuditEvent) AuditEventResponse {
+	resp := AuditEventResponse{
+		ID:         e.ID,
+		CreatedAt:  e.CreatedAt,
+		Action:     e.Action,
+		ActorID:    e.ActorID,
+		UserID:     e.UserID,
+		Email:      e.Email,
+		TargetType: e.TargetType,
+		TargetID:   e.TargetID,
+		IP:         e.IP,
+		UserAgent:  e.UserAgent,
+		RequestID:  e.RequestID,
+	}
+	if e.Before != "" {
+		json.Unmarshal([]byte(e.Before), &resp.Before)
+	}
+	if e.After != "" {
+		json.Unmarshal([]byte(e.After), &resp.After)
+	}
+	if e.Details != "" {
+		json.Unmarshal([]byte(e.Details), &resp.Details)
+	}
+	return resp
+}
+
+// ListAuditEventsHandler возвращает записи журнала аудита
+// @Summary		Журнал аудита
+// @Description	Возвращает события журнала аудита от новых к старым. Фильтр action принимает несколько типов через запятую; значение с точкой на конце (например, queue.) выбирает все события раздела. Для следующей страницы передайте before_id — ID последней полученной записи
+// @Tags			admin
+// @Produce		json
+// @Param			action		query	string	false	"Типы событий через запятую, например queue.joined,queue.left или queue."
+// @Param			actor_id	query	int		false	"Кто выполнил действие"
+// @Param			user_id		query	int		false	"Пользователь, к которому относится событие"
+// @Param			target_type	query	string	false	"Тип объекта: queue, user, webhook, job"
+// @Param			target_id	query	string	false	"ID объекта"
+// @Param			request_id	query	string	false	"ID запроса (заголовок X-Request-ID)"
+// @Param			ip			query	string	false	"IP-адрес"
+// @Param			from		query	string	false	"Начало периода (RFC 3339)"
+// @Param			to			query	string	false	"Конец периода (RFC 3339)"
+// @Param			before_id	query	int		false	"Вернуть записи с ID меньше указанного"
+// @Param			limit		query	int		false	"Количество записей (по умолчанию 50, максимум 500)"
+// @Security		BearerAuth
+// @Success		200	{array}		AuditEventResponse		"События"
+// @Failure		400	{object}	response.ErrorResponse	"Ошибка валидации (VALIDATION_ERROR)"
+// @Failure		403	{object}	response.ErrorResponse	"Недостаточно прав (FORBIDDEN)"
+// @Failure		500	{object}	response.ErrorResponse	"Ошибка сервера (DB_ERROR)"
+// @Router			/admin/audit-events [get]
+func (h *Handler) ListAuditEventsHandler(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 50
+	}
+
+	query := h.DB.Model(&models.AuditEvent{})
+	if actions := c.Query("action"); actions != "" {
+		var exact []string
+		var conds []string
+		var args []interface{}
+		for _, a := range strings.Split(actions, ",") {
+			a = strings.TrimSpace(a)
+			switch {
+			case a == "":
+			case strings.HasSuffix(a, "."):
+				conds = append(conds, "action LIKE ?")
+				args = append(args, a+"%")
+			default:
+				exact = append(exact, a)
+			}
+		}
+		if len(exact) > 0 {
+			conds = append(conds, "action IN ?")
+			args = append(args, exact)
+		}
+		if len(conds) > 0 {
+			query = query.Where(strings.Join(conds, " OR "), args...)
+		}
+	}
+	for _, column := range []string{"actor_id", "user_id"} {
+		if value := c.Query(column); value != "" {
+			id, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, response.ErrorResponse{
+					Code:    "VALIDATION_ERROR",
+					Message: "Неверное значение параметра " + column,
+				})
+				return
+			}
+			query = query.Where(column+" = ?", id)
+		}
+	}
+	if value := c.Query("before_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, response.ErrorResponse{
+				Code:    "VALIDATION_ERROR",
+				Message: "Неверное значение параметра before_id",
+			})
+			return
+		}
+		query = query.Where("id < ?", id)
+	}
+	for _, column := range []string{"target_type", "target_id", "request_id", "ip"} {
+		if value := c.Query(column); value != "" {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+	for param, op := range map[string]string{"from": ">=", "to": "<="} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, response.ErrorResponse{
+				Code:    "VALIDATION_ERROR",
+				Message: "Неверный формат даты в параметре " + param + ", ожидается RFC 3339",
+			})
+			return
+		}
+		query = query.Where("created_at "+op+" ?", t)
+	}
+
+	var events []models.AuditEvent
+	if err := query.Order("id DESC").Limit(limit).Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка загрузки журнала аудита",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	result := make([]AuditEventResponse, 0, len(events))
+	for _, e := range events {
+		result = append(result, toAuditEventResponse(e))
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -1,21 +1,20 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
-	"os"
+	"strings"
+	"test_hack/internal/audit"
 	"test_hack/internal/models"
+	"test_hack/internal/notify"
 	"test_hack/internal/response"
-	"test_hack/internal/storage"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
-)
-
-var (
-	AccessSecret  = []byte(os.Getenv("JWT_ACCESS_SECRET"))
-	refreshSecret = []byte(os.Getenv("JWT_REFRESH_SECRET"))
+	"gorm.io/gorm"
 )
 
 type RegisterRequest struct {
@@ -23,6 +22,8 @@ type RegisterRequest struct {
 	Surname  string `json:"surname" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
+	GroupID  string `json:"group_id"`                                 // ID учебной группы из /groups (необязательно)
+	Language string `json:"language" binding:"omitempty,oneof=ru en"` // Язык писем и уведомлений, по умолчанию ru
 }
 
 type LoginRequest struct {
@@ -31,16 +32,16 @@ type LoginRequest struct {
 }
 
 // @Summary		Регистрация пользователя
-// @Description	Регистрация нового пользователя
+// @Description	Регистрация нового пользователя. На email отправляется ссылка для подтверждения адреса
 // @Tags			auth
 // @Accept			json
 // @Produce		json
 // @Param			user	body		RegisterRequest				true	"Данные пользователя"
 // @Success		201		{object}	response.SuccessResponse	"Успешная регистрация"
-// @Failure		400		{object}	response.ErrorResponse		"Ошибка валидации (VALIDATION_ERROR) или пользователь уже существует (EMAIL_EXISTS)"
+// @Failure		400		{object}	response.ErrorResponse		"Ошибка валидации (VALIDATION_ERROR), запрещённый домен (EMAIL_DOMAIN_NOT_ALLOWED) или пользователь уже существует (EMAIL_EXISTS)"
 // @Failure		500		{object}	response.ErrorResponse		"Ошибка сервера (PASSWORD_HASH_ERROR, DB_ERROR)"
 // @Router			/auth/register [post]
-func Register(c *gin.Context) {
+func (h *Handler) Register(c *gin.Context) {
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, response.ErrorResponse{
@@ -50,9 +51,18 @@ func Register(c *gin.Context) {
 		})
 		return
 	}
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+
+	if !h.emailDomainAllowed(req.Email) {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "EMAIL_DOMAIN_NOT_ALLOWED",
+			Message: "Регистрация с этим почтовым доменом запрещена",
+			Details: strings.Join(h.Config.Accounts.EmailAllowedDomains, ","),
+		})
+		return
+	}
 
-	var existingUser models.User
-	if err := storage.DB.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
+	if _, err := h.Users().FindByEmail(req.Email); err == nil {
 		c.JSON(http.StatusBadRequest, response.ErrorResponse{
 			Code:    "EMAIL_EXISTS",
 			Message: "Пользователь с таким email уже существует",
@@ -74,9 +84,23 @@ func Register(c *gin.Context) {
 		Surname:      req.Surname,
 		Email:        req.Email,
 		PasswordHash: string(hashedPassword),
+		Role:         models.RoleStudent,
+		GroupID:      req.GroupID,
+		Language:     req.Language,
+	}
+	if user.Language == "" {
+		user.Language = notify.DefaultLang
 	}
 
-	if err := storage.DB.Create(&user).Error; err != nil {
+	if err := h.DB.Create(&user).Error; err != nil {
+		// Параллельная регистрация с тем же email успела раньше — см. idx_users_email_lower.
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			c.JSON(http.StatusBadRequest, response.ErrorResponse{
+				Code:    "EMAIL_EXISTS",
+				Message: "Пользователь с таким email уже существует",
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
 			Code:    "DB_ERROR",
 			Message: "Ошибка при создании пользователя",
@@ -84,23 +108,29 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	if err := h.sendVerificationEmail(user); err != nil {
+		log.Printf("Ошибка отправки письма подтверждения (user_id=%d): %v", user.ID, err)
+	}
+
 	c.JSON(http.StatusCreated, response.SuccessResponse{
-		Message: "Пользователь успешно зарегистрирован",
+		Message: "Пользователь успешно зарегистрирован. Подтвердите email по ссылке из письма",
 	})
 }
 
 // @Summary		Авторизация пользователя
-// @Description	Авторизация пользователя и получение токенов
+// @Description	Авторизация пользователя и получение токенов. Если у пользователя подключена 2FA, вместо токенов возвращается токен второго шага (202). После 3 неудачных попыток для email следующие возможны с нарастающей паузой, после 5 — вход по email блокируется на 15 минут; после 20 неудач с одного IP блокируется IP
 // @Tags			auth
 // @Accept			json
 // @Produce		json
 // @Param			user	body		LoginRequest			true	"Данные для авторизации"
-// @Success		200		{object}	response.TokenResponse	"Успешная авторизация"
+// @Success		200		{object}	response.TokenResponse			"Успешная авторизация"
+// @Success		202		{object}	response.MFAChallengeResponse	"Пароль верный, требуется код 2FA через /auth/login/mfa"
 // @Failure		400		{object}	response.ErrorResponse	"Ошибка валидации данных (VALIDATION_ERROR)"
 // @Failure		401		{object}	response.ErrorResponse	"Неверные учетные данные (INVALID_CREDENTIALS)"
+// @Failure		429		{object}	response.ErrorResponse	"Вход временно заблокирован (LOCKED_OUT) или слишком частые попытки (TOO_MANY_ATTEMPTS); заголовок Retry-After содержит паузу в секундах"
 // @Failure		500		{object}	response.ErrorResponse	"Ошибка сервера (TOKEN_GENERATION_ERROR)"
 // @Router			/auth/login [post]
-func Login(c *gin.Context) {
+func (h *Handler) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, response.ErrorResponse{
@@ -110,9 +140,32 @@ func Login(c *gin.Context) {
 		})
 		return
 	}
+	// Счётчики неудачных попыток и поиск пользователя не зависят от регистра email.
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+
+	ip := c.ClientIP()
+	if block := h.checkLoginAllowed(ip, req.Email); block != nil {
+		audit.RecordRequest(h.DB, c, audit.Event{
+			Action:    audit.ActionLoginLocked,
+			Email:     req.Email,
+			IP:        ip,
+			UserAgent: c.Request.UserAgent(),
+			Details:   map[string]interface{}{"code": block.Code},
+		})
+		respondLoginBlocked(c, block)
+		return
+	}
 
-	var user models.User
-	if err := storage.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
+	user, err := h.Users().FindByEmail(req.Email)
+	if err != nil {
+		failures := h.registerLoginFailure(ip, req.Email)
+		audit.RecordRequest(h.DB, c, audit.Event{
+			Action:    audit.ActionLoginFailed,
+			Email:     req.Email,
+			IP:        ip,
+			UserAgent: c.Request.UserAgent(),
+			Details:   map[string]interface{}{"reason": "user_not_found", "failures": failures},
+		})
 		c.JSON(http.StatusUnauthorized, response.ErrorResponse{
 			Code:    "INVALID_CREDENTIALS",
 			Message: "Неверный email или пароль",
@@ -121,40 +174,75 @@ func Login(c *gin.Context) {
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		failures := h.registerLoginFailure(ip, req.Email)
+		audit.RecordRequest(h.DB, c, audit.Event{
+			Action:    audit.ActionLoginFailed,
+			UserID:    &user.ID,
+			Email:     req.Email,
+			IP:        ip,
+			UserAgent: c.Request.UserAgent(),
+			Details:   map[string]interface{}{"reason": "invalid_password", "failures": failures},
+		})
 		c.JSON(http.StatusUnauthorized, response.ErrorResponse{
 			Code:    "INVALID_CREDENTIALS",
 			Message: "Неверный email или пароль",
 		})
 		return
 	}
-
-	accessToken, err := generateToken(user.ID, time.Minute*15, AccessSecret)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
-			Code:    "TOKEN_GENERATION_ERROR",
-			Message: "Ошибка при генерации access токена",
+	// С подключённой 2FA неудачи сбрасываются только после верного кода в LoginMFA: иначе каждый вход
+	// с известным паролем давал бы новые попытки подобрать код.
+	if user.TOTPEnabled {
+		mfaToken, err := h.generateMFAChallenge(user, models.SessionMethodPassword)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+				Code:    "TOKEN_GENERATION_ERROR",
+				Message: "Ошибка при генерации токена второго шага входа",
+			})
+			return
+		}
+		c.JSON(http.StatusAccepted, response.MFAChallengeResponse{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+			ExpiresIn:   int(mfaChallengeTTL.Seconds()),
 		})
 		return
 	}
 
-	refreshToken, err := generateToken(user.ID, time.Hour*24*7, refreshSecret)
+	h.resetLoginFailures(req.Email)
+	h.issueTokens(c, user, models.SessionMethodPassword)
+}
+
+// issueTokens открывает сессию и отвечает парой access и refresh токенов для пользователя, завершившего вход
+// способом method (models.SessionMethod*).
+func (h *Handler) issueTokens(c *gin.Context, user models.User, method string) {
+	tokens, err := h.startSession(c, user, method)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
 			Code:    "TOKEN_GENERATION_ERROR",
-			Message: "Ошибка при генерации refresh токена",
+			Message: "Ошибка при генерации токенов",
 		})
 		return
 	}
+	c.JSON(http.StatusOK, tokens)
+}
 
-	c.JSON(http.StatusOK, response.TokenResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-	})
+// newTokenPair выпускает access и refresh токены сессии пользователя.
+func (h *Handler) newTokenPair(user models.User, sessionID uint) (response.TokenResponse, error) {
+	accessToken, err := generateToken(user.ID, sessionID, h.Config.JWT.AccessTTL, []byte(h.Config.JWT.AccessSecret))
+	if err != nil {
+		return response.TokenResponse{}, err
+	}
+	refreshToken, err := h.generateRefreshToken(user, sessionID)
+	if err != nil {
+		return response.TokenResponse{}, err
+	}
+	return response.TokenResponse{AccessToken: accessToken, RefreshToken: refreshToken}, nil
 }
 
-func generateToken(userID uint, duration time.Duration, secret []byte) (string, error) {
+func generateToken(userID, sessionID uint, duration time.Duration, secret []byte) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": userID,
+		"sid":     sessionID,
 		"exp":     time.Now().Add(duration).Unix(),
 		"iat":     time.Now().Unix(),
 	}
@@ -162,12 +250,26 @@ func generateToken(userID uint, duration time.Duration, secret []byte) (string,
 	return token.SignedString(secret)
 }
 
+// generateRefreshToken выпускает refresh токен с текущей версией токенов пользователя.
+// При увеличении User.TokenVersion (например, после сброса пароля) все ранее выданные refresh токены перестают действовать.
+func (h *Handler) generateRefreshToken(user models.User, sessionID uint) (string, error) {
+	claims := jwt.MapClaims{
+		"user_id": user.ID,
+		"sid":     sessionID,
+		"ver":     user.TokenVersion,
+		"exp":     time.Now().Add(h.Config.JWT.RefreshTTL).Unix(),
+		"iat":     time.Now().Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(h.Config.JWT.RefreshSecret))
+}
+
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
 // @Summary		Обновление access токена
-// @Description	Обновление access токена с помощью refresh токена
+// @Description	Обновление access токена с помощью refresh токена. Продлевает сессию, к которой относится токен; токены завершённой сессии отклоняются
 // @Tags			auth
 // @Accept			json
 // @Produce		json
@@ -177,7 +279,7 @@ type RefreshTokenRequest struct {
 // @Failure		401				{object}	response.ErrorResponse	"Неверный или просроченный refresh токен (INVALID_REFRESH_TOKEN) или пользователь не найден (USER_NOT_FOUND)"
 // @Failure		500				{object}	response.ErrorResponse	"Ошибка сервера (TOKEN_GENERATION_ERROR)"
 // @Router			/auth/refresh [post]
-func RefreshToken(c *gin.Context) {
+func (h *Handler) RefreshToken(c *gin.Context) {
 	var req RefreshTokenRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, response.ErrorResponse{
@@ -189,7 +291,7 @@ func RefreshToken(c *gin.Context) {
 	}
 
 	token, err := jwt.Parse(req.RefreshToken, func(token *jwt.Token) (interface{}, error) {
-		return refreshSecret, nil
+		return []byte(h.Config.JWT.RefreshSecret), nil
 	})
 	if err != nil || !token.Valid {
 		c.JSON(http.StatusUnauthorized, response.ErrorResponse{
@@ -219,8 +321,8 @@ func RefreshToken(c *gin.Context) {
 
 	userID := uint(userIDFloat)
 
-	var user models.User
-	if err := storage.DB.First(&user, userID).Error; err != nil {
+	user, err := h.Users().FindByID(userID)
+	if err != nil {
 		c.JSON(http.StatusUnauthorized, response.ErrorResponse{
 			Code:    "USER_NOT_FOUND",
 			Message: "Пользователь не найден",
@@ -228,28 +330,33 @@ func RefreshToken(c *gin.Context) {
 		return
 	}
 
-	newAccessToken, err := generateToken(user.ID, time.Minute*15, AccessSecret)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
-			Code:    "TOKEN_GENERATION_ERROR",
-			Message: "Ошибка при генерации access токена",
+	// Токены без версии выпущены до её появления и соответствуют версии 0.
+	version, _ := claims["ver"].(float64)
+	if int(version) != user.TokenVersion {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{
+			Code:    "INVALID_REFRESH_TOKEN",
+			Message: "Неверный или просроченный refresh токен",
 		})
 		return
 	}
 
-	newRefreshToken, err := generateToken(userID, time.Hour*24*7, refreshSecret)
+	tokens, err := h.refreshSession(c, user, claims)
+	if errors.Is(err, ErrSessionRevoked) {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{
+			Code:    "INVALID_REFRESH_TOKEN",
+			Message: "Сессия завершена",
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
 			Code:    "TOKEN_GENERATION_ERROR",
-			Message: "Ошибка при генерации нового refresh токена",
+			Message: "Ошибка при генерации токенов",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, response.TokenResponse{
-		AccessToken:  newAccessToken,
-		RefreshToken: newRefreshToken,
-	})
+	c.JSON(http.StatusOK, tokens)
 }
 
 // GetMyProfileHandler godoc
@@ -263,7 +370,7 @@ func RefreshToken(c *gin.Context) {
 // @Failure		401	{object}	response.ErrorResponse	"Ошибка авторизации (UNAUTHORIZED)"
 // @Failure		500	{object}	response.ErrorResponse	"Ошибка сервера (DB_ERROR)"
 // @Router			/profile [get]
-func GetMyProfileHandler(c *gin.Context) {
+func (h *Handler) GetMyProfileHandler(c *gin.Context) {
 	userID := c.GetUint("userID")
 	if userID == 0 {
 		c.JSON(http.StatusUnauthorized, response.ErrorResponse{
@@ -272,8 +379,8 @@ func GetMyProfileHandler(c *gin.Context) {
 		})
 		return
 	}
-	var user models.User
-	if err := storage.DB.First(&user, userID).Error; err != nil {
+	user, err := h.Users().FindByID(userID)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
 			Code:    "DB_ERROR",
 			Message: "Ошибка при получении данных пользователя",
@@ -281,10 +388,20 @@ func GetMyProfileHandler(c *gin.Context) {
 		})
 		return
 	}
-	c.JSON(http.StatusOK, response.ProfileResponse{
-		ID:      user.ID,
-		Name:    user.Name,
-		Surname: user.Surname,
-		Email:   user.Email,
-	})
+	c.JSON(http.StatusOK, h.profileResponse(user))
+}
+
+func (h *Handler) profileResponse(user models.User) response.ProfileResponse {
+	return response.ProfileResponse{
+		ID:                user.ID,
+		Name:              user.Name,
+		Surname:           user.Surname,
+		Email:             user.Email,
+		Role:              user.Role,
+		GroupID:           user.GroupID,
+		Language:          user.Language,
+		EmailVerified:     user.EmailVerified,
+		TwoFactorEnabled:  user.TOTPEnabled,
+		TwoFactorRequired: h.Config.MFA.Required(user.Role),
+	}
 }
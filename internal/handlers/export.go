@@ -0,0 +1,461 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"test_hack/internal/models"
+	"test_hack/internal/response"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	dataExportTTL = 24 * time.Hour
+	// Выгрузка, которая дольше dataExportStaleAfter остаётся в статусе pending (например, сервер
+	// перезапустился во время формирования), считается неудавшейся.
+	dataExportStaleAfter = time.Hour
+)
+
+// UserDataExport — все данные, которые хранятся о пользователе.
+type UserDataExport struct {
+	ExportedAt           time.Time                   `json:"exported_at"`
+	User                 ExportUser                  `json:"user"`
+	QueueHistory         []ExportQueueEntry          `json:"queue_history"`
+	NotificationSettings *ExportNotificationSettings `json:"notification_settings"`
+	Notifications        []ExportNotification        `json:"notifications"`
+	PushSubscriptions    []ExportPushSubscription    `json:"push_subscriptions"`
+	Sessions             []ExportSession             `json:"sessions"`
+}
+
+type ExportUser struct {
+	ID               uint       `json:"id"`
+	Name             string     `json:"name"`
+	Surname          string     `json:"surname"`
+	Email            string     `json:"email"`
+	Role             string     `json:"role"`
+	GroupID          string     `json:"group_id"`
+	Language         string     `json:"language"`
+	EmailVerified    bool       `json:"email_verified"`
+	EmailVerifiedAt  *time.Time `json:"email_verified_at"`
+	TwoFactorEnabled bool       `json:"two_factor_enabled"`
+	TelegramLinked   bool       `json:"telegram_linked"`
+	SSOLinked        bool       `json:"sso_linked"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+type ExportQueueEntry struct {
+	QueueID      uint       `json:"queue_id"`
+	ScheduleID   uint       `json:"schedule_id"`
+	ScheduleName string     `json:"schedule_name"`
+	EventStart   *time.Time `json:"event_start"`
+	Position     int        `json:"position"`
+	Status       string     `json:"status"`
+	JoinedAt     time.Time  `json:"joined_at"`
+	ExitedAt     *time.Time `json:"exited_at"`
+	ServedAt     *time.Time `json:"served_at"`
+	ServedByName string     `json:"served_by,omitempty"`
+	StillInQueue bool       `json:"still_in_queue"`
+}
+
+type ExportNotificationSettings struct {
+	QueueOpened        bool   `json:"queue_opened"`
+	BeforeEvent        bool   `json:"before_event"`
+	BeforeEventMinutes int    `json:"before_event_minutes"`
+	PositionReached    bool   `json:"position_reached"`
+	PositionThreshold  int    `json:"position_threshold"`
+	Channels           string `json:"channels"`
+}
+
+type ExportNotification struct {
+	QueueID uint      `json:"queue_id"`
+	Kind    string    `json:"kind"`
+	Title   string    `json:"title"`
+	Body    string    `json:"body"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+type ExportPushSubscription struct {
+	Endpoint   string     `json:"endpoint"`
+	UserAgent  string     `json:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+type ExportSession struct {
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+// collectUserData собирает выгрузку данных пользователя из всех таблиц.
+func (h *Handler) collectUserData(userID uint) (*UserDataExport, error) {
+	var user models.User
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	export := &UserDataExport{
+		ExportedAt: time.Now(),
+		User: ExportUser{
+			ID:               user.ID,
+			Name:             user.Name,
+			Surname:          user.Surname,
+			Email:            user.Email,
+			Role:             user.Role,
+			GroupID:          user.GroupID,
+			Language:         user.Language,
+			EmailVerified:    user.EmailVerified,
+			EmailVerifiedAt:  user.EmailVerifiedAt,
+			TwoFactorEnabled: user.TOTPEnabled,
+			TelegramLinked:   user.TelegramChatID != nil,
+			SSOLinked:        user.OIDCSubject != nil,
+			CreatedAt:        user.CreatedAt,
+			UpdatedAt:        user.UpdatedAt,
+		},
+		QueueHistory:      []ExportQueueEntry{},
+		Notifications:     []ExportNotification{},
+		PushSubscriptions: []ExportPushSubscription{},
+		Sessions:          []ExportSession{},
+	}
+
+	var entries []models.QueueEntry
+	if err := h.DB.Preload("ServedBy").Where("user_id = ?", userID).Order("created_at ASC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	// Прошедшие очереди и события удаляются планировщиком мягко, поэтому используем Unscoped.
+	queueIDs := make([]uint, 0, len(entries))
+	for _, e := range entries {
+		queueIDs = append(queueIDs, e.QueueID)
+	}
+	queues := make(map[uint]models.Queue)
+	schedules := make(map[uint]models.Schedule)
+	if len(queueIDs) > 0 {
+		var qs []models.Queue
+		if err := h.DB.Unscoped().Where("id IN ?", queueIDs).Find(&qs).Error; err != nil {
+			return nil, err
+		}
+		scheduleIDs := make([]uint, 0, len(qs))
+		for _, q := range qs {
+			queues[q.ID] = q
+			scheduleIDs = append(scheduleIDs, q.ScheduleID)
+		}
+		var ss []models.Schedule
+		if err := h.DB.Unscoped().Where("id IN ?", scheduleIDs).Find(&ss).Error; err != nil {
+			return nil, err
+		}
+		for _, s := range ss {
+			schedules[s.ID] = s
+		}
+	}
+	for _, e := range entries {
+		item := ExportQueueEntry{
+			QueueID:      e.QueueID,
+			Position:     e.Position,
+			Status:       e.Status,
+			JoinedAt:     e.CreatedAt,
+			ExitedAt:     e.ExitedAt,
+			ServedAt:     e.ServedAt,
+			StillInQueue: e.ExitedAt == nil,
+		}
+		if e.ServedBy != nil {
+			item.ServedByName = e.ServedBy.Name + " " + e.ServedBy.Surname
+		}
+		if q, ok := queues[e.QueueID]; ok {
+			item.ScheduleID = q.ScheduleID
+			if s, ok := schedules[q.ScheduleID]; ok {
+				item.ScheduleName = s.Name
+				start := s.StartTime
+				item.EventStart = &start
+			}
+		}
+		export.QueueHistory = append(export.QueueHistory, item)
+	}
+
+	var settings models.NotificationSettings
+	if err := h.DB.Where("user_id = ?", userID).Limit(1).Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	if settings.ID != 0 {
+		export.NotificationSettings = &ExportNotificationSettings{
+			QueueOpened:        settings.QueueOpened,
+			BeforeEvent:        settings.BeforeEvent,
+			BeforeEventMinutes: settings.BeforeEventMinutes,
+			PositionReached:    settings.PositionReached,
+			PositionThreshold:  settings.PositionThreshold,
+			Channels:           settings.Channels,
+		}
+	}
+
+	var notifications []models.Notification
+	if err := h.DB.Where("user_id = ?", userID).Order("sent_at ASC").Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+	for _, n := range notifications {
+		export.Notifications = append(export.Notifications, ExportNotification{
+			QueueID: n.QueueID, Kind: n.Kind, Title: n.Title, Body: n.Body, SentAt: n.SentAt,
+		})
+	}
+
+	var subs []models.PushSubscription
+	if err := h.DB.Where("user_id = ?", userID).Find(&subs).Error; err != nil {
+		return nil, err
+	}
+	for _, s := range subs {
+		export.PushSubscriptions = append(export.PushSubscriptions, ExportPushSubscription{
+			Endpoint: s.Endpoint, UserAgent: s.UserAgent, CreatedAt: s.CreatedAt, LastUsedAt: s.LastUsedAt,
+		})
+	}
+
+	sessions, err := h.activeSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sessions {
+		export.Sessions = append(export.Sessions, ExportSession{
+			UserAgent: s.UserAgent, IP: s.IP, CreatedAt: s.CreatedAt, LastUsedAt: s.LastUsedAt,
+		})
+	}
+	return export, nil
+}
+
+// encodeUserData кодирует выгрузку в JSON или в ZIP-архив с отдельным файлом на каждый раздел.
+func encodeUserData(export *UserDataExport, format string) ([]byte, error) {
+	if format != "zip" {
+		return json.MarshalIndent(export, "", "  ")
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, part := range []struct {
+		name string
+		data interface{}
+	}{
+		{"user.json", export.User},
+		{"queue_history.json", export.QueueHistory},
+		{"notification_settings.json", export.NotificationSettings},
+		{"notifications.json", export.Notifications},
+		{"push_subscriptions.json", export.PushSubscriptions},
+		{"sessions.json", export.Sessions},
+	} {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: part.name, Method: zip.Deflate, Modified: export.ExportedAt})
+		if err != nil {
+			return nil, err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(part.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// sendExportFile отдаёт файл выгрузки как вложение.
+func sendExportFile(c *gin.Context, userID uint, format string, data []byte, createdAt time.Time) {
+	contentType := "application/json"
+	if format == "zip" {
+		contentType = "application/zip"
+	}
+	filename := fmt.Sprintf("user-%d-export-%s.%s", userID, createdAt.Format("20060102-150405"), format)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, contentType, data)
+}
+
+func toDataExportResponse(e models.DataExport) response.DataExportResponse {
+	return response.DataExportResponse{
+		ID:          e.ID,
+		Status:      e.Status,
+		Format:      e.Format,
+		CreatedAt:   e.CreatedAt,
+		CompletedAt: e.CompletedAt,
+		ExpiresAt:   e.ExpiresAt,
+		Error:       e.Error,
+		DownloadURL: fmt.Sprintf("/profile/export/%d", e.ID),
+	}
+}
+
+// generateDataExport формирует фоновую выгрузку и сохраняет результат.
+func (h *Handler) generateDataExport(export models.DataExport) {
+	now := time.Now()
+	updates := map[string]interface{}{"completed_at": now}
+	data, err := h.collectUserData(export.UserID)
+	if err == nil {
+		data.ExportedAt = now
+		var file []byte
+		file, err = encodeUserData(data, export.Format)
+		updates["data"] = file
+	}
+	if err != nil {
+		log.Printf("Ошибка формирования выгрузки данных %d: %v", export.ID, err)
+		updates["status"] = models.DataExportStatusFailed
+		updates["error"] = err.Error()
+		delete(updates, "data")
+	} else {
+		updates["status"] = models.DataExportStatusReady
+	}
+	if err := h.DB.Model(&models.DataExport{}).Where("id = ?", export.ID).Updates(updates).Error; err != nil {
+		log.Printf("Ошибка сохранения выгрузки данных %d: %v", export.ID, err)
+	}
+}
+
+// ExportProfileDataHandler godoc
+// @Summary		Выгрузка персональных данных
+// @Description	Выгружает профиль, историю очередей с названиями событий, настройки и историю напоминаний, push-подписки и активные сессии. Небольшая выгрузка отдаётся сразу файлом; если записей в очередях больше EXPORT_ASYNC_THRESHOLD (по умолчанию 500) или передан async=true, выгрузка формируется в фоне, а ответ 202 содержит адрес для проверки состояния. Готовая фоновая выгрузка хранится 24 часа
+// @Tags			profile
+// @Produce		json
+// @Produce		application/zip
+// @Security		BearerAuth
+// @Param			format	query		string							false	"Формат: json (по умолчанию) или zip"
+// @Param			async	query		bool							false	"Сформировать выгрузку в фоне"
+// @Success		200		{object}	UserDataExport					"Файл выгрузки (JSON или ZIP)"
+// @Success		202		{object}	response.DataExportResponse		"Выгрузка формируется в фоне"
+// @Failure		400		{object}	response.ErrorResponse			"Неверный формат (VALIDATION_ERROR)"
+// @Failure		401		{object}	response.ErrorResponse			"Ошибка авторизации (UNAUTHORIZED)"
+// @Failure		429		{object}	response.ErrorResponse			"Слишком много запросов (RATE_LIMITED)"
+// @Failure		500		{object}	response.ErrorResponse			"Ошибка сервера (DB_ERROR, EXPORT_ERROR)"
+// @Router			/profile/export [get]
+func (h *Handler) ExportProfileDataHandler(c *gin.Context) {
+	userID := c.GetUint("userID")
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{
+			Code:    "UNAUTHORIZED",
+			Message: "Ошибка авторизации",
+		})
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "VALIDATION_ERROR",
+			Message: "Формат выгрузки должен быть json или zip",
+		})
+		return
+	}
+
+	async := c.Query("async") == "true"
+	if !async {
+		var entries int64
+		if err := h.DB.Model(&models.QueueEntry{}).Where("user_id = ?", userID).Count(&entries).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+				Code:    "DB_ERROR",
+				Message: "Ошибка при подсчёте записей",
+				Details: err.Error(),
+			})
+			return
+		}
+		async = entries > int64(h.Config.Export.AsyncThreshold)
+	}
+
+	if async {
+		export := models.DataExport{
+			UserID:    userID,
+			Format:    format,
+			Status:    models.DataExportStatusPending,
+			ExpiresAt: time.Now().Add(dataExportTTL),
+		}
+		if err := h.DB.Create(&export).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+				Code:    "DB_ERROR",
+				Message: "Ошибка при создании выгрузки",
+				Details: err.Error(),
+			})
+			return
+		}
+		go h.generateDataExport(export)
+		c.JSON(http.StatusAccepted, toDataExportResponse(export))
+		return
+	}
+
+	data, err := h.collectUserData(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка при сборе данных",
+			Details: err.Error(),
+		})
+		return
+	}
+	file, err := encodeUserData(data, format)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "EXPORT_ERROR",
+			Message: "Ошибка формирования файла выгрузки",
+			Details: err.Error(),
+		})
+		return
+	}
+	sendExportFile(c, userID, format, file, data.ExportedAt)
+}
+
+// GetProfileDataExportHandler godoc
+// @Summary		Состояние фоновой выгрузки
+// @Description	Возвращает готовый файл выгрузки или её состояние, пока она формируется
+// @Tags			profile
+// @Produce		json
+// @Produce		application/zip
+// @Security		BearerAuth
+// @Param			id	path		int								true	"ID выгрузки"
+// @Success		200	{object}	response.DataExportResponse		"Состояние выгрузки (pending, failed) или файл, если status=ready"
+// @Failure		400	{object}	response.ErrorResponse			"Неверный ID (VALIDATION_ERROR)"
+// @Failure		401	{object}	response.ErrorResponse			"Ошибка авторизации (UNAUTHORIZED)"
+// @Failure		404	{object}	response.ErrorResponse			"Выгрузка не найдена или истекла (EXPORT_NOT_FOUND)"
+// @Router			/profile/export/{id} [get]
+func (h *Handler) GetProfileDataExportHandler(c *gin.Context) {
+	userID := c.GetUint("userID")
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{
+			Code:    "UNAUTHORIZED",
+			Message: "Ошибка авторизации",
+		})
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "VALIDATION_ERROR",
+			Message: "Неверный идентификатор выгрузки",
+		})
+		return
+	}
+
+	var export models.DataExport
+	if err := h.DB.Where("id = ? AND user_id = ? AND expires_at > ?", id, userID, time.Now()).First(&export).Error; err != nil {
+		c.JSON(http.StatusNotFound, response.ErrorResponse{
+			Code:    "EXPORT_NOT_FOUND",
+			Message: "Выгрузка не найдена или истекла",
+		})
+		return
+	}
+	if export.Status == models.DataExportStatusReady {
+		sendExportFile(c, userID, export.Format, export.Data, export.CreatedAt)
+		return
+	}
+	c.JSON(http.StatusOK, toDataExportResponse(export))
+}
+
+// CleanDataExports удаляет истёкшие выгрузки и помечает зависшие как неудавшиеся.
+func (h *Handler) CleanDataExports() (int64, error) {
+	now := time.Now()
+	stale := h.DB.Model(&models.DataExport{}).
+		Where("status = ? AND created_at < ?", models.DataExportStatusPending, now.Add(-dataExportStaleAfter)).
+		Updates(map[string]interface{}{
+			"status":       models.DataExportStatusFailed,
+			"error":        "формирование выгрузки прервано, запросите её заново",
+			"completed_at": now,
+		})
+	if stale.Error != nil {
+		return 0, stale.Error
+	}
+	expired := h.DB.Unscoped().Where("expires_at < ?", now).Delete(&models.DataExport{})
+	return stale.RowsAffected + expired.RowsAffected, expired.Error
+}
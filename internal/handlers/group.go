@@ -5,10 +5,8 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
-	"time"
 
 	"test_hack/internal/response"
-	"test_hack/internal/storage"
 
 	"github.com/gin-gonic/gin"
 )
@@ -38,23 +36,25 @@ var ctx = context.Background()
 // @Success		200		{object}	GroupResponse	"Успешный ответ с данными групп"
 // @Failure		500		{object}	response.ErrorResponse	"Ошибка сервера (API_ERROR, CACHE_ERROR, DECODE_ERROR)"
 // @Router			/groups [get]
-func GetGroupsHandler(c *gin.Context) {
+func (h *Handler) GetGroupsHandler(c *gin.Context) {
 	cacheKey := "groups_all"
-	redisClient := storage.RedisClient // предполагается, что клиент Redis инициализирован в storage
+	redisClient := h.Redis // предполагается, что клиент Redis инициализирован в storage
 
 	// Проверка кэша
 	cached, err := redisClient.Get(ctx, cacheKey).Result()
 	if err == nil && cached != "" {
 		var groups GroupResponse
 		if err := json.Unmarshal([]byte(cached), &groups); err == nil {
+			h.Metrics.CacheLookup("groups", true)
 			c.JSON(http.StatusOK, groups)
 			return
 		}
 	}
+	h.Metrics.CacheLookup("groups", false)
 
 	// Запрос к внешнему API
-	apiURL := "https://api.profcomff.com/timetable/group/?limit=1000"
-	resp, err := http.Get(apiURL)
+	apiURL := h.Config.Timetable.APIURL + "/group/?limit=1000"
+	resp, err := h.TimetableClient.Get(apiURL)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
 			Code:    "API_ERROR",
@@ -85,8 +85,8 @@ func GetGroupsHandler(c *gin.Context) {
 		return
 	}
 
-	// Кэширование результата на 6 часов
-	redisClient.Set(ctx, cacheKey, string(body), time.Hour*6)
+	// Кэширование результата на Cache.GroupsTTL (по умолчанию 6 часов)
+	redisClient.Set(ctx, cacheKey, string(body), h.Config.Cache.GroupsTTL)
 
 	c.JSON(http.StatusOK, groups)
 }
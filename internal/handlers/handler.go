@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+
+	"test_hack/internal/config"
+	"test_hack/internal/jobs"
+	"test_hack/internal/metrics"
+	"test_hack/internal/notify"
+	"test_hack/internal/service"
+	"test_hack/internal/webhooks"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// Handler содержит зависимости HTTP-обработчиков. Обработчики — методы Handler, поэтому
+// несколько экземпляров приложения (например, в параллельных тестах) не делят общее состояние.
+type Handler struct {
+	DB    *gorm.DB
+	Redis *redis.Client
+	Hub   *Hub
+	// Events получает события очередей вместо Hub (например, NewRedisPublisher в queuectl); nil — рассылка через Hub.
+	Events service.Publisher
+	// Jobs — реестр фоновых задач для /admin/jobs.
+	Jobs *jobs.Registry
+	// Webhooks доставляет события очередей на вебхуки.
+	Webhooks *webhooks.Dispatcher
+	// Notify — каналы напоминаний и почтовый сервис для писем верификации и сброса пароля.
+	Notify *notify.Notifier
+	// Config — настройки приложения: ключи и время жизни токенов, кэш, адрес API расписания.
+	Config config.Config
+	// TimetableClient выполняет запросы к API расписания; время запросов попадает в метрики (service="timetable").
+	TimetableClient *http.Client
+	// Metrics — метрики приложения (обращения к кэшу, события очередей); nil — не учитываются.
+	Metrics *metrics.Metrics
+
+	// sso — настроенный провайдер OpenID Connect, см. InitOIDC.
+	sso *oidcSSO
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"test_hack/internal/models"
+	"test_hack/internal/response"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// QueueHistoryEntry описывает одну запись очереди вместе с итоговым статусом.
+type QueueHistoryEntry struct {
+	EntryID         uint       `json:"entry_id"`
+	UserID          uint       `json:"user_id"`
+	Name            string     `json:"name"`
+	Surname         string     `json:"surname"`
+	Email           string     `json:"email"`
+	Position        int        `json:"position"`
+	JoinedAt        time.Time  `json:"joined_at"`
+	LeftAt          *time.Time `json:"left_at,omitempty"`
+	Status          string     `json:"status"`
+	ServedByID      *uint      `json:"served_by_id,omitempty"`
+	ServedByName    string     `json:"served_by_name,omitempty"`
+	ServedBySurname string     `json:"served_by_surname,omitempty"`
+	ServedAt        *time.Time `json:"served_at,omitempty"`
+}
+
+// QueueHistoryResponse содержит очередь, событие расписания и все записи очереди.
+type QueueHistoryResponse struct {
+	QueueID      uint                `json:"queue_id"`
+	ScheduleID   uint                `json:"schedule_id"`
+	ScheduleName string              `json:"schedule_name"`
+	StartTime    time.Time           `json:"start_time"`
+	IsActive     bool                `json:"is_active"`
+	OpensAt      time.Time           `json:"opens_at"`
+	ClosesAt     time.Time           `json:"closes_at"`
+	Entries      []QueueHistoryEntry `json:"entries"`
+}
+
+// GetQueueHistoryHandler возвращает полную историю очереди
+// @Summary		История очереди
+// @Description	Возвращает все записи очереди (включая вышедших и принятых) с временем входа, выхода, итоговым статусом и тем, кто принял участника. Доступно и для закрытых очередей
+// @Tags			reports
+// @Produce		json
+// @Param			id	path	string	true	"ID очереди"
+// @Security		BearerAuth
+// @Success		200	{object}	QueueHistoryResponse	"История очереди"
+// @Failure		400	{object}	response.ErrorResponse	"Ошибка валидации (INVALID_QUEUE_ID)"
+// @Failure		403	{object}	response.ErrorResponse	"Недостаточно прав (FORBIDDEN)"
+// @Failure		404	{object}	response.ErrorResponse	"Очередь не найдена (QUEUE_NOT_FOUND)"
+// @Failure		500	{object}	response.ErrorResponse	"Ошибка сервера (DB_ERROR)"
+// @Router			/api/queues/{id}/history [get]
+func (h *Handler) GetQueueHistoryHandler(c *gin.Context) {
+	queueID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "INVALID_QUEUE_ID",
+			Message: "Неверный идентификатор очереди",
+		})
+		return
+	}
+
+	// Закрытые очереди и прошедшие события удаляются планировщиком мягко, поэтому ищем без учёта deleted_at.
+	var queue models.Queue
+	if err := h.DB.Unscoped().First(&queue, queueID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, response.ErrorResponse{
+			Code:    "QUEUE_NOT_FOUND",
+			Message: "Очередь не найдена",
+		})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка загрузки очереди",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	// Событие расписания могло быть удалено окончательно: тогда история отдаётся без его названия.
+	var schedule models.Schedule
+	if err := h.DB.Unscoped().Limit(1).Find(&schedule, queue.ScheduleID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка загрузки события расписания",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	entries, err := h.loadQueueHistory([]uint{queue.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка загрузки истории очереди",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	history := make([]QueueHistoryEntry, 0, len(entries))
+	for _, entry := range entries {
+		history = append(history, toHistoryEntry(entry))
+	}
+
+	c.JSON(http.StatusOK, QueueHistoryResponse{
+		QueueID:      queue.ID,
+		ScheduleID:   queue.ScheduleID,
+		ScheduleName: schedule.Name,
+		StartTime:    schedule.StartTime,
+		IsActive:     queue.IsActive,
+		OpensAt:      queue.OpensAt,
+		ClosesAt:     queue.ClosesAt,
+		Entries:      history,
+	})
+}
+
+// loadQueueHistory загружает все записи указанных очередей вместе с участниками и принявшими их преподавателями.
+func (h *Handler) loadQueueHistory(queueIDs []uint) ([]models.QueueEntry, error) {
+	var entries []models.QueueEntry
+	err := h.DB.
+		Preload("User").
+		Preload("ServedBy").
+		Where("queue_id IN ?", queueIDs).
+		Order("created_at ASC").
+		Find(&entries).Error
+	return entries, err
+}
+
+func toHistoryEntry(entry models.QueueEntry) QueueHistoryEntry {
+	item := QueueHistoryEntry{
+		EntryID:    entry.ID,
+		UserID:     entry.UserID,
+		Name:       entry.User.Name,
+		Surname:    entry.User.Surname,
+		Email:      entry.User.Email,
+		Position:   entry.Position,
+		JoinedAt:   entry.CreatedAt,
+		LeftAt:     entry.ExitedAt,
+		Status:     entry.Status,
+		ServedByID: entry.ServedByID,
+		ServedAt:   entry.ServedAt,
+	}
+	if entry.ServedBy != nil {
+		item.ServedByName = entry.ServedBy.Name
+		item.ServedBySurname = entry.ServedBy.Surname
+	}
+	return item
+}
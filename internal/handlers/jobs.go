@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"test_hack/internal/audit"
+	"test_hack/internal/jobs"
+	"test_hack/internal/models"
+	"test_hack/internal/response"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JobRunResponse описывает один запуск фоновой задачи.
+type JobRunResponse struct {
+	ID           uint       `json:"id"`
+	JobName      string     `json:"job_name"`
+	Trigger      string     `json:"trigger"`
+	Status       string     `json:"status"`
+	StartedAt    time.Time  `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+	DurationMs   int64      `json:"duration_ms"`
+	AffectedRows int64      `json:"affected_rows"`
+	Error        string     `json:"error,omitempty"`
+}
+
+// JobStatusResponse описывает зарегистрированную задачу и её последний запуск.
+type JobStatusResponse struct {
+	Name     string          `json:"name"`
+	Schedule string          `json:"schedule"`
+	Running  bool            `json:"running"`
+	LastRun  *JobRunResponse `json:"last_run,omitempty"`
+}
+
+func toJobRunResponse(run models.JobRun) JobRunResponse {
+	return JobRunResponse{
+		ID:           run.ID,
+		JobName:      run.JobName,
+		Trigger:      run.Trigger,
+		Status:       run.Status,
+		StartedAt:    run.StartedAt,
+		FinishedAt:   run.FinishedAt,
+		DurationMs:   run.DurationMs,
+		AffectedRows: run.AffectedRows,
+		Error:        run.Error,
+	}
+}
+
+// ListJobsHandler возвращает список фоновых задач
+// @Summary		Список фоновых задач
+// @Description	Возвращает зарегистрированные задачи планировщика, их расписание и результат последнего запуска
+// @Tags			admin
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{array}		JobStatusResponse	"Список задач"
+// @Failure		403	{object}	response.ErrorResponse	"Недостаточно прав (FORBIDDEN)"
+// @Failure		500	{object}	response.ErrorResponse	"Ошибка сервера (DB_ERROR)"
+// @Router			/admin/jobs [get]
+func (h *Handler) ListJobsHandler(c *gin.Context) {
+	result := []JobStatusResponse{}
+	for _, job := range h.Jobs.List() {
+		item := JobStatusResponse{
+			Name:     job.Name,
+			Schedule: job.Spec,
+			Running:  h.Jobs.IsRunning(job.Name),
+		}
+
+		var lastRun models.JobRun
+		err := h.DB.Where("job_name = ?", job.Name).Order("started_at DESC").Limit(1).Find(&lastRun).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+				Code:    "DB_ERROR",
+				Message: "Ошибка загрузки истории запусков",
+				Details: err.Error(),
+			})
+			return
+		}
+		if lastRun.ID != 0 {
+			run := toJobRunResponse(lastRun)
+			item.LastRun = &run
+		}
+		result = append(result, item)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ListJobRunsHandler возвращает историю запусков задачи
+// @Summary		История запусков задачи
+// @Description	Возвращает последние запуски задачи: время начала и окончания, длительность, ошибку и число затронутых записей
+// @Tags			admin
+// @Produce		json
+// @Param			name	path	string	true	"Имя задачи"
+// @Param			limit	query	int		false	"Количество записей (по умолчанию 50, максимум 500)"
+// @Security		BearerAuth
+// @Success		200	{array}		JobRunResponse	"История запусков"
+// @Failure		403	{object}	response.ErrorResponse	"Недостаточно прав (FORBIDDEN)"
+// @Failure		500	{object}	response.ErrorResponse	"Ошибка сервера (DB_ERROR)"
+// @Router			/admin/jobs/{name}/runs [get]
+func (h *Handler) ListJobRunsHandler(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 50
+	}
+
+	var runs []models.JobRun
+	if err := h.DB.
+		Where("job_name = ?", c.Param("name")).
+		Order("started_at DESC").
+		Limit(limit).
+		Find(&runs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка загрузки истории запусков",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	result := make([]JobRunResponse, 0, len(runs))
+	for _, run := range runs {
+		result = append(result, toJobRunResponse(run))
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// RunJobHandler немедленно запускает фоновую задачу
+// @Summary		Ручной запуск задачи
+// @Description	Синхронно выполняет задачу планировщика и возвращает результат запуска
+// @Tags			admin
+// @Produce		json
+// @Param			name	path	string	true	"Имя задачи"
+// @Security		BearerAuth
+// @Success		200	{object}	JobRunResponse	"Результат запуска"
+// @Failure		403	{object}	response.ErrorResponse	"Недостаточно прав (FORBIDDEN)"
+// @Failure		404	{object}	response.ErrorResponse	"Задача не найдена (JOB_NOT_FOUND)"
+// @Failure		409	{object}	response.ErrorResponse	"Задача уже выполняется (JOB_RUNNING)"
+// @Router			/admin/jobs/{name}/run [post]
+func (h *Handler) RunJobHandler(c *gin.Context) {
+	run, err := h.Jobs.Run(c.Param("name"), models.JobTriggerManual)
+	switch {
+	case errors.Is(err, jobs.ErrJobNotFound):
+		c.JSON(http.StatusNotFound, response.ErrorResponse{
+			Code:    "JOB_NOT_FOUND",
+			Message: "Задача не найдена",
+		})
+		return
+	case errors.Is(err, jobs.ErrJobRunning):
+		c.JSON(http.StatusConflict, response.ErrorResponse{
+			Code:    "JOB_RUNNING",
+			Message: "Задача уже выполняется",
+		})
+		return
+	}
+
+	audit.RecordRequest(h.DB, c, audit.Event{
+		Action:     audit.ActionJobRun,
+		TargetType: audit.TargetJob,
+		TargetID:   run.JobName,
+		After:      map[string]interface{}{"run_id": run.ID, "status": run.Status, "affected_rows": run.AffectedRows},
+	})
+	c.JSON(http.StatusOK, toJobRunResponse(*run))
+}
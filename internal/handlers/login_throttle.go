@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"test_hack/internal/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+// Ограничения на неудачные попытки входа. Неудачи считаются в скользящем окне отдельно для IP и для email;
+// неверный пароль и неверный код второго фактора учитываются одинаково.
+const (
+	loginFailureWindow = 15 * time.Minute
+	// После loginDelayAfter неудач для email каждая следующая попытка возможна только после паузы,
+	// которая удваивается: 2, 4, ... секунд.
+	loginDelayAfter = 3
+	// После loginEmailLockAfter неудач email блокируется на loginLockDuration.
+	loginEmailLockAfter = 5
+	// После loginIPLockAfter неудач с одного IP (по любым email) IP блокируется на loginLockDuration.
+	loginIPLockAfter  = 20
+	loginLockDuration = 15 * time.Minute
+)
+
+const (
+	loginFailuresPrefix = "login_failures:"
+	loginLockPrefix     = "login_lock:"
+	loginWaitPrefix     = "login_wait:"
+)
+
+// loginBlock описывает причину, по которой попытка входа отклонена до проверки пароля.
+type loginBlock struct {
+	Code       string // LOCKED_OUT или TOO_MANY_ATTEMPTS
+	RetryAfter time.Duration
+}
+
+// respondLoginBlocked отвечает 429 с паузой из block в заголовке Retry-After.
+func respondLoginBlocked(c *gin.Context, block *loginBlock) {
+	seconds := int(math.Ceil(block.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	message := "Слишком много неудачных попыток входа, вход временно заблокирован"
+	if block.Code == "TOO_MANY_ATTEMPTS" {
+		message = "Слишком частые попытки входа, повторите позже"
+	}
+	c.JSON(http.StatusTooManyRequests, response.ErrorResponse{
+		Code:    block.Code,
+		Message: message,
+		Details: fmt.Sprintf("Повторите через %d с", seconds),
+	})
+}
+
+func loginKeys(ip, email string) (ipKey, emailKey string) {
+	return "ip:" + ip, "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// checkLoginAllowed проверяет блокировки IP и email и паузу между попытками.
+// Проверка выполняется до bcrypt, поэтому заблокированные попытки не нагружают CPU.
+// Если Redis недоступен, вход не блокируется.
+func (h *Handler) checkLoginAllowed(ip, email string) *loginBlock {
+	ipKey, emailKey := loginKeys(ip, email)
+
+	pipe := h.Redis.Pipeline()
+	ipLock := pipe.PTTL(ctx, loginLockPrefix+ipKey)
+	emailLock := pipe.PTTL(ctx, loginLockPrefix+emailKey)
+	wait := pipe.PTTL(ctx, loginWaitPrefix+emailKey)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		log.Println("Ошибка проверки ограничений входа:", err)
+		return nil
+	}
+
+	if ttl := maxDuration(ipLock.Val(), emailLock.Val()); ttl > 0 {
+		return &loginBlock{Code: "LOCKED_OUT", RetryAfter: ttl}
+	}
+	if ttl := wait.Val(); ttl > 0 {
+		return &loginBlock{Code: "TOO_MANY_ATTEMPTS", RetryAfter: ttl}
+	}
+	return nil
+}
+
+// registerLoginFailure учитывает неудачную попытку и при необходимости назначает паузу или блокировку.
+// Возвращает число неудач для email в текущем окне.
+func (h *Handler) registerLoginFailure(ip, email string) int64 {
+	ipKey, emailKey := loginKeys(ip, email)
+	now := time.Now()
+	member := fmt.Sprintf("%d", now.UnixNano())
+	windowStart := fmt.Sprintf("%d", now.Add(-loginFailureWindow).UnixNano())
+
+	pipe := h.Redis.TxPipeline()
+	counts := make([]*redis.IntCmd, 0, 2)
+	for _, key := range []string{ipKey, emailKey} {
+		k := loginFailuresPrefix + key
+		pipe.ZAdd(ctx, k, &redis.Z{Score: float64(now.UnixNano()), Member: member})
+		pipe.ZRemRangeByScore(ctx, k, "-inf", windowStart)
+		counts = append(counts, pipe.ZCard(ctx, k))
+		pipe.Expire(ctx, k, loginFailureWindow)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Println("Ошибка учёта неудачной попытки входа:", err)
+		return 0
+	}
+	ipFailures, emailFailures := counts[0].Val(), counts[1].Val()
+
+	if ipFailures >= loginIPLockAfter {
+		h.Redis.Set(ctx, loginLockPrefix+ipKey, 1, loginLockDuration)
+	}
+	switch {
+	case emailFailures >= loginEmailLockAfter:
+		h.Redis.Set(ctx, loginLockPrefix+emailKey, 1, loginLockDuration)
+		// После блокировки счётчик начинается заново.
+		h.Redis.Del(ctx, loginFailuresPrefix+emailKey)
+	case emailFailures >= loginDelayAfter:
+		h.Redis.Set(ctx, loginWaitPrefix+emailKey, 1, loginDelay(emailFailures))
+	}
+	return emailFailures
+}
+
+// loginDelay возвращает паузу перед следующей попыткой после failures неудач.
+func loginDelay(failures int64) time.Duration {
+	if failures < loginDelayAfter {
+		return 0
+	}
+	return time.Duration(1<<(failures-loginDelayAfter+1)) * time.Second
+}
+
+// resetLoginFailures сбрасывает счётчик и паузу для email после успешного входа (с 2FA — после верного кода).
+// Счётчик IP не сбрасывается, чтобы перебор по разным аккаунтам с одного адреса всё равно ограничивался.
+func (h *Handler) resetLoginFailures(email string) {
+	_, emailKey := loginKeys("", email)
+	h.Redis.Del(ctx, loginFailuresPrefix+emailKey, loginWaitPrefix+emailKey)
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
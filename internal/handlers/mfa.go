@@ -0,0 +1,602 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image/png"
+	"net/http"
+	"strings"
+	"test_hack/internal/audit"
+	"test_hack/internal/models"
+	"test_hack/internal/response"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	mfaChallengeTTL = 5 * time.Minute
+	// Не больше mfaMaxAttempts попыток ввести код на один токен второго шага входа.
+	mfaMaxAttempts     = 5
+	mfaAttemptsPrefix  = "mfa_attempts:"
+	totpUsedPrefix     = "totp_used:"
+	recoveryCodesCount = 10
+)
+
+var errInvalidMFAToken = errors.New("неверный или просроченный токен второго шага входа")
+
+// totpOpts — параметры TOTP, которые понимают все распространённые приложения-аутентификаторы.
+// Skew 1 допускает расхождение часов на один 30-секундный интервал.
+var totpOpts = totp.ValidateOpts{Period: 30, Skew: 1, Digits: otp.DigitsSix, Algorithm: otp.AlgorithmSHA1}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"` // Код из приложения-аутентификатора
+}
+
+type DisableMFARequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"` // Код из приложения или код восстановления
+}
+
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"` // Код из приложения или код восстановления
+}
+
+// mfaChallengeSecret выводится из JWT_ACCESS_SECRET, но отличается от него, чтобы токен второго шага
+// нельзя было использовать как access токен.
+func (h *Handler) mfaChallengeSecret() []byte {
+	sum := sha256.Sum256(append([]byte("mfa-challenge:"), h.Config.JWT.AccessSecret...))
+	return sum[:]
+}
+
+// generateMFAChallenge выпускает токен второго шага входа для пользователя, прошедшего первый шаг способом
+// method (models.SessionMethod*); после второго шага сессия открывается с этим способом.
+func (h *Handler) generateMFAChallenge(user models.User, method string) (string, error) {
+	jti, err := newOneTimeToken()
+	if err != nil {
+		return "", err
+	}
+	claims := jwt.MapClaims{
+		"user_id": user.ID,
+		"typ":     "mfa",
+		"ver":     user.TokenVersion,
+		"jti":     jti,
+		"amr":     method,
+		"exp":     time.Now().Add(mfaChallengeTTL).Unix(),
+		"iat":     time.Now().Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(h.mfaChallengeSecret())
+}
+
+// parseMFAChallenge проверяет токен второго шага и возвращает пользователя, идентификатор токена
+// и способ первого шага входа.
+func (h *Handler) parseMFAChallenge(tokenString string) (models.User, string, string, error) {
+	var user models.User
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return h.mfaChallengeSecret(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid {
+		return user, "", "", errInvalidMFAToken
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["typ"] != "mfa" {
+		return user, "", "", errInvalidMFAToken
+	}
+	userID, _ := claims["user_id"].(float64)
+	version, _ := claims["ver"].(float64)
+	jti, _ := claims["jti"].(string)
+	method, _ := claims["amr"].(string)
+	if err := h.DB.First(&user, uint(userID)).Error; err != nil {
+		return user, "", "", errInvalidMFAToken
+	}
+	if !user.TOTPEnabled || int(version) != user.TokenVersion || jti == "" {
+		return user, "", "", errInvalidMFAToken
+	}
+	return user, jti, method, nil
+}
+
+// validateTOTP проверяет код из приложения. Каждый код принимается только один раз,
+// чтобы перехваченный код нельзя было повторить в пределах его срока действия.
+func (h *Handler) validateTOTP(user models.User, code string) bool {
+	ok, err := totp.ValidateCustom(code, user.TOTPSecret, time.Now(), totpOpts)
+	if err != nil || !ok {
+		return false
+	}
+	key := fmt.Sprintf("%s%d:%s", totpUsedPrefix, user.ID, code)
+	fresh, err := h.Redis.SetNX(ctx, key, 1, 3*time.Duration(totpOpts.Period)*time.Second).Result()
+	if err != nil {
+		// Без Redis защита от повтора недоступна, но вход не блокируется.
+		return true
+	}
+	return fresh
+}
+
+// normalizeRecoveryCode приводит код восстановления к виду, в котором хранится его хеш.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+// useRecoveryCode помечает код восстановления использованным. Возвращает false, если код неверный или уже использован.
+func (h *Handler) useRecoveryCode(userID uint, code string) (bool, error) {
+	res := h.DB.Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	return res.RowsAffected == 1, res.Error
+}
+
+// verifySecondFactor проверяет код из приложения или код восстановления.
+// Возвращает true в usedRecovery, если вход выполнен по коду восстановления.
+func (h *Handler) verifySecondFactor(user models.User, code string) (ok, usedRecovery bool, err error) {
+	code = strings.TrimSpace(code)
+	if len(code) == int(totpOpts.Digits) {
+		return h.validateTOTP(user, code), false, nil
+	}
+	ok, err = h.useRecoveryCode(user.ID, code)
+	return ok, ok, err
+}
+
+// replaceRecoveryCodes удаляет прежние коды восстановления пользователя и создаёт новые.
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, recoveryCodesCount)
+	records := make([]models.MFARecoveryCode, 0, recoveryCodesCount)
+	for i := 0; i < recoveryCodesCount; i++ {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		records = append(records, models.MFARecoveryCode{UserID: userID, CodeHash: hashToken(raw)})
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// currentUser загружает пользователя, указанного в access токене.
+func (h *Handler) currentUser(c *gin.Context) (models.User, bool) {
+	userID := c.GetUint("userID")
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{
+			Code:    "UNAUTHORIZED",
+			Message: "Ошибка авторизации",
+		})
+		return models.User{}, false
+	}
+	user, err := h.Users().FindByID(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка при получении данных пользователя",
+			Details: err.Error(),
+		})
+		return user, false
+	}
+	return user, true
+}
+
+// SetupMFAHandler godoc
+// @Summary		Начало подключения 2FA
+// @Description	Генерирует новый секрет TOTP и возвращает URI otpauth:// и QR-код для приложения-аутентификатора. 2FA включается только после подтверждения кодом через /profile/2fa/enable
+// @Tags			profile
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	response.MFASetupResponse	"Данные для приложения-аутентификатора"
+// @Failure		401	{object}	response.ErrorResponse		"Ошибка авторизации (UNAUTHORIZED)"
+// @Failure		409	{object}	response.ErrorResponse		"2FA уже подключена (MFA_ALREADY_ENABLED)"
+// @Failure		500	{object}	response.ErrorResponse		"Ошибка сервера (MFA_SETUP_ERROR, DB_ERROR)"
+// @Router			/profile/2fa/setup [post]
+func (h *Handler) SetupMFAHandler(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, response.ErrorResponse{
+			Code:    "MFA_ALREADY_ENABLED",
+			Message: "Двухфакторная аутентификация уже подключена",
+		})
+		return
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      h.Config.MFA.Issuer,
+		AccountName: user.Email,
+		Period:      totpOpts.Period,
+		Digits:      totpOpts.Digits,
+		Algorithm:   totpOpts.Algorithm,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "MFA_SETUP_ERROR",
+			Message: "Ошибка генерации секрета",
+			Details: err.Error(),
+		})
+		return
+	}
+	var qr bytes.Buffer
+	img, err := key.Image(256, 256)
+	if err == nil {
+		err = png.Encode(&qr, img)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "MFA_SETUP_ERROR",
+			Message: "Ошибка генерации QR-кода",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if err := h.DB.Model(&user).Update("totp_secret", key.Secret()).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка сохранения секрета",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.MFASetupResponse{
+		Secret:     key.Secret(),
+		OTPAuthURL: key.URL(),
+		QRCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(qr.Bytes()),
+	})
+}
+
+// EnableMFAHandler godoc
+// @Summary		Включение 2FA
+// @Description	Включает 2FA после проверки кода из приложения и возвращает 10 одноразовых кодов восстановления. Коды показываются только один раз
+// @Tags			profile
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			body	body		MFACodeRequest					true	"Код из приложения"
+// @Success		200		{object}	response.RecoveryCodesResponse	"2FA включена"
+// @Failure		400		{object}	response.ErrorResponse			"Ошибка валидации (VALIDATION_ERROR), 2FA не настроена (MFA_NOT_SETUP) или неверный код (INVALID_MFA_CODE)"
+// @Failure		401		{object}	response.ErrorResponse			"Ошибка авторизации (UNAUTHORIZED)"
+// @Failure		409		{object}	response.ErrorResponse			"2FA уже подключена (MFA_ALREADY_ENABLED)"
+// @Failure		500		{object}	response.ErrorResponse			"Ошибка сервера (DB_ERROR)"
+// @Router			/profile/2fa/enable [post]
+func (h *Handler) EnableMFAHandler(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "VALIDATION_ERROR",
+			Message: "Ошибка валидации данных",
+			Details: err.Error(),
+		})
+		return
+	}
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, response.ErrorResponse{
+			Code:    "MFA_ALREADY_ENABLED",
+			Message: "Двухфакторная аутентификация уже подключена",
+		})
+		return
+	}
+	if user.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "MFA_NOT_SETUP",
+			Message: "Сначала получите секрет через /profile/2fa/setup",
+		})
+		return
+	}
+	if !h.validateTOTP(user, strings.TrimSpace(req.Code)) {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "INVALID_MFA_CODE",
+			Message: "Неверный код подтверждения",
+		})
+		return
+	}
+
+	var codes []string
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"totp_enabled":    true,
+			"totp_enabled_at": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка включения 2FA",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	audit.RecordRequest(h.DB, c, audit.Event{
+		Action:    audit.ActionMFAEnabled,
+		UserID:    &user.ID,
+		Email:     user.Email,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	c.JSON(http.StatusOK, response.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableMFAHandler godoc
+// @Summary		Отключение 2FA
+// @Description	Отключает 2FA после проверки пароля и кода из приложения (или кода восстановления). Недоступно для ролей из MFA_REQUIRED_ROLES
+// @Tags			profile
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			body	body		DisableMFARequest			true	"Пароль и код"
+// @Success		200		{object}	response.MessageResponse	"2FA отключена"
+// @Failure		400		{object}	response.ErrorResponse		"Ошибка валидации (VALIDATION_ERROR), 2FA не подключена (MFA_NOT_ENABLED) или неверный код (INVALID_MFA_CODE)"
+// @Failure		401		{object}	response.ErrorResponse		"Ошибка авторизации (UNAUTHORIZED) или неверный пароль (INVALID_CREDENTIALS)"
+// @Failure		403		{object}	response.ErrorResponse		"2FA обязательна для роли (MFA_REQUIRED)"
+// @Failure		500		{object}	response.ErrorResponse		"Ошибка сервера (DB_ERROR)"
+// @Router			/profile/2fa/disable [post]
+func (h *Handler) DisableMFAHandler(c *gin.Context) {
+	var req DisableMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "VALIDATION_ERROR",
+			Message: "Ошибка валидации данных",
+			Details: err.Error(),
+		})
+		return
+	}
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "MFA_NOT_ENABLED",
+			Message: "Двухфакторная аутентификация не подключена",
+		})
+		return
+	}
+	if h.Config.MFA.Required(user.Role) {
+		c.JSON(http.StatusForbidden, response.ErrorResponse{
+			Code:    "MFA_REQUIRED",
+			Message: "Для вашей роли двухфакторная аутентификация обязательна",
+		})
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{
+			Code:    "INVALID_CREDENTIALS",
+			Message: "Неверный пароль",
+		})
+		return
+	}
+	if ok, _, err := h.verifySecondFactor(user, req.Code); err != nil || !ok {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "INVALID_MFA_CODE",
+			Message: "Неверный код подтверждения",
+		})
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"totp_enabled":    false,
+			"totp_enabled_at": nil,
+			"totp_secret":     "",
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.MFARecoveryCode{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка отключения 2FA",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	audit.RecordRequest(h.DB, c, audit.Event{
+		Action:    audit.ActionMFADisabled,
+		UserID:    &user.ID,
+		Email:     user.Email,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	c.JSON(http.StatusOK, response.MessageResponse{Message: "Двухфакторная аутентификация отключена"})
+}
+
+// RegenerateRecoveryCodesHandler godoc
+// @Summary		Новые коды восстановления
+// @Description	Выпускает 10 новых кодов восстановления; прежние коды перестают действовать. Требует код из приложения
+// @Tags			profile
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			body	body		MFACodeRequest					true	"Код из приложения"
+// @Success		200		{object}	response.RecoveryCodesResponse	"Новые коды восстановления"
+// @Failure		400		{object}	response.ErrorResponse			"Ошибка валидации (VALIDATION_ERROR), 2FA не подключена (MFA_NOT_ENABLED) или неверный код (INVALID_MFA_CODE)"
+// @Failure		401		{object}	response.ErrorResponse			"Ошибка авторизации (UNAUTHORIZED)"
+// @Failure		500		{object}	response.ErrorResponse			"Ошибка сервера (DB_ERROR)"
+// @Router			/profile/2fa/recovery-codes [post]
+func (h *Handler) RegenerateRecoveryCodesHandler(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "VALIDATION_ERROR",
+			Message: "Ошибка валидации данных",
+			Details: err.Error(),
+		})
+		return
+	}
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "MFA_NOT_ENABLED",
+			Message: "Двухфакторная аутентификация не подключена",
+		})
+		return
+	}
+	if !h.validateTOTP(user, strings.TrimSpace(req.Code)) {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "INVALID_MFA_CODE",
+			Message: "Неверный код подтверждения",
+		})
+		return
+	}
+
+	var codes []string
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка создания кодов восстановления",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// @Summary		Второй шаг входа
+// @Description	Завершает вход пользователя с подключённой 2FA: принимает токен из ответа /auth/login и код из приложения или код восстановления. Токен действует 5 минут и допускает не больше 5 попыток. Неверные коды учитываются в ограничениях входа для email и IP так же, как неверный пароль
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			body	body		MFALoginRequest			true	"Токен второго шага и код"
+// @Success		200		{object}	response.TokenResponse	"Успешная авторизация"
+// @Failure		400		{object}	response.ErrorResponse	"Ошибка валидации данных (VALIDATION_ERROR)"
+// @Failure		401		{object}	response.ErrorResponse	"Неверный или просроченный токен (INVALID_MFA_TOKEN) или неверный код (INVALID_MFA_CODE)"
+// @Failure		429		{object}	response.ErrorResponse	"Превышено число попыток для токена или слишком частые попытки (TOO_MANY_ATTEMPTS), вход заблокирован (LOCKED_OUT)"
+// @Failure		500		{object}	response.ErrorResponse	"Ошибка сервера (CACHE_ERROR, DB_ERROR, TOKEN_GENERATION_ERROR)"
+// @Router			/auth/login/mfa [post]
+func (h *Handler) LoginMFA(c *gin.Context) {
+	var req MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "VALIDATION_ERROR",
+			Message: "Ошибка валидации данных",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	user, jti, method, err := h.parseMFAChallenge(req.MFAToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{
+			Code:    "INVALID_MFA_TOKEN",
+			Message: "Неверный или просроченный токен второго шага входа, войдите заново",
+		})
+		return
+	}
+
+	ip := c.ClientIP()
+	if block := h.checkLoginAllowed(ip, user.Email); block != nil {
+		audit.RecordRequest(h.DB, c, audit.Event{
+			Action:    audit.ActionLoginLocked,
+			UserID:    &user.ID,
+			Email:     user.Email,
+			IP:        ip,
+			UserAgent: c.Request.UserAgent(),
+			Details:   map[string]interface{}{"code": block.Code, "step": "mfa"},
+		})
+		respondLoginBlocked(c, block)
+		return
+	}
+
+	attemptsKey := mfaAttemptsPrefix + jti
+	attempts, err := h.Redis.Incr(ctx, attemptsKey).Result()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "CACHE_ERROR",
+			Message: "Ошибка проверки лимита попыток",
+			Details: err.Error(),
+		})
+		return
+	}
+	if attempts == 1 {
+		h.Redis.Expire(ctx, attemptsKey, mfaChallengeTTL)
+	}
+	if attempts > mfaMaxAttempts {
+		c.JSON(http.StatusTooManyRequests, response.ErrorResponse{
+			Code:    "TOO_MANY_ATTEMPTS",
+			Message: "Слишком много неверных кодов, войдите заново",
+		})
+		return
+	}
+
+	ok, usedRecovery, err := h.verifySecondFactor(user, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка проверки кода",
+			Details: err.Error(),
+		})
+		return
+	}
+	if !ok {
+		// Каждый вход с верным паролем выдаёт новый токен, поэтому лимита на токен недостаточно:
+		// неверные коды учитываются в общих ограничениях входа для email и IP.
+		failures := h.registerLoginFailure(ip, user.Email)
+		audit.RecordRequest(h.DB, c, audit.Event{
+			Action:    audit.ActionMFAFailed,
+			UserID:    &user.ID,
+			Email:     user.Email,
+			IP:        ip,
+			UserAgent: c.Request.UserAgent(),
+			Details:   map[string]interface{}{"attempt": attempts, "failures": failures},
+		})
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{
+			Code:    "INVALID_MFA_CODE",
+			Message: "Неверный код подтверждения",
+		})
+		return
+	}
+	// Токен второго шага одноразовый.
+	h.Redis.Set(ctx, attemptsKey, mfaMaxAttempts+1, mfaChallengeTTL)
+	h.resetLoginFailures(user.Email)
+
+	if usedRecovery {
+		var left int64
+		h.DB.Model(&models.MFARecoveryCode{}).Where("user_id = ? AND used_at IS NULL", user.ID).Count(&left)
+		audit.RecordRequest(h.DB, c, audit.Event{
+			Action:    audit.ActionRecoveryCodeUsed,
+			UserID:    &user.ID,
+			Email:     user.Email,
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			Details:   map[string]interface{}{"codes_left": left},
+		})
+	}
+
+	h.issueTokens(c, user, method)
+}
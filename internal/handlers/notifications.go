@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"test_hack/internal/models"
+	"test_hack/internal/notify"
+	"test_hack/internal/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationSettingsRequest — настройки напоминаний пользователя.
+type NotificationSettingsRequest struct {
+	QueueOpened        bool     `json:"queue_opened"`
+	BeforeEvent        bool     `json:"before_event"`
+	BeforeEventMinutes int      `json:"before_event_minutes" binding:"min=1,max=1440"`
+	PositionReached    bool     `json:"position_reached"`
+	PositionThreshold  int      `json:"position_threshold" binding:"min=1,max=100"`
+	Channels           []string `json:"channels"`
+}
+
+// NotificationSettingsResponse — настройки напоминаний и доступные каналы доставки.
+type NotificationSettingsResponse struct {
+	QueueOpened        bool     `json:"queue_opened" example:"true"`
+	BeforeEvent        bool     `json:"before_event" example:"true"`
+	BeforeEventMinutes int      `json:"before_event_minutes" example:"30"`
+	PositionReached    bool     `json:"position_reached" example:"true"`
+	PositionThreshold  int      `json:"position_threshold" example:"3"`
+	Channels           []string `json:"channels"`
+	AvailableChannels  []string `json:"available_channels"`
+}
+
+func (h *Handler) notificationSettingsResponse(s models.NotificationSettings) NotificationSettingsResponse {
+	channels := []string{}
+	for _, name := range strings.Split(s.Channels, ",") {
+		if name != "" {
+			channels = append(channels, name)
+		}
+	}
+	return NotificationSettingsResponse{
+		QueueOpened:        s.QueueOpened,
+		BeforeEvent:        s.BeforeEvent,
+		BeforeEventMinutes: s.BeforeEventMinutes,
+		PositionReached:    s.PositionReached,
+		PositionThreshold:  s.PositionThreshold,
+		Channels:           channels,
+		AvailableChannels:  h.Notify.Channels(),
+	}
+}
+
+// GetNotificationSettingsHandler возвращает настройки напоминаний
+// @Summary		Настройки напоминаний
+// @Description	Возвращает настройки напоминаний пользователя (или настройки по умолчанию) и доступные каналы доставки
+// @Tags			profile
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	NotificationSettingsResponse	"Настройки напоминаний"
+// @Failure		500	{object}	response.ErrorResponse	"Ошибка сервера (DB_ERROR)"
+// @Router			/profile/notifications [get]
+func (h *Handler) GetNotificationSettingsHandler(c *gin.Context) {
+	settings, err := notify.SettingsFor(h.DB, c.GetUint("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка загрузки настроек напоминаний",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, h.notificationSettingsResponse(settings))
+}
+
+// UpdateNotificationSettingsHandler сохраняет настройки напоминаний
+// @Summary		Изменение настроек напоминаний
+// @Description	Сохраняет, о чём и по каким каналам напоминать пользователю
+// @Tags			profile
+// @Accept			json
+// @Produce		json
+// @Param			settings	body	NotificationSettingsRequest	true	"Настройки напоминаний"
+// @Security		BearerAuth
+// @Success		200	{object}	NotificationSettingsResponse	"Настройки сохранены"
+// @Failure		400	{object}	response.ErrorResponse	"Ошибка валидации (VALIDATION_ERROR, UNKNOWN_CHANNEL)"
+// @Failure		500	{object}	response.ErrorResponse	"Ошибка сервера (DB_ERROR)"
+// @Router			/profile/notifications [put]
+func (h *Handler) UpdateNotificationSettingsHandler(c *gin.Context) {
+	var req NotificationSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "VALIDATION_ERROR",
+			Message: "Ошибка валидации данных",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	for _, name := range req.Channels {
+		if !h.Notify.HasChannel(name) {
+			c.JSON(http.StatusBadRequest, response.ErrorResponse{
+				Code:    "UNKNOWN_CHANNEL",
+				Message: "Неизвестный канал доставки",
+				Details: name,
+			})
+			return
+		}
+	}
+
+	settings, err := notify.SettingsFor(h.DB, c.GetUint("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка загрузки настроек напоминаний",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	settings.QueueOpened = req.QueueOpened
+	settings.BeforeEvent = req.BeforeEvent
+	settings.BeforeEventMinutes = req.BeforeEventMinutes
+	settings.PositionReached = req.PositionReached
+	settings.PositionThreshold = req.PositionThreshold
+	settings.Channels = strings.Join(req.Channels, ",")
+
+	if err := h.DB.Save(&settings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка сохранения настроек напоминаний",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.notificationSettingsResponse(settings))
+}
@@ -0,0 +1,443 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"test_hack/internal/audit"
+	"test_hack/internal/models"
+	"test_hack/internal/notify"
+	"test_hack/internal/response"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+const (
+	oidcStateTTL    = 10 * time.Minute
+	oidcStatePrefix = "oidc_state:"
+)
+
+var (
+	errOIDCEmailNotVerified = errors.New("провайдер не подтвердил email")
+	errOIDCAccountMismatch  = errors.New("пользователь с этим email уже привязан к другому аккаунту SSO")
+	errOIDCSignupDisabled   = errors.New("регистрация через SSO отключена")
+)
+
+// oidcSSO — настроенный провайдер OpenID Connect. nil, если SSO не настроен.
+type oidcSSO struct {
+	oauth    oauth2.Config
+	verifier *oidc.IDTokenVerifier
+	// successURL — страница фронтенда, на которую перенаправляется пользователь с токенами во фрагменте URL.
+	// Если пуст, callback отвечает JSON.
+	successURL  string
+	allowSignup bool
+}
+
+// oidcState сохраняется в Redis между /auth/oidc/login и /auth/oidc/callback.
+type oidcState struct {
+	Verifier string `json:"verifier"` // PKCE code_verifier
+	Nonce    string `json:"nonce"`
+}
+
+// oidcClaims — поля ID токена, по которым создаётся или находится пользователь.
+type oidcClaims struct {
+	Email string `json:"email"`
+	// Некоторые провайдеры передают email_verified строкой
+	EmailVerified interface{} `json:"email_verified"`
+	GivenName     string      `json:"given_name"`
+	FamilyName    string      `json:"family_name"`
+	Name          string      `json:"name"`
+}
+
+// InitOIDC загружает настройки провайдера Config.OIDC.IssuerURL через discovery
+// (/.well-known/openid-configuration). Если адрес провайдера не задан, вход через SSO отключён.
+func (h *Handler) InitOIDC(ctx context.Context) error {
+	cfg := h.Config.OIDC
+	issuer := cfg.IssuerURL
+	if issuer == "" {
+		h.sso = nil
+		return nil
+	}
+	provider, err := oidc.NewProvider(ctx, issuer)
+	if err != nil {
+		h.sso = nil
+		return fmt.Errorf("discovery провайдера %s: %w", issuer, err)
+	}
+
+	redirectURL := cfg.RedirectURL
+	if redirectURL == "" {
+		redirectURL = strings.TrimRight(h.Config.Server.PublicURL, "/") + "/auth/oidc/callback"
+	}
+	scopes := []string{oidc.ScopeOpenID, "email", "profile"}
+	if len(cfg.Scopes) > 0 {
+		scopes = cfg.Scopes
+	}
+
+	h.sso = &oidcSSO{
+		oauth: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			Endpoint:     provider.Endpoint(),
+			RedirectURL:  redirectURL,
+			Scopes:       scopes,
+		},
+		verifier:    provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		successURL:  cfg.SuccessURL,
+		allowSignup: cfg.AllowSignup,
+	}
+	return nil
+}
+
+func (h *Handler) oidcDisabled(c *gin.Context) bool {
+	if h.sso != nil {
+		return false
+	}
+	c.JSON(http.StatusNotFound, response.ErrorResponse{
+		Code:    "OIDC_DISABLED",
+		Message: "Вход через SSO не настроен",
+	})
+	return true
+}
+
+// @Summary		Вход через SSO
+// @Description	Перенаправляет на страницу входа провайдера OpenID Connect (authorization code flow с PKCE). Ссылка действует 10 минут
+// @Tags			auth
+// @Success		302	"Перенаправление к провайдеру"
+// @Failure		404	{object}	response.ErrorResponse	"SSO не настроен (OIDC_DISABLED)"
+// @Failure		500	{object}	response.ErrorResponse	"Ошибка сервера (TOKEN_GENERATION_ERROR, CACHE_ERROR)"
+// @Router			/auth/oidc/login [get]
+func (h *Handler) OIDCLogin(c *gin.Context) {
+	if h.oidcDisabled(c) {
+		return
+	}
+
+	state, err := newOneTimeToken()
+	var nonce string
+	if err == nil {
+		nonce, err = newOneTimeToken()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "TOKEN_GENERATION_ERROR",
+			Message: "Ошибка генерации параметров входа",
+		})
+		return
+	}
+
+	st := oidcState{Verifier: oauth2.GenerateVerifier(), Nonce: nonce}
+	data, _ := json.Marshal(st)
+	if err := h.Redis.Set(ctx, oidcStatePrefix+state, data, oidcStateTTL).Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "CACHE_ERROR",
+			Message: "Ошибка сохранения состояния входа",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.Redirect(http.StatusFound, h.sso.oauth.AuthCodeURL(state, oidc.Nonce(st.Nonce), oauth2.S256ChallengeOption(st.Verifier)))
+}
+
+// @Summary		Завершение входа через SSO
+// @Description	Принимает код авторизации от провайдера, проверяет ID токен и выдаёт токены. Пользователь находится по идентификатору SSO или по подтверждённому провайдером email; если пользователя нет, он создаётся. Если задан OIDC_SUCCESS_URL, вместо JSON выполняется перенаправление на него с токенами во фрагменте URL
+// @Tags			auth
+// @Produce		json
+// @Param			code	query		string							true	"Код авторизации"
+// @Param			state	query		string							true	"Состояние из /auth/oidc/login"
+// @Success		200		{object}	response.TokenResponse			"Успешная авторизация"
+// @Success		202		{object}	response.MFAChallengeResponse	"Требуется код 2FA через /auth/login/mfa"
+// @Success		302		"Перенаправление на OIDC_SUCCESS_URL"
+// @Failure		400		{object}	response.ErrorResponse	"Ошибка провайдера (OIDC_PROVIDER_ERROR), неверное состояние (INVALID_OIDC_STATE) или в токене нет email (OIDC_EMAIL_MISSING)"
+// @Failure		401		{object}	response.ErrorResponse	"Неверный ID токен (INVALID_ID_TOKEN)"
+// @Failure		403		{object}	response.ErrorResponse	"Email не подтверждён провайдером (OIDC_EMAIL_NOT_VERIFIED), запрещённый домен (EMAIL_DOMAIN_NOT_ALLOWED) или регистрация отключена (OIDC_SIGNUP_DISABLED)"
+// @Failure		404		{object}	response.ErrorResponse	"SSO не настроен (OIDC_DISABLED)"
+// @Failure		409		{object}	response.ErrorResponse	"Email привязан к другому аккаунту SSO (OIDC_ACCOUNT_MISMATCH)"
+// @Failure		500		{object}	response.ErrorResponse	"Ошибка сервера (DB_ERROR, TOKEN_GENERATION_ERROR)"
+// @Failure		502		{object}	response.ErrorResponse	"Ошибка обмена кода у провайдера (OIDC_EXCHANGE_ERROR)"
+// @Router			/auth/oidc/callback [get]
+func (h *Handler) OIDCCallback(c *gin.Context) {
+	if h.oidcDisabled(c) {
+		return
+	}
+	if providerErr := c.Query("error"); providerErr != "" {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "OIDC_PROVIDER_ERROR",
+			Message: "Провайдер отклонил вход",
+			Details: strings.TrimSpace(providerErr + " " + c.Query("error_description")),
+		})
+		return
+	}
+
+	// Состояние одноразовое: повторный callback с тем же state отклоняется.
+	var st oidcState
+	raw, err := "", errors.New("пустой state")
+	if state := c.Query("state"); state != "" {
+		raw, err = h.popRedisValue(oidcStatePrefix + state)
+	}
+	if err != nil || json.Unmarshal([]byte(raw), &st) != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "INVALID_OIDC_STATE",
+			Message: "Ссылка входа недействительна или истекла, начните вход заново",
+		})
+		return
+	}
+
+	reqCtx := c.Request.Context()
+	token, err := h.sso.oauth.Exchange(reqCtx, c.Query("code"), oauth2.VerifierOption(st.Verifier))
+	if err != nil {
+		c.JSON(http.StatusBadGateway, response.ErrorResponse{
+			Code:    "OIDC_EXCHANGE_ERROR",
+			Message: "Не удалось получить токены у провайдера",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	invalidToken := response.ErrorResponse{
+		Code:    "INVALID_ID_TOKEN",
+		Message: "Провайдер вернул недействительный ID токен",
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, invalidToken)
+		return
+	}
+	idToken, err := h.sso.verifier.Verify(reqCtx, rawIDToken)
+	if err != nil {
+		invalidToken.Details = err.Error()
+		c.JSON(http.StatusUnauthorized, invalidToken)
+		return
+	}
+	if idToken.Nonce != st.Nonce {
+		invalidToken.Details = "nonce не совпадает"
+		c.JSON(http.StatusUnauthorized, invalidToken)
+		return
+	}
+	var claims oidcClaims
+	if err := idToken.Claims(&claims); err != nil {
+		invalidToken.Details = err.Error()
+		c.JSON(http.StatusUnauthorized, invalidToken)
+		return
+	}
+	if claims.Email == "" {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "OIDC_EMAIL_MISSING",
+			Message: "Провайдер не передал email; проверьте scope email",
+		})
+		return
+	}
+	if !h.emailDomainAllowed(claims.Email) {
+		c.JSON(http.StatusForbidden, response.ErrorResponse{
+			Code:    "EMAIL_DOMAIN_NOT_ALLOWED",
+			Message: "Вход с этим почтовым доменом запрещён",
+			Details: strings.Join(h.Config.Accounts.EmailAllowedDomains, ","),
+		})
+		return
+	}
+
+	user, linked, err := h.findOrCreateOIDCUser(idToken.Subject, claims)
+	switch {
+	case errors.Is(err, errOIDCEmailNotVerified):
+		c.JSON(http.StatusForbidden, response.ErrorResponse{
+			Code:    "OIDC_EMAIL_NOT_VERIFIED",
+			Message: "Провайдер не подтвердил email",
+		})
+		return
+	case errors.Is(err, errOIDCSignupDisabled):
+		c.JSON(http.StatusForbidden, response.ErrorResponse{
+			Code:    "OIDC_SIGNUP_DISABLED",
+			Message: "Пользователь не найден, регистрация через SSO отключена",
+		})
+		return
+	case errors.Is(err, errOIDCAccountMismatch):
+		c.JSON(http.StatusConflict, response.ErrorResponse{
+			Code:    "OIDC_ACCOUNT_MISMATCH",
+			Message: "Пользователь с этим email уже привязан к другому аккаунту SSO",
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка входа через SSO",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	event := audit.Event{
+		Action:    audit.ActionOIDCLogin,
+		UserID:    &user.ID,
+		Email:     user.Email,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Details:   map[string]interface{}{"issuer": idToken.Issuer, "subject": idToken.Subject},
+	}
+	if linked {
+		event.Action = audit.ActionOIDCLinked
+	}
+	audit.RecordRequest(h.DB, c, event)
+
+	h.completeOIDCLogin(c, user)
+}
+
+// completeOIDCLogin выдаёт токены (или токен второго шага при подключённой 2FA)
+// в JSON либо перенаправлением на OIDC_SUCCESS_URL.
+func (h *Handler) completeOIDCLogin(c *gin.Context, user models.User) {
+	fragment := url.Values{}
+	var body interface{}
+	status := http.StatusOK
+	if user.TOTPEnabled {
+		mfaToken, err := h.generateMFAChallenge(user, models.SessionMethodSSO)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+				Code:    "TOKEN_GENERATION_ERROR",
+				Message: "Ошибка при генерации токена второго шага входа",
+			})
+			return
+		}
+		status = http.StatusAccepted
+		body = response.MFAChallengeResponse{MFARequired: true, MFAToken: mfaToken, ExpiresIn: int(mfaChallengeTTL.Seconds())}
+		fragment.Set("mfa_token", mfaToken)
+	} else {
+		tokens, err := h.startSession(c, user, models.SessionMethodSSO)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+				Code:    "TOKEN_GENERATION_ERROR",
+				Message: "Ошибка при генерации токенов",
+			})
+			return
+		}
+		body = tokens
+		fragment.Set("access_token", tokens.AccessToken)
+		fragment.Set("refresh_token", tokens.RefreshToken)
+	}
+
+	if h.sso.successURL != "" {
+		// Фрагмент URL не передаётся на сервер фронтенда и не попадает в его логи.
+		c.Redirect(http.StatusFound, h.sso.successURL+"#"+fragment.Encode())
+		return
+	}
+	c.JSON(status, body)
+}
+
+// resetUnverifiedAccount привязывает SSO к неподтверждённому аккаунту: подтверждает email, заменяет пароль
+// случайным, отключает 2FA и завершает все сессии и выданные refresh-токены.
+func (h *Handler) resetUnverifiedAccount(user *models.User, subject string, now time.Time) error {
+	password, err := newOneTimeToken()
+	if err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"oidc_subject":      subject,
+			"email_verified":    true,
+			"email_verified_at": now,
+			"password_hash":     string(hash),
+			"token_version":     gorm.Expr("token_version + 1"),
+			"totp_enabled":      false,
+			"totp_enabled_at":   nil,
+			"totp_secret":       "",
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return revokeSessions(tx, user.ID)
+	})
+	if err != nil {
+		return err
+	}
+	// Updates с выражением не обновляет поля структуры, поэтому перечитываем пользователя.
+	return h.DB.First(user, user.ID).Error
+}
+
+// findOrCreateOIDCUser находит пользователя по идентификатору SSO, затем по email, и при необходимости
+// привязывает аккаунт SSO или создаёт нового пользователя. linked — аккаунт SSO впервые привязан
+// к уже существующему пользователю.
+func (h *Handler) findOrCreateOIDCUser(subject string, claims oidcClaims) (user models.User, linked bool, err error) {
+	if err = h.DB.Where("oidc_subject = ?", subject).First(&user).Error; err == nil {
+		return user, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, false, err
+	}
+
+	// Связывать аккаунты по email можно только если провайдер подтвердил email.
+	if claims.EmailVerified != true && claims.EmailVerified != "true" {
+		return user, false, errOIDCEmailNotVerified
+	}
+
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+	now := time.Now()
+	err = h.DB.Where("LOWER(email) = ?", email).First(&user).Error
+	switch {
+	case err == nil:
+		if user.OIDCSubject != nil && *user.OIDCSubject != subject {
+			return user, false, errOIDCAccountMismatch
+		}
+		if user.EmailVerified {
+			if err := h.DB.Model(&user).Update("oidc_subject", subject).Error; err != nil {
+				return user, false, err
+			}
+			return user, true, nil
+		}
+		// Неподтверждённый аккаунт мог зарегистрировать кто угодно, указав чужой email. Владелец email
+		// подтвердил его через SSO, поэтому всё, что задал автор регистрации, сбрасывается: пароль,
+		// 2FA и сессии.
+		if err := h.resetUnverifiedAccount(&user, subject, now); err != nil {
+			return user, false, err
+		}
+		return user, true, nil
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return user, false, err
+	}
+
+	if h.sso != nil && !h.sso.allowSignup {
+		return user, false, errOIDCSignupDisabled
+	}
+
+	// Пароль у пользователя SSO случайный; при необходимости его можно задать через сброс пароля.
+	password, err := newOneTimeToken()
+	if err != nil {
+		return user, false, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return user, false, err
+	}
+	name, surname := claims.GivenName, claims.FamilyName
+	if name == "" && surname == "" {
+		parts := strings.Fields(claims.Name)
+		if len(parts) > 0 {
+			name, surname = parts[0], strings.Join(parts[1:], " ")
+		}
+	}
+	if name == "" {
+		name, _, _ = strings.Cut(email, "@")
+	}
+	user = models.User{
+		Name:            name,
+		Surname:         surname,
+		Email:           email,
+		PasswordHash:    string(hash),
+		Role:            models.RoleStudent,
+		Language:        notify.DefaultLang,
+		EmailVerified:   true,
+		EmailVerifiedAt: &now,
+		OIDCSubject:     &subject,
+	}
+	return user, false, h.DB.Create(&user).Error
+}
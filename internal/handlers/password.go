@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strings"
+	"test_hack/internal/models"
+	"test_hack/internal/notify"
+	"test_hack/internal/response"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	passwordResetTTL       = time.Hour
+	passwordResetKeyPrefix = "password_reset_limit:"
+	// Не больше emailActionLimit писем одного типа на один email за emailActionWindow.
+	emailActionLimit  = 3
+	emailActionWindow = time.Hour
+)
+
+// passwordResetExpiresIn — срок действия ссылки для текста письма на языке пользователя.
+var passwordResetExpiresIn = map[string]string{
+	notify.LangRU: "1 час",
+	notify.LangEN: "1 hour",
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// hashToken возвращает SHA-256 хеш одноразового токена для хранения в базе.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// allowEmailAction учитывает отправку письма на email и сообщает, не превышен ли лимит.
+func (h *Handler) allowEmailAction(prefix, email string) (bool, error) {
+	key := prefix + email
+	count, err := h.Redis.Incr(ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+	if count == 1 {
+		h.Redis.Expire(ctx, key, emailActionWindow)
+	}
+	return count <= emailActionLimit, nil
+}
+
+// newOneTimeToken генерирует случайный токен для ссылок в письмах.
+func newOneTimeToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// @Summary		Запрос сброса пароля
+// @Description	Отправляет на email ссылку для сброса пароля. Ответ не зависит от того, зарегистрирован ли email. Не больше 3 запросов на один email в час
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			body	body		ForgotPasswordRequest		true	"Email пользователя"
+// @Success		200		{object}	response.MessageResponse	"Запрос принят"
+// @Failure		400		{object}	response.ErrorResponse		"Ошибка валидации (VALIDATION_ERROR)"
+// @Failure		429		{object}	response.ErrorResponse		"Слишком много запросов (TOO_MANY_REQUESTS)"
+// @Failure		500		{object}	response.ErrorResponse		"Ошибка сервера (CACHE_ERROR, TOKEN_GENERATION_ERROR, DB_ERROR)"
+// @Router			/auth/forgot-password [post]
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "VALIDATION_ERROR",
+			Message: "Ошибка валидации данных",
+			Details: err.Error(),
+		})
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	// Лимит считается до поиска пользователя, чтобы по ответу нельзя было узнать, существует ли email.
+	allowed, err := h.allowEmailAction(passwordResetKeyPrefix, email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "CACHE_ERROR",
+			Message: "Ошибка проверки лимита запросов",
+			Details: err.Error(),
+		})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusTooManyRequests, response.ErrorResponse{
+			Code:    "TOO_MANY_REQUESTS",
+			Message: "Слишком много запросов на сброс пароля, попробуйте позже",
+		})
+		return
+	}
+
+	accepted := response.MessageResponse{Message: "Если email зарегистрирован, на него отправлена ссылка для сброса пароля"}
+
+	var user models.User
+	if err := h.DB.Where("LOWER(email) = ?", email).First(&user).Error; err != nil {
+		c.JSON(http.StatusOK, accepted)
+		return
+	}
+
+	token, err := newOneTimeToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "TOKEN_GENERATION_ERROR",
+			Message: "Ошибка генерации токена",
+		})
+		return
+	}
+
+	now := time.Now()
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		// Действует только последняя отправленная ссылка.
+		if err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: hashToken(token),
+			ExpiresAt: now.Add(passwordResetTTL),
+			RequestIP: c.ClientIP(),
+		}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка создания токена сброса пароля",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	resetURL := h.Config.Accounts.PasswordResetURL
+	expiresIn, ok := passwordResetExpiresIn[user.Language]
+	if !ok {
+		expiresIn = passwordResetExpiresIn[notify.DefaultLang]
+	}
+	if err := h.Notify.SendTemplate(user.Email, user.Language, notify.TemplatePasswordReset, map[string]interface{}{
+		"Name":      user.Name,
+		"URL":       resetURL + "?token=" + token,
+		"ExpiresIn": expiresIn,
+	}); err != nil {
+		log.Printf("Ошибка отправки письма сброса пароля (user_id=%d): %v", user.ID, err)
+	}
+
+	c.JSON(http.StatusOK, accepted)
+}
+
+// @Summary		Сброс пароля
+// @Description	Устанавливает новый пароль по токену из письма. Токен одноразовый и действует 1 час; после сброса все сессии завершаются и выданные токены перестают действовать
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			body	body		ResetPasswordRequest		true	"Токен из письма и новый пароль"
+// @Success		200		{object}	response.MessageResponse	"Пароль изменён"
+// @Failure		400		{object}	response.ErrorResponse		"Ошибка валидации (VALIDATION_ERROR) или недействительный токен (INVALID_RESET_TOKEN)"
+// @Failure		500		{object}	response.ErrorResponse		"Ошибка сервера (PASSWORD_HASH_ERROR, DB_ERROR)"
+// @Router			/auth/reset-password [post]
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "VALIDATION_ERROR",
+			Message: "Ошибка валидации данных",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	invalidToken := response.ErrorResponse{
+		Code:    "INVALID_RESET_TOKEN",
+		Message: "Ссылка для сброса пароля недействительна или истекла",
+	}
+
+	var resetToken models.PasswordResetToken
+	if err := h.DB.
+		Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashToken(req.Token), time.Now()).
+		First(&resetToken).Error; err != nil {
+		c.JSON(http.StatusBadRequest, invalidToken)
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "PASSWORD_HASH_ERROR",
+			Message: "Ошибка при хешировании пароля",
+		})
+		return
+	}
+
+	used := false
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		// Условное обновление гарантирует, что при параллельных запросах токен сработает только один раз.
+		claim := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", resetToken.ID).
+			Update("used_at", time.Now())
+		if claim.Error != nil || claim.RowsAffected == 0 {
+			return claim.Error
+		}
+		used = true
+		if err := tx.Model(&models.User{}).Where("id = ?", resetToken.UserID).Updates(map[string]interface{}{
+			"password_hash": string(hashedPassword),
+			"token_version": gorm.Expr("token_version + 1"),
+		}).Error; err != nil {
+			return err
+		}
+		return revokeSessions(tx, resetToken.UserID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка при изменении пароля",
+			Details: err.Error(),
+		})
+		return
+	}
+	if !used {
+		c.JSON(http.StatusBadRequest, invalidToken)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.MessageResponse{Message: "Пароль изменён, войдите с новым паролем"})
+}
@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"test_hack/internal/audit"
+	"test_hack/internal/models"
+	"test_hack/internal/requestid"
+	"test_hack/internal/response"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Данные, которыми заменяются персональные данные удалённого пользователя.
+const (
+	deletedUserName    = "Удалённый"
+	deletedUserSurname = "пользователь"
+)
+
+// ssoReauthWindow — в течение этого времени после входа через SSO пользователь может удалить аккаунт
+// без пароля: у созданных через SSO аккаунтов пароль случайный и пользователю неизвестен.
+const ssoReauthWindow = 10 * time.Minute
+
+// UpdateProfileRequest — изменяемые поля профиля. Незаданные поля не меняются.
+type UpdateProfileRequest struct {
+	Name     *string `json:"name" binding:"omitempty,min=1,max=100"`
+	Surname  *string `json:"surname" binding:"omitempty,min=1,max=100"`
+	GroupID  *string `json:"group_id" binding:"omitempty,max=50"`      // Пустая строка — убрать группу
+	Language *string `json:"language" binding:"omitempty,oneof=ru en"` // Язык писем и уведомлений
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// DeleteProfileRequest — подтверждение удаления аккаунта: пароль или код 2FA. Без них удаление
+// подтверждается свежим входом через SSO (не раньше 10 минут назад).
+type DeleteProfileRequest struct {
+	Password string `json:"password"` // Текущий пароль
+	Code     string `json:"code"`     // Код из приложения-аутентификатора или код восстановления
+}
+
+// UpdateProfileHandler godoc
+// @Summary		Изменение профиля
+// @Description	Изменяет имя, фамилию, группу и язык пользователя. Незаданные поля не меняются
+// @Tags			profile
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			body	body		UpdateProfileRequest		true	"Новые значения полей"
+// @Success		200		{object}	response.ProfileResponse	"Обновлённый профиль"
+// @Failure		400		{object}	response.ErrorResponse		"Ошибка валидации (VALIDATION_ERROR)"
+// @Failure		401		{object}	response.ErrorResponse		"Ошибка авторизации (UNAUTHORIZED)"
+// @Failure		500		{object}	response.ErrorResponse		"Ошибка сервера (DB_ERROR)"
+// @Router			/profile [put]
+func (h *Handler) UpdateProfileHandler(c *gin.Context) {
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "VALIDATION_ERROR",
+			Message: "Ошибка валидации данных",
+			Details: err.Error(),
+		})
+		return
+	}
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = strings.TrimSpace(*req.Name)
+	}
+	if req.Surname != nil {
+		updates["surname"] = strings.TrimSpace(*req.Surname)
+	}
+	if req.GroupID != nil {
+		updates["group_id"] = strings.TrimSpace(*req.GroupID)
+	}
+	if req.Language != nil {
+		updates["language"] = *req.Language
+	}
+	if name, ok := updates["name"]; ok && name == "" {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "VALIDATION_ERROR",
+			Message: "Ошибка валидации данных",
+			Details: "имя не может быть пустым",
+		})
+		return
+	}
+	if surname, ok := updates["surname"]; ok && surname == "" {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "VALIDATION_ERROR",
+			Message: "Ошибка валидации данных",
+			Details: "фамилия не может быть пустой",
+		})
+		return
+	}
+
+	if len(updates) > 0 {
+		err := h.DB.Model(&user).Updates(updates).Error
+		if err == nil {
+			err = h.DB.First(&user, user.ID).Error
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+				Code:    "DB_ERROR",
+				Message: "Ошибка при изменении профиля",
+				Details: err.Error(),
+			})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, h.profileResponse(user))
+}
+
+// ChangePasswordHandler godoc
+// @Summary		Смена пароля
+// @Description	Меняет пароль после проверки текущего. Все сессии завершаются, выданные ранее токены перестают действовать; в ответе — новая пара токенов для текущего клиента
+// @Tags			profile
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			body	body		ChangePasswordRequest	true	"Текущий и новый пароль"
+// @Success		200		{object}	response.TokenResponse	"Пароль изменён"
+// @Failure		400		{object}	response.ErrorResponse	"Ошибка валидации (VALIDATION_ERROR)"
+// @Failure		401		{object}	response.ErrorResponse	"Ошибка авторизации (UNAUTHORIZED) или неверный текущий пароль (INVALID_CREDENTIALS)"
+// @Failure		500		{object}	response.ErrorResponse	"Ошибка сервера (PASSWORD_HASH_ERROR, DB_ERROR, TOKEN_GENERATION_ERROR)"
+// @Router			/profile/password [post]
+func (h *Handler) ChangePasswordHandler(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "VALIDATION_ERROR",
+			Message: "Ошибка валидации данных",
+			Details: err.Error(),
+		})
+		return
+	}
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.OldPassword)); err != nil {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{
+			Code:    "INVALID_CREDENTIALS",
+			Message: "Неверный текущий пароль",
+		})
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "PASSWORD_HASH_ERROR",
+			Message: "Ошибка при хешировании пароля",
+		})
+		return
+	}
+	// Все сессии завершаются; текущий клиент получает новую сессию вместе с токенами в ответе.
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"password_hash": string(hashedPassword),
+			"token_version": gorm.Expr("token_version + 1"),
+		}).Error; err != nil {
+			return err
+		}
+		return revokeSessions(tx, user.ID)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка при изменении пароля",
+			Details: err.Error(),
+		})
+		return
+	}
+	// Перечитываем пользователя, чтобы новый refresh токен получил увеличенную версию.
+	if err := h.DB.First(&user, user.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка при получении данных пользователя",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	audit.RecordRequest(h.DB, c, audit.Event{
+		Action:    audit.ActionPasswordChanged,
+		UserID:    &user.ID,
+		Email:     user.Email,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	h.issueTokens(c, user, models.SessionMethodPassword)
+}
+
+// DeleteProfileHandler godoc
+// @Summary		Удаление аккаунта
+// @Description	Выводит пользователя из всех активных очередей (позиции остальных участников сдвигаются, участники получают событие user_left) и обезличивает аккаунт: имя, email, пароль, группа и привязки удаляются, история очередей сохраняется без персональных данных, а из журнала аудита удаляются email, IP и User-Agent пользователя. Удаление подтверждается паролем, кодом 2FA (если она подключена) или входом через SSO не раньше 10 минут назад — так аккаунт без пароля удаляется после повторного входа через SSO
+// @Tags			profile
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			body	body		DeleteProfileRequest		true	"Пароль или код 2FA для подтверждения"
+// @Success		200		{object}	response.MessageResponse	"Аккаунт удалён"
+// @Failure		400		{object}	response.ErrorResponse		"Ошибка валидации (VALIDATION_ERROR)"
+// @Failure		401		{object}	response.ErrorResponse		"Ошибка авторизации (UNAUTHORIZED), неверный пароль (INVALID_CREDENTIALS), неверный код (INVALID_MFA_CODE) или нужен повторный вход (REAUTHENTICATION_REQUIRED)"
+// @Failure		429		{object}	response.ErrorResponse		"Слишком много попыток (RATE_LIMITED)"
+// @Failure		500		{object}	response.ErrorResponse		"Ошибка сервера (DB_ERROR)"
+// @Router			/profile [delete]
+func (h *Handler) DeleteProfileHandler(c *gin.Context) {
+	var req DeleteProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "VALIDATION_ERROR",
+			Message: "Ошибка валидации данных",
+			Details: err.Error(),
+		})
+		return
+	}
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	if !h.confirmDeletion(c, user, req) {
+		return
+	}
+
+	if err := h.DeleteUser(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка при удалении аккаунта",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	// Персональные данные уже удалены из журнала, поэтому событие записывается без email, IP и User-Agent.
+	audit.Record(h.DB, audit.Event{
+		Action:    audit.ActionAccountDeleted,
+		ActorID:   &user.ID,
+		UserID:    &user.ID,
+		RequestID: requestid.Get(c),
+	})
+	c.JSON(http.StatusOK, response.MessageResponse{Message: "Аккаунт удалён"})
+}
+
+// confirmDeletion проверяет подтверждение удаления аккаунта: пароль, код 2FA или сессию, открытую входом
+// через SSO не раньше ssoReauthWindow назад. При отказе ответ уже отправлен.
+func (h *Handler) confirmDeletion(c *gin.Context, user models.User, req DeleteProfileRequest) bool {
+	switch {
+	case req.Password != "":
+		if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) == nil {
+			return true
+		}
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{
+			Code:    "INVALID_CREDENTIALS",
+			Message: "Неверный пароль",
+		})
+		return false
+	case req.Code != "":
+		if user.TOTPEnabled {
+			if ok, _, err := h.verifySecondFactor(user, req.Code); err == nil && ok {
+				return true
+			}
+		}
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{
+			Code:    "INVALID_MFA_CODE",
+			Message: "Неверный код подтверждения",
+		})
+		return false
+	}
+
+	var count int64
+	err := h.DB.Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND method = ? AND revoked_at IS NULL AND created_at > ?",
+			c.GetUint("sessionID"), user.ID, models.SessionMethodSSO, time.Now().Add(-ssoReauthWindow)).
+		Count(&count).Error
+	if err == nil && count == 1 {
+		return true
+	}
+	c.JSON(http.StatusUnauthorized, response.ErrorResponse{
+		Code:    "REAUTHENTICATION_REQUIRED",
+		Message: "Подтвердите удаление паролем, кодом 2FA или войдите через SSO заново",
+	})
+	return false
+}
+
+// DeleteUser выводит пользователя из всех активных очередей, обезличивает его запись и удаляет его email,
+// IP и User-Agent из журнала аудита.
+// Запись пользователя не удаляется, чтобы история очередей и отчёты о посещаемости остались согласованными.
+func (h *Handler) DeleteUser(userID uint) error {
+	// Выход сдвигает позиции остальных участников и рассылает user_left.
+	left, err := h.Queues().LeaveAll(userID)
+	for _, e := range left {
+		audit.Record(h.DB, audit.Event{
+			Action:     audit.ActionQueueLeft,
+			ActorID:    &userID,
+			UserID:     &userID,
+			TargetType: audit.TargetQueue,
+			TargetID:   e.QueueID,
+			Before:     map[string]interface{}{"position": e.Position},
+			Details:    map[string]interface{}{"reason": "account_deleted"},
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	return h.DB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Select("id", "email").First(&user, userID).Error; err != nil {
+			return err
+		}
+		if err := audit.ScrubUser(tx, userID, user.Email); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"name":              deletedUserName,
+			"surname":           deletedUserSurname,
+			"email":             fmt.Sprintf("deleted-%d@deleted.invalid", userID),
+			"password_hash":     "",
+			"role":              models.RoleStudent,
+			"group_id":          "",
+			"telegram_chat_id":  nil,
+			"oidc_subject":      nil,
+			"totp_secret":       "",
+			"totp_enabled":      false,
+			"totp_enabled_at":   nil,
+			"email_verified":    false,
+			"email_verified_at": nil,
+			"token_version":     gorm.Expr("token_version + 1"),
+		}).Error; err != nil {
+			return err
+		}
+		for _, model := range []interface{}{
+			&models.NotificationSettings{},
+			&models.Notification{},
+			&models.DataExport{},
+			&models.PushSubscription{},
+			&models.MFARecoveryCode{},
+			&models.PasswordResetToken{},
+			&models.EmailVerificationToken{},
+			&models.Session{},
+		} {
+			if err := tx.Unscoped().Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
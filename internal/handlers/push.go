@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"test_hack/internal/models"
+	"test_hack/internal/notify"
+	"test_hack/internal/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VAPIDPublicKeyResponse — публичный ключ VAPID для вызова pushManager.subscribe в браузере.
+type VAPIDPublicKeyResponse struct {
+	PublicKey string `json:"public_key" example:"BNcRdreALRFXTkOOUHK1EtK2wtaz5Ry4YfYCA_0QTpQtUbVlUls0VJXg7A8u-Ts1XbjhazAkj7I99e8QcYP7DkM"`
+}
+
+// PushSubscriptionRequest — объект PushSubscription, полученный в браузере (subscription.toJSON()).
+type PushSubscriptionRequest struct {
+	Endpoint string `json:"endpoint" binding:"required,url" example:"https://fcm.googleapis.com/fcm/send/abc123"`
+	Keys     struct {
+		P256dh string `json:"p256dh" binding:"required"`
+		Auth   string `json:"auth" binding:"required"`
+	} `json:"keys" binding:"required"`
+}
+
+// DeletePushSubscriptionRequest — адрес подписки, которую нужно удалить.
+type DeletePushSubscriptionRequest struct {
+	Endpoint string `json:"endpoint" binding:"required"`
+}
+
+// GetVAPIDPublicKeyHandler возвращает публичный ключ VAPID
+// @Summary		Публичный ключ VAPID
+// @Description	Возвращает ключ applicationServerKey для подписки браузера на Web Push
+// @Tags			push
+// @Produce		json
+// @Success		200	{object}	VAPIDPublicKeyResponse	"Публичный ключ"
+// @Failure		503	{object}	response.ErrorResponse	"Web Push не настроен (PUSH_DISABLED)"
+// @Router			/push/vapid-public-key [get]
+func (h *Handler) GetVAPIDPublicKeyHandler(c *gin.Context) {
+	sender := h.Notify.PushSender()
+	if sender == nil {
+		c.JSON(http.StatusServiceUnavailable, response.ErrorResponse{
+			Code:    "PUSH_DISABLED",
+			Message: "Web Push не настроен",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, VAPIDPublicKeyResponse{PublicKey: sender.PublicKey})
+}
+
+// SubscribePushHandler сохраняет подписку браузера на Web Push
+// @Summary		Подписка на Web Push
+// @Description	Сохраняет подписку браузера и включает канал webpush в настройках напоминаний. Повторная регистрация того же endpoint обновляет ключи
+// @Tags			profile
+// @Accept			json
+// @Produce		json
+// @Param			subscription	body	PushSubscriptionRequest	true	"Объект PushSubscription"
+// @Security		BearerAuth
+// @Success		201	{object}	response.MessageResponse	"Подписка сохранена"
+// @Failure		400	{object}	response.ErrorResponse	"Ошибка валидации (VALIDATION_ERROR)"
+// @Failure		503	{object}	response.ErrorResponse	"Web Push не настроен (PUSH_DISABLED)"
+// @Failure		500	{object}	response.ErrorResponse	"Ошибка сервера (DB_ERROR)"
+// @Router			/profile/push/subscriptions [post]
+func (h *Handler) SubscribePushHandler(c *gin.Context) {
+	if h.Notify.PushSender() == nil {
+		c.JSON(http.StatusServiceUnavailable, response.ErrorResponse{
+			Code:    "PUSH_DISABLED",
+			Message: "Web Push не настроен",
+		})
+		return
+	}
+
+	var req PushSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "VALIDATION_ERROR",
+			Message: "Ошибка валидации данных",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	userID := c.GetUint("userID")
+	// Браузер может переоформить подписку с тем же endpoint — в этом случае запись переходит текущему пользователю.
+	var sub models.PushSubscription
+	h.DB.Where("endpoint = ?", req.Endpoint).Limit(1).Find(&sub)
+	sub.UserID = userID
+	sub.Endpoint = req.Endpoint
+	sub.P256dh = req.Keys.P256dh
+	sub.Auth = req.Keys.Auth
+	sub.UserAgent = c.GetHeader("User-Agent")
+	if err := h.DB.Save(&sub).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка сохранения подписки",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if err := h.enableChannel(userID, "webpush"); err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка сохранения настроек напоминаний",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, response.MessageResponse{Message: "Подписка сохранена"})
+}
+
+// UnsubscribePushHandler удаляет подписку браузера на Web Push
+// @Summary		Отписка от Web Push
+// @Description	Удаляет подписку браузера пользователя по endpoint
+// @Tags			profile
+// @Accept			json
+// @Produce		json
+// @Param			subscription	body	DeletePushSubscriptionRequest	true	"Адрес подписки"
+// @Security		BearerAuth
+// @Success		200	{object}	response.MessageResponse	"Подписка удалена"
+// @Failure		400	{object}	response.ErrorResponse	"Ошибка валидации (VALIDATION_ERROR)"
+// @Failure		404	{object}	response.ErrorResponse	"Подписка не найдена (SUBSCRIPTION_NOT_FOUND)"
+// @Failure		500	{object}	response.ErrorResponse	"Ошибка сервера (DB_ERROR)"
+// @Router			/profile/push/subscriptions [delete]
+func (h *Handler) UnsubscribePushHandler(c *gin.Context) {
+	var req DeletePushSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "VALIDATION_ERROR",
+			Message: "Ошибка валидации данных",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	result := h.DB.Unscoped().
+		Where("user_id = ? AND endpoint = ?", c.GetUint("userID"), req.Endpoint).
+		Delete(&models.PushSubscription{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка удаления подписки",
+			Details: result.Error.Error(),
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, response.ErrorResponse{
+			Code:    "SUBSCRIPTION_NOT_FOUND",
+			Message: "Подписка не найдена",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.MessageResponse{Message: "Подписка удалена"})
+}
+
+// enableChannel добавляет канал доставки в настройки напоминаний пользователя, если его там нет.
+func (h *Handler) enableChannel(userID uint, name string) error {
+	settings, err := notify.SettingsFor(h.DB, userID)
+	if err != nil {
+		return err
+	}
+	for _, ch := range strings.Split(settings.Channels, ",") {
+		if ch == name {
+			return nil
+		}
+	}
+	if settings.Channels == "" {
+		settings.Channels = name
+	} else {
+		settings.Channels += "," + name
+	}
+	return h.DB.Save(&settings).Error
+}
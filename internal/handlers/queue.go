@@ -1,16 +1,25 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"test_hack/internal/audit"
 	"test_hack/internal/models"
 	"test_hack/internal/response"
-	"test_hack/internal/storage"
-	"time"
+	"test_hack/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Ошибки операций с очередью, общие для HTTP-обработчиков, WebSocket и Telegram-бота.
+var (
+	ErrQueueNotFound  = service.ErrQueueNotFound
+	ErrQueueInactive  = service.ErrQueueInactive
+	ErrAlreadyInQueue = service.ErrAlreadyInQueue
+	ErrNotInQueue     = service.ErrNotInQueue
+)
+
 // JoinQueueHandler обрабатывает запрос на вступление в очередь
 // @Summary		Вступление в очередь
 // @Description	Добавляет пользователя в очередь и уведомляет других участников
@@ -21,10 +30,12 @@ import (
 // @Security		BearerAuth
 // @Success		200	{object}	response.MessageResponse	"Успешное вступление в очередь с указанием позиции"
 // @Failure		400	{object}	response.ErrorResponse	"Ошибка валидации (INVALID_QUEUE_ID, ALREADY_IN_QUEUE, QUEUE_INACTIVE)"
+// @Failure		403	{object}	response.ErrorResponse	"Email не подтверждён (EMAIL_NOT_VERIFIED)"
 // @Failure		404	{object}	response.ErrorResponse	"Очередь не найдена (QUEUE_NOT_FOUND)"
+// @Failure		429	{object}	response.ErrorResponse	"Слишком много запросов (RATE_LIMITED)"
 // @Failure		500	{object}	response.ErrorResponse	"Ошибка сервера (DB_ERROR)"
 // @Router			/api/queues/{id}/join [post]
-func JoinQueueHandler(c *gin.Context) {
+func (h *Handler) JoinQueueHandler(c *gin.Context) {
 	queueIDStr := c.Param("id")
 	queueID, err := strconv.Atoi(queueIDStr)
 	if err != nil {
@@ -36,47 +47,33 @@ func JoinQueueHandler(c *gin.Context) {
 	}
 
 	userID := c.GetUint("userID")
-	var existingEntry models.QueueEntry
-	if err := storage.DB.Where("user_id = ? AND queue_id = ? AND exited_at IS NULL", userID, queueID).First(&existingEntry).Error; err == nil {
+	newPosition, err := h.Queues().Join(userID, uint(queueID))
+	switch {
+	case errors.Is(err, ErrAlreadyInQueue):
 		c.JSON(http.StatusBadRequest, response.ErrorResponse{
 			Code:    "ALREADY_IN_QUEUE",
 			Message: "Пользователь уже состоит в этой очереди",
 		})
 		return
-	}
-
-	var queue models.Queue
-	if err := storage.DB.First(&queue, queueID).Error; err != nil {
+	case errors.Is(err, ErrQueueNotFound):
 		c.JSON(http.StatusNotFound, response.ErrorResponse{
 			Code:    "QUEUE_NOT_FOUND",
 			Message: "Очередь не найдена",
 		})
 		return
-	}
-
-	now := time.Now()
-	// Проверяем, что очередь активна: открыта и время не вышло (между OpensAt и ClosesAt)
-	if now.Before(queue.OpensAt) || now.After(queue.ClosesAt) || !queue.IsActive {
+	case errors.Is(err, ErrQueueInactive):
 		c.JSON(http.StatusBadRequest, response.ErrorResponse{
 			Code:    "QUEUE_INACTIVE",
 			Message: "Очередь не активна",
 		})
 		return
-	}
-
-	var maxPosition int
-	row := storage.DB.Model(&models.QueueEntry{}).Where("queue_id = ? AND exited_at IS NULL", queueID).Select("COALESCE(MAX(position),0)").Row()
-	_ = row.Scan(&maxPosition)
-	newPosition := maxPosition + 1
-
-	entry := models.QueueEntry{
-		UserID:   userID,
-		QueueID:  uint(queueID),
-		Position: newPosition,
-		ExitedAt: nil,
-	}
-
-	if err := storage.DB.Create(&entry).Error; err != nil {
+	case errors.Is(err, ErrEmailNotVerified):
+		c.JSON(http.StatusForbidden, response.ErrorResponse{
+			Code:    "EMAIL_NOT_VERIFIED",
+			Message: "Подтвердите email, чтобы вставать в очереди",
+		})
+		return
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
 			Code:    "DB_ERROR",
 			Message: "Ошибка добаления в очередь",
@@ -85,15 +82,13 @@ func JoinQueueHandler(c *gin.Context) {
 		return
 	}
 
-	HubInstance.BroadcastWSMessage(WSMessage{
-		EventType: "user_joined",
-		QueueID:   queueIDStr,
-		Data: map[string]interface{}{
-			"user_id":  userID,
-			"position": newPosition,
-		},
+	audit.RecordRequest(h.DB, c, audit.Event{
+		Action:     audit.ActionQueueJoined,
+		UserID:     &userID,
+		TargetType: audit.TargetQueue,
+		TargetID:   queueID,
+		After:      map[string]interface{}{"position": newPosition},
 	})
-
 	c.JSON(http.StatusOK, gin.H{"message": "Вступление в очередь прошла успешно", "position": newPosition})
 }
 
@@ -107,9 +102,10 @@ func JoinQueueHandler(c *gin.Context) {
 // @Security		BearerAuth
 // @Success		200	{object}	response.SuccessResponse	"Успешный выход из очереди"
 // @Failure		400	{object}	response.ErrorResponse	"Ошибка валидации (INVALID_QUEUE_ID, NOT_IN_QUEUE)"
+// @Failure		429	{object}	response.ErrorResponse	"Слишком много запросов (RATE_LIMITED)"
 // @Failure		500	{object}	response.ErrorResponse	"Ошибка сервера (DB_ERROR)"
 // @Router			/api/queues/{id}/leave [post]
-func LeaveQueueHandler(c *gin.Context) {
+func (h *Handler) LeaveQueueHandler(c *gin.Context) {
 	queueIDStr := c.Param("id")
 	queueID, err := strconv.Atoi(queueIDStr)
 	if err != nil {
@@ -121,20 +117,15 @@ func LeaveQueueHandler(c *gin.Context) {
 	}
 
 	userID := c.GetUint("userID")
-
-	var entry models.QueueEntry
-	if err := storage.DB.
-		Where("user_id = ? AND queue_id = ? AND exited_at IS NULL", userID, queueID).
-		First(&entry).Error; err != nil {
+	position, err := h.Queues().Leave(userID, uint(queueID))
+	switch {
+	case errors.Is(err, ErrNotInQueue):
 		c.JSON(http.StatusBadRequest, response.ErrorResponse{
 			Code:    "NOT_IN_QUEUE",
 			Message: "Активная запись в очереди не найдена",
 		})
 		return
-	}
-	now := time.Now()
-	entry.ExitedAt = &now
-	if err := storage.DB.Save(&entry).Error; err != nil {
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
 			Code:    "DB_ERROR",
 			Message: "Ошибка при выходе из очереди",
@@ -143,46 +134,98 @@ func LeaveQueueHandler(c *gin.Context) {
 		return
 	}
 
-	// Пересчитываем позиции для всех участников очереди, чья позиция больше, чем у вышедшего.
-	var entries []models.QueueEntry
-	if err := storage.DB.
-		Where("queue_id = ? AND exited_at IS NULL AND position > ?", queueID, entry.Position).
-		Find(&entries).Error; err == nil {
-		for _, e := range entries {
-			e.Position -= 1
-			storage.DB.Save(&e)
+	audit.RecordRequest(h.DB, c, audit.Event{
+		Action:     audit.ActionQueueLeft,
+		UserID:     &userID,
+		TargetType: audit.TargetQueue,
+		TargetID:   queueID,
+		Before:     map[string]interface{}{"position": position},
+	})
+	c.JSON(http.StatusOK, gin.H{"message": "Вы успешно вышли из очереди"})
+}
+
+type ServeRequest struct {
+	// ID пользователя, которого нужно принять. Если не указан — принимается первый в очереди.
+	UserID uint `json:"user_id"`
+}
+
+// ServeQueueHandler отмечает участника очереди как принятого
+// @Summary		Приём участника очереди
+// @Description	Преподаватель отмечает участника как принятого (по умолчанию — первого в очереди). Позиции остальных участников сдвигаются
+// @Tags			queue
+// @Accept			json
+// @Produce		json
+// @Param			id		path		string			true	"ID очереди"
+// @Param			body	body		ServeRequest	false	"Кого принять"
+// @Security		BearerAuth
+// @Success		200	{object}	response.MessageResponse	"Участник принят"
+// @Failure		400	{object}	response.ErrorResponse	"Ошибка валидации (INVALID_QUEUE_ID, VALIDATION_ERROR)"
+// @Failure		403	{object}	response.ErrorResponse	"Недостаточно прав (FORBIDDEN)"
+// @Failure		404	{object}	response.ErrorResponse	"Участник не найден (NOT_IN_QUEUE, QUEUE_EMPTY)"
+// @Failure		500	{object}	response.ErrorResponse	"Ошибка сервера (DB_ERROR)"
+// @Router			/api/queues/{id}/serve [post]
+func (h *Handler) ServeQueueHandler(c *gin.Context) {
+	queueIDStr := c.Param("id")
+	queueID, err := strconv.Atoi(queueIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "INVALID_QUEUE_ID",
+			Message: "Неверный идентификатор очереди",
+		})
+		return
+	}
+
+	var req ServeRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, response.ErrorResponse{
+				Code:    "VALIDATION_ERROR",
+				Message: "Ошибка валидации данных",
+				Details: err.Error(),
+			})
+			return
 		}
 	}
 
-	// Готовим сообщение для рассылки через WebSocket.
-	HubInstance.BroadcastWSMessage(WSMessage{
-		EventType: "user_left",
-		QueueID:   queueIDStr,
-		Data: map[string]interface{}{
-			"user_id":       userID,
-			"left_position": entry.Position,
-		},
-	})
+	servedBy := c.GetUint("userID")
+	entry, err := h.Queues().Serve(uint(queueID), req.UserID, servedBy)
+	switch {
+	case errors.Is(err, service.ErrQueueEmpty):
+		c.JSON(http.StatusNotFound, response.ErrorResponse{
+			Code:    "QUEUE_EMPTY",
+			Message: "В очереди нет ожидающих участников",
+		})
+		return
+	case errors.Is(err, ErrNotInQueue):
+		c.JSON(http.StatusNotFound, response.ErrorResponse{
+			Code:    "NOT_IN_QUEUE",
+			Message: "Активная запись в очереди не найдена",
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка при приёме участника",
+			Details: err.Error(),
+		})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Вы успешно вышли из очереди"})
+	audit.RecordRequest(h.DB, c, audit.Event{
+		Action:     audit.ActionQueueServed,
+		UserID:     &entry.UserID,
+		TargetType: audit.TargetQueue,
+		TargetID:   queueID,
+		Before:     map[string]interface{}{"position": entry.Position, "status": entry.Status},
+		After:      map[string]interface{}{"status": models.EntryStatusServed},
+	})
+	c.JSON(http.StatusOK, gin.H{"message": "Участник принят", "user_id": entry.UserID})
 }
 
-type Participant struct {
-	UserID   uint   `json:"user_id"`
-	Name     string `json:"name"`
-	Surname  string `json:"surname"`
-	Position int    `json:"position"`
-}
+type Participant = service.Participant
 
 // QueueStatusResponse содержит статус очереди и список участников.
-type QueueStatusResponse struct {
-	QueueID      uint          `json:"queue_id"`
-	ScheduleID   uint          `json:"schedule_id"`
-	IsActive     bool          `json:"is_active"`
-	OpensAt      time.Time     `json:"opens_at"`
-	ClosesAt     time.Time     `json:"closes_at"`
-	Participants []Participant `json:"participants"`
-}
+type QueueStatusResponse = service.QueueStatus
 
 // GetQueueStatusHandler обрабатывает запрос на получение статуса очереди
 // @Summary		Получение статуса очереди
@@ -195,9 +238,10 @@ type QueueStatusResponse struct {
 // @Success		200	{object}	response.SwaggerQueueStatusResponse	"Успешное получение статуса очереди"
 // @Failure		400	{object}	response.ErrorResponse	"Ошибка валидации (INVALID_QUEUE_ID)"
 // @Failure		404	{object}	response.ErrorResponse	"Очередь не найдена (QUEUE_NOT_FOUND)"
+// @Failure		429	{object}	response.ErrorResponse	"Слишком много запросов (RATE_LIMITED)"
 // @Failure		500	{object}	response.ErrorResponse	"Ошибка сервера (DB_ERROR)"
 // @Router			/api/queues/{id}/status [get]
-func GetQueueStatusHandler(c *gin.Context) {
+func (h *Handler) GetQueueStatusHandler(c *gin.Context) {
 	// Извлекаем queueID из параметров URL
 	queueIDStr := c.Param("id")
 	queueID, err := strconv.Atoi(queueIDStr)
@@ -209,23 +253,15 @@ func GetQueueStatusHandler(c *gin.Context) {
 		return
 	}
 
-	// Загружаем очередь по ID
-	var queue models.Queue
-	if err := storage.DB.First(&queue, queueID).Error; err != nil {
+	status, err := h.Queues().Status(uint(queueID))
+	switch {
+	case errors.Is(err, ErrQueueNotFound):
 		c.JSON(http.StatusNotFound, response.ErrorResponse{
 			Code:    "QUEUE_NOT_FOUND",
 			Message: "Очередь не найдена",
 		})
 		return
-	}
-
-	// Загружаем записи участников очереди, где exited_at is null, упорядоченные по position
-	var entries []models.QueueEntry
-	if err := storage.DB.
-		Preload("User").
-		Where("queue_id = ? AND exited_at IS NULL", queueID).
-		Order("position ASC").
-		Find(&entries).Error; err != nil {
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
 			Code:    "DB_ERROR",
 			Message: "Ошибка загрузки записей очереди",
@@ -234,26 +270,5 @@ func GetQueueStatusHandler(c *gin.Context) {
 		return
 	}
 
-	// Формируем список участников с нужными полями (имя и фамилия)
-	participants := make([]Participant, 0, len(entries))
-	for _, entry := range entries {
-		participant := Participant{
-			UserID:   entry.UserID,
-			Name:     entry.User.Name,
-			Surname:  entry.User.Surname,
-			Position: entry.Position,
-		}
-		participants = append(participants, participant)
-	}
-
-	response := QueueStatusResponse{
-		QueueID:      queue.ID,
-		ScheduleID:   queue.ScheduleID,
-		IsActive:     queue.IsActive,
-		OpensAt:      queue.OpensAt,
-		ClosesAt:     queue.ClosesAt,
-		Participants: participants,
-	}
-
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, status)
 }
@@ -0,0 +1,419 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"test_hack/internal/models"
+	"test_hack/internal/response"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	reportDateLayout = "2006-01-02"
+	reportTimeLayout = "2006-01-02 15:04"
+
+	// attendanceAbsent — статус студента группы, который не вставал в очередь на событие.
+	attendanceAbsent = "absent"
+)
+
+// Человекочитаемые названия статусов для CSV/XLSX выгрузок.
+var attendanceStatusLabels = map[string]string{
+	models.EntryStatusWaiting:   "В очереди",
+	models.EntryStatusLeft:      "Вышел из очереди",
+	models.EntryStatusServed:    "Принят",
+	models.EntryStatusNotServed: "Не принят",
+	attendanceAbsent:            "Отсутствовал",
+}
+
+// AttendanceRow — строка отчёта о посещаемости: один участник на одно событие расписания.
+type AttendanceRow struct {
+	ScheduleID   uint       `json:"schedule_id"`
+	ScheduleName string     `json:"schedule_name"`
+	StartTime    time.Time  `json:"start_time"`
+	QueueID      uint       `json:"queue_id,omitempty"`
+	UserID       uint       `json:"user_id"`
+	Name         string     `json:"name"`
+	Surname      string     `json:"surname"`
+	Email        string     `json:"email"`
+	GroupID      string     `json:"group_id"`
+	Status       string     `json:"status"`
+	JoinedAt     *time.Time `json:"joined_at,omitempty"`
+	LeftAt       *time.Time `json:"left_at,omitempty"`
+	ServedBy     string     `json:"served_by,omitempty"`
+}
+
+// AttendanceReport — отчёт о посещаемости за период.
+type AttendanceReport struct {
+	GroupID    string          `json:"group_id,omitempty"`
+	ScheduleID uint            `json:"schedule_id,omitempty"`
+	From       string          `json:"from"`
+	To         string          `json:"to"`
+	Rows       []AttendanceRow `json:"rows"`
+}
+
+// GetAttendanceReportHandler формирует отчёт о посещаемости
+// @Summary		Отчёт о посещаемости
+// @Description	Отчёт по группе и/или событию расписания за период: кто вставал в очередь, кого приняли, кто отсутствовал. Поддерживается выгрузка в CSV и XLSX
+// @Tags			reports
+// @Produce		json
+// @Produce		text/csv
+// @Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param			group_id	query	string	false	"ID группы (обязателен, если не указан schedule_id)"
+// @Param			schedule_id	query	int		false	"ID события расписания"
+// @Param			from		query	string	false	"Начало периода (YYYY-MM-DD), по умолчанию 30 дней назад"
+// @Param			to			query	string	false	"Конец периода включительно (YYYY-MM-DD), по умолчанию сегодня; не раньше from"
+// @Param			format		query	string	false	"Формат: json, csv или xlsx"	Enums(json, csv, xlsx)
+// @Security		BearerAuth
+// @Success		200	{object}	AttendanceReport	"Отчёт о посещаемости"
+// @Failure		400	{object}	response.ErrorResponse	"Ошибка валидации (MISSING_FILTER, INVALID_DATE, INVALID_SCHEDULE_ID, INVALID_FORMAT)"
+// @Failure		403	{object}	response.ErrorResponse	"Недостаточно прав (FORBIDDEN)"
+// @Failure		500	{object}	response.ErrorResponse	"Ошибка сервера (DB_ERROR, EXPORT_ERROR)"
+// @Router			/api/reports/attendance [get]
+func (h *Handler) GetAttendanceReportHandler(c *gin.Context) {
+	groupID := c.Query("group_id")
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" && format != "xlsx" {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "INVALID_FORMAT",
+			Message: "Поддерживаются форматы json, csv и xlsx",
+		})
+		return
+	}
+
+	var scheduleID uint
+	if s := c.Query("schedule_id"); s != "" {
+		id, err := strconv.Atoi(s)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, response.ErrorResponse{
+				Code:    "INVALID_SCHEDULE_ID",
+				Message: "Неверный идентификатор события",
+			})
+			return
+		}
+		scheduleID = uint(id)
+	}
+
+	if groupID == "" && scheduleID == 0 {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "MISSING_FILTER",
+			Message: "Необходимо указать group_id или schedule_id",
+		})
+		return
+	}
+
+	now := time.Now()
+	from, err := parseReportDate(c.Query("from"), now.AddDate(0, 0, -30))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "INVALID_DATE",
+			Message: "Неверный формат даты from, ожидается YYYY-MM-DD",
+		})
+		return
+	}
+	to, err := parseReportDate(c.Query("to"), now)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "INVALID_DATE",
+			Message: "Неверный формат даты to, ожидается YYYY-MM-DD",
+		})
+		return
+	}
+	if from.After(to) {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "INVALID_DATE",
+			Message: "Дата from не может быть позже даты to",
+		})
+		return
+	}
+
+	report, err := h.buildAttendanceReport(groupID, scheduleID, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка формирования отчёта",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	filename := fmt.Sprintf("attendance_%s_%s", report.From, report.To)
+	switch format {
+	case "csv":
+		// Таблица собирается в буфер, чтобы об ошибке можно было сообщить до отправки заголовков.
+		var buf bytes.Buffer
+		// BOM, чтобы Excel корректно распознал кириллицу в UTF-8
+		buf.WriteString("\xEF\xBB\xBF")
+		w := csv.NewWriter(&buf)
+		if err := w.WriteAll(attendanceTable(report)); err != nil {
+			log.Println("Ошибка формирования CSV-отчёта:", err)
+			c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+				Code:    "EXPORT_ERROR",
+				Message: "Ошибка формирования CSV",
+				Details: err.Error(),
+			})
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.csv"`)
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+	case "xlsx":
+		file, err := attendanceWorkbook(report)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+				Code:    "EXPORT_ERROR",
+				Message: "Ошибка формирования XLSX",
+				Details: err.Error(),
+			})
+			return
+		}
+		defer file.Close()
+		buf, err := file.WriteToBuffer()
+		if err != nil {
+			log.Println("Ошибка формирования XLSX-отчёта:", err)
+			c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+				Code:    "EXPORT_ERROR",
+				Message: "Ошибка формирования XLSX",
+				Details: err.Error(),
+			})
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.xlsx"`)
+		c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", buf.Bytes())
+	default:
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+func parseReportDate(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Date(fallback.Year(), fallback.Month(), fallback.Day(), 0, 0, 0, 0, time.Local), nil
+	}
+	return time.ParseInLocation(reportDateLayout, value, time.Local)
+}
+
+// buildAttendanceReport собирает строки отчёта: участников очередей за период и студентов группы,
+// которые в очередь не вставали.
+func (h *Handler) buildAttendanceReport(groupID string, scheduleID uint, from, to time.Time) (*AttendanceReport, error) {
+	report := &AttendanceReport{
+		GroupID:    groupID,
+		ScheduleID: scheduleID,
+		From:       from.Format(reportDateLayout),
+		To:         to.Format(reportDateLayout),
+		Rows:       []AttendanceRow{},
+	}
+
+	// Прошедшие события и очереди удаляются планировщиком мягко, поэтому используем Unscoped.
+	query := h.DB.Unscoped().Where("start_time >= ? AND start_time < ?", from, to.AddDate(0, 0, 1))
+	if scheduleID != 0 {
+		query = query.Where("id = ?", scheduleID)
+	}
+	if groupID != "" {
+		query = query.Where("group_ids LIKE ?", "%"+groupID+"%")
+	}
+	var candidates []models.Schedule
+	if err := query.Order("start_time ASC").Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	// LIKE по списку "67,203,111" даёт ложные совпадения (6 найдётся в 67), поэтому фильтруем точно.
+	var schedules []models.Schedule
+	for _, s := range candidates {
+		if groupID == "" || scheduleHasGroup(s, groupID) {
+			schedules = append(schedules, s)
+		}
+	}
+	if len(schedules) == 0 {
+		return report, nil
+	}
+
+	var students []models.User
+	if groupID != "" {
+		if err := h.DB.
+			Where("group_id = ? AND role = ?", groupID, models.RoleStudent).
+			Order("surname ASC, name ASC").
+			Find(&students).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	scheduleIDs := make([]uint, 0, len(schedules))
+	for _, s := range schedules {
+		scheduleIDs = append(scheduleIDs, s.ID)
+	}
+	var queues []models.Queue
+	if err := h.DB.Unscoped().Where("schedule_id IN ?", scheduleIDs).Find(&queues).Error; err != nil {
+		return nil, err
+	}
+	queueBySchedule := make(map[uint]models.Queue)
+	queueIDs := make([]uint, 0, len(queues))
+	for _, q := range queues {
+		queueBySchedule[q.ScheduleID] = q
+		queueIDs = append(queueIDs, q.ID)
+	}
+
+	// Для каждой очереди оставляем по одной записи на пользователя:
+	// запись «принят» важнее остальных, иначе берём последнюю.
+	entriesByQueue := make(map[uint]map[uint]models.QueueEntry)
+	if len(queueIDs) > 0 {
+		entries, err := h.loadQueueHistory(queueIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if entriesByQueue[e.QueueID] == nil {
+				entriesByQueue[e.QueueID] = make(map[uint]models.QueueEntry)
+			}
+			if prev, ok := entriesByQueue[e.QueueID][e.UserID]; ok && prev.Status == models.EntryStatusServed {
+				continue
+			}
+			entriesByQueue[e.QueueID][e.UserID] = e
+		}
+	}
+
+	for _, s := range schedules {
+		queue, hasQueue := queueBySchedule[s.ID]
+		byUser := entriesByQueue[queue.ID]
+
+		seen := make(map[uint]bool)
+		var rows []AttendanceRow
+		for _, student := range students {
+			seen[student.ID] = true
+			row := AttendanceRow{
+				ScheduleID:   s.ID,
+				ScheduleName: s.Name,
+				StartTime:    s.StartTime,
+				UserID:       student.ID,
+				Name:         student.Name,
+				Surname:      student.Surname,
+				Email:        student.Email,
+				GroupID:      student.GroupID,
+				Status:       attendanceAbsent,
+			}
+			if hasQueue {
+				row.QueueID = queue.ID
+			}
+			if entry, ok := byUser[student.ID]; ok {
+				fillAttendanceEntry(&row, entry)
+			}
+			rows = append(rows, row)
+		}
+
+		// Участники очереди не из указанной группы (или без группы) тоже попадают в отчёт.
+		var others []AttendanceRow
+		for userID, entry := range byUser {
+			if seen[userID] {
+				continue
+			}
+			row := AttendanceRow{
+				ScheduleID:   s.ID,
+				ScheduleName: s.Name,
+				StartTime:    s.StartTime,
+				QueueID:      queue.ID,
+				UserID:       entry.UserID,
+				Name:         entry.User.Name,
+				Surname:      entry.User.Surname,
+				Email:        entry.User.Email,
+				GroupID:      entry.User.GroupID,
+			}
+			fillAttendanceEntry(&row, entry)
+			others = append(others, row)
+		}
+		sort.Slice(others, func(i, j int) bool {
+			if others[i].Surname != others[j].Surname {
+				return others[i].Surname < others[j].Surname
+			}
+			return others[i].Name < others[j].Name
+		})
+
+		report.Rows = append(report.Rows, rows...)
+		report.Rows = append(report.Rows, others...)
+	}
+
+	return report, nil
+}
+
+func fillAttendanceEntry(row *AttendanceRow, entry models.QueueEntry) {
+	joinedAt := entry.CreatedAt
+	row.Status = entry.Status
+	row.JoinedAt = &joinedAt
+	row.LeftAt = entry.ExitedAt
+	if entry.ServedBy != nil {
+		row.ServedBy = strings.TrimSpace(entry.ServedBy.Surname + " " + entry.ServedBy.Name)
+	}
+}
+
+func scheduleHasGroup(s models.Schedule, groupID string) bool {
+	for _, id := range strings.Split(s.GroupIDs, ",") {
+		if strings.TrimSpace(id) == groupID {
+			return true
+		}
+	}
+	return false
+}
+
+// attendanceTable представляет отчёт в виде таблицы с заголовком для CSV/XLSX.
+func attendanceTable(report *AttendanceReport) [][]string {
+	table := [][]string{{
+		"Событие", "Начало", "Фамилия", "Имя", "Email", "Группа",
+		"Статус", "Вход в очередь", "Выход из очереди", "Принял",
+	}}
+	for _, row := range report.Rows {
+		status := attendanceStatusLabels[row.Status]
+		if status == "" {
+			status = row.Status
+		}
+		table = append(table, []string{
+			row.ScheduleName,
+			row.StartTime.Format(reportTimeLayout),
+			row.Surname,
+			row.Name,
+			row.Email,
+			row.GroupID,
+			status,
+			formatReportTime(row.JoinedAt),
+			formatReportTime(row.LeftAt),
+			row.ServedBy,
+		})
+	}
+	return table
+}
+
+func formatReportTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(reportTimeLayout)
+}
+
+func attendanceWorkbook(report *AttendanceReport) (*excelize.File, error) {
+	file := excelize.NewFile()
+	sheet := "Посещаемость"
+	if err := file.SetSheetName("Sheet1", sheet); err != nil {
+		file.Close()
+		return nil, err
+	}
+	for i, row := range attendanceTable(report) {
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		values := make([]interface{}, len(row))
+		for j, v := range row {
+			values[j] = v
+		}
+		if err := file.SetSheetRow(sheet, cell, &values); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return file, nil
+}
@@ -3,15 +3,17 @@ package handlers
 import (
 	"context"
 	"encoding/json"
-	"io/ioutil"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
 	"test_hack/internal/models"
 	"test_hack/internal/response"
-	"test_hack/internal/storage"
 
 	"github.com/gin-gonic/gin"
 )
@@ -62,6 +64,64 @@ type ScheduleWithQueue struct {
 
 var scheduleCtx = context.Background()
 
+// errScheduleDecode — ответ API расписания не удалось разобрать.
+var errScheduleDecode = errors.New("ошибка декодирования данных расписания")
+
+// ImportSchedule загружает из API расписания события группы groupID за период с start по end
+// (по датам, включительно) и сохраняет те, которых ещё нет в базе. Возвращает сохранённые события.
+func (h *Handler) ImportSchedule(groupID string, start, end time.Time) ([]models.Schedule, error) {
+	apiURL := h.Config.Timetable.APIURL + "/event/?start=" +
+		start.Format("2006-01-02") + "&end=" + end.Format("2006-01-02") + "&group_id=" + url.QueryEscape(groupID)
+	resp, err := h.TimetableClient.Get(apiURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var externalResp ScheduleResponse
+	if err := json.Unmarshal(body, &externalResp); err != nil {
+		return nil, fmt.Errorf("%w: %v", errScheduleDecode, err)
+	}
+
+	var imported []models.Schedule
+	for _, event := range externalResp.Items {
+		startT, err1 := time.Parse(customTimeLayout, event.StartTS)
+		endT, err2 := time.Parse(customTimeLayout, event.EndTS)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+
+		var groupIDs []string
+		for _, grp := range event.Group {
+			groupIDs = append(groupIDs, strconv.Itoa(grp.ID))
+		}
+
+		// Пропускаем события, которые уже есть в базе.
+		var existing models.Schedule
+		if err := h.DB.Where("external_id = ?", strconv.Itoa(event.ID)).First(&existing).Error; err == nil {
+			continue
+		}
+
+		newEvent := models.Schedule{
+			ExternalID: strconv.Itoa(event.ID),
+			Name:       event.Name,
+			StartTime:  startT,
+			EndTime:    endT,
+			GroupIDs:   strings.Join(groupIDs, ","),
+		}
+		if err := h.DB.Create(&newEvent).Error; err != nil {
+			continue
+		}
+		imported = append(imported, newEvent)
+	}
+	return imported, nil
+}
+
 // GetScheduleHandler получает расписание с внешнего API
 // @Summary		Получение расписания
 // @Description	Получает расписание по заданным параметрам (group_id), кэширует результат в Redis
@@ -73,7 +133,7 @@ var scheduleCtx = context.Background()
 // @Failure		400		{object}	response.ErrorResponse	"Ошибка валидации данных (MISSING_GROUP_ID)"
 // @Failure		500		{object}	response.ErrorResponse	"Ошибка сервера (DB_ERROR, API_ERROR, DECODE_ERROR)"
 // @Router			/schedule [get]
-func GetFullScheduleHandler(c *gin.Context) {
+func (h *Handler) GetFullScheduleHandler(c *gin.Context) {
 	groupIDStr := c.Query("group_id")
 	if groupIDStr == "" {
 		c.JSON(http.StatusBadRequest, response.ErrorResponse{
@@ -90,18 +150,21 @@ func GetFullScheduleHandler(c *gin.Context) {
 
 	// Создаем уникальный ключ для кэширования пустого результата
 	cacheKeyEmpty := "no_events:" + groupIDStr + ":" + startTime.Format("2006-01-02") + ":" + endTime.Format("2006-01-02")
-	redisClient := storage.RedisClient
+	redisClient := h.Redis
 
 	// Проверка кэша: если установлен маркер "нет событий", сразу возвращаем пустой массив.
 	if val, err := redisClient.Get(scheduleCtx, cacheKeyEmpty).Result(); err == nil && val == "true" {
+		h.Metrics.CacheLookup("empty_schedule", true)
 		c.JSON(http.StatusOK, gin.H{"message": "Нет событий на выбранный период", "data": []ScheduleWithQueue{}})
 		return
 	}
 
+	h.Metrics.CacheLookup("empty_schedule", false)
+
 	// Попытка извлечь расписание из БД
 	var schedules []models.Schedule
 	pattern := "%" + groupIDStr + "%"
-	if err := storage.DB.
+	if err := h.DB.
 		Where("start_time BETWEEN ? AND ? AND group_ids LIKE ?", startTime, endTime, pattern).
 		Find(&schedules).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
@@ -112,86 +175,31 @@ func GetFullScheduleHandler(c *gin.Context) {
 		return
 	}
 
-	// Если в БД расписание не найдено – вызываем внешний API
+	// Если в БД расписание не найдено – загружаем его из внешнего API
 	if len(schedules) == 0 {
-		apiURL := "https://api.profcomff.com/timetable/event/?start=" +
-			startTime.Format("2006-01-02") + "&end=" + endTime.Format("2006-01-02") + "&group_id=" + groupIDStr
-		resp, err := http.Get(apiURL)
-		if err != nil {
+		imported, err := h.ImportSchedule(groupIDStr, startTime, endTime)
+		if errors.Is(err, errScheduleDecode) {
 			c.JSON(http.StatusInternalServerError, response.ErrorResponse{
-				Code:    "API_ERROR",
-				Message: "Не удалось получить данные расписания",
+				Code:    "DECODE_ERROR",
+				Message: "Ошибка декодирования данных расписания",
 				Details: err.Error(),
 			})
 			return
 		}
-		defer resp.Body.Close()
-
-		body, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, response.ErrorResponse{
 				Code:    "API_ERROR",
-				Message: "Ошибка чтения ответа внешнего API",
-				Details: err.Error(),
-			})
-			return
-		}
-
-		var externalResp ScheduleResponse
-		if err := json.Unmarshal(body, &externalResp); err != nil {
-			c.JSON(http.StatusInternalServerError, response.ErrorResponse{
-				Code:    "DECODE_ERROR",
-				Message: "Ошибка декодирования данных расписания",
+				Message: "Не удалось получить данные расписания",
 				Details: err.Error(),
 			})
 			return
 		}
-
-		// Если внешний API возвращает пустой список, запоминаем это в Redis, чтобы не дергать его повторно.
-		if externalResp.Total == 0 || len(externalResp.Items) == 0 {
-			redisClient.Set(scheduleCtx, cacheKeyEmpty, "true", 15*time.Minute)
-			c.JSON(http.StatusOK, gin.H{"message": "Нет событий на выбранный период", "data": []ScheduleWithQueue{}})
-			return
-		}
-
-		// Обрабатываем каждый элемент из внешнего API:
-		for _, event := range externalResp.Items {
-			startT, err1 := time.Parse(customTimeLayout, event.StartTS)
-			endT, err2 := time.Parse(customTimeLayout, event.EndTS)
-			if err1 != nil || err2 != nil {
-				continue
-			}
-
-			var groupIDs []string
-			for _, grp := range event.Group {
-				groupIDs = append(groupIDs, strconv.Itoa(grp.ID))
-			}
-			groupsJoined := strings.Join(groupIDs, ",")
-
-			// Проверяем, существует ли событие с таким ExternalID.
-			var existing models.Schedule
-			if err := storage.DB.Where("external_id = ?", strconv.Itoa(event.ID)).First(&existing).Error; err == nil {
-				continue
-			}
-
-			newEvent := models.Schedule{
-				ExternalID: strconv.Itoa(event.ID),
-				Name:       event.Name,
-				StartTime:  startT,
-				EndTime:    endT,
-				GroupIDs:   groupsJoined,
-			}
-
-			if err := storage.DB.Create(&newEvent).Error; err != nil {
-				continue
-			}
-			schedules = append(schedules, newEvent)
-		}
+		schedules = imported
 	}
 
 	// Если после загрузки все равно расписание пусто, возвращаем пустой результат
 	if len(schedules) == 0 {
-		redisClient.Set(scheduleCtx, cacheKeyEmpty, "true", 15*time.Minute)
+		redisClient.Set(scheduleCtx, cacheKeyEmpty, "true", h.Config.Cache.EmptyScheduleTTL)
 		c.JSON(http.StatusOK, gin.H{"message": "Нет событий на выбранный период", "data": []ScheduleWithQueue{}})
 		return
 	}
@@ -203,7 +211,7 @@ func GetFullScheduleHandler(c *gin.Context) {
 	}
 	// Получаем очереди для этих расписаний
 	var queues []models.Queue
-	storage.DB.Where("schedule_id IN ?", scheduleIDs).Find(&queues)
+	h.DB.Where("schedule_id IN ?", scheduleIDs).Find(&queues)
 
 	// Строим мапу scheduleID -> Queue
 	queueMap := make(map[uint]models.Queue)
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"test_hack/internal/repository"
+	"test_hack/internal/service"
+	"test_hack/internal/webhooks"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// EventsChannel — канал Redis, через который процессы без хаба (queuectl) передают события очередей серверу.
+const EventsChannel = "queue_events"
+
+// hubPublisher рассылает события очередей клиентам WebSocket; хаб дублирует их на вебхуки.
+type hubPublisher struct {
+	hub *Hub
+}
+
+func (p hubPublisher) Publish(queueID uint, eventType string, data interface{}) {
+	p.hub.BroadcastWSMessage(WSMessage{
+		EventType: eventType,
+		QueueID:   strconv.Itoa(int(queueID)),
+		Data:      data,
+	})
+}
+
+// redisPublisher отправляет события очередей в канал EventsChannel, откуда их забирают запущенные серверы
+// и рассылают своим клиентам WebSocket (см. Hub.Relay). Вебхукам событие только записывается в очередь
+// доставки: его отправит задача DeliverWebhooks сервера, даже если сервер сейчас не запущен.
+type redisPublisher struct {
+	rdb      *redis.Client
+	webhooks *webhooks.Dispatcher
+}
+
+// NewRedisPublisher создаёт публикатор событий для процессов без хаба WebSocket.
+func NewRedisPublisher(rdb *redis.Client, dispatcher *webhooks.Dispatcher) service.Publisher {
+	return redisPublisher{rdb: rdb, webhooks: dispatcher}
+}
+
+func (p redisPublisher) Publish(queueID uint, eventType string, data interface{}) {
+	msg := WSMessage{
+		EventType: eventType,
+		QueueID:   strconv.Itoa(int(queueID)),
+		Data:      data,
+		Timestamp: time.Now().Unix(),
+	}
+	b, err := json.Marshal(msg)
+	if err != nil {
+		log.Println("Ошибка сериализации WSMessage:", err)
+		return
+	}
+	if p.webhooks != nil {
+		p.webhooks.Enqueue(msg.QueueID, eventType, b)
+	}
+	if err := p.rdb.Publish(context.Background(), EventsChannel, b).Err(); err != nil {
+		log.Printf("Событие %s очереди %d не передано серверу: %v", eventType, queueID, err)
+	}
+}
+
+// Queues возвращает сервис очередей поверх текущего подключения к базе. События рассылаются через Events,
+// а если он не задан — через хаб WebSocket.
+func (h *Handler) Queues() *service.QueueService {
+	var events service.Publisher = hubPublisher{hub: h.Hub}
+	if h.Events != nil {
+		events = h.Events
+	}
+	queues := service.NewQueueService(
+		repository.NewQueueRepository(h.DB),
+		repository.NewUserRepository(h.DB),
+		repository.NewScheduleRepository(h.DB),
+		events,
+	)
+	queues.RequireVerifiedEmail = func() bool { return h.Config.Accounts.RequireEmailVerification }
+	queues.Metrics = h.Metrics
+	return queues
+}
+
+// Users возвращает хранилище пользователей поверх текущего подключения к базе.
+func (h *Handler) Users() repository.UserRepository {
+	return repository.NewUserRepository(h.DB)
+}
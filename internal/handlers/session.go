@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"test_hack/internal/audit"
+	"test_hack/internal/auth"
+	"test_hack/internal/models"
+	"test_hack/internal/response"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+const (
+	// sessionTouchInterval — как часто AuthMiddleware обновляет время последнего использования сессии.
+	sessionTouchInterval = time.Minute
+)
+
+// ErrSessionRevoked — сессия токена завершена или удалена.
+var ErrSessionRevoked = auth.ErrSessionRevoked
+
+// startSession открывает новую сессию для устройства, с которого выполнен вход способом method, и выпускает её токены.
+func (h *Handler) startSession(c *gin.Context, user models.User, method string) (response.TokenResponse, error) {
+	now := time.Now()
+	session := models.Session{
+		UserID:     user.ID,
+		UserAgent:  c.Request.UserAgent(),
+		IP:         c.ClientIP(),
+		Method:     method,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(h.Config.JWT.RefreshTTL),
+	}
+	if err := h.DB.Create(&session).Error; err != nil {
+		return response.TokenResponse{}, err
+	}
+	return h.newTokenPair(user, session.ID)
+}
+
+// refreshSession продлевает сессию, к которой относится refresh токен.
+// Токены без claim "sid" выпущены до появления сессий — для них открывается новая сессия.
+func (h *Handler) refreshSession(c *gin.Context, user models.User, claims jwt.MapClaims) (response.TokenResponse, error) {
+	sid, _ := claims["sid"].(float64)
+	if sid == 0 {
+		return h.startSession(c, user, "")
+	}
+
+	now := time.Now()
+	result := h.DB.Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", uint(sid), user.ID).
+		Updates(map[string]interface{}{
+			"last_used_at": now,
+			"expires_at":   now.Add(h.Config.JWT.RefreshTTL),
+			"ip":           c.ClientIP(),
+			"user_agent":   c.Request.UserAgent(),
+		})
+	if result.Error != nil {
+		return response.TokenResponse{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return response.TokenResponse{}, ErrSessionRevoked
+	}
+	return h.newTokenPair(user, uint(sid))
+}
+
+// CheckSession проверяет, что сессия access токена не завершена, и отмечает время её использования.
+// Токены без сессии (sessionID == 0) выпущены до её появления и принимаются до истечения срока.
+func (h *Handler) CheckSession(userID, sessionID uint) error {
+	if sessionID == 0 {
+		return nil
+	}
+	var session models.Session
+	if err := h.DB.Select("id", "revoked_at", "last_used_at").
+		Where("id = ? AND user_id = ?", sessionID, userID).
+		First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSessionRevoked
+		}
+		return err
+	}
+	if session.RevokedAt != nil {
+		return ErrSessionRevoked
+	}
+	if time.Since(session.LastUsedAt) > sessionTouchInterval {
+		h.DB.Model(&session).UpdateColumn("last_used_at", time.Now())
+	}
+	return nil
+}
+
+// revokeSessions завершает все действующие сессии пользователя.
+func revokeSessions(tx *gorm.DB, userID uint) error {
+	return tx.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// activeSessions возвращает действующие сессии пользователя, начиная с последней использованной.
+func (h *Handler) activeSessions(userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	err := h.DB.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// ListSessionsHandler godoc
+// @Summary		Активные сессии
+// @Description	Список устройств, на которых выполнен вход: браузер, IP, время входа и последнего использования. Сессия, к которой относится текущий токен, помечена current=true
+// @Tags			profile
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{array}		response.SessionResponse	"Активные сессии"
+// @Failure		401	{object}	response.ErrorResponse		"Ошибка авторизации (UNAUTHORIZED)"
+// @Failure		500	{object}	response.ErrorResponse		"Ошибка сервера (DB_ERROR)"
+// @Router			/profile/sessions [get]
+func (h *Handler) ListSessionsHandler(c *gin.Context) {
+	userID := c.GetUint("userID")
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{
+			Code:    "UNAUTHORIZED",
+			Message: "Ошибка авторизации",
+		})
+		return
+	}
+	sessions, err := h.activeSessions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка при получении сессий",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	current := c.GetUint("sessionID")
+	result := make([]response.SessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		result = append(result, response.SessionResponse{
+			ID:         s.ID,
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			CreatedAt:  s.CreatedAt,
+			LastUsedAt: s.LastUsedAt,
+			Current:    s.ID == current,
+		})
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// RevokeSessionHandler godoc
+// @Summary		Завершение сессии
+// @Description	Завершает сессию: её access и refresh токены перестают приниматься. Завершение текущей сессии равносильно выходу из аккаунта
+// @Tags			profile
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		int							true	"ID сессии"
+// @Success		200	{object}	response.MessageResponse	"Сессия завершена"
+// @Failure		400	{object}	response.ErrorResponse		"Неверный ID (VALIDATION_ERROR)"
+// @Failure		401	{object}	response.ErrorResponse		"Ошибка авторизации (UNAUTHORIZED)"
+// @Failure		404	{object}	response.ErrorResponse		"Сессия не найдена или уже завершена (SESSION_NOT_FOUND)"
+// @Failure		500	{object}	response.ErrorResponse		"Ошибка сервера (DB_ERROR)"
+// @Router			/profile/sessions/{id} [delete]
+func (h *Handler) RevokeSessionHandler(c *gin.Context) {
+	userID := c.GetUint("userID")
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{
+			Code:    "UNAUTHORIZED",
+			Message: "Ошибка авторизации",
+		})
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "VALIDATION_ERROR",
+			Message: "Неверный идентификатор сессии",
+		})
+		return
+	}
+
+	result := h.DB.Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка при завершении сессии",
+			Details: result.Error.Error(),
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, response.ErrorResponse{
+			Code:    "SESSION_NOT_FOUND",
+			Message: "Сессия не найдена или уже завершена",
+		})
+		return
+	}
+
+	audit.RecordRequest(h.DB, c, audit.Event{
+		Action:    audit.ActionSessionRevoked,
+		UserID:    &userID,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Details:   map[string]interface{}{"session_id": id},
+	})
+	c.JSON(http.StatusOK, response.MessageResponse{Message: "Сессия завершена"})
+}
+
+// CleanExpiredSessions удаляет сессии, срок действия refresh токенов которых истёк.
+func (h *Handler) CleanExpiredSessions() (int64, error) {
+	result := h.DB.Unscoped().Where("expires_at < ?", time.Now()).Delete(&models.Session{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"net/http"
+	"strconv"
+	"test_hack/internal/models"
+	"test_hack/internal/response"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	telegramLinkKeyPrefix = "telegram_link:"
+	telegramLinkTTL       = 10 * time.Minute
+)
+
+var ErrInvalidLinkCode = errors.New("код привязки недействителен или истёк")
+
+// TelegramLinkResponse содержит одноразовый код для привязки Telegram.
+type TelegramLinkResponse struct {
+	Code      string    `json:"code" example:"K7M2QX9P"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// Ссылка для открытия бота с кодом (если задан TELEGRAM_BOT_USERNAME)
+	Link string `json:"link,omitempty" example:"https://t.me/queue_bot?start=K7M2QX9P"`
+}
+
+// CreateTelegramLinkCodeHandler выдаёт одноразовый код привязки Telegram
+// @Summary		Код привязки Telegram
+// @Description	Выдаёт одноразовый код, который нужно отправить боту командой /start <код>. Код действует 10 минут
+// @Tags			profile
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	TelegramLinkResponse	"Код привязки"
+// @Failure		500	{object}	response.ErrorResponse	"Ошибка сервера (CODE_GENERATION_ERROR, CACHE_ERROR)"
+// @Router			/profile/telegram/link [post]
+func (h *Handler) CreateTelegramLinkCodeHandler(c *gin.Context) {
+	code, err := randomCode(8)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "CODE_GENERATION_ERROR",
+			Message: "Ошибка генерации кода",
+		})
+		return
+	}
+
+	userID := c.GetUint("userID")
+	if err := h.Redis.Set(ctx, telegramLinkKeyPrefix+code, userID, telegramLinkTTL).Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "CACHE_ERROR",
+			Message: "Ошибка сохранения кода привязки",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	resp := TelegramLinkResponse{
+		Code:      code,
+		ExpiresAt: time.Now().Add(telegramLinkTTL),
+	}
+	if username := h.Config.Telegram.BotUsername; username != "" {
+		resp.Link = "https://t.me/" + username + "?start=" + code
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// UnlinkTelegramHandler отвязывает Telegram от аккаунта
+// @Summary		Отвязка Telegram
+// @Description	Отвязывает чат Telegram от аккаунта; бот перестаёт присылать уведомления
+// @Tags			profile
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	response.MessageResponse	"Telegram отвязан"
+// @Failure		500	{object}	response.ErrorResponse	"Ошибка сервера (DB_ERROR)"
+// @Router			/profile/telegram [delete]
+func (h *Handler) UnlinkTelegramHandler(c *gin.Context) {
+	if err := h.DB.Model(&models.User{}).
+		Where("id = ?", c.GetUint("userID")).
+		Update("telegram_chat_id", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка отвязки Telegram",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.MessageResponse{Message: "Telegram отвязан"})
+}
+
+// LinkTelegramChat привязывает чат Telegram к пользователю по одноразовому коду.
+// Если чат был привязан к другому аккаунту, старая привязка снимается.
+func (h *Handler) LinkTelegramChat(code string, chatID int64) (models.User, error) {
+	var user models.User
+	value, err := h.popRedisValue(telegramLinkKeyPrefix + code)
+	if err != nil {
+		return user, ErrInvalidLinkCode
+	}
+	userID, err := strconv.Atoi(value)
+	if err != nil {
+		return user, ErrInvalidLinkCode
+	}
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		return user, ErrInvalidLinkCode
+	}
+
+	if err := h.DB.Model(&models.User{}).
+		Where("telegram_chat_id = ? AND id <> ?", chatID, user.ID).
+		Update("telegram_chat_id", nil).Error; err != nil {
+		return user, err
+	}
+	user.TelegramChatID = &chatID
+	if err := h.DB.Model(&user).Update("telegram_chat_id", chatID).Error; err != nil {
+		return user, err
+	}
+	return user, nil
+}
+
+// popRedisValue атомарно читает и удаляет ключ Redis, чтобы одноразовое значение нельзя было использовать дважды.
+func (h *Handler) popRedisValue(key string) (string, error) {
+	var get *redis.StringCmd
+	_, err := h.Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, key)
+		pipe.Del(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return get.Val(), nil
+}
+
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// randomCode генерирует случайный код из символов, которые сложно перепутать при вводе.
+func randomCode(length int) (string, error) {
+	code := make([]byte, length)
+	max := big.NewInt(int64(len(codeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = codeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
@@ -7,7 +7,6 @@ import (
 	"strings"
 	"test_hack/internal/models"
 	"test_hack/internal/response"
-	"test_hack/internal/storage"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -37,12 +36,12 @@ type UserQueueItem struct {
 // @Success		200	{array}		UserQueueItem	"List of queues the user is part of"
 // @Failure		500	{object}	response.ErrorResponse	"Server error (DB_ERROR)"
 // @Router			/profile/queues [get]
-func GetUserQueuesHandler(c *gin.Context) {
+func (h *Handler) GetUserQueuesHandler(c *gin.Context) {
 	userID := c.GetUint("userID")
 
 	// Get all active queue entries for the user
 	var queueEntries []models.QueueEntry
-	if err := storage.DB.
+	if err := h.DB.
 		Where("user_id = ? AND exited_at IS NULL", userID).
 		Find(&queueEntries).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
@@ -66,7 +65,7 @@ func GetUserQueuesHandler(c *gin.Context) {
 
 	// Get queue details
 	var queues []models.Queue
-	if err := storage.DB.
+	if err := h.DB.
 		Where("id IN ?", queueIDs).
 		Find(&queues).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
@@ -87,7 +86,7 @@ func GetUserQueuesHandler(c *gin.Context) {
 
 	// Get schedule details
 	var schedules []models.Schedule
-	if err := storage.DB.
+	if err := h.DB.
 		Where("id IN ?", scheduleIDs).
 		Find(&schedules).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
@@ -116,15 +115,18 @@ func GetUserQueuesHandler(c *gin.Context) {
 	// cache group information or fetch it from the external API
 	groupMap := make(map[string]string) // Map group ID to group number
 	cacheKey := "groups_all"
-	cached, err := storage.RedisClient.Get(ctx, cacheKey).Result()
+	cached, err := h.Redis.Get(ctx, cacheKey).Result()
+	hit := false
 	if err == nil && cached != "" {
 		var groupResponse GroupResponse
 		if err := json.Unmarshal([]byte(cached), &groupResponse); err == nil {
+			hit = true
 			for _, group := range groupResponse.Items {
 				groupMap[strconv.Itoa(group.ID)] = group.Number
 			}
 		}
 	}
+	h.Metrics.CacheLookup("groups", hit)
 
 	// Build response
 	var result []UserQueueItem
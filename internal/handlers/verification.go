@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"test_hack/internal/models"
+	"test_hack/internal/notify"
+	"test_hack/internal/response"
+	"test_hack/internal/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	emailVerificationTTL       = 24 * time.Hour
+	emailVerificationKeyPrefix = "email_verification_limit:"
+)
+
+var emailVerificationExpiresIn = map[string]string{
+	notify.LangRU: "24 часа",
+	notify.LangEN: "24 hours",
+}
+
+// ErrEmailNotVerified возвращается при попытке встать в очередь с неподтверждённым email,
+// если включено REQUIRE_EMAIL_VERIFICATION.
+var ErrEmailNotVerified = service.ErrEmailNotVerified
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// emailDomainAllowed проверяет email по списку доменов Config.Accounts.EmailAllowedDomains.
+// Поддомены разрешённого домена тоже разрешены. Пустой список — разрешены любые домены.
+func (h *Handler) emailDomainAllowed(email string) bool {
+	allowed := h.Config.Accounts.EmailAllowedDomains
+	if len(allowed) == 0 {
+		return true
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	for _, d := range allowed {
+		d = strings.ToLower(strings.TrimSpace(d))
+		if d != "" && (domain == d || strings.HasSuffix(domain, "."+d)) {
+			return true
+		}
+	}
+	return false
+}
+
+// sendVerificationEmail выпускает новый токен подтверждения (старые перестают действовать) и отправляет письмо.
+func (h *Handler) sendVerificationEmail(user models.User) error {
+	token, err := newOneTimeToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.EmailVerificationToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.EmailVerificationToken{
+			UserID:    user.ID,
+			TokenHash: hashToken(token),
+			ExpiresAt: now.Add(emailVerificationTTL),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	baseURL := h.Config.Server.PublicURL
+	expiresIn, ok := emailVerificationExpiresIn[user.Language]
+	if !ok {
+		expiresIn = emailVerificationExpiresIn[notify.DefaultLang]
+	}
+	return h.Notify.SendTemplate(user.Email, user.Language, notify.TemplateEmailVerification, map[string]interface{}{
+		"Name":      user.Name,
+		"URL":       strings.TrimRight(baseURL, "/") + "/auth/verify?token=" + token,
+		"ExpiresIn": expiresIn,
+	})
+}
+
+// @Summary		Подтверждение email
+// @Description	Подтверждает email по одноразовому токену из письма. Токен действует 24 часа
+// @Tags			auth
+// @Produce		json
+// @Param			token	query		string						true	"Токен из письма"
+// @Success		200		{object}	response.MessageResponse	"Email подтверждён"
+// @Failure		400		{object}	response.ErrorResponse		"Недействительный токен (INVALID_VERIFICATION_TOKEN)"
+// @Failure		500		{object}	response.ErrorResponse		"Ошибка сервера (DB_ERROR)"
+// @Router			/auth/verify [get]
+func (h *Handler) VerifyEmail(c *gin.Context) {
+	invalidToken := response.ErrorResponse{
+		Code:    "INVALID_VERIFICATION_TOKEN",
+		Message: "Ссылка для подтверждения email недействительна или истекла",
+	}
+
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, invalidToken)
+		return
+	}
+
+	var verification models.EmailVerificationToken
+	if err := h.DB.
+		Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashToken(token), time.Now()).
+		First(&verification).Error; err != nil {
+		c.JSON(http.StatusBadRequest, invalidToken)
+		return
+	}
+
+	used := false
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		claim := tx.Model(&models.EmailVerificationToken{}).
+			Where("id = ? AND used_at IS NULL", verification.ID).
+			Update("used_at", now)
+		if claim.Error != nil || claim.RowsAffected == 0 {
+			return claim.Error
+		}
+		used = true
+		return tx.Model(&models.User{}).Where("id = ?", verification.UserID).Updates(map[string]interface{}{
+			"email_verified":    true,
+			"email_verified_at": now,
+		}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка подтверждения email",
+			Details: err.Error(),
+		})
+		return
+	}
+	if !used {
+		c.JSON(http.StatusBadRequest, invalidToken)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.MessageResponse{Message: "Email подтверждён"})
+}
+
+// @Summary		Повторная отправка письма подтверждения
+// @Description	Отправляет новое письмо для подтверждения email; ссылки из предыдущих писем перестают действовать. Ответ не зависит от того, зарегистрирован ли email. Не больше 3 писем на один email в час
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			body	body		ResendVerificationRequest	true	"Email пользователя"
+// @Success		200		{object}	response.MessageResponse	"Запрос принят"
+// @Failure		400		{object}	response.ErrorResponse		"Ошибка валидации (VALIDATION_ERROR)"
+// @Failure		429		{object}	response.ErrorResponse		"Слишком много запросов (TOO_MANY_REQUESTS)"
+// @Failure		500		{object}	response.ErrorResponse		"Ошибка сервера (CACHE_ERROR)"
+// @Router			/auth/resend-verification [post]
+func (h *Handler) ResendVerification(c *gin.Context) {
+	var req ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "VALIDATION_ERROR",
+			Message: "Ошибка валидации данных",
+			Details: err.Error(),
+		})
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	allowed, err := h.allowEmailAction(emailVerificationKeyPrefix, email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "CACHE_ERROR",
+			Message: "Ошибка проверки лимита запросов",
+			Details: err.Error(),
+		})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusTooManyRequests, response.ErrorResponse{
+			Code:    "TOO_MANY_REQUESTS",
+			Message: "Слишком много запросов, попробуйте позже",
+		})
+		return
+	}
+
+	var user models.User
+	if err := h.DB.Where("LOWER(email) = ? AND email_verified = ?", email, false).First(&user).Error; err == nil {
+		if err := h.sendVerificationEmail(user); err != nil {
+			log.Printf("Ошибка отправки письма подтверждения (user_id=%d): %v", user.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, response.MessageResponse{Message: "Если email зарегистрирован и не подтверждён, на него отправлено письмо"})
+}
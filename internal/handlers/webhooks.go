@@ -0,0 +1,464 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"test_hack/internal/audit"
+	"test_hack/internal/models"
+	"test_hack/internal/response"
+	"test_hack/internal/webhooks"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookRequest — параметры вебхука.
+type WebhookRequest struct {
+	URL string `json:"url" binding:"required,url" example:"https://lms.example.com/hooks/queue"`
+	// Типы событий: user_joined, user_left, user_served, queue_closed, queue_update. Пустой список — все события
+	Events      []string `json:"events"`
+	Description string   `json:"description" example:"Дашборд кафедры"`
+	IsActive    *bool    `json:"is_active"`
+	// Не чаще одного события queue_update каждой очереди за столько секунд; 0 — каждое обновление
+	QueueUpdateInterval int `json:"queue_update_interval" binding:"min=0" example:"300"`
+}
+
+// WebhookResponse описывает вебхук. Секрет возвращается только при создании.
+type WebhookResponse struct {
+	ID          uint      `json:"id"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"`
+	Description string    `json:"description"`
+	IsActive    bool      `json:"is_active"`
+	Secret      string    `json:"secret,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	// Минимальный интервал между событиями queue_update одной очереди в секундах
+	QueueUpdateInterval int `json:"queue_update_interval"`
+}
+
+// WebhookDeliveryResponse — запись журнала доставки.
+type WebhookDeliveryResponse struct {
+	ID             uint       `json:"id"`
+	WebhookID      uint       `json:"webhook_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status" example:"pending"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	Payload        string     `json:"payload"`
+}
+
+// WebhookDeadLetterResponse — событие, которое не удалось доставить.
+type WebhookDeadLetterResponse struct {
+	ID         uint       `json:"id"`
+	WebhookID  uint       `json:"webhook_id"`
+	DeliveryID uint       `json:"delivery_id"`
+	EventType  string     `json:"event_type"`
+	Attempts   int        `json:"attempts"`
+	LastError  string     `json:"last_error"`
+	RetriedAt  *time.Time `json:"retried_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Payload    string     `json:"payload"`
+}
+
+func toWebhookResponse(hook models.Webhook) WebhookResponse {
+	events := []string{}
+	for _, e := range strings.Split(hook.Events, ",") {
+		if e != "" {
+			events = append(events, e)
+		}
+	}
+	return WebhookResponse{
+		ID:                  hook.ID,
+		URL:                 hook.URL,
+		Events:              events,
+		Description:         hook.Description,
+		IsActive:            hook.IsActive,
+		CreatedAt:           hook.CreatedAt,
+		QueueUpdateInterval: hook.QueueUpdateInterval,
+	}
+}
+
+func toWebhookDeliveryResponse(d models.WebhookDelivery) WebhookDeliveryResponse {
+	resp := WebhookDeliveryResponse{
+		ID:             d.ID,
+		WebhookID:      d.WebhookID,
+		EventType:      d.EventType,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		DeliveredAt:    d.DeliveredAt,
+		CreatedAt:      d.CreatedAt,
+		Payload:        d.Payload,
+	}
+	if d.Status == models.DeliveryStatusPending {
+		next := d.NextAttemptAt
+		resp.NextAttemptAt = &next
+	}
+	return resp
+}
+
+// bindWebhookRequest разбирает запрос и проверяет типы событий. При ошибке ответ уже отправлен.
+func bindWebhookRequest(c *gin.Context) (WebhookRequest, bool) {
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "VALIDATION_ERROR",
+			Message: "Ошибка валидации данных",
+			Details: err.Error(),
+		})
+		return req, false
+	}
+	for _, event := range req.Events {
+		if !webhooks.IsKnownEvent(event) {
+			c.JSON(http.StatusBadRequest, response.ErrorResponse{
+				Code:    "UNKNOWN_EVENT",
+				Message: "Неизвестный тип события",
+				Details: event,
+			})
+			return req, false
+		}
+	}
+	return req, true
+}
+
+// findWebhook загружает вебхук по параметру пути id. При ошибке ответ уже отправлен.
+func (h *Handler) findWebhook(c *gin.Context) (models.Webhook, bool) {
+	var hook models.Webhook
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Code:    "INVALID_WEBHOOK_ID",
+			Message: "Неверный идентификатор вебхука",
+		})
+		return hook, false
+	}
+	if err := h.DB.First(&hook, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, response.ErrorResponse{
+			Code:    "WEBHOOK_NOT_FOUND",
+			Message: "Вебхук не найден",
+		})
+		return hook, false
+	}
+	return hook, true
+}
+
+// ListWebhooksHandler возвращает список вебхуков
+// @Summary		Список вебхуков
+// @Description	Возвращает зарегистрированные вебхуки и их фильтры событий
+// @Tags			admin
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{array}		WebhookResponse	"Список вебхуков"
+// @Failure		403	{object}	response.ErrorResponse	"Недостаточно прав (FORBIDDEN)"
+// @Failure		500	{object}	response.ErrorResponse	"Ошибка сервера (DB_ERROR)"
+// @Router			/admin/webhooks [get]
+func (h *Handler) ListWebhooksHandler(c *gin.Context) {
+	var hooks []models.Webhook
+	if err := h.DB.Order("id ASC").Find(&hooks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка загрузки вебхуков",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	result := make([]WebhookResponse, 0, len(hooks))
+	for _, hook := range hooks {
+		result = append(result, toWebhookResponse(hook))
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// CreateWebhookHandler регистрирует вебхук
+// @Summary		Регистрация вебхука
+// @Description	Регистрирует адрес для получения событий очередей. События отправляются POST-запросом с JSON в формате сообщений WebSocket и заголовками X-Webhook-Event, X-Webhook-Delivery, X-Webhook-Timestamp и X-Webhook-Signature (sha256=hex(HMAC-SHA256(secret, "<timestamp>.<body>"))). Секрет возвращается только в этом ответе
+// @Tags			admin
+// @Accept			json
+// @Produce		json
+// @Param			webhook	body	WebhookRequest	true	"Параметры вебхука"
+// @Security		BearerAuth
+// @Success		201	{object}	WebhookResponse	"Вебхук создан"
+// @Failure		400	{object}	response.ErrorResponse	"Ошибка валидации (VALIDATION_ERROR, UNKNOWN_EVENT)"
+// @Failure		403	{object}	response.ErrorResponse	"Недостаточно прав (FORBIDDEN)"
+// @Failure		500	{object}	response.ErrorResponse	"Ошибка сервера (SECRET_GENERATION_ERROR, DB_ERROR)"
+// @Router			/admin/webhooks [post]
+func (h *Handler) CreateWebhookHandler(c *gin.Context) {
+	req, ok := bindWebhookRequest(c)
+	if !ok {
+		return
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "SECRET_GENERATION_ERROR",
+			Message: "Ошибка генерации секрета",
+		})
+		return
+	}
+
+	hook := models.Webhook{
+		URL:                 req.URL,
+		Secret:              hex.EncodeToString(secret),
+		Events:              strings.Join(req.Events, ","),
+		Description:         req.Description,
+		IsActive:            req.IsActive == nil || *req.IsActive,
+		QueueUpdateInterval: req.QueueUpdateInterval,
+	}
+	if err := h.DB.Create(&hook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка создания вебхука",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	resp := toWebhookResponse(hook)
+	audit.RecordRequest(h.DB, c, audit.Event{
+		Action:     audit.ActionWebhookCreated,
+		TargetType: audit.TargetWebhook,
+		TargetID:   hook.ID,
+		After:      resp,
+	})
+	resp.Secret = hook.Secret
+	c.JSON(http.StatusCreated, resp)
+}
+
+// UpdateWebhookHandler изменяет вебхук
+// @Summary		Изменение вебхука
+// @Description	Изменяет адрес, фильтр событий, интервал queue_update, описание или активность вебхука
+// @Tags			admin
+// @Accept			json
+// @Produce		json
+// @Param			id		path	int				true	"ID вебхука"
+// @Param			webhook	body	WebhookRequest	true	"Параметры вебхука"
+// @Security		BearerAuth
+// @Success		200	{object}	WebhookResponse	"Вебхук изменён"
+// @Failure		400	{object}	response.ErrorResponse	"Ошибка валидации (INVALID_WEBHOOK_ID, VALIDATION_ERROR, UNKNOWN_EVENT)"
+// @Failure		403	{object}	response.ErrorResponse	"Недостаточно прав (FORBIDDEN)"
+// @Failure		404	{object}	response.ErrorResponse	"Вебхук не найден (WEBHOOK_NOT_FOUND)"
+// @Failure		500	{object}	response.ErrorResponse	"Ошибка сервера (DB_ERROR)"
+// @Router			/admin/webhooks/{id} [put]
+func (h *Handler) UpdateWebhookHandler(c *gin.Context) {
+	hook, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+	req, ok := bindWebhookRequest(c)
+	if !ok {
+		return
+	}
+
+	before := toWebhookResponse(hook)
+	hook.URL = req.URL
+	hook.Events = strings.Join(req.Events, ",")
+	hook.Description = req.Description
+	hook.QueueUpdateInterval = req.QueueUpdateInterval
+	if req.IsActive != nil {
+		hook.IsActive = *req.IsActive
+	}
+	if err := h.DB.Save(&hook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка изменения вебхука",
+			Details: err.Error(),
+		})
+		return
+	}
+	after := toWebhookResponse(hook)
+	audit.RecordRequest(h.DB, c, audit.Event{
+		Action:     audit.ActionWebhookUpdated,
+		TargetType: audit.TargetWebhook,
+		TargetID:   hook.ID,
+		Before:     before,
+		After:      after,
+	})
+	c.JSON(http.StatusOK, after)
+}
+
+// DeleteWebhookHandler удаляет вебхук
+// @Summary		Удаление вебхука
+// @Description	Удаляет вебхук; оставшиеся в очереди доставки события будут отменены
+// @Tags			admin
+// @Produce		json
+// @Param			id	path	int	true	"ID вебхука"
+// @Security		BearerAuth
+// @Success		200	{object}	response.MessageResponse	"Вебхук удалён"
+// @Failure		400	{object}	response.ErrorResponse	"Ошибка валидации (INVALID_WEBHOOK_ID)"
+// @Failure		403	{object}	response.ErrorResponse	"Недостаточно прав (FORBIDDEN)"
+// @Failure		404	{object}	response.ErrorResponse	"Вебхук не найден (WEBHOOK_NOT_FOUND)"
+// @Failure		500	{object}	response.ErrorResponse	"Ошибка сервера (DB_ERROR)"
+// @Router			/admin/webhooks/{id} [delete]
+func (h *Handler) DeleteWebhookHandler(c *gin.Context) {
+	hook, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+	if err := h.DB.Delete(&hook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка удаления вебхука",
+			Details: err.Error(),
+		})
+		return
+	}
+	audit.RecordRequest(h.DB, c, audit.Event{
+		Action:     audit.ActionWebhookDeleted,
+		TargetType: audit.TargetWebhook,
+		TargetID:   hook.ID,
+		Before:     toWebhookResponse(hook),
+	})
+	c.JSON(http.StatusOK, response.MessageResponse{Message: "Вебхук удалён"})
+}
+
+// ListWebhookDeliveriesHandler возвращает журнал доставки вебхука
+// @Summary		Журнал доставки вебхука
+// @Description	Возвращает последние попытки доставки событий: статус, число попыток, код ответа и ошибку
+// @Tags			admin
+// @Produce		json
+// @Param			id		path	int		true	"ID вебхука"
+// @Param			status	query	string	false	"Фильтр по статусу (pending, success, dead)"
+// @Param			limit	query	int		false	"Количество записей (по умолчанию 50, максимум 500)"
+// @Security		BearerAuth
+// @Success		200	{array}		WebhookDeliveryResponse	"Журнал доставки"
+// @Failure		400	{object}	response.ErrorResponse	"Ошибка валидации (INVALID_WEBHOOK_ID)"
+// @Failure		403	{object}	response.ErrorResponse	"Недостаточно прав (FORBIDDEN)"
+// @Failure		404	{object}	response.ErrorResponse	"Вебхук не найден (WEBHOOK_NOT_FOUND)"
+// @Failure		500	{object}	response.ErrorResponse	"Ошибка сервера (DB_ERROR)"
+// @Router			/admin/webhooks/{id}/deliveries [get]
+func (h *Handler) ListWebhookDeliveriesHandler(c *gin.Context) {
+	hook, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 50
+	}
+
+	query := h.DB.Where("webhook_id = ?", hook.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("created_at DESC").Limit(limit).Find(&deliveries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка загрузки журнала доставки",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	result := make([]WebhookDeliveryResponse, 0, len(deliveries))
+	for _, d := range deliveries {
+		result = append(result, toWebhookDeliveryResponse(d))
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// ListWebhookDeadLettersHandler возвращает недоставленные события
+// @Summary		Недоставленные события
+// @Description	Возвращает события, которые не удалось доставить после всех попыток
+// @Tags			admin
+// @Produce		json
+// @Param			webhook_id	query	int	false	"Фильтр по вебхуку"
+// @Param			limit		query	int	false	"Количество записей (по умолчанию 50, максимум 500)"
+// @Security		BearerAuth
+// @Success		200	{array}		WebhookDeadLetterResponse	"Недоставленные события"
+// @Failure		403	{object}	response.ErrorResponse	"Недостаточно прав (FORBIDDEN)"
+// @Failure		500	{object}	response.ErrorResponse	"Ошибка сервера (DB_ERROR)"
+// @Router			/admin/webhooks/dead-letters [get]
+func (h *Handler) ListWebhookDeadLettersHandler(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 50
+	}
+
+	query := h.DB.Model(&models.WebhookDeadLetter{})
+	if webhookID := c.Query("webhook_id"); webhookID != "" {
+		query = query.Where("webhook_id = ?", webhookID)
+	}
+	var letters []models.WebhookDeadLetter
+	if err := query.Order("created_at DESC").Limit(limit).Find(&letters).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка загрузки недоставленных событий",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	result := make([]WebhookDeadLetterResponse, 0, len(letters))
+	for _, l := range letters {
+		result = append(result, WebhookDeadLetterResponse{
+			ID:         l.ID,
+			WebhookID:  l.WebhookID,
+			DeliveryID: l.DeliveryID,
+			EventType:  l.EventType,
+			Attempts:   l.Attempts,
+			LastError:  l.LastError,
+			RetriedAt:  l.RetriedAt,
+			CreatedAt:  l.CreatedAt,
+			Payload:    l.Payload,
+		})
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// RetryWebhookDeadLetterHandler повторно отправляет недоставленное событие
+// @Summary		Повторная отправка события
+// @Description	Создаёт новую доставку для недоставленного события и сразу выполняет первую попытку
+// @Tags			admin
+// @Produce		json
+// @Param			id	path	int	true	"ID недоставленного события"
+// @Security		BearerAuth
+// @Success		200	{object}	WebhookDeliveryResponse	"Новая доставка"
+// @Failure		403	{object}	response.ErrorResponse	"Недостаточно прав (FORBIDDEN)"
+// @Failure		404	{object}	response.ErrorResponse	"Событие не найдено (DEAD_LETTER_NOT_FOUND)"
+// @Failure		409	{object}	response.ErrorResponse	"Событие уже отправлено повторно (ALREADY_RETRIED)"
+// @Failure		500	{object}	response.ErrorResponse	"Ошибка сервера (DB_ERROR)"
+// @Router			/admin/webhooks/dead-letters/{id}/retry [post]
+func (h *Handler) RetryWebhookDeadLetterHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	delivery, err := h.Webhooks.RetryDeadLetter(uint(id))
+	switch {
+	case errors.Is(err, webhooks.ErrDeadLetterNotFound):
+		c.JSON(http.StatusNotFound, response.ErrorResponse{
+			Code:    "DEAD_LETTER_NOT_FOUND",
+			Message: "Недоставленное событие не найдено",
+		})
+		return
+	case errors.Is(err, webhooks.ErrAlreadyRetried):
+		c.JSON(http.StatusConflict, response.ErrorResponse{
+			Code:    "ALREADY_RETRIED",
+			Message: "Событие уже отправлено повторно",
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Code:    "DB_ERROR",
+			Message: "Ошибка повторной отправки события",
+			Details: err.Error(),
+		})
+		return
+	}
+	audit.RecordRequest(h.DB, c, audit.Event{
+		Action:     audit.ActionWebhookRetried,
+		TargetType: audit.TargetWebhook,
+		TargetID:   delivery.WebhookID,
+		After:      map[string]interface{}{"dead_letter_id": id, "delivery_id": delivery.ID, "status": delivery.Status},
+	})
+	c.JSON(http.StatusOK, toWebhookDeliveryResponse(delivery))
+}
@@ -1,10 +1,15 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"sync"
+	"sync/atomic"
+	"test_hack/internal/metrics"
+	"test_hack/internal/webhooks"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -23,6 +28,16 @@ type Hub struct {
 	broadcast chan BroadcastMessage
 	// Mutex для защиты карты клиентов.
 	mu sync.RWMutex
+	// webhooks получает копию каждого события; nil — вебхуки не отправляются.
+	webhooks *webhooks.Dispatcher
+	// metrics учитывает подключения и потерянные сообщения; nil — не учитываются.
+	metrics *metrics.Metrics
+	// done закрывается при остановке хаба (см. Shutdown); после этого Run завершается,
+	// а отправка в каналы хаба не блокируется.
+	done         chan struct{}
+	shutdownOnce sync.Once
+	// running — выполняется ли цикл Run (см. Alive).
+	running atomic.Bool
 }
 
 // BroadcastMessage представляет сообщение для рассылки в определённую очередь.
@@ -32,35 +47,41 @@ type BroadcastMessage struct {
 }
 
 type WSMessage struct {
-	EventType string      `json:"event_type"`     // Тип события: "user_joined", "user_left", "queue_closed", "queue_update", ...
+	EventType string      `json:"event_type"`     // Тип события: "user_joined", "user_left", "user_served", "queue_closed", "queue_update", ...
 	QueueID   string      `json:"queue_id"`       // Идентификатор очереди (как строка)
 	Data      interface{} `json:"data,omitempty"` // Дополнительные данные, зависящие от события
 	Timestamp int64       `json:"timestamp"`      // Метка времени (Unix)
 }
 
-// Создаем глобальный экземпляр хаба.
-var HubInstance = NewHub()
-
-// NewHub создает новый Hub.
-func NewHub() *Hub {
+// NewHub создает новый Hub. События дублируются на вебхуки через dispatcher, если он задан;
+// подключения учитываются в m.
+func NewHub(dispatcher *webhooks.Dispatcher, m *metrics.Metrics) *Hub {
 	return &Hub{
 		clients:    make(map[string]map[*Client]bool),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		broadcast:  make(chan BroadcastMessage),
+		webhooks:   dispatcher,
+		metrics:    m,
+		done:       make(chan struct{}),
 	}
 }
 
 // Run запускает цикл обработки каналов хаба.
 func (h *Hub) Run() {
+	h.running.Store(true)
+	defer h.running.Store(false)
 	for {
 		select {
+		case <-h.done:
+			return
 		case client := <-h.register:
 			h.mu.Lock()
 			if h.clients[client.QueueID] == nil {
 				h.clients[client.QueueID] = make(map[*Client]bool)
 			}
 			h.clients[client.QueueID][client] = true
+			h.metrics.SetWSConnections(client.QueueID, len(h.clients[client.QueueID]))
 			h.mu.Unlock()
 		case client := <-h.unregister:
 			h.mu.Lock()
@@ -71,11 +92,14 @@ func (h *Hub) Run() {
 					if len(clients) == 0 {
 						delete(h.clients, client.QueueID)
 					}
+					h.metrics.SetWSConnections(client.QueueID, len(clients))
 				}
 			}
 			h.mu.Unlock()
 		case message := <-h.broadcast:
-			h.mu.RLock()
+			// Медленные клиенты удаляются из карты прямо здесь, поэтому нужна блокировка на запись:
+			// ClientCount читает карту под RLock из других горутин.
+			h.mu.Lock()
 			if clients, ok := h.clients[message.QueueID]; ok {
 				for client := range clients {
 					select {
@@ -83,10 +107,15 @@ func (h *Hub) Run() {
 					default:
 						close(client.Send)
 						delete(clients, client)
+						h.metrics.WSMessageDropped()
+						h.metrics.SetWSConnections(message.QueueID, len(clients))
 					}
 				}
+				if len(clients) == 0 {
+					delete(h.clients, message.QueueID)
+				}
 			}
-			h.mu.RUnlock()
+			h.mu.Unlock()
 		}
 	}
 }
@@ -103,7 +132,10 @@ type Client struct {
 // В данном примере мы не обрабатываем входящие сообщения, а просто отслеживаем разрыв соединения.
 func (c *Client) readPump() {
 	defer func() {
-		c.Hub.unregister <- c
+		select {
+		case c.Hub.unregister <- c:
+		case <-c.Hub.done:
+		}
 		c.Conn.Close()
 	}()
 	c.Conn.SetReadLimit(512)
@@ -172,7 +204,7 @@ var upgrader = websocket.Upgrader{
 // @Failure		400	{object}	response.ErrorResponse	"Ошибка валидации (INVALID_QUEUE_ID)"
 // @Failure		500	{object}	response.ErrorResponse	"Ошибка сервера (WEBSOCKET_ERROR)"
 // @Router			/api/queues/{id}/ws [get]
-func QueueWebSocketHandler(c *gin.Context) {
+func (h *Handler) QueueWebSocketHandler(c *gin.Context) {
 	queueID := c.Param("id")
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -181,13 +213,19 @@ func QueueWebSocketHandler(c *gin.Context) {
 	}
 	// Создаем нового клиента
 	client := &Client{
-		Hub:     HubInstance,
+		Hub:     h.Hub,
 		Conn:    conn,
 		Send:    make(chan []byte, 256),
 		QueueID: queueID,
 	}
-	// Регистрируем клиента в Hub
-	HubInstance.register <- client
+	// Регистрируем клиента в Hub; после остановки хаба новые подключения сразу закрываются.
+	select {
+	case h.Hub.register <- client:
+	case <-h.Hub.done:
+		conn.WriteControl(websocket.CloseMessage, h.Hub.closeFrame(0), time.Now().Add(time.Second))
+		conn.Close()
+		return
+	}
 
 	// Запускаем горутины для отправки и приема сообщений
 	go client.writePump()
@@ -202,8 +240,79 @@ func (h *Hub) BroadcastWSMessage(msg WSMessage) {
 		log.Println("Ошибка сериализации WSMessage:", err)
 		return
 	}
-	h.broadcast <- BroadcastMessage{
-		QueueID: msg.QueueID,
-		Message: b,
+	select {
+	case h.broadcast <- BroadcastMessage{QueueID: msg.QueueID, Message: b}:
+	case <-h.done:
+	}
+	if h.webhooks != nil {
+		h.webhooks.Publish(msg.QueueID, msg.EventType, b)
+	}
+}
+
+// Relay рассылает клиентам WebSocket событие, опубликованное другим процессом (см. EventsChannel).
+// На вебхуки событие не дублируется: публикующий процесс сам ставит его в очередь доставки.
+func (h *Hub) Relay(message []byte) {
+	var msg WSMessage
+	if err := json.Unmarshal(message, &msg); err != nil || msg.QueueID == "" {
+		log.Println("Некорректное событие из канала", EventsChannel, err)
+		return
+	}
+	select {
+	case h.broadcast <- BroadcastMessage{QueueID: msg.QueueID, Message: message}:
+	case <-h.done:
 	}
 }
+
+// Alive сообщает, выполняется ли цикл Run. Без него регистрация клиентов и рассылка событий блокируются.
+func (h *Hub) Alive() bool {
+	return h.running.Load()
+}
+
+// ClientCount возвращает количество клиентов WebSocket, подключённых к очереди queueID.
+func (h *Hub) ClientCount(queueID string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients[queueID])
+}
+
+// closeFrame возвращает кадр закрытия с кодом 1012 (Service Restart). В тексте причины передаётся
+// подсказка клиенту, через сколько миллисекунд переподключиться: {"reconnect_after_ms":5000}.
+func (h *Hub) closeFrame(reconnectAfter time.Duration) []byte {
+	reason := fmt.Sprintf(`{"reconnect_after_ms":%d}`, reconnectAfter.Milliseconds())
+	return websocket.FormatCloseMessage(websocket.CloseServiceRestart, reason)
+}
+
+// Shutdown останавливает хаб: отправляет всем клиентам кадр закрытия с подсказкой переподключиться
+// через reconnectAfter и закрывает соединения. Ждёт отправки кадров, пока не истечёт ctx.
+// Повторные вызовы ничего не делают.
+func (h *Hub) Shutdown(ctx context.Context, reconnectAfter time.Duration) {
+	h.shutdownOnce.Do(func() {
+		h.mu.Lock()
+		clients := h.clients
+		h.clients = make(map[string]map[*Client]bool)
+		close(h.done)
+		h.mu.Unlock()
+
+		frame := h.closeFrame(reconnectAfter)
+		deadline := time.Now().Add(5 * time.Second)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+
+		var wg sync.WaitGroup
+		for queueID, set := range clients {
+			h.metrics.SetWSConnections(queueID, 0)
+			for client := range set {
+				wg.Add(1)
+				go func(c *Client) {
+					defer wg.Done()
+					// WriteControl можно вызывать одновременно с writePump.
+					c.Conn.WriteControl(websocket.CloseMessage, frame, deadline)
+					c.Conn.Close()
+				}(client)
+			}
+		}
+		wg.Wait()
+		log.Println("WebSocket-клиенты отключены.")
+	})
+}
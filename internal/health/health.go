@@ -0,0 +1,141 @@
+// Package health отвечает на проверки живости и готовности сервиса: /healthz сообщает, что процесс
+// работает, а /readyz проверяет зависимости (базу данных, Redis, фоновые процессы) и возвращает 503,
+// если хотя бы одна из них недоступна.
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Статусы сервиса и его компонентов.
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// DefaultTimeout — время на все проверки готовности, если Checker.Timeout не задан.
+const DefaultTimeout = 2 * time.Second
+
+// Check проверяет один компонент; nil означает, что компонент работает.
+type Check func(ctx context.Context) error
+
+// Component — результат проверки компонента.
+type Component struct {
+	Status    string `json:"status" example:"up"`
+	Error     string `json:"error,omitempty" example:"dial tcp 127.0.0.1:6379: connect: connection refused"`
+	LatencyMs int64  `json:"latency_ms" example:"3"`
+}
+
+// Report — результат проверки готовности: общий статус и статус каждого компонента.
+type Report struct {
+	Status     string               `json:"status" example:"up"`
+	Components map[string]Component `json:"components,omitempty"`
+}
+
+// Checker выполняет зарегистрированные проверки готовности.
+type Checker struct {
+	// Timeout ограничивает время всех проверок; по умолчанию DefaultTimeout.
+	Timeout time.Duration
+
+	names  []string
+	checks map[string]Check
+}
+
+// New создаёт Checker без проверок.
+func New() *Checker {
+	return &Checker{checks: make(map[string]Check)}
+}
+
+// Add регистрирует проверку компонента name.
+func (c *Checker) Add(name string, check Check) {
+	if _, ok := c.checks[name]; !ok {
+		c.names = append(c.names, name)
+	}
+	c.checks[name] = check
+}
+
+// Check выполняет все проверки параллельно. Общий статус — down, если хотя бы один компонент недоступен.
+func (c *Checker) Check(ctx context.Context) Report {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	results := make([]Component, len(c.names))
+	var wg sync.WaitGroup
+	for i, name := range c.names {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = run(ctx, check)
+		}(i, c.checks[name])
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Components: make(map[string]Component, len(c.names))}
+	for i, name := range c.names {
+		report.Components[name] = results[i]
+		if results[i].Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+// run выполняет проверку и прерывает ожидание по ctx, даже если сама проверка контекст не учитывает.
+func run(ctx context.Context, check Check) Component {
+	start := time.Now()
+	errc := make(chan error, 1)
+	go func() { errc <- check(ctx) }()
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	result := Component{Status: StatusUp, LatencyMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Liveness сообщает, что процесс запущен и обрабатывает запросы. Зависимости не проверяются.
+//
+// @Summary		Проверка живости
+// @Description	Возвращает 200, пока процесс обрабатывает HTTP-запросы. Зависимости не проверяются — для этого есть /readyz.
+// @Tags			health
+// @Produce		json
+// @Success		200	{object}	health.Report	"Сервис работает"
+// @Router			/healthz [get]
+func Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, Report{Status: StatusUp})
+}
+
+// Readiness выполняет проверки готовности и возвращает их результат: 200, если все компоненты работают,
+// и 503 в противном случае.
+//
+// @Summary		Проверка готовности
+// @Description	Проверяет базу данных, Redis, хаб WebSocket и планировщик фоновых задач. Возвращает статус каждого компонента; если хотя бы один недоступен — код 503.
+// @Tags			health
+// @Produce		json
+// @Success		200	{object}	health.Report	"Все компоненты работают"
+// @Failure		503	{object}	health.Report	"Хотя бы один компонент недоступен"
+// @Router			/readyz [get]
+func (c *Checker) Readiness(ctx *gin.Context) {
+	report := c.Check(ctx.Request.Context())
+	status := http.StatusOK
+	if report.Status != StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	ctx.JSON(status, report)
+}
@@ -0,0 +1,137 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"test_hack/internal/metrics"
+	"test_hack/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrJobNotFound = errors.New("задача не найдена")
+	ErrJobRunning  = errors.New("задача уже выполняется")
+)
+
+// Job описывает фоновую задачу: имя, расписание cron и функцию, возвращающую число затронутых записей.
+type Job struct {
+	Name string
+	Spec string
+	Run  func() (int64, error)
+}
+
+// Registry хранит фоновые задачи и записывает их запуски в таблицу job_runs.
+type Registry struct {
+	db      *gorm.DB
+	metrics *metrics.Metrics
+
+	mu      sync.Mutex
+	jobs    map[string]Job
+	running map[string]bool
+}
+
+// NewRegistry создаёт пустой реестр задач. Длительность и результат запусков учитываются в m.
+func NewRegistry(db *gorm.DB, m *metrics.Metrics) *Registry {
+	return &Registry{
+		db:      db,
+		metrics: m,
+		jobs:    make(map[string]Job),
+		running: make(map[string]bool),
+	}
+}
+
+// Register добавляет задачу в реестр. Повторная регистрация с тем же именем заменяет задачу.
+func (r *Registry) Register(job Job) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs[job.Name] = job
+}
+
+// List возвращает зарегистрированные задачи, отсортированные по имени.
+func (r *Registry) List() []Job {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := make([]Job, 0, len(r.jobs))
+	for _, job := range r.jobs {
+		list = append(list, job)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// IsRunning сообщает, выполняется ли задача в данный момент.
+func (r *Registry) IsRunning(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.running[name]
+}
+
+// Run выполняет задачу и записывает результат в таблицу job_runs.
+// Одна и та же задача не может выполняться параллельно: второй запуск вернёт ErrJobRunning.
+func (r *Registry) Run(name, trigger string) (*models.JobRun, error) {
+	r.mu.Lock()
+	job, ok := r.jobs[name]
+	if !ok {
+		r.mu.Unlock()
+		return nil, ErrJobNotFound
+	}
+	if r.running[name] {
+		r.mu.Unlock()
+		return nil, ErrJobRunning
+	}
+	r.running[name] = true
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		delete(r.running, name)
+		r.mu.Unlock()
+	}()
+
+	run := models.JobRun{
+		JobName:   name,
+		Trigger:   trigger,
+		Status:    models.JobStatusRunning,
+		StartedAt: time.Now(),
+	}
+	if err := r.db.Create(&run).Error; err != nil {
+		log.Printf("Ошибка записи запуска задачи %s: %v", name, err)
+	}
+
+	affected, err := execute(job)
+
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	run.DurationMs = finishedAt.Sub(run.StartedAt).Milliseconds()
+	run.AffectedRows = affected
+	run.Status = models.JobStatusSuccess
+	if err != nil {
+		run.Status = models.JobStatusFailed
+		run.Error = err.Error()
+		log.Printf("Задача %s завершилась с ошибкой за %d мс: %v", name, run.DurationMs, err)
+	}
+	r.metrics.ObserveJob(name, run.Status, finishedAt.Sub(run.StartedAt))
+	if run.ID != 0 {
+		if err := r.db.Save(&run).Error; err != nil {
+			log.Printf("Ошибка сохранения результата задачи %s: %v", name, err)
+		}
+	}
+
+	return &run, nil
+}
+
+// execute запускает функцию задачи, превращая панику в ошибку, чтобы она попала в job_runs.
+func execute(job Job) (affected int64, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("паника: %v", r)
+		}
+	}()
+	return job.Run()
+}
@@ -0,0 +1,242 @@
+// Package metrics собирает метрики Prometheus: HTTP-запросы, подключения WebSocket, события очередей,
+// фоновые задачи, запросы к внешним API и обращения к кэшу Redis. Метрики отдаются на /metrics (см. Handler).
+//
+// Метрики принадлежат экземпляру Metrics со своим реестром, а не реестру по умолчанию: каждое приложение
+// (и каждый тест) считает их независимо. Экземпляр передаётся зависимостям при сборке приложения;
+// методы допускают nil-получатель — тогда метрики не учитываются.
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"test_hack/internal/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics — метрики одного экземпляра приложения.
+type Metrics struct {
+	// Registry — реестр, из которого Handler отдаёт метрики; в него же можно добавить свои сборщики.
+	Registry *prometheus.Registry
+
+	httpRequestDuration     *prometheus.HistogramVec
+	wsConnections           *prometheus.GaugeVec
+	wsDroppedMessages       prometheus.Counter
+	queueJoins              prometheus.Counter
+	queueLeaves             prometheus.Counter
+	jobDuration             *prometheus.HistogramVec
+	jobRuns                 *prometheus.CounterVec
+	externalRequestDuration *prometheus.HistogramVec
+	cacheRequests           *prometheus.CounterVec
+}
+
+// New создаёт метрики в новом реестре вместе со стандартными метриками Go-рантайма и процесса.
+func New() *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+
+		httpRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Время обработки HTTP-запросов по маршрутам и кодам ответа.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+
+		wsConnections: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "ws_connections",
+			Help: "Активные подключения WebSocket по очередям.",
+		}, []string{"queue_id"}),
+
+		wsDroppedMessages: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "ws_dropped_messages_total",
+			Help: "Сообщения, не доставленные клиентам WebSocket из-за переполненного буфера; такие клиенты отключаются.",
+		}),
+
+		queueJoins: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "queue_joins_total",
+			Help: "Вступления в очереди.",
+		}),
+
+		queueLeaves: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "queue_leaves_total",
+			Help: "Выходы из очередей.",
+		}),
+
+		jobDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "job_duration_seconds",
+			Help:    "Время выполнения фоновых задач.",
+			Buckets: []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60, 300},
+		}, []string{"job"}),
+
+		jobRuns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "job_runs_total",
+			Help: "Запуски фоновых задач по результату (success, failed).",
+		}, []string{"job", "status"}),
+
+		externalRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "external_request_duration_seconds",
+			Help:    "Время запросов к внешним API по сервисам и кодам ответа (error — запрос не выполнен).",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"service", "status"}),
+
+		cacheRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cache_requests_total",
+			Help: "Обращения к кэшу Redis по результату (hit, miss).",
+		}, []string{"cache", "result"}),
+	}
+	m.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequestDuration,
+		m.wsConnections,
+		m.wsDroppedMessages,
+		m.queueJoins,
+		m.queueLeaves,
+		m.jobDuration,
+		m.jobRuns,
+		m.externalRequestDuration,
+		m.cacheRequests,
+	)
+	return m
+}
+
+// Middleware измеряет время обработки HTTP-запросов. Маршрут берётся из шаблона gin (/api/queues/:id/join),
+// запросы без маршрута учитываются как unmatched. Подключения WebSocket не измеряются — их учитывает
+// ws_connections.
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if m == nil || c.IsWebsocket() {
+			c.Next()
+			return
+		}
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		m.httpRequestDuration.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler отдаёт метрики из Registry вместе с collectors. Ошибка одного сборщика не мешает отдать
+// остальные метрики.
+func (m *Metrics) Handler(collectors ...prometheus.Collector) gin.HandlerFunc {
+	extra := prometheus.NewRegistry()
+	extra.MustRegister(collectors...)
+	h := promhttp.HandlerFor(prometheus.Gatherers{m.Registry, extra}, promhttp.HandlerOpts{
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+	return gin.WrapH(h)
+}
+
+// RequireToken пропускает только запросы с заголовком Authorization: Bearer <token>.
+func RequireToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		got := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, response.ErrorResponse{
+				Code:    "UNAUTHORIZED",
+				Message: "Неверный токен доступа к метрикам",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// SetWSConnections задаёт количество подключений WebSocket к очереди queueID.
+// Очереди без подключений удаляются из метрики.
+func (m *Metrics) SetWSConnections(queueID string, n int) {
+	if m == nil {
+		return
+	}
+	if n == 0 {
+		m.wsConnections.DeleteLabelValues(queueID)
+		return
+	}
+	m.wsConnections.WithLabelValues(queueID).Set(float64(n))
+}
+
+// WSMessageDropped учитывает сообщение, которое не удалось поставить в буфер клиента WebSocket.
+func (m *Metrics) WSMessageDropped() {
+	if m == nil {
+		return
+	}
+	m.wsDroppedMessages.Inc()
+}
+
+// QueueJoined учитывает вступление в очередь.
+func (m *Metrics) QueueJoined() {
+	if m == nil {
+		return
+	}
+	m.queueJoins.Inc()
+}
+
+// QueueLeft учитывает выход из очереди.
+func (m *Metrics) QueueLeft() {
+	if m == nil {
+		return
+	}
+	m.queueLeaves.Inc()
+}
+
+// ObserveJob учитывает запуск фоновой задачи job со статусом status и длительностью d.
+func (m *Metrics) ObserveJob(job, status string, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.jobDuration.WithLabelValues(job).Observe(d.Seconds())
+	m.jobRuns.WithLabelValues(job, status).Inc()
+}
+
+// CacheLookup учитывает обращение к кэшу cache: hit — значение найдено в Redis.
+// Доля попаданий: sum(rate(cache_requests_total{result="hit"}[5m])) / sum(rate(cache_requests_total[5m])).
+func (m *Metrics) CacheLookup(cache string, hit bool) {
+	if m == nil {
+		return
+	}
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cacheRequests.WithLabelValues(cache, result).Inc()
+}
+
+// Transport измеряет время запросов к внешнему сервису service, выполняемых через base.
+// Если base равен nil, используется http.DefaultTransport.
+func (m *Metrics) Transport(service string, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if m == nil {
+		return base
+	}
+	return &transport{metrics: m, service: service, base: base}
+}
+
+type transport struct {
+	metrics *Metrics
+	service string
+	base    http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	t.metrics.externalRequestDuration.WithLabelValues(t.service, status).Observe(time.Since(start).Seconds())
+	return resp, err
+}
@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var queueLengthDesc = prometheus.NewDesc(
+	"queue_length",
+	"Участники активных очередей (ожидающие приёма).",
+	[]string{"queue_id"}, nil,
+)
+
+// QueueLengths возвращает сборщик метрики queue_length. Длины очередей запрашиваются функцией lengths
+// при каждом обращении к /metrics, поэтому метрика одинакова на всех экземплярах сервера.
+func QueueLengths(lengths func() (map[uint]int64, error)) prometheus.Collector {
+	return queueLengthCollector(lengths)
+}
+
+type queueLengthCollector func() (map[uint]int64, error)
+
+func (c queueLengthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- queueLengthDesc
+}
+
+func (c queueLengthCollector) Collect(ch chan<- prometheus.Metric) {
+	lengths, err := c()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(queueLengthDesc, err)
+		return
+	}
+	for queueID, n := range lengths {
+		ch <- prometheus.MustNewConstMetric(queueLengthDesc, prometheus.GaugeValue, float64(n), strconv.FormatUint(uint64(queueID), 10))
+	}
+}
@@ -0,0 +1,69 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// Usage — справка по команде migrate.
+const Usage = `использование: migrate <команда>
+  up          применить все новые миграции
+  down [N]    откатить N последних миграций (по умолчанию 1)
+  status      показать применённые и ожидающие миграции`
+
+// Command выполняет команду migrate с аргументами args (up, down [N] или status) и пишет результат в out.
+func Command(ctx context.Context, m *Migrator, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("не указана команда\n%s", Usage)
+	}
+
+	switch args[0] {
+	case "up":
+		done, err := m.Up(ctx)
+		for _, mig := range done {
+			fmt.Fprintln(out, "применена", mig)
+		}
+		if err == nil && len(done) == 0 {
+			fmt.Fprintln(out, "новых миграций нет")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("down: ожидается положительное число шагов, получено %q", args[1])
+			}
+			steps = n
+		}
+		done, err := m.Down(ctx, steps)
+		for _, mig := range done {
+			fmt.Fprintln(out, "откачена", mig)
+		}
+		if err == nil && len(done) == 0 {
+			fmt.Fprintln(out, "нет применённых миграций")
+		}
+		return err
+	case "status":
+		list, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range list {
+			applied := "ожидает"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Local().Format(time.DateTime)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("неизвестная команда %q\n%s", args[0], Usage)
+	}
+}
@@ -0,0 +1,98 @@
+// Package migrations управляет схемой базы данных. Миграции — пары SQL-файлов
+// sql/<версия>_<название>.up.sql и .down.sql, встроенные в бинарный файл. Применённые версии
+// хранятся в таблице schema_migrations.
+//
+// Чтобы изменить схему, добавьте пару файлов со следующим номером версии. Уже выпущенные
+// миграции не редактируются: базы, где они применены, не увидят изменений.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// Migration — одна версия схемы.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// Embedded возвращает встроенные миграции по возрастанию версии.
+func Embedded() ([]Migration, error) {
+	return Parse(files, "sql")
+}
+
+// Parse читает миграции из каталога dir файловой системы fsys. Версии должны идти подряд начиная с 1,
+// у каждой версии должны быть оба файла — up и down.
+func Parse(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		file := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(file, ".sql") {
+			continue
+		}
+		base := strings.TrimSuffix(file, ".sql")
+		direction := path.Ext(base)
+		if direction != ".up" && direction != ".down" {
+			return nil, fmt.Errorf("%s: ожидается имя вида 0001_name.up.sql или 0001_name.down.sql", file)
+		}
+		versionStr, name, ok := strings.Cut(strings.TrimSuffix(base, direction), "_")
+		version, err := strconv.ParseInt(versionStr, 10, 64)
+		if !ok || err != nil || name == "" {
+			return nil, fmt.Errorf("%s: ожидается имя вида 0001_name.up.sql или 0001_name.down.sql", file)
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, file))
+		if err != nil {
+			return nil, err
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("версия %d: разные названия %q и %q", version, m.Name, name)
+		}
+		if direction == ".up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		list = append(list, *m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+
+	for i, m := range list {
+		if m.Version != int64(i+1) {
+			return nil, fmt.Errorf("%s: ожидается версия %d, версии должны идти подряд", m, i+1)
+		}
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			return nil, fmt.Errorf("%s: нужны непустые файлы .up.sql и .down.sql", m)
+		}
+	}
+	return list, nil
+}
@@ -0,0 +1,18 @@
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS data_exports;
+DROP TABLE IF EXISTS mfa_recovery_codes;
+DROP TABLE IF EXISTS audit_events;
+DROP TABLE IF EXISTS email_verification_tokens;
+DROP TABLE IF EXISTS password_reset_tokens;
+DROP TABLE IF EXISTS vapid_keys;
+DROP TABLE IF EXISTS push_subscriptions;
+DROP TABLE IF EXISTS webhook_dead_letters;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS notification_settings;
+DROP TABLE IF EXISTS job_runs;
+DROP TABLE IF EXISTS queue_entries;
+DROP TABLE IF EXISTS queues;
+DROP TABLE IF EXISTS schedules;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,366 @@
+-- Исходная схема, совпадающая с той, что создавал AutoMigrate. Все объекты создаются с IF NOT EXISTS,
+-- поэтому миграция безопасно применяется и к пустой базе, и к базе, созданной AutoMigrate.
+-- В базе первой версии сервера таблицы users и queue_entries уже есть, но без появившихся позже
+-- столбцов, поэтому они добавляются через ADD COLUMN IF NOT EXISTS. Таблицы schedules и queues
+-- с первой версии не менялись.
+
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    name text NOT NULL,
+    surname text NOT NULL,
+    email text NOT NULL,
+    password_hash text NOT NULL,
+    role text NOT NULL DEFAULT 'student',
+    group_id text,
+    language text NOT NULL DEFAULT 'ru',
+    telegram_chat_id bigint,
+    token_version bigint NOT NULL DEFAULT 0,
+    email_verified boolean NOT NULL DEFAULT false,
+    totp_secret text NOT NULL DEFAULT '',
+    totp_enabled boolean NOT NULL DEFAULT false,
+    oidc_subject text,
+    email_verified_at timestamptz,
+    totp_enabled_at timestamptz
+);
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role text NOT NULL DEFAULT 'student',
+    ADD COLUMN IF NOT EXISTS group_id text,
+    ADD COLUMN IF NOT EXISTS language text NOT NULL DEFAULT 'ru',
+    ADD COLUMN IF NOT EXISTS telegram_chat_id bigint,
+    ADD COLUMN IF NOT EXISTS token_version bigint NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS totp_secret text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS totp_enabled boolean NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS oidc_subject text,
+    ADD COLUMN IF NOT EXISTS totp_enabled_at timestamptz;
+-- Пользователи, зарегистрированные до появления подтверждения email, считаются подтверждёнными
+-- с момента регистрации, иначе при REQUIRE_EMAIL_VERIFICATION=true они потеряли бы доступ к очередям.
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = current_schema() AND table_name = 'users' AND column_name = 'email_verified'
+    ) THEN
+        ALTER TABLE users
+            ADD COLUMN email_verified boolean NOT NULL DEFAULT false,
+            ADD COLUMN IF NOT EXISTS email_verified_at timestamptz;
+        UPDATE users SET email_verified = true, email_verified_at = created_at;
+    END IF;
+END
+$$;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+CREATE INDEX IF NOT EXISTS idx_users_group_id ON users (group_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_telegram_chat_id ON users (telegram_chat_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_oidc_subject ON users (oidc_subject);
+
+CREATE TABLE IF NOT EXISTS schedules (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    external_id text,
+    name text NOT NULL,
+    start_time timestamptz NOT NULL,
+    end_time timestamptz NOT NULL,
+    group_ids text NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_schedules_deleted_at ON schedules (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_schedules_external_id ON schedules (external_id);
+CREATE INDEX IF NOT EXISTS idx_schedules_start_time ON schedules (start_time);
+
+CREATE TABLE IF NOT EXISTS queues (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    schedule_id bigint NOT NULL,
+    opens_at timestamptz,
+    closes_at timestamptz,
+    is_active boolean DEFAULT false,
+    max_participants bigint
+);
+CREATE INDEX IF NOT EXISTS idx_queues_deleted_at ON queues (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_queues_schedule_id ON queues (schedule_id);
+CREATE INDEX IF NOT EXISTS idx_queues_opens_at ON queues (opens_at);
+CREATE INDEX IF NOT EXISTS idx_queues_closes_at ON queues (closes_at);
+
+CREATE TABLE IF NOT EXISTS queue_entries (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    user_id bigint NOT NULL,
+    queue_id bigint NOT NULL,
+    position bigint NOT NULL,
+    exited_at timestamptz,
+    status text NOT NULL DEFAULT 'waiting',
+    served_by_id bigint,
+    served_at timestamptz,
+    CONSTRAINT fk_queue_entries_user FOREIGN KEY (user_id) REFERENCES users (id),
+    CONSTRAINT fk_queue_entries_served_by FOREIGN KEY (served_by_id) REFERENCES users (id)
+);
+-- В первой версии статуса не было: покинутые записи отличались только exited_at.
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = current_schema() AND table_name = 'queue_entries' AND column_name = 'status'
+    ) THEN
+        ALTER TABLE queue_entries ADD COLUMN status text NOT NULL DEFAULT 'waiting';
+        UPDATE queue_entries SET status = 'left' WHERE exited_at IS NOT NULL;
+    END IF;
+END
+$$;
+ALTER TABLE queue_entries
+    ADD COLUMN IF NOT EXISTS served_by_id bigint,
+    ADD COLUMN IF NOT EXISTS served_at timestamptz;
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conname = 'fk_queue_entries_served_by' AND conrelid = 'queue_entries'::regclass
+    ) THEN
+        ALTER TABLE queue_entries
+            ADD CONSTRAINT fk_queue_entries_served_by FOREIGN KEY (served_by_id) REFERENCES users (id);
+    END IF;
+END
+$$;
+CREATE INDEX IF NOT EXISTS idx_queue_entries_deleted_at ON queue_entries (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_queue_entries_user_id ON queue_entries (user_id);
+CREATE INDEX IF NOT EXISTS idx_queue_entries_queue_id ON queue_entries (queue_id);
+CREATE INDEX IF NOT EXISTS idx_queue_entries_position ON queue_entries (position);
+CREATE INDEX IF NOT EXISTS idx_queue_entries_status ON queue_entries (status);
+
+CREATE TABLE IF NOT EXISTS job_runs (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    job_name text NOT NULL,
+    trigger text NOT NULL,
+    status text NOT NULL,
+    started_at timestamptz NOT NULL,
+    finished_at timestamptz,
+    duration_ms bigint,
+    affected_rows bigint,
+    error text
+);
+CREATE INDEX IF NOT EXISTS idx_job_runs_deleted_at ON job_runs (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_job_runs_job_name ON job_runs (job_name);
+CREATE INDEX IF NOT EXISTS idx_job_runs_status ON job_runs (status);
+CREATE INDEX IF NOT EXISTS idx_job_runs_started_at ON job_runs (started_at);
+
+CREATE TABLE IF NOT EXISTS notification_settings (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    user_id bigint NOT NULL,
+    queue_opened boolean,
+    before_event boolean,
+    before_event_minutes bigint NOT NULL,
+    position_reached boolean,
+    position_threshold bigint NOT NULL,
+    channels text NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_notification_settings_deleted_at ON notification_settings (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_settings_user_id ON notification_settings (user_id);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    user_id bigint NOT NULL,
+    queue_id bigint NOT NULL,
+    kind text NOT NULL,
+    title text NOT NULL,
+    body text NOT NULL,
+    sent_at timestamptz,
+    error text
+);
+CREATE INDEX IF NOT EXISTS idx_notifications_deleted_at ON notifications (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_once ON notifications (user_id, queue_id, kind);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    url text NOT NULL,
+    secret text NOT NULL,
+    events text,
+    description text,
+    is_active boolean
+);
+CREATE INDEX IF NOT EXISTS idx_webhooks_deleted_at ON webhooks (deleted_at);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    webhook_id bigint NOT NULL,
+    event_type text NOT NULL,
+    payload text NOT NULL,
+    status text NOT NULL,
+    attempts bigint NOT NULL,
+    next_attempt_at timestamptz,
+    last_status_code bigint,
+    last_error text,
+    delivered_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_deleted_at ON webhook_deliveries (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_event_type ON webhook_deliveries (event_type);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries (status);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_next_attempt_at ON webhook_deliveries (next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS webhook_dead_letters (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    webhook_id bigint NOT NULL,
+    delivery_id bigint NOT NULL,
+    event_type text NOT NULL,
+    payload text NOT NULL,
+    attempts bigint,
+    last_error text,
+    retried_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_dead_letters_deleted_at ON webhook_dead_letters (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_dead_letters_webhook_id ON webhook_dead_letters (webhook_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_dead_letters_delivery_id ON webhook_dead_letters (delivery_id);
+
+CREATE TABLE IF NOT EXISTS push_subscriptions (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    user_id bigint NOT NULL,
+    endpoint text NOT NULL,
+    p256dh text NOT NULL,
+    auth text NOT NULL,
+    user_agent text,
+    last_used_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_push_subscriptions_deleted_at ON push_subscriptions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_push_subscriptions_user_id ON push_subscriptions (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_push_subscriptions_endpoint ON push_subscriptions (endpoint);
+
+CREATE TABLE IF NOT EXISTS vapid_keys (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    public_key text NOT NULL,
+    private_key text NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_vapid_keys_deleted_at ON vapid_keys (deleted_at);
+
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    user_id bigint NOT NULL,
+    token_hash text NOT NULL,
+    expires_at timestamptz NOT NULL,
+    used_at timestamptz,
+    request_ip text
+);
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_deleted_at ON password_reset_tokens (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_password_reset_tokens_token_hash ON password_reset_tokens (token_hash);
+
+CREATE TABLE IF NOT EXISTS email_verification_tokens (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    user_id bigint NOT NULL,
+    token_hash text NOT NULL,
+    expires_at timestamptz NOT NULL,
+    used_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_email_verification_tokens_deleted_at ON email_verification_tokens (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_email_verification_tokens_user_id ON email_verification_tokens (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_email_verification_tokens_token_hash ON email_verification_tokens (token_hash);
+
+CREATE TABLE IF NOT EXISTS audit_events (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    action text NOT NULL,
+    actor_id bigint,
+    user_id bigint,
+    email text,
+    target_type text,
+    target_id text,
+    before text,
+    after text,
+    ip text,
+    request_id text,
+    user_agent text,
+    details text
+);
+CREATE INDEX IF NOT EXISTS idx_audit_events_deleted_at ON audit_events (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events (action);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor_id ON audit_events (actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_user_id ON audit_events (user_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_email ON audit_events (email);
+CREATE INDEX IF NOT EXISTS idx_audit_target ON audit_events (target_type, target_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_ip ON audit_events (ip);
+CREATE INDEX IF NOT EXISTS idx_audit_events_request_id ON audit_events (request_id);
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    user_id bigint NOT NULL,
+    code_hash text NOT NULL,
+    used_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_deleted_at ON mfa_recovery_codes (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user_id ON mfa_recovery_codes (user_id);
+
+CREATE TABLE IF NOT EXISTS data_exports (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    user_id bigint NOT NULL,
+    format text NOT NULL,
+    status text NOT NULL DEFAULT 'pending',
+    data bytea,
+    error text,
+    completed_at timestamptz,
+    expires_at timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_data_exports_deleted_at ON data_exports (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_data_exports_user_id ON data_exports (user_id);
+CREATE INDEX IF NOT EXISTS idx_data_exports_status ON data_exports (status);
+CREATE INDEX IF NOT EXISTS idx_data_exports_expires_at ON data_exports (expires_at);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    user_id bigint NOT NULL,
+    last_used_at timestamptz NOT NULL,
+    expires_at timestamptz NOT NULL,
+    revoked_at timestamptz,
+    user_agent text,
+    ip text
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_deleted_at ON sessions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions (expires_at);
@@ -0,0 +1,29 @@
+-- У пользователя может быть только одна активная запись в очереди. Раньше это проверялось только в коде,
+-- и параллельные запросы на вступление могли создать дубликаты. Перед созданием индекса оставляем
+-- самую раннюю активную запись, а остальные закрываем как покинутые.
+UPDATE queue_entries AS e
+SET exited_at = now(), status = 'left', updated_at = now()
+WHERE e.exited_at IS NULL
+  AND e.deleted_at IS NULL
+  AND EXISTS (
+      SELECT 1 FROM queue_entries AS o
+      WHERE o.queue_id = e.queue_id
+        AND o.user_id = e.user_id
+        AND o.exited_at IS NULL
+        AND o.deleted_at IS NULL
+        AND o.id < e.id
+  );
+
+-- После закрытия дубликатов в позициях могли появиться пропуски — пересчитываем их по порядку.
+UPDATE queue_entries AS e
+SET position = r.position
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY queue_id ORDER BY position, id) AS position
+    FROM queue_entries
+    WHERE exited_at IS NULL AND deleted_at IS NULL
+) AS r
+WHERE e.id = r.id AND e.position <> r.position;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_queue_entries_active_user
+    ON queue_entries (queue_id, user_id)
+    WHERE exited_at IS NULL AND deleted_at IS NULL;
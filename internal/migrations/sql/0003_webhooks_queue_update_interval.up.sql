@@ -0,0 +1,3 @@
+-- Минимальный интервал между событиями queue_update одной очереди для вебхука: планировщик рассылает
+-- состояние активных очередей каждую минуту. 0 — отправлять каждое обновление.
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS queue_update_interval bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- Способ входа, которым открыта сессия: по свежему входу через SSO пользователь без пароля подтверждает удаление аккаунта.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS method text NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+-- Email сравнивается без учёта регистра: регистрация и вход приводят его к нижнему регистру, а поиск
+-- идёт по LOWER(email). Индекс не даёт завести второй аккаунт, отличающийся только регистром email,
+-- и ускоряет поиск. Если такие аккаунты уже есть, миграция остановится с ошибкой о дубликате —
+-- их нужно объединить вручную.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (LOWER(email));
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrAuditImmutable возвращается при попытке изменить или удалить запись журнала аудита.
+var ErrAuditImmutable = errors.New("журнал аудита доступен только для добавления записей")
+
+// AuditEvent — запись журнала аудита о действии, важном для безопасности, или об изменении состояния.
+// Журнал только пополняется: хуки BeforeUpdate и BeforeDelete запрещают изменять и удалять записи.
+type AuditEvent struct {
+	gorm.Model
+	Action     string `gorm:"index;not null"`         // Тип события, например auth.login_failed или queue.joined
+	ActorID    *uint  `gorm:"index"`                  // Кто выполнил действие (nil — система или неизвестный пользователь)
+	UserID     *uint  `gorm:"index"`                  // Пользователь, к которому относится событие (nil — неизвестен)
+	Email      string `gorm:"index"`                  // Email из запроса, если пользователь не найден
+	TargetType string `gorm:"index:idx_audit_target"` // Тип изменённого объекта: queue, user, webhook, job
+	TargetID   string `gorm:"index:idx_audit_target"`
+	Before     string `gorm:"type:text"` // Состояние объекта до изменения в формате JSON
+	After      string `gorm:"type:text"` // Состояние объекта после изменения в формате JSON
+	IP         string `gorm:"index"`
+	RequestID  string `gorm:"index"`
+	UserAgent  string
+	Details    string `gorm:"type:text"` // Дополнительные данные в формате JSON
+}
+
+func (AuditEvent) BeforeUpdate(*gorm.DB) error { return ErrAuditImmutable }
+
+func (AuditEvent) BeforeDelete(*gorm.DB) error { return ErrAuditImmutable }
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PasswordResetToken — одноразовый токен сброса пароля. В базе хранится только SHA-256 хеш токена.
+type PasswordResetToken struct {
+	gorm.Model
+	UserID    uint       `gorm:"index;not null"`
+	TokenHash string     `gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time // Время использования или отзыва (nil — токен ещё действует)
+	RequestIP string
+}
+
+// EmailVerificationToken — одноразовый токен подтверждения email. В базе хранится только SHA-256 хеш токена.
+type EmailVerificationToken struct {
+	gorm.Model
+	UserID    uint       `gorm:"index;not null"`
+	TokenHash string     `gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time // Время использования или отзыва (nil — токен ещё действует)
+}
+
+// MFARecoveryCode — одноразовый код восстановления для входа без приложения-аутентификатора.
+// В базе хранится только SHA-256 хеш кода.
+type MFARecoveryCode struct {
+	gorm.Model
+	UserID   uint       `gorm:"index;not null"`
+	CodeHash string     `gorm:"not null"`
+	UsedAt   *time.Time // Время использования (nil — код ещё действует)
+}
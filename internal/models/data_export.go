@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Статусы выгрузки персональных данных
+const (
+	DataExportStatusPending = "pending" // Выгрузка формируется
+	DataExportStatusReady   = "ready"   // Архив готов к скачиванию
+	DataExportStatusFailed  = "failed"  // Ошибка формирования
+)
+
+// DataExport — выгрузка персональных данных пользователя, сформированная в фоне.
+type DataExport struct {
+	gorm.Model
+	UserID      uint       `gorm:"index;not null"`
+	Format      string     `gorm:"not null"`                       // json или zip
+	Status      string     `gorm:"index;not null;default:pending"` // См. DataExportStatus*
+	Data        []byte     // Готовый файл выгрузки
+	Error       string     // Текст ошибки, если выгрузка не сформирована
+	CompletedAt *time.Time // Время завершения формирования
+	ExpiresAt   time.Time  `gorm:"index;not null"` // После этого времени выгрузка удаляется
+}
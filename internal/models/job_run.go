@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Статусы запуска фоновой задачи
+const (
+	JobStatusRunning = "running"
+	JobStatusSuccess = "success"
+	JobStatusFailed  = "failed"
+)
+
+// Источники запуска фоновой задачи
+const (
+	JobTriggerSchedule = "schedule" // Запуск по расписанию cron
+	JobTriggerManual   = "manual"   // Ручной запуск администратором
+	JobTriggerCLI      = "cli"      // Запуск командой queuectl
+)
+
+// JobRun хранит информацию об одном запуске фоновой задачи планировщика.
+type JobRun struct {
+	gorm.Model
+	JobName      string     `gorm:"index;not null"`
+	Trigger      string     `gorm:"not null"`
+	Status       string     `gorm:"index;not null"`
+	StartedAt    time.Time  `gorm:"index;not null"`
+	FinishedAt   *time.Time // Время завершения (nil — задача ещё выполняется)
+	DurationMs   int64      // Длительность выполнения в миллисекундах
+	AffectedRows int64      // Количество затронутых записей
+	Error        string     // Текст ошибки, если задача завершилась неудачно
+}
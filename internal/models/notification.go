@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// NotificationSettings хранит настройки напоминаний пользователя.
+type NotificationSettings struct {
+	gorm.Model
+	UserID             uint   `gorm:"uniqueIndex;not null"`
+	QueueOpened        bool   // Уведомлять об открытии очереди на событие своей группы
+	BeforeEvent        bool   // Напоминать о начале события, в очереди на которое стоит пользователь
+	BeforeEventMinutes int    `gorm:"not null"` // За сколько минут до начала события напоминать
+	PositionReached    bool   // Уведомлять, когда пользователь приближается к началу очереди
+	PositionThreshold  int    `gorm:"not null"` // Позиция, начиная с которой отправляется уведомление
+	Channels           string `gorm:"not null"` // Каналы доставки через запятую, например "email,telegram"
+}
+
+// Notification фиксирует отправленное напоминание, чтобы не отправлять его повторно.
+type Notification struct {
+	gorm.Model
+	UserID  uint   `gorm:"uniqueIndex:idx_notification_once;not null"`
+	QueueID uint   `gorm:"uniqueIndex:idx_notification_once;not null"`
+	Kind    string `gorm:"uniqueIndex:idx_notification_once;not null"` // Тип напоминания: queue_opened, event_soon, turn_near
+	Title   string `gorm:"not null"`
+	Body    string `gorm:"not null"`
+	SentAt  time.Time
+	Error   string // Ошибки доставки по каналам, если были
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PushSubscription — подписка браузера пользователя на Web Push (объект PushSubscription из Push API).
+type PushSubscription struct {
+	gorm.Model
+	UserID     uint   `gorm:"index;not null"`
+	Endpoint   string `gorm:"type:text;uniqueIndex;not null"` // Адрес push-сервиса браузера
+	P256dh     string `gorm:"not null"`                       // Публичный ключ браузера для шифрования сообщений
+	Auth       string `gorm:"not null"`                       // Секрет аутентификации браузера
+	UserAgent  string
+	LastUsedAt *time.Time // Время последней успешной доставки
+}
+
+// VAPIDKeys — ключевая пара VAPID, которой сервер подписывает запросы к push-сервисам.
+// Создаётся автоматически при первом запуске, если ключи не заданы в окружении. Таблица хранит одну
+// строку с ID VAPIDKeysID.
+type VAPIDKeys struct {
+	gorm.Model
+	PublicKey  string `gorm:"not null"`
+	PrivateKey string `gorm:"not null"`
+}
+
+// VAPIDKeysID — ID единственной строки таблицы vapid_keys.
+const VAPIDKeysID = 1
+
+// TableName задаёт имя таблицы: по правилам именования GORM получилось бы v_api_d_keys.
+func (VAPIDKeys) TableName() string { return "vapid_keys" }
@@ -6,11 +6,23 @@ import (
 	"gorm.io/gorm"
 )
 
+// Статусы записи в очереди
+const (
+	EntryStatusWaiting   = "waiting"    // Участник ожидает своей очереди
+	EntryStatusLeft      = "left"       // Участник сам покинул очередь
+	EntryStatusServed    = "served"     // Участник принят преподавателем
+	EntryStatusNotServed = "not_served" // Очередь закрылась до того, как участника приняли
+)
+
 type QueueEntry struct {
 	gorm.Model
-	UserID   uint       `gorm:"index;not null"`
-	User     User       `gorm:"foreignKey:UserID"`
-	QueueID  uint       `gorm:"index;not null"`
-	Position int        `gorm:"index;not null"` // Текущая позиция в очереди
-	ExitedAt *time.Time // Время выхода из очереди, если пользователь покинул очередь (nil — активный участник)
+	UserID     uint       `gorm:"index;not null"`
+	User       User       `gorm:"foreignKey:UserID"`
+	QueueID    uint       `gorm:"index;not null"`
+	Position   int        `gorm:"index;not null"` // Текущая позиция в очереди
+	ExitedAt   *time.Time // Время выхода из очереди, если пользователь покинул очередь (nil — активный участник)
+	Status     string     `gorm:"index;not null;default:waiting"` // Итоговый статус записи (см. EntryStatus*)
+	ServedByID *uint      // Кто принял участника (преподаватель или администратор)
+	ServedBy   *User      `gorm:"foreignKey:ServedByID"`
+	ServedAt   *time.Time // Время, когда участника приняли
 }
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Способы входа, которыми открыта сессия
+const (
+	SessionMethodPassword = "password" // Пароль, в том числе со вторым фактором
+	SessionMethodSSO      = "sso"      // Единый вход через OpenID Connect
+)
+
+// Session — вход пользователя с определённого устройства. Access и refresh токены содержат ID сессии
+// в claim "sid"; после отзыва сессии её токены перестают приниматься.
+type Session struct {
+	gorm.Model
+	UserID     uint       `gorm:"index;not null"`
+	LastUsedAt time.Time  `gorm:"not null"`
+	ExpiresAt  time.Time  `gorm:"index;not null"` // Срок действия последнего выданного refresh токена
+	RevokedAt  *time.Time // Время отзыва (nil — сессия действует)
+	UserAgent  string
+	IP         string
+	Method     string `gorm:"not null;default:''"` // Способ входа: SessionMethodPassword или SessionMethodSSO (пусто — неизвестен)
+}
@@ -1,13 +1,33 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
+// Роли пользователей
+const (
+	RoleStudent = "student"
+	RoleTeacher = "teacher"
+	RoleAdmin   = "admin"
+)
+
 type User struct {
 	gorm.Model
-	Name         string `gorm:"not null"`
-	Surname      string `gorm:"not null"`
-	Email        string `gorm:"uniqueIndex;not null"`
-	PasswordHash string `gorm:"not null"`
+	Name            string     `gorm:"not null"`
+	Surname         string     `gorm:"not null"`
+	Email           string     `gorm:"uniqueIndex;not null"`
+	PasswordHash    string     `gorm:"not null"`
+	Role            string     `gorm:"not null;default:student"`        // Роль пользователя: student, teacher или admin
+	GroupID         string     `gorm:"index"`                           // ID учебной группы из внешнего API (может быть пустым)
+	Language        string     `gorm:"not null;default:ru"`             // Язык писем и уведомлений: ru или en
+	TelegramChatID  *int64     `gorm:"uniqueIndex"`                     // ID чата с Telegram-ботом (nil — Telegram не привязан)
+	TokenVersion    int        `gorm:"not null;default:0"`              // Версия refresh токенов; увеличивается, чтобы отозвать все выданные токены
+	EmailVerified   bool       `gorm:"not null;default:false"`          // Email подтверждён по ссылке из письма
+	TOTPSecret      string     `gorm:"not null;default:''"`             // Секрет TOTP в base32; задаётся при начале подключения 2FA
+	TOTPEnabled     bool       `gorm:"not null;default:false"`          // Вход требует код из приложения-аутентификатора
+	OIDCSubject     *string    `gorm:"column:oidc_subject;uniqueIndex"` // Идентификатор (sub) пользователя у провайдера SSO (nil — вход через SSO не выполнялся)
+	EmailVerifiedAt *time.Time // Время подтверждения email
+	TOTPEnabledAt   *time.Time // Время подключения 2FA
 }
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Статусы доставки вебхука
+const (
+	DeliveryStatusPending = "pending" // Ожидает отправки или повторной попытки
+	DeliveryStatusSuccess = "success" // Получатель ответил кодом 2xx
+	DeliveryStatusDead    = "dead"    // Попытки исчерпаны, доставка перенесена в webhook_dead_letters
+)
+
+// Webhook — внешний адрес, на который отправляются события очередей.
+type Webhook struct {
+	gorm.Model
+	URL         string `gorm:"not null"`
+	Secret      string `gorm:"not null"` // Ключ HMAC-подписи запросов
+	Events      string // Типы событий через запятую, например "user_joined,queue_closed"; пусто — все события
+	Description string
+	IsActive    bool
+	// QueueUpdateInterval — не чаще одного события queue_update одной очереди за это число секунд; 0 — без ограничения
+	QueueUpdateInterval int `gorm:"not null;default:0"`
+}
+
+// WebhookDelivery — журнал доставки одного события на один вебхук.
+type WebhookDelivery struct {
+	gorm.Model
+	WebhookID      uint      `gorm:"index;not null"`
+	EventType      string    `gorm:"index;not null"`
+	Payload        string    `gorm:"type:text;not null"`
+	Status         string    `gorm:"index;not null"`
+	Attempts       int       `gorm:"not null"`
+	NextAttemptAt  time.Time `gorm:"index"`
+	LastStatusCode int       // HTTP-код последнего ответа (0 — ответа не было)
+	LastError      string
+	DeliveredAt    *time.Time
+}
+
+// WebhookDeadLetter хранит события, которые не удалось доставить после всех попыток.
+type WebhookDeadLetter struct {
+	gorm.Model
+	WebhookID  uint   `gorm:"index;not null"`
+	DeliveryID uint   `gorm:"uniqueIndex;not null"`
+	EventType  string `gorm:"not null"`
+	Payload    string `gorm:"type:text;not null"`
+	Attempts   int
+	LastError  string
+	RetriedAt  *time.Time // Время ручной повторной отправки администратором
+}
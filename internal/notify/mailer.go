@@ -0,0 +1,221 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"test_hack/internal/config"
+	"test_hack/internal/models"
+)
+
+// Mail — письмо в виде простого текста.
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer отправляет письма. Реализации: SMTPMailer для боевого окружения,
+// FileMailer и MemoryMailer для локальной разработки и тестов.
+type Mailer interface {
+	Send(mail Mail) error
+}
+
+// NewMailer создаёт почтовый сервис по настройкам cfg: SMTP-сервер, каталог с файлами .eml или память.
+func NewMailer(cfg config.Mail) Mailer {
+	backend := cfg.BackendName()
+	var mailer Mailer
+	switch backend {
+	case "smtp":
+		mailer = &SMTPMailer{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.User,
+			Password: cfg.SMTP.Password,
+			From:     cfg.From,
+			Timeout:  cfg.SMTP.Timeout,
+		}
+	case "memory":
+		mailer = &MemoryMailer{}
+	default:
+		mailer = &FileMailer{Dir: cfg.CaptureDir, From: cfg.From}
+	}
+
+	log.Printf("Почтовый сервис: %s", backend)
+	return mailer
+}
+
+// SendTemplate отправляет письмо по шаблону на языке получателя через почтовый сервис, см. SetMailer.
+func (n *Notifier) SendTemplate(to, lang, name string, data interface{}) error {
+	n.mu.RLock()
+	mailer := n.mailer
+	n.mu.RUnlock()
+	if mailer == nil {
+		return errors.New("почтовый сервис не настроен")
+	}
+	subject, body, err := Render(lang, name, data)
+	if err != nil {
+		return err
+	}
+	return mailer.Send(Mail{To: to, Subject: subject, Body: body})
+}
+
+// EmailChannel доставляет напоминания по электронной почте.
+type EmailChannel struct {
+	Mailer Mailer
+}
+
+func (EmailChannel) Name() string { return "email" }
+
+func (ch EmailChannel) Send(user models.User, msg Message) error {
+	title, body := msg.Localized(user.Language)
+	return ch.Mailer.Send(Mail{To: user.Email, Subject: title, Body: body})
+}
+
+// SMTPMailer отправляет письма через SMTP-сервер.
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	// Timeout ограничивает подключение и весь обмен с сервером, чтобы зависший сервер не держал
+	// отправку бесконечно. Ноль — defaultSMTPTimeout.
+	Timeout time.Duration
+}
+
+const defaultSMTPTimeout = 30 * time.Second
+
+func (m *SMTPMailer) Send(mail Mail) error {
+	addr := net.JoinHostPort(m.Host, m.Port)
+	msg := buildMessage(m.From, mail)
+	timeout := m.Timeout
+	if timeout <= 0 {
+		timeout = defaultSMTPTimeout
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	var err error
+	// На порту 465 соединение сразу устанавливается по TLS, на остальных выполняется STARTTLS, если сервер его поддерживает.
+	if m.Port == "465" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: m.Host})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		conn.Close()
+		return err
+	}
+	client, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if m.Port != "465" {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: m.Host}); err != nil {
+				return err
+			}
+		}
+	}
+	if m.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.Username, m.Password, m.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(m.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(mail.To); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// FileMailer сохраняет письма в каталог в формате .eml вместо отправки.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+func (m *FileMailer) Send(mail Mail) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d_%s.eml", time.Now().UnixNano(), unsafeFileChars.ReplaceAllString(mail.To, "_"))
+	return os.WriteFile(filepath.Join(m.Dir, name), buildMessage(m.From, mail), 0o644)
+}
+
+// MemoryMailer хранит отправленные письма в памяти. Используется в тестах.
+type MemoryMailer struct {
+	mu    sync.Mutex
+	mails []Mail
+}
+
+func (m *MemoryMailer) Send(mail Mail) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mails = append(m.mails, mail)
+	return nil
+}
+
+// Messages возвращает копию всех «отправленных» писем.
+func (m *MemoryMailer) Messages() []Mail {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Mail(nil), m.mails...)
+}
+
+// Reset очищает список писем.
+func (m *MemoryMailer) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mails = nil
+}
+
+// buildMessage собирает письмо в формате RFC 5322 с текстом в UTF-8 (quoted-printable).
+func buildMessage(from string, mail Mail) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", mail.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", mail.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	qp.Write([]byte(mail.Body))
+	qp.Close()
+	return buf.Bytes()
+}
@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"log"
+
+	"test_hack/internal/models"
+)
+
+// QueueOpenedMessage — напоминание об открытии очереди на событие.
+func QueueOpenedMessage(schedule models.Schedule, queue models.Queue) Message {
+	return newMessage(KindQueueOpened, queue.ID, map[string]interface{}{
+		"QueueID":      queue.ID,
+		"ScheduleID":   schedule.ID,
+		"ScheduleName": schedule.Name,
+		"StartTime":    schedule.StartTime.Format("02.01 15:04"),
+		"ClosesAt":     queue.ClosesAt.Format("02.01 15:04"),
+	})
+}
+
+// EventSoonMessage — напоминание о скором начале события.
+func EventSoonMessage(schedule models.Schedule, queue models.Queue, position int) Message {
+	return newMessage(KindEventSoon, queue.ID, map[string]interface{}{
+		"QueueID":      queue.ID,
+		"ScheduleID":   schedule.ID,
+		"ScheduleName": schedule.Name,
+		"StartTime":    schedule.StartTime.Format("15:04"),
+		"Position":     position,
+	})
+}
+
+// TurnNearMessage — напоминание о приближении очереди пользователя.
+func TurnNearMessage(schedule models.Schedule, queue models.Queue, position int) Message {
+	return newMessage(KindTurnNear, queue.ID, map[string]interface{}{
+		"QueueID":      queue.ID,
+		"ScheduleID":   schedule.ID,
+		"ScheduleName": schedule.Name,
+		"Position":     position,
+	})
+}
+
+// newMessage формирует напоминание; заголовок и текст заполняются на языке по умолчанию
+// и сохраняются в истории уведомлений.
+func newMessage(kind string, queueID uint, data map[string]interface{}) Message {
+	msg := Message{Kind: kind, QueueID: queueID, Data: data}
+	title, body, err := Render(DefaultLang, kind, data)
+	if err != nil {
+		log.Printf("Ошибка шаблона уведомления %s: %v", kind, err)
+	}
+	msg.Title, msg.Body = title, body
+	return msg
+}
+
+// Localized возвращает заголовок и текст напоминания на языке пользователя.
+func (m Message) Localized(lang string) (title, body string) {
+	if lang == "" || lang == DefaultLang {
+		return m.Title, m.Body
+	}
+	title, body, err := Render(lang, m.Kind, m.Data)
+	if err != nil {
+		return m.Title, m.Body
+	}
+	return title, body
+}
@@ -0,0 +1,239 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"test_hack/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Типы напоминаний
+const (
+	KindQueueOpened = "queue_opened" // Открылась очередь на событие группы пользователя
+	KindEventSoon   = "event_soon"   // Скоро начнётся событие, в очереди на которое стоит пользователь
+	KindTurnNear    = "turn_near"    // Пользователь приблизился к началу очереди
+)
+
+// Message — напоминание, которое доставляется пользователю по выбранным им каналам.
+type Message struct {
+	Kind    string
+	QueueID uint
+	Title   string
+	Body    string
+	Data    map[string]interface{}
+}
+
+// Channel — канал доставки уведомлений (журнал, email, Telegram и т.д.).
+type Channel interface {
+	// Name возвращает имя канала, которое пользователь указывает в настройках.
+	Name() string
+	// Send доставляет сообщение пользователю.
+	Send(user models.User, msg Message) error
+}
+
+// DefaultChannels — каналы, которые используются, пока пользователь не изменил настройки.
+var DefaultChannels = []string{"email"}
+
+// Notifier хранит подключённые каналы доставки, почтовый сервис и отправитель Web Push
+// одного экземпляра приложения.
+type Notifier struct {
+	mu       sync.RWMutex
+	channels map[string]Channel
+	mailer   Mailer
+	push     *PushSender
+}
+
+// NewNotifier создаёт Notifier с подключённым каналом LogChannel.
+func NewNotifier() *Notifier {
+	n := &Notifier{channels: make(map[string]Channel)}
+	n.Register(LogChannel{})
+	return n
+}
+
+// Register подключает канал доставки. Повторная регистрация с тем же именем заменяет канал.
+func (n *Notifier) Register(ch Channel) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.channels[ch.Name()] = ch
+}
+
+// Channels возвращает имена подключённых каналов.
+func (n *Notifier) Channels() []string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	names := make([]string, 0, len(n.channels))
+	for name := range n.channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HasChannel сообщает, подключён ли канал с указанным именем.
+func (n *Notifier) HasChannel(name string) bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	_, ok := n.channels[name]
+	return ok
+}
+
+// SetMailer задаёт почтовый сервис для писем верификации и сброса пароля и подключает канал email.
+func (n *Notifier) SetMailer(mailer Mailer) {
+	n.mu.Lock()
+	n.mailer = mailer
+	n.mu.Unlock()
+	n.Register(EmailChannel{Mailer: mailer})
+}
+
+// SetPushSender задаёт отправитель Web Push и подключает канал webpush.
+func (n *Notifier) SetPushSender(sender *PushSender, db *gorm.DB) {
+	n.mu.Lock()
+	n.push = sender
+	n.mu.Unlock()
+	n.Register(PushChannel{Sender: sender, DB: db})
+}
+
+// PushSender возвращает отправитель Web Push; nil, если Web Push не настроен.
+func (n *Notifier) PushSender() *PushSender {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.push
+}
+
+// DefaultSettings возвращает настройки напоминаний по умолчанию.
+func DefaultSettings(userID uint) models.NotificationSettings {
+	return models.NotificationSettings{
+		UserID:             userID,
+		QueueOpened:        true,
+		BeforeEvent:        true,
+		BeforeEventMinutes: 30,
+		PositionReached:    true,
+		PositionThreshold:  3,
+		Channels:           strings.Join(DefaultChannels, ","),
+	}
+}
+
+// SettingsFor загружает настройки пользователя или возвращает настройки по умолчанию, если их ещё нет.
+func SettingsFor(db *gorm.DB, userID uint) (models.NotificationSettings, error) {
+	var settings models.NotificationSettings
+	err := db.Where("user_id = ?", userID).Limit(1).Find(&settings).Error
+	if err != nil {
+		return settings, err
+	}
+	if settings.ID == 0 {
+		return DefaultSettings(userID), nil
+	}
+	return settings, nil
+}
+
+// SettingsForUsers загружает настройки сразу для нескольких пользователей.
+// Для пользователей без сохранённых настроек подставляются настройки по умолчанию.
+func SettingsForUsers(db *gorm.DB, userIDs []uint) (map[uint]models.NotificationSettings, error) {
+	result := make(map[uint]models.NotificationSettings, len(userIDs))
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+	var stored []models.NotificationSettings
+	if err := db.Where("user_id IN ?", userIDs).Find(&stored).Error; err != nil {
+		return nil, err
+	}
+	for _, s := range stored {
+		result[s.UserID] = s
+	}
+	for _, id := range userIDs {
+		if _, ok := result[id]; !ok {
+			result[id] = DefaultSettings(id)
+		}
+	}
+	return result, nil
+}
+
+// Send доставляет сообщение по всем каналам из настроек пользователя.
+// Ошибки отдельных каналов объединяются, остальные каналы при этом всё равно получают сообщение.
+func (n *Notifier) Send(user models.User, settings models.NotificationSettings, msg Message) error {
+	_, err := n.deliver(user, settings, msg)
+	return err
+}
+
+// deliver отправляет сообщение по каналам пользователя и возвращает число каналов, принявших его.
+func (n *Notifier) deliver(user models.User, settings models.NotificationSettings, msg Message) (int, error) {
+	var delivered int
+	var errs []error
+	for _, name := range strings.Split(settings.Channels, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		n.mu.RLock()
+		ch, ok := n.channels[name]
+		n.mu.RUnlock()
+		if !ok {
+			continue
+		}
+		if err := ch.Send(user, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		delivered++
+	}
+	return delivered, errors.Join(errs...)
+}
+
+// SendOnce отправляет напоминание, только если напоминание того же типа по этой очереди
+// пользователю ещё не отправлялось. Возвращает true, если сообщение было отправлено.
+//
+// Запись в notifications создаётся до отправки и служит блокировкой: параллельный запуск задачи на другом
+// экземпляре не отправит напоминание второй раз. Если ни один канал не принял сообщение, запись удаляется,
+// и следующий запуск задачи повторит отправку. Если часть каналов приняла сообщение, запись остаётся
+// с текстом ошибок, чтобы не дублировать напоминание в доставленных каналах.
+func (n *Notifier) SendOnce(db *gorm.DB, user models.User, settings models.NotificationSettings, msg Message) (bool, error) {
+	record := models.Notification{
+		UserID:  user.ID,
+		QueueID: msg.QueueID,
+		Kind:    msg.Kind,
+		Title:   msg.Title,
+		Body:    msg.Body,
+		SentAt:  time.Now(),
+	}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	delivered, err := n.deliver(user, settings, msg)
+	if err == nil {
+		return true, nil
+	}
+	if delivered == 0 {
+		// Уникальный индекс учитывает и мягко удалённые строки, поэтому запись удаляется окончательно.
+		if delErr := db.Unscoped().Delete(&record).Error; delErr != nil {
+			return false, errors.Join(err, delErr)
+		}
+		return false, err
+	}
+	if updErr := db.Model(&record).Update("error", err.Error()).Error; updErr != nil {
+		return true, errors.Join(err, updErr)
+	}
+	return true, err
+}
+
+// LogChannel выводит уведомления в журнал приложения. Используется при локальной разработке.
+type LogChannel struct{}
+
+func (LogChannel) Name() string { return "log" }
+
+func (LogChannel) Send(user models.User, msg Message) error {
+	log.Printf("Уведомление для пользователя %d (%s): %s — %s", user.ID, user.Email, msg.Title, msg.Body)
+	return nil
+}
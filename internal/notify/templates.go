@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Языки сообщений
+const (
+	LangRU      = "ru"
+	LangEN      = "en"
+	DefaultLang = LangRU
+)
+
+// Шаблоны писем, не связанных с напоминаниями
+const (
+	TemplateEmailVerification = "email_verification"
+	TemplatePasswordReset     = "password_reset"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// templates хранит разобранные шаблоны по ключу "язык/имя". Каждый шаблон определяет блоки subject и body.
+var templates = loadTemplates()
+
+func loadTemplates() map[string]*template.Template {
+	result := make(map[string]*template.Template)
+	for _, lang := range []string{LangRU, LangEN} {
+		files, err := templateFS.ReadDir("templates/" + lang)
+		if err != nil {
+			panic(err)
+		}
+		for _, f := range files {
+			name := strings.TrimSuffix(f.Name(), ".tmpl")
+			result[lang+"/"+name] = template.Must(template.ParseFS(templateFS, "templates/"+lang+"/"+f.Name()))
+		}
+	}
+	return result
+}
+
+// Render формирует тему и текст сообщения по шаблону на указанном языке.
+// Если для языка нет шаблона, используется язык по умолчанию.
+func Render(lang, name string, data interface{}) (subject, body string, err error) {
+	tmpl, ok := templates[lang+"/"+name]
+	if !ok {
+		tmpl, ok = templates[DefaultLang+"/"+name]
+	}
+	if !ok {
+		return "", "", fmt.Errorf("шаблон %q не найден", name)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "subject", data); err != nil {
+		return "", "", err
+	}
+	subject = strings.TrimSpace(buf.String())
+
+	buf.Reset()
+	if err := tmpl.ExecuteTemplate(&buf, "body", data); err != nil {
+		return "", "", err
+	}
+	return subject, strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,171 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"test_hack/internal/config"
+	"test_hack/internal/metrics"
+	"test_hack/internal/models"
+
+	"github.com/SherClockHolmes/webpush-go"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PushSender отправляет зашифрованные Web Push-сообщения, подписанные ключами VAPID.
+type PushSender struct {
+	PublicKey  string
+	PrivateKey string
+	// Subject — контакт администратора для push-сервисов (mailto: или https:).
+	Subject string
+	// HTTPClient можно заменить, чтобы направлять запросы на тестовый push-сервис.
+	HTTPClient *http.Client
+	// TTL — сколько секунд push-сервис хранит сообщение, если браузер недоступен.
+	TTL int
+}
+
+// ErrSubscriptionGone возвращается, если push-сервис сообщил, что подписка больше не действует.
+var ErrSubscriptionGone = errors.New("подписка больше не действует")
+
+// NewPushSender создаёт отправитель Web Push с ключами VAPID из cfg. Если ключи не заданы, пара берётся
+// из таблицы vapid_keys или генерируется и сохраняется при первом запуске. Без cfg.Subject контактом
+// администратора считается mailto:mailFrom. Время запросов к push-сервисам учитывается в m.
+func NewPushSender(db *gorm.DB, cfg config.WebPush, mailFrom string, m *metrics.Metrics) (*PushSender, error) {
+	publicKey, privateKey := cfg.PublicKey, cfg.PrivateKey
+	if publicKey == "" || privateKey == "" {
+		keys, err := loadOrCreateVAPIDKeys(db)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, privateKey = keys.PublicKey, keys.PrivateKey
+	}
+
+	subject := cfg.Subject
+	if subject == "" {
+		subject = "mailto:" + mailFrom
+	}
+
+	sender := &PushSender{
+		PublicKey:  publicKey,
+		PrivateKey: privateKey,
+		Subject:    subject,
+		HTTPClient: &http.Client{Timeout: 10 * time.Second, Transport: m.Transport("webpush", nil)},
+		TTL:        3600,
+	}
+	return sender, nil
+}
+
+// loadOrCreateVAPIDKeys загружает ключи VAPID или создаёт их. Экземпляры, одновременно запущенные
+// с пустой таблицей, вставляют строку с одним и тем же ID: вставка проигравших пропускается,
+// и все они читают ключи победителя.
+func loadOrCreateVAPIDKeys(db *gorm.DB) (models.VAPIDKeys, error) {
+	var keys models.VAPIDKeys
+	if err := db.Order("id DESC").Limit(1).Find(&keys).Error; err != nil {
+		return keys, err
+	}
+	if keys.ID != 0 {
+		return keys, nil
+	}
+
+	privateKey, publicKey, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		return keys, err
+	}
+	keys = models.VAPIDKeys{PublicKey: publicKey, PrivateKey: privateKey}
+	keys.ID = models.VAPIDKeysID
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&keys)
+	if result.Error != nil {
+		return keys, result.Error
+	}
+	if result.RowsAffected == 0 {
+		keys = models.VAPIDKeys{}
+		err := db.First(&keys, models.VAPIDKeysID).Error
+		return keys, err
+	}
+	log.Println("Сгенерированы новые ключи VAPID.")
+	return keys, nil
+}
+
+// Send шифрует и отправляет сообщение на одну подписку. Возвращает HTTP-код ответа push-сервиса.
+func (s *PushSender) Send(ctx context.Context, sub models.PushSubscription, payload []byte) (int, error) {
+	resp, err := webpush.SendNotificationWithContext(ctx, payload, &webpush.Subscription{
+		Endpoint: sub.Endpoint,
+		Keys:     webpush.Keys{P256dh: sub.P256dh, Auth: sub.Auth},
+	}, &webpush.Options{
+		HTTPClient:      s.HTTPClient,
+		Subscriber:      s.Subject,
+		TTL:             s.TTL,
+		Urgency:         webpush.UrgencyHigh,
+		VAPIDPublicKey:  s.PublicKey,
+		VAPIDPrivateKey: s.PrivateKey,
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return resp.StatusCode, ErrSubscriptionGone
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return resp.StatusCode, fmt.Errorf("push-сервис ответил кодом %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// PushPayload — содержимое push-сообщения, которое получает service worker.
+type PushPayload struct {
+	Kind    string `json:"kind"`
+	QueueID uint   `json:"queue_id,omitempty"`
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+}
+
+// PushChannel доставляет напоминания во все браузеры, в которых пользователь оформил подписку.
+// Подписки, отозванные браузером, удаляются.
+type PushChannel struct {
+	Sender *PushSender
+	DB     *gorm.DB
+}
+
+func (PushChannel) Name() string { return "webpush" }
+
+func (ch PushChannel) Send(user models.User, msg Message) error {
+	var subs []models.PushSubscription
+	if err := ch.DB.Where("user_id = ?", user.ID).Find(&subs).Error; err != nil {
+		return err
+	}
+	if len(subs) == 0 {
+		return errors.New("нет подписок Web Push")
+	}
+
+	title, body := msg.Localized(user.Language)
+	payload, err := json.Marshal(PushPayload{Kind: msg.Kind, QueueID: msg.QueueID, Title: title, Body: body})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, sub := range subs {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		_, err := ch.Sender.Send(ctx, sub, payload)
+		cancel()
+		switch {
+		case errors.Is(err, ErrSubscriptionGone):
+			ch.DB.Unscoped().Delete(&sub)
+		case err != nil:
+			errs = append(errs, err)
+		default:
+			ch.DB.Model(&sub).Update("last_used_at", time.Now())
+		}
+	}
+	return errors.Join(errs...)
+}
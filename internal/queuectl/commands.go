@@ -0,0 +1,265 @@
+package queuectl
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"test_hack/internal/audit"
+	"test_hack/internal/migrations"
+	"test_hack/internal/models"
+	"test_hack/internal/notify"
+	"test_hack/internal/repository"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cliDetails помечает события аудита, выполненные из командной строки.
+var cliDetails = map[string]interface{}{"channel": "cli"}
+
+func (c *CLI) migrate(ctx context.Context, args []string) error {
+	m, err := c.App.Migrator()
+	if err != nil {
+		return err
+	}
+	return migrations.Command(ctx, m, args, c.Out)
+}
+
+func (c *CLI) createAdmin(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	email := fs.String("email", "", "email администратора")
+	name := fs.String("name", "", "имя")
+	surname := fs.String("surname", "", "фамилия")
+	password := fs.String("password", "", "пароль (не менее 6 символов); без флага читается из stdin")
+	if err := fs.Parse(args); err != nil {
+		return ErrUsage
+	}
+	*email = strings.ToLower(strings.TrimSpace(*email))
+	if *email == "" {
+		return fmt.Errorf("%w: укажите -email", ErrUsage)
+	}
+
+	users := c.App.Handler.Users()
+	if user, err := users.FindByEmail(*email); err == nil {
+		if user.Role == models.RoleAdmin {
+			return fmt.Errorf("пользователь %s уже администратор", *email)
+		}
+		if err := users.Update(user.ID, map[string]interface{}{"role": models.RoleAdmin}); err != nil {
+			return err
+		}
+		audit.Record(c.App.DB, audit.Event{
+			Action:     audit.ActionRoleChanged,
+			UserID:     &user.ID,
+			TargetType: audit.TargetUser,
+			TargetID:   user.ID,
+			Before:     map[string]interface{}{"role": user.Role},
+			After:      map[string]interface{}{"role": models.RoleAdmin},
+			Details:    cliDetails,
+		})
+		fmt.Fprintf(c.Out, "Пользователь %s (id=%d) назначен администратором\n", *email, user.ID)
+		return nil
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+
+	if *name == "" || *surname == "" {
+		return fmt.Errorf("%w: для нового пользователя укажите -name и -surname", ErrUsage)
+	}
+	if *password == "" {
+		fmt.Fprint(c.Err, "Пароль: ")
+		line, err := bufio.NewReader(c.In).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		*password = strings.TrimRight(line, "\r\n")
+	}
+	if len(*password) < 6 {
+		return errors.New("пароль должен содержать не менее 6 символов")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(*password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	user := models.User{
+		Name:            *name,
+		Surname:         *surname,
+		Email:           *email,
+		PasswordHash:    string(hash),
+		Role:            models.RoleAdmin,
+		Language:        notify.DefaultLang,
+		EmailVerified:   true,
+		EmailVerifiedAt: &now,
+	}
+	if err := users.Create(&user); err != nil {
+		return err
+	}
+	audit.Record(c.App.DB, audit.Event{
+		Action:     audit.ActionAdminCreated,
+		UserID:     &user.ID,
+		Email:      user.Email,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+		Details:    cliDetails,
+	})
+	fmt.Fprintf(c.Out, "Администратор %s создан (id=%d)\n", user.Email, user.ID)
+	return nil
+}
+
+func (c *CLI) importSchedule(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("import-schedule", flag.ContinueOnError)
+	group := fs.String("group", "", "ID группы во внешнем API")
+	fromStr := fs.String("from", "", "первый день периода, 2006-01-02")
+	toStr := fs.String("to", "", "последний день периода, 2006-01-02")
+	if err := fs.Parse(args); err != nil {
+		return ErrUsage
+	}
+	if *group == "" {
+		return fmt.Errorf("%w: укажите -group", ErrUsage)
+	}
+	from, err := time.ParseInLocation(time.DateOnly, *fromStr, time.Local)
+	if err != nil {
+		return fmt.Errorf("%w: -from: ожидается дата вида 2006-01-02", ErrUsage)
+	}
+	to, err := time.ParseInLocation(time.DateOnly, *toStr, time.Local)
+	if err != nil || to.Before(from) {
+		return fmt.Errorf("%w: -to: ожидается дата вида 2006-01-02 не раньше -from", ErrUsage)
+	}
+
+	imported, err := c.App.Handler.ImportSchedule(*group, from, to)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(c.Out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tEXTERNAL ID\tSTART\tNAME")
+	for _, s := range imported {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.ID, s.ExternalID, s.StartTime.Format("2006-01-02 15:04"), s.Name)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.Out, "Загружено новых событий: %d\n", len(imported))
+	return nil
+}
+
+func (c *CLI) queue(ctx context.Context, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("%w: ожидается queue close ID или queue reopen ID", ErrUsage)
+	}
+	id, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil || id == 0 {
+		return fmt.Errorf("%w: неверный ID очереди %q", ErrUsage, args[1])
+	}
+	queueID := uint(id)
+
+	switch args[0] {
+	case "close":
+		before, err := c.App.Handler.Queues().Close(queueID)
+		if err != nil {
+			return err
+		}
+		audit.Record(c.App.DB, audit.Event{
+			Action:     audit.ActionQueueClosed,
+			TargetType: audit.TargetQueue,
+			TargetID:   queueID,
+			Before:     map[string]interface{}{"is_active": before.IsActive, "closes_at": before.ClosesAt},
+			After:      map[string]interface{}{"is_active": false},
+			Details:    map[string]interface{}{"channel": "cli", "reason": "forced"},
+		})
+		fmt.Fprintf(c.Out, "Очередь %d закрыта\n", queueID)
+	case "reopen":
+		fs := flag.NewFlagSet("queue reopen", flag.ContinueOnError)
+		untilStr := fs.String("until", "", "новое время закрытия, 2006-01-02T15:04 (по умолчанию прежнее)")
+		if err := fs.Parse(args[2:]); err != nil {
+			return ErrUsage
+		}
+		var until time.Time
+		if *untilStr != "" {
+			if until, err = time.ParseInLocation("2006-01-02T15:04", *untilStr, time.Local); err != nil {
+				return fmt.Errorf("%w: -until: ожидается время вида 2006-01-02T15:04", ErrUsage)
+			}
+		}
+		queue, err := c.App.Handler.Queues().Reopen(queueID, until)
+		if err != nil {
+			return err
+		}
+		audit.Record(c.App.DB, audit.Event{
+			Action:     audit.ActionQueueReopened,
+			TargetType: audit.TargetQueue,
+			TargetID:   queueID,
+			Before:     map[string]interface{}{"is_active": false},
+			After:      map[string]interface{}{"is_active": true, "closes_at": queue.ClosesAt},
+			Details:    cliDetails,
+		})
+		fmt.Fprintf(c.Out, "Очередь %d открыта до %s\n", queueID, queue.ClosesAt.Local().Format("2006-01-02 15:04"))
+	default:
+		return fmt.Errorf("%w: неизвестное действие %q", ErrUsage, args[0])
+	}
+	return nil
+}
+
+func (c *CLI) listQueues(ctx context.Context, args []string) error {
+	svc := c.App.Handler.Queues()
+	queues, err := svc.Queues.ListActive()
+	if err != nil {
+		return err
+	}
+	if len(queues) == 0 {
+		fmt.Fprintln(c.Out, "Активных очередей нет")
+		return nil
+	}
+	for _, q := range queues {
+		status, err := svc.Status(q.ID)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.Out, "Очередь %d (schedule_id=%d), открыта до %s, участников: %d\n",
+			status.QueueID, status.ScheduleID, status.ClosesAt.Local().Format("2006-01-02 15:04"), len(status.Participants))
+		for _, p := range status.Participants {
+			fmt.Fprintf(c.Out, "  %3d. %s %s (user_id=%d)\n", p.Position, p.Surname, p.Name, p.UserID)
+		}
+	}
+	return nil
+}
+
+func (c *CLI) listJobs(ctx context.Context, args []string) error {
+	w := tabwriter.NewWriter(c.Out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSCHEDULE")
+	for _, job := range c.App.Jobs.List() {
+		fmt.Fprintf(w, "%s\t%s\n", job.Name, job.Spec)
+	}
+	return w.Flush()
+}
+
+func (c *CLI) runJob(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w: укажите имя задачи, список — queuectl jobs", ErrUsage)
+	}
+	// Задачи напоминаний отправляют письма и сообщения в Telegram, поэтому подключаем каналы доставки.
+	c.App.InitIntegrations(ctx)
+
+	run, err := c.App.Jobs.Run(args[0], models.JobTriggerCLI)
+	if err != nil {
+		return err
+	}
+	audit.Record(c.App.DB, audit.Event{
+		Action:     audit.ActionJobRun,
+		TargetType: audit.TargetJob,
+		TargetID:   args[0],
+		After:      map[string]interface{}{"status": run.Status, "affected_rows": run.AffectedRows},
+		Details:    cliDetails,
+	})
+	fmt.Fprintf(c.Out, "Задача %s: %s за %d мс, затронуто записей: %d\n", run.JobName, run.Status, run.DurationMs, run.AffectedRows)
+	if run.Status == models.JobStatusFailed {
+		return errors.New(run.Error)
+	}
+	return nil
+}
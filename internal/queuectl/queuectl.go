@@ -0,0 +1,67 @@
+// Package queuectl реализует подкоманды инструмента администратора cmd/queuectl: миграции базы данных,
+// создание администратора, импорт расписания, управление очередями и ручной запуск фоновых задач.
+package queuectl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"test_hack/internal/app"
+)
+
+// Usage — справка по командам queuectl.
+const Usage = `использование: queuectl <команда> [аргументы]
+
+команды:
+  migrate up|down [N]|status                  управление схемой базы данных
+  create-admin -email E -name N -surname S    создать администратора (или повысить существующего пользователя)
+               [-password P]                  без -password пароль читается из stdin
+  import-schedule -group ID -from ДАТА -to ДАТА
+                                              загрузить расписание группы из API (даты в формате 2006-01-02)
+  queue close ID                              принудительно закрыть очередь
+  queue reopen ID [-until 2006-01-02T15:04]   снова открыть закрытую очередь
+  queues                                      активные очереди и их участники
+  jobs                                        фоновые задачи
+  run-job NAME                                выполнить фоновую задачу один раз`
+
+// ErrUsage — неверные аргументы команды; вместе с ошибкой выводится справка.
+var ErrUsage = errors.New("неверные аргументы")
+
+// command — подкоманда queuectl.
+type command func(c *CLI, ctx context.Context, args []string) error
+
+var commands = map[string]command{
+	"migrate":         (*CLI).migrate,
+	"create-admin":    (*CLI).createAdmin,
+	"import-schedule": (*CLI).importSchedule,
+	"queue":           (*CLI).queue,
+	"queues":          (*CLI).listQueues,
+	"jobs":            (*CLI).listJobs,
+	"run-job":         (*CLI).runJob,
+}
+
+// Known сообщает, есть ли подкоманда с таким именем.
+func Known(name string) bool {
+	_, ok := commands[name]
+	return ok
+}
+
+// CLI выполняет подкоманды над приложением App. Результат пишется в Out, приглашения — в Err,
+// пароль для create-admin без -password читается из In.
+type CLI struct {
+	App *app.App
+	In  io.Reader
+	Out io.Writer
+	Err io.Writer
+}
+
+// Run выполняет подкоманду name с аргументами args.
+func (c *CLI) Run(ctx context.Context, name string, args []string) error {
+	cmd, ok := commands[name]
+	if !ok {
+		return fmt.Errorf("%w: неизвестная команда %q", ErrUsage, name)
+	}
+	return cmd(c, ctx, args)
+}
@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"test_hack/internal/config"
+	"test_hack/internal/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+const keyPrefix = "ratelimit:"
+
+// Limiter создаёт middleware ограничения запросов для одного экземпляра приложения.
+type Limiter struct {
+	// Redis хранит корзины, общие для всех экземпляров; nil — только память экземпляра.
+	Redis *redis.Client
+	// Config — включение ограничений и лимиты, переопределяющие значения по умолчанию.
+	Config config.RateLimit
+	// fallback используется, когда Redis не подключён или возвращает ошибку.
+	// Лимиты в нём действуют только в пределах экземпляра.
+	fallback *MemoryStore
+}
+
+// NewLimiter создаёт Limiter с корзинами в Redis client.
+func NewLimiter(client *redis.Client, cfg config.RateLimit) *Limiter {
+	return &Limiter{Redis: client, Config: cfg, fallback: NewMemoryStore()}
+}
+
+// take берёт токен из Redis, а при его недоступности — из памяти экземпляра.
+func (l *Limiter) take(c *gin.Context, key string, limit Limit) Result {
+	if l.Redis != nil {
+		res, err := RedisStore{Client: l.Redis}.Take(c.Request.Context(), key, limit)
+		if err == nil {
+			return res
+		}
+		log.Println("Ошибка ограничения запросов в Redis, используется память процесса:", err)
+	}
+	res, _ := l.fallback.Take(c.Request.Context(), key, limit)
+	return res
+}
+
+// Middleware ограничивает частоту запросов к маршруту лимитом def или лимитом name из Config.
+// Корзина заводится на пользователя, если перед middleware подключён AuthMiddleware, иначе на IP.
+func (l *Limiter) Middleware(name string, def Limit) gin.HandlerFunc {
+	if !l.Config.Enabled {
+		return func(c *gin.Context) { c.Next() }
+	}
+	limit := def
+	if value, ok := l.Config.Limits()[name]; ok {
+		// Формат проверяется в config.Validate; некорректный лимит не заменяет значение по умолчанию.
+		if parsed, err := ParseLimit(value); err == nil {
+			limit = parsed
+		}
+	}
+	return handler(name, limit, l.take)
+}
+
+// New создаёт middleware с явно заданным хранилищем, без учёта переменных окружения.
+func New(name string, limit Limit, store Store) gin.HandlerFunc {
+	return handler(name, limit, func(c *gin.Context, key string, limit Limit) Result {
+		res, err := store.Take(c.Request.Context(), key, limit)
+		if err != nil {
+			// Ошибка хранилища не должна блокировать API.
+			log.Println("Ошибка ограничения запросов:", err)
+			return Result{Allowed: true, Remaining: limit.Burst}
+		}
+		return res
+	})
+}
+
+func handler(name string, limit Limit, take func(c *gin.Context, key string, limit Limit) Result) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limit.Burst <= 0 {
+			c.Next()
+			return
+		}
+
+		res := take(c, keyPrefix+name+":"+clientKey(c), limit)
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit.Burst))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+
+		if !res.Allowed {
+			retryAfter := ceilSeconds(res.RetryAfter)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, response.ErrorResponse{
+				Code:    "RATE_LIMITED",
+				Message: "Слишком много запросов, попробуйте позже",
+				Details: fmt.Sprintf("лимит %s, повторите через %d с", limit, retryAfter),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// clientKey возвращает идентификатор клиента: пользователя, если он авторизован, иначе IP.
+func clientKey(c *gin.Context) string {
+	if userID := c.GetUint("userID"); userID != 0 {
+		return "user:" + strconv.FormatUint(uint64(userID), 10)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
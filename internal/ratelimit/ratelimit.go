@@ -0,0 +1,191 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Limit — параметры token bucket: ёмкость Burst и скорость пополнения Rate (токенов в секунду).
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Per возвращает лимит n запросов за период; ёмкость корзины равна n.
+func Per(n int, period time.Duration) Limit {
+	return Limit{Rate: float64(n) / period.Seconds(), Burst: n}
+}
+
+// String возвращает лимит в формате "N/период", например "10/1m0s".
+func (l Limit) String() string {
+	if l.Rate <= 0 {
+		return fmt.Sprintf("%d/∞", l.Burst)
+	}
+	period := time.Duration(float64(l.Burst) / l.Rate * float64(time.Second))
+	return fmt.Sprintf("%d/%s", l.Burst, period)
+}
+
+// ParseLimit разбирает лимит в формате "N/период", например "10/1m" или "5/10s".
+func ParseLimit(s string) (Limit, error) {
+	parts := strings.SplitN(strings.TrimSpace(s), "/", 2)
+	if len(parts) != 2 {
+		return Limit{}, fmt.Errorf("лимит %q: ожидается формат N/период", s)
+	}
+	n, err := strconv.Atoi(parts[0])
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("лимит %q: неверное число запросов", s)
+	}
+	period, err := time.ParseDuration(parts[1])
+	if err != nil || period <= 0 {
+		return Limit{}, fmt.Errorf("лимит %q: неверный период", s)
+	}
+	return Per(n, period), nil
+}
+
+// Result — результат попытки взять токен.
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration // Через сколько появится токен (только если запрос отклонён)
+	Reset      time.Duration // Через сколько корзина заполнится полностью
+}
+
+// Store хранит состояние корзин.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// result вычисляет оставшиеся токены и время ожидания по состоянию корзины после попытки.
+func result(allowed bool, tokens float64, limit Limit) Result {
+	r := Result{Allowed: allowed, Remaining: int(math.Floor(tokens))}
+	if limit.Rate > 0 {
+		r.Reset = time.Duration((float64(limit.Burst) - tokens) / limit.Rate * float64(time.Second))
+		if !allowed {
+			r.RetryAfter = time.Duration((1 - tokens) / limit.Rate * float64(time.Second))
+		}
+	}
+	return r
+}
+
+// tokenBucketScript атомарно пополняет корзину с момента последнего запроса и пытается взять токен.
+// Возвращает {1|0, оставшиеся токены строкой}, так как Redis округляет дробные числа в ответах Lua.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil then
+  tokens = burst
+  ts = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+if rate > 0 then
+  redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate) + 1000)
+end
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore хранит корзины в Redis, поэтому лимиты общие для всех экземпляров приложения.
+type RedisStore struct {
+	Client *redis.Client
+}
+
+func (s RedisStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	ratePerMs := limit.Rate / 1000
+	now := time.Now().UnixMilli()
+	values, err := tokenBucketScript.Run(ctx, s.Client, []string{key}, ratePerMs, limit.Burst, now).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(values) != 2 {
+		return Result{}, fmt.Errorf("неожиданный ответ скрипта ограничения: %v", values)
+	}
+	allowed, _ := values[0].(int64)
+	tokensStr, _ := values[1].(string)
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return Result{}, err
+	}
+	return result(allowed == 1, tokens, limit), nil
+}
+
+// MemoryStore хранит корзины в памяти процесса. Используется, если Redis недоступен, и в тестах.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	// Now позволяет подменить время в тестах.
+	Now func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	ts     time.Time
+	// full — за сколько пополняется пустая корзина её собственного лимита (0 — не пополняется).
+	// После этого времени без запросов корзина полна, и её можно удалить.
+	full time.Duration
+}
+
+// NewMemoryStore создаёт хранилище корзин в памяти.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket), Now: time.Now}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.Now()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), ts: now}
+		s.buckets[key] = b
+		// Периодически удаляем полные корзины, чтобы карта не росла бесконечно.
+		if len(s.buckets)%1024 == 0 {
+			s.cleanup(now)
+		}
+	}
+	b.full = refillTime(limit)
+	if elapsed := now.Sub(b.ts).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.Rate)
+	}
+	b.ts = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return result(allowed, b.tokens, limit), nil
+}
+
+// cleanup удаляет корзины, которые успели полностью пополниться. Корзины разных маршрутов
+// пополняются с разной скоростью, поэтому время пополнения берётся из лимита каждой корзины.
+func (s *MemoryStore) cleanup(now time.Time) {
+	for key, b := range s.buckets {
+		if b.full > 0 && now.Sub(b.ts) > b.full {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// refillTime возвращает время пополнения пустой корзины лимита limit; 0 — корзина не пополняется.
+func refillTime(limit Limit) time.Duration {
+	if limit.Rate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(limit.Burst) / limit.Rate * float64(time.Second))
+}
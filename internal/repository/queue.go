@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"time"
+
+	"test_hack/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// QueueRepository — хранилище очередей и записей участников.
+type QueueRepository interface {
+	FindByID(id uint) (models.Queue, error)
+	FindByScheduleID(scheduleID uint) (models.Queue, error)
+	// ListActive возвращает очереди с is_active = true.
+	ListActive() ([]models.Queue, error)
+	// ActiveLengths возвращает количество участников (exited_at IS NULL) в каждой активной очереди.
+	ActiveLengths() (map[uint]int64, error)
+	// ListExpired возвращает активные очереди, время закрытия которых наступило к моменту now.
+	ListExpired(now time.Time) ([]models.Queue, error)
+	Create(queue *models.Queue) error
+	// Close делает очередь неактивной; ожидающие участники получают статус not_served.
+	Close(queueID uint) error
+	// Reopen делает очередь активной до closesAt, переносит время открытия на opensAt и возвращает
+	// участникам со статусом not_served статус waiting.
+	Reopen(queueID uint, opensAt, closesAt time.Time) error
+	// DeleteClosingBefore удаляет очереди, закрывшиеся раньше t.
+	DeleteClosingBefore(t time.Time) (int64, error)
+
+	// ActiveEntries возвращает участников очереди (exited_at IS NULL) вместе с пользователями, по возрастанию позиции.
+	ActiveEntries(queueID uint) ([]models.QueueEntry, error)
+	// ActiveEntry возвращает активную запись пользователя в очереди.
+	ActiveEntry(queueID, userID uint) (models.QueueEntry, error)
+	// FirstActiveEntry возвращает участника с наименьшей позицией.
+	FirstActiveEntry(queueID uint) (models.QueueEntry, error)
+	// ActiveEntriesByUser возвращает активные записи пользователя во всех очередях.
+	ActiveEntriesByUser(userID uint) ([]models.QueueEntry, error)
+	// AppendEntry ставит запись в конец очереди: позиция назначается атомарно.
+	// Если у пользователя уже есть активная запись в очереди, возвращает ErrDuplicate.
+	AppendEntry(entry *models.QueueEntry) error
+	// ExitEntry сохраняет выход участника из очереди и в той же транзакции сдвигает на одну позицию
+	// вперёд стоявших после него. entry.Position заменяется текущей позицией участника.
+	// Если запись уже неактивна, возвращает ErrNotFound.
+	ExitEntry(entry *models.QueueEntry) error
+}
+
+type queueRepository struct {
+	db *gorm.DB
+}
+
+// NewQueueRepository создаёт хранилище очередей поверх GORM.
+func NewQueueRepository(db *gorm.DB) QueueRepository {
+	return &queueRepository{db: db}
+}
+
+func (r *queueRepository) FindByID(id uint) (models.Queue, error) {
+	var queue models.Queue
+	err := r.db.First(&queue, id).Error
+	return queue, notFound(err)
+}
+
+func (r *queueRepository) FindByScheduleID(scheduleID uint) (models.Queue, error) {
+	var queue models.Queue
+	err := r.db.Where("schedule_id = ?", scheduleID).First(&queue).Error
+	return queue, notFound(err)
+}
+
+func (r *queueRepository) ListActive() ([]models.Queue, error) {
+	var queues []models.Queue
+	err := r.db.Where("is_active = ?", true).Find(&queues).Error
+	return queues, err
+}
+
+func (r *queueRepository) ActiveLengths() (map[uint]int64, error) {
+	var rows []struct {
+		QueueID uint
+		Length  int64
+	}
+	err := r.db.Model(&models.Queue{}).
+		Select("queues.id AS queue_id, COUNT(queue_entries.id) AS length").
+		Joins("LEFT JOIN queue_entries ON queue_entries.queue_id = queues.id AND queue_entries.exited_at IS NULL AND queue_entries.deleted_at IS NULL").
+		Where("queues.is_active = ?", true).
+		Group("queues.id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	lengths := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		lengths[row.QueueID] = row.Length
+	}
+	return lengths, nil
+}
+
+func (r *queueRepository) ListExpired(now time.Time) ([]models.Queue, error) {
+	var queues []models.Queue
+	err := r.db.Where("is_active = ? AND closes_at <= ?", true, now).Find(&queues).Error
+	return queues, err
+}
+
+func (r *queueRepository) Create(queue *models.Queue) error {
+	return r.db.Create(queue).Error
+}
+
+func (r *queueRepository) Close(queueID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Queue{}).Where("id = ?", queueID).Update("is_active", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.QueueEntry{}).
+			Where("queue_id = ? AND exited_at IS NULL AND status = ?", queueID, models.EntryStatusWaiting).
+			Update("status", models.EntryStatusNotServed).Error
+	})
+}
+
+func (r *queueRepository) Reopen(queueID uint, opensAt, closesAt time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Queue{}).Where("id = ?", queueID).Updates(map[string]interface{}{
+			"is_active": true,
+			"opens_at":  opensAt,
+			"closes_at": closesAt,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.QueueEntry{}).
+			Where("queue_id = ? AND exited_at IS NULL AND status = ?", queueID, models.EntryStatusNotServed).
+			Update("status", models.EntryStatusWaiting).Error
+	})
+}
+
+func (r *queueRepository) DeleteClosingBefore(t time.Time) (int64, error) {
+	result := r.db.Where("closes_at < ?", t).Delete(&models.Queue{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *queueRepository) ActiveEntries(queueID uint) ([]models.QueueEntry, error) {
+	var entries []models.QueueEntry
+	err := r.db.Preload("User").
+		Where("queue_id = ? AND exited_at IS NULL", queueID).
+		Order("position ASC").
+		Find(&entries).Error
+	return entries, err
+}
+
+func (r *queueRepository) ActiveEntry(queueID, userID uint) (models.QueueEntry, error) {
+	var entry models.QueueEntry
+	err := r.db.Where("user_id = ? AND queue_id = ? AND exited_at IS NULL", userID, queueID).First(&entry).Error
+	return entry, notFound(err)
+}
+
+func (r *queueRepository) FirstActiveEntry(queueID uint) (models.QueueEntry, error) {
+	var entry models.QueueEntry
+	err := r.db.Where("queue_id = ? AND exited_at IS NULL", queueID).Order("position ASC").First(&entry).Error
+	return entry, notFound(err)
+}
+
+func (r *queueRepository) ActiveEntriesByUser(userID uint) ([]models.QueueEntry, error) {
+	var entries []models.QueueEntry
+	err := r.db.Where("user_id = ? AND exited_at IS NULL", userID).Find(&entries).Error
+	return entries, err
+}
+
+func (r *queueRepository) AppendEntry(entry *models.QueueEntry) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Блокировка строки очереди упорядочивает параллельные вступления в одну очередь.
+		if err := tx.Exec("SELECT id FROM queues WHERE id = ? FOR UPDATE", entry.QueueID).Error; err != nil {
+			return err
+		}
+		var maxPosition int
+		if err := tx.Model(&models.QueueEntry{}).
+			Where("queue_id = ? AND exited_at IS NULL", entry.QueueID).
+			Select("COALESCE(MAX(position),0)").
+			Row().Scan(&maxPosition); err != nil {
+			return err
+		}
+		entry.Position = maxPosition + 1
+		return duplicate(tx.Create(entry).Error)
+	})
+}
+
+func (r *queueRepository) ExitEntry(entry *models.QueueEntry) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Та же блокировка, что и в AppendEntry: выходы и вступления в одну очередь не пересекаются.
+		if err := tx.Exec("SELECT id FROM queues WHERE id = ? FOR UPDATE", entry.QueueID).Error; err != nil {
+			return err
+		}
+		// Позиция могла сдвинуться, пока запись читалась без блокировки.
+		var current models.QueueEntry
+		if err := tx.Select("id", "position").Where("id = ? AND exited_at IS NULL", entry.ID).First(&current).Error; err != nil {
+			return notFound(err)
+		}
+		entry.Position = current.Position
+		if err := tx.Save(entry).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.QueueEntry{}).
+			Where("queue_id = ? AND exited_at IS NULL AND position > ?", entry.QueueID, entry.Position).
+			Update("position", gorm.Expr("position - 1")).Error
+	})
+}
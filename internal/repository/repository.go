@@ -0,0 +1,34 @@
+// Package repository изолирует доступ к базе данных: обработчики, планировщик и боты работают
+// с интерфейсами хранилищ, а реализации поверх GORM собраны здесь. В тестах интерфейсы
+// подменяются реализациями в памяти.
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotFound возвращается, если запись не найдена.
+	ErrNotFound = errors.New("запись не найдена")
+	// ErrDuplicate возвращается, если запись нарушает уникальный индекс.
+	ErrDuplicate = errors.New("запись уже существует")
+)
+
+// notFound заменяет gorm.ErrRecordNotFound на ErrNotFound, чтобы вызывающий код не зависел от GORM.
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// duplicate заменяет gorm.ErrDuplicatedKey на ErrDuplicate. GORM переводит ошибки драйвера
+// только при включённом gorm.Config.TranslateError, см. storage.Open.
+func duplicate(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrDuplicate
+	}
+	return err
+}
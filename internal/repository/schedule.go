@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"time"
+
+	"test_hack/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ScheduleRepository — хранилище событий расписания.
+type ScheduleRepository interface {
+	FindByID(id uint) (models.Schedule, error)
+	// ListStartingBetween возвращает события, начинающиеся в интервале [from, to].
+	ListStartingBetween(from, to time.Time) ([]models.Schedule, error)
+	// DeleteEndedBefore удаляет события, закончившиеся раньше t.
+	DeleteEndedBefore(t time.Time) (int64, error)
+}
+
+type scheduleRepository struct {
+	db *gorm.DB
+}
+
+// NewScheduleRepository создаёт хранилище расписания поверх GORM.
+func NewScheduleRepository(db *gorm.DB) ScheduleRepository {
+	return &scheduleRepository{db: db}
+}
+
+func (r *scheduleRepository) FindByID(id uint) (models.Schedule, error) {
+	var schedule models.Schedule
+	err := r.db.First(&schedule, id).Error
+	return schedule, notFound(err)
+}
+
+func (r *scheduleRepository) ListStartingBetween(from, to time.Time) ([]models.Schedule, error) {
+	var schedules []models.Schedule
+	err := r.db.Where("start_time BETWEEN ? AND ?", from, to).Find(&schedules).Error
+	return schedules, err
+}
+
+func (r *scheduleRepository) DeleteEndedBefore(t time.Time) (int64, error) {
+	result := r.db.Where("end_time < ?", t).Delete(&models.Schedule{})
+	return result.RowsAffected, result.Error
+}
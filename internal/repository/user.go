@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"strings"
+	"test_hack/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// UserRepository — хранилище пользователей.
+type UserRepository interface {
+	FindByID(id uint) (models.User, error)
+	FindByEmail(email string) (models.User, error)
+	Create(user *models.User) error
+	// Update изменяет перечисленные поля пользователя.
+	Update(id uint, fields map[string]interface{}) error
+}
+
+type userRepository struct {
+	db *gorm.DB
+}
+
+// NewUserRepository создаёт хранилище пользователей поверх GORM.
+func NewUserRepository(db *gorm.DB) UserRepository {
+	return &userRepository{db: db}
+}
+
+func (r *userRepository) FindByID(id uint) (models.User, error) {
+	var user models.User
+	err := r.db.First(&user, id).Error
+	return user, notFound(err)
+}
+
+func (r *userRepository) FindByEmail(email string) (models.User, error) {
+	var user models.User
+	err := r.db.Where("LOWER(email) = LOWER(?)", strings.TrimSpace(email)).First(&user).Error
+	return user, notFound(err)
+}
+
+func (r *userRepository) Create(user *models.User) error {
+	return r.db.Create(user).Error
+}
+
+func (r *userRepository) Update(id uint, fields map[string]interface{}) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Updates(fields).Error
+}
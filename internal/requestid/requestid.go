@@ -0,0 +1,38 @@
+package requestid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Header — заголовок, в котором клиент или прокси может передать ID запроса. Сервер возвращает его в ответе.
+const Header = "X-Request-ID"
+
+const contextKey = "requestID"
+
+// Middleware присваивает каждому запросу ID: берёт его из заголовка X-Request-ID или генерирует новый.
+// ID попадает в заголовок ответа и в журнал аудита.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(Header)
+		if id == "" || len(id) > 64 {
+			id = newID()
+		}
+		c.Set(contextKey, id)
+		c.Header(Header, id)
+		c.Next()
+	}
+}
+
+// Get возвращает ID текущего запроса или пустую строку, если middleware не подключён.
+func Get(c *gin.Context) string {
+	return c.GetString(contextKey)
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -100,7 +100,7 @@ type SwaggerQueueStatusResponse struct {
 
 // WSMessage представляет сообщение WebSocket
 type WSMessage struct {
-	EventType string      `json:"event_type" example:"queue_update" enum:"user_joined,user_left,queue_closed,queue_update"`
+	EventType string      `json:"event_type" example:"queue_update" enum:"user_joined,user_left,user_served,queue_closed,queue_update"`
 	QueueID   string      `json:"queue_id" example:"1"`
 	Data      interface{} `json:"data,omitempty"`
 	Timestamp int64       `json:"timestamp" example:"1609459200"`
@@ -118,6 +118,13 @@ type WSUserLeftData struct {
 	LeftPosition int  `json:"left_position" example:"5"`
 }
 
+// WSUserServedData представляет данные события приёма участника преподавателем
+type WSUserServedData struct {
+	UserID         uint `json:"user_id" example:"123"`
+	ServedPosition int  `json:"served_position" example:"1"`
+	ServedBy       uint `json:"served_by" example:"7"`
+}
+
 // WSQueueUpdateData представляет данные события обновления очереди
 type WSQueueUpdateData struct {
 	QueueID      uint                 `json:"queue_id" example:"1"`
@@ -129,8 +136,67 @@ type WSQueueUpdateData struct {
 }
 
 type ProfileResponse struct {
-	ID      uint   `json:"id"`
-	Name    string `json:"name"`
-	Surname string `json:"surname"`
-	Email   string `json:"email"`
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	Surname  string `json:"surname"`
+	Email    string `json:"email"`
+	Role     string `json:"role" example:"student"`
+	GroupID  string `json:"group_id,omitempty" example:"67"`
+	Language string `json:"language" example:"ru"`
+	// Подтверждён ли email по ссылке из письма
+	EmailVerified bool `json:"email_verified" example:"true"`
+	// Подключена ли двухфакторная аутентификация
+	TwoFactorEnabled bool `json:"two_factor_enabled" example:"false"`
+	// Требует ли роль пользователя 2FA для доступа к привилегированным эндпоинтам
+	TwoFactorRequired bool `json:"two_factor_required" example:"false"`
+}
+
+// MFAChallengeResponse возвращается при входе, если у пользователя подключена 2FA.
+// Токены выдаются после подтверждения кода через /auth/login/mfa.
+type MFAChallengeResponse struct {
+	MFARequired bool `json:"mfa_required" example:"true"`
+	// Короткоживущий токен для второго шага входа
+	// example: eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9...
+	MFAToken string `json:"mfa_token"`
+	// Время жизни токена в секундах
+	ExpiresIn int `json:"expires_in" example:"300"`
+}
+
+// MFASetupResponse содержит данные для добавления аккаунта в приложение-аутентификатор
+type MFASetupResponse struct {
+	// Секрет в base32 для ручного ввода
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXP"`
+	// URI otpauth:// для QR-кода
+	OTPAuthURL string `json:"otpauth_url" example:"otpauth://totp/PracticeQueue:ivan@example.com?issuer=PracticeQueue&secret=JBSWY3DPEHPK3PXP"`
+	// QR-код в формате data:image/png;base64
+	QRCode string `json:"qr_code"`
+}
+
+// RecoveryCodesResponse содержит коды восстановления. Они показываются только один раз.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes" example:"k7d2m-q9x4a,p3n8r-w6t1c"`
+}
+
+// DataExportResponse описывает состояние фоновой выгрузки персональных данных
+type DataExportResponse struct {
+	ID          uint       `json:"id" example:"12"`
+	Status      string     `json:"status" example:"pending"` // pending, ready или failed
+	Format      string     `json:"format" example:"zip"`     // json или zip
+	CreatedAt   time.Time  `json:"created_at" example:"2023-01-01T12:00:00Z"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" example:"2023-01-01T12:00:05Z"`
+	ExpiresAt   time.Time  `json:"expires_at" example:"2023-01-02T12:00:00Z"`
+	Error       string     `json:"error,omitempty"`
+	// Адрес для проверки состояния и скачивания готового файла
+	DownloadURL string `json:"download_url" example:"/profile/export/12"`
+}
+
+// SessionResponse описывает сессию пользователя на одном устройстве
+type SessionResponse struct {
+	ID         uint      `json:"id" example:"3"`
+	UserAgent  string    `json:"user_agent" example:"Mozilla/5.0 (Windows NT 10.0; Win64; x64)"`
+	IP         string    `json:"ip" example:"192.0.2.10"`
+	CreatedAt  time.Time `json:"created_at" example:"2023-01-01T12:00:00Z"`
+	LastUsedAt time.Time `json:"last_used_at" example:"2023-01-01T14:30:00Z"`
+	// Сессия, к которой относится токен запроса
+	Current bool `json:"current" example:"true"`
 }
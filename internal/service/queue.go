@@ -0,0 +1,413 @@
+// Package service содержит бизнес-правила приложения. Сервисы работают с хранилищами через интерфейсы
+// из internal/repository и не зависят от HTTP, поэтому их используют обработчики, планировщик,
+// Telegram-бот и WebSocket.
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"test_hack/internal/metrics"
+	"test_hack/internal/models"
+	"test_hack/internal/repository"
+)
+
+// Ошибки операций с очередью.
+var (
+	ErrQueueNotFound    = errors.New("очередь не найдена")
+	ErrQueueInactive    = errors.New("очередь не активна")
+	ErrQueueActive      = errors.New("очередь уже активна")
+	ErrClosesInPast     = errors.New("время закрытия очереди уже прошло")
+	ErrAlreadyInQueue   = errors.New("пользователь уже состоит в этой очереди")
+	ErrNotInQueue       = errors.New("активная запись в очереди не найдена")
+	ErrQueueEmpty       = errors.New("в очереди нет ожидающих участников")
+	ErrEmailNotVerified = errors.New("email не подтверждён")
+)
+
+// Окно, в котором для событий расписания создаются очереди.
+const upcomingEventsWindow = 56*time.Hour + 5*time.Minute
+
+// Publisher получает события очередей (user_joined, user_left, user_served, queue_closed, queue_update)
+// и рассылает их подписчикам: клиентам WebSocket и вебхукам.
+type Publisher interface {
+	Publish(queueID uint, eventType string, data interface{})
+}
+
+// Participant — участник очереди.
+type Participant struct {
+	UserID   uint   `json:"user_id"`
+	Name     string `json:"name"`
+	Surname  string `json:"surname"`
+	Position int    `json:"position"`
+}
+
+// QueueStatus содержит состояние очереди и список участников по порядку.
+type QueueStatus struct {
+	QueueID      uint          `json:"queue_id"`
+	ScheduleID   uint          `json:"schedule_id"`
+	IsActive     bool          `json:"is_active"`
+	OpensAt      time.Time     `json:"opens_at"`
+	ClosesAt     time.Time     `json:"closes_at"`
+	Participants []Participant `json:"participants"`
+}
+
+// QueueService реализует правила работы с очередями: вступление, выход, приём участников,
+// открытие и закрытие очередей по расписанию.
+type QueueService struct {
+	Queues    repository.QueueRepository
+	Users     repository.UserRepository
+	Schedules repository.ScheduleRepository
+	Events    Publisher
+	// RequireVerifiedEmail сообщает, нужно ли подтверждение email для вступления в очередь. nil — не нужно.
+	RequireVerifiedEmail func() bool
+	// Now возвращает текущее время; подменяется в тестах.
+	Now func() time.Time
+	// Metrics учитывает вступления и выходы; nil — не учитываются.
+	Metrics *metrics.Metrics
+}
+
+// NewQueueService создаёт сервис очередей.
+func NewQueueService(queues repository.QueueRepository, users repository.UserRepository, schedules repository.ScheduleRepository, events Publisher) *QueueService {
+	return &QueueService{
+		Queues:    queues,
+		Users:     users,
+		Schedules: schedules,
+		Events:    events,
+		Now:       time.Now,
+	}
+}
+
+// Join ставит пользователя в конец очереди, уведомляет участников и возвращает позицию пользователя.
+func (s *QueueService) Join(userID, queueID uint) (int, error) {
+	if s.RequireVerifiedEmail != nil && s.RequireVerifiedEmail() {
+		user, err := s.Users.FindByID(userID)
+		if err != nil {
+			return 0, err
+		}
+		if !user.EmailVerified {
+			return 0, ErrEmailNotVerified
+		}
+	}
+
+	if _, err := s.Queues.ActiveEntry(queueID, userID); err == nil {
+		return 0, ErrAlreadyInQueue
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return 0, err
+	}
+
+	queue, err := s.Queues.FindByID(queueID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return 0, ErrQueueNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	// Очередь принимает участников, пока она активна и текущее время между OpensAt и ClosesAt.
+	now := s.Now()
+	if now.Before(queue.OpensAt) || now.After(queue.ClosesAt) || !queue.IsActive {
+		return 0, ErrQueueInactive
+	}
+
+	entry := models.QueueEntry{
+		UserID:  userID,
+		QueueID: queueID,
+		Status:  models.EntryStatusWaiting,
+	}
+	if err := s.Queues.AppendEntry(&entry); err != nil {
+		// Параллельный запрос того же пользователя успел вступить раньше — см. idx_queue_entries_active_user.
+		if errors.Is(err, repository.ErrDuplicate) {
+			return 0, ErrAlreadyInQueue
+		}
+		return 0, err
+	}
+
+	s.Metrics.QueueJoined()
+	s.Events.Publish(queueID, "user_joined", map[string]interface{}{
+		"user_id":  userID,
+		"position": entry.Position,
+	})
+	return entry.Position, nil
+}
+
+// Leave выводит пользователя из очереди, сдвигает позиции остальных участников, уведомляет их
+// и возвращает позицию, которую занимал пользователь.
+func (s *QueueService) Leave(userID, queueID uint) (int, error) {
+	entry, err := s.Queues.ActiveEntry(queueID, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return 0, ErrNotInQueue
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	now := s.Now()
+	entry.ExitedAt = &now
+	entry.Status = models.EntryStatusLeft
+	if err := s.Queues.ExitEntry(&entry); errors.Is(err, repository.ErrNotFound) {
+		return 0, ErrNotInQueue
+	} else if err != nil {
+		return 0, err
+	}
+
+	s.Metrics.QueueLeft()
+	s.Events.Publish(queueID, "user_left", map[string]interface{}{
+		"user_id":       userID,
+		"left_position": entry.Position,
+	})
+	return entry.Position, nil
+}
+
+// LeaveAll выводит пользователя из всех очередей, в которых он стоит.
+// Возвращает записи в том состоянии, в каком они были до выхода.
+func (s *QueueService) LeaveAll(userID uint) ([]models.QueueEntry, error) {
+	entries, err := s.Queues.ActiveEntriesByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	left := make([]models.QueueEntry, 0, len(entries))
+	for _, e := range entries {
+		position, err := s.Leave(userID, e.QueueID)
+		if errors.Is(err, ErrNotInQueue) {
+			continue
+		}
+		if err != nil {
+			return left, err
+		}
+		e.Position = position
+		left = append(left, e)
+	}
+	return left, nil
+}
+
+// Serve отмечает участника как принятого преподавателем servedBy. Если userID равен 0,
+// принимается первый в очереди. Позиции остальных участников сдвигаются.
+// Возвращает запись участника до приёма.
+func (s *QueueService) Serve(queueID, userID, servedBy uint) (models.QueueEntry, error) {
+	for {
+		before, err := s.serve(queueID, userID, servedBy)
+		// Первого участника успели принять или вывести параллельно — принимается следующий.
+		if userID == 0 && errors.Is(err, errEntryGone) {
+			continue
+		}
+		return before, err
+	}
+}
+
+// errEntryGone — запись участника стала неактивной между чтением и выходом из очереди.
+var errEntryGone = errors.New("entry is no longer active")
+
+func (s *QueueService) serve(queueID, userID, servedBy uint) (models.QueueEntry, error) {
+	var entry models.QueueEntry
+	var err error
+	if userID != 0 {
+		entry, err = s.Queues.ActiveEntry(queueID, userID)
+	} else {
+		entry, err = s.Queues.FirstActiveEntry(queueID)
+	}
+	if err != nil {
+		return entry, serveError(err, userID)
+	}
+
+	before := entry
+	now := s.Now()
+	entry.ExitedAt = &now
+	entry.ServedAt = &now
+	entry.ServedByID = &servedBy
+	entry.Status = models.EntryStatusServed
+	if err := s.Queues.ExitEntry(&entry); errors.Is(err, repository.ErrNotFound) && userID == 0 {
+		return before, errEntryGone
+	} else if err != nil {
+		return before, serveError(err, userID)
+	}
+	before.Position = entry.Position
+
+	s.Events.Publish(queueID, "user_served", map[string]interface{}{
+		"user_id":         entry.UserID,
+		"served_position": entry.Position,
+		"served_by":       servedBy,
+	})
+	return before, nil
+}
+
+// serveError переводит ErrNotFound хранилища в ошибку приёма: ErrNotInQueue, если участник
+// указан явно, иначе ErrQueueEmpty.
+func serveError(err error, userID uint) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound) && userID != 0:
+		return ErrNotInQueue
+	case errors.Is(err, repository.ErrNotFound):
+		return ErrQueueEmpty
+	}
+	return err
+}
+
+// Status возвращает состояние очереди и список участников.
+func (s *QueueService) Status(queueID uint) (QueueStatus, error) {
+	queue, err := s.Queues.FindByID(queueID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return QueueStatus{}, ErrQueueNotFound
+	}
+	if err != nil {
+		return QueueStatus{}, err
+	}
+	return s.status(queue)
+}
+
+func (s *QueueService) status(queue models.Queue) (QueueStatus, error) {
+	entries, err := s.Queues.ActiveEntries(queue.ID)
+	if err != nil {
+		return QueueStatus{}, err
+	}
+	participants := make([]Participant, 0, len(entries))
+	for _, entry := range entries {
+		participants = append(participants, Participant{
+			UserID:   entry.UserID,
+			Name:     entry.User.Name,
+			Surname:  entry.User.Surname,
+			Position: entry.Position,
+		})
+	}
+	return QueueStatus{
+		QueueID:      queue.ID,
+		ScheduleID:   queue.ScheduleID,
+		IsActive:     queue.IsActive,
+		OpensAt:      queue.OpensAt,
+		ClosesAt:     queue.ClosesAt,
+		Participants: participants,
+	}, nil
+}
+
+// BroadcastActiveStatuses рассылает актуальное состояние всех активных очередей событием queue_update.
+// Возвращает количество очередей, по которым отправлено обновление.
+func (s *QueueService) BroadcastActiveStatuses() (int64, error) {
+	queues, err := s.Queues.ListActive()
+	if err != nil {
+		return 0, err
+	}
+	var sent int64
+	for _, queue := range queues {
+		status, err := s.status(queue)
+		if err != nil {
+			log.Printf("Ошибка при получении записей очереди (queue_id=%d): %v", queue.ID, err)
+			continue
+		}
+		s.Events.Publish(queue.ID, "queue_update", status)
+		sent++
+	}
+	return sent, nil
+}
+
+// CloseExpired закрывает активные очереди, время закрытия которых наступило, и уведомляет участников.
+// Участники, которых не успели принять, получают статус not_served. Возвращает закрытые очереди.
+func (s *QueueService) CloseExpired() ([]models.Queue, error) {
+	queues, err := s.Queues.ListExpired(s.Now())
+	if err != nil {
+		return nil, err
+	}
+	closed := make([]models.Queue, 0, len(queues))
+	for _, q := range queues {
+		if err := s.Queues.Close(q.ID); err != nil {
+			log.Println("Ошибка закрытия очереди для schedule_id", q.ScheduleID, ":", err)
+			continue
+		}
+		q.IsActive = false
+		closed = append(closed, q)
+		s.Events.Publish(q.ID, "queue_closed", nil)
+	}
+	return closed, nil
+}
+
+// Close принудительно закрывает активную очередь раньше времени закрытия и уведомляет участников.
+// Участники, которых не успели принять, получают статус not_served. Возвращает очередь до закрытия.
+func (s *QueueService) Close(queueID uint) (models.Queue, error) {
+	queue, err := s.Queues.FindByID(queueID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return queue, ErrQueueNotFound
+	}
+	if err != nil {
+		return queue, err
+	}
+	if !queue.IsActive {
+		return queue, ErrQueueInactive
+	}
+	if err := s.Queues.Close(queueID); err != nil {
+		return queue, err
+	}
+	s.Events.Publish(queueID, "queue_closed", nil)
+	return queue, nil
+}
+
+// Reopen снова открывает закрытую очередь до closesAt; нулевое closesAt оставляет прежнее время закрытия.
+// Участники, получившие при закрытии статус not_served, возвращаются на свои места.
+// Возвращает очередь после открытия.
+func (s *QueueService) Reopen(queueID uint, closesAt time.Time) (models.Queue, error) {
+	queue, err := s.Queues.FindByID(queueID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return queue, ErrQueueNotFound
+	}
+	if err != nil {
+		return queue, err
+	}
+	if queue.IsActive {
+		return queue, ErrQueueActive
+	}
+	if closesAt.IsZero() {
+		closesAt = queue.ClosesAt
+	}
+	now := s.Now()
+	if !closesAt.After(now) {
+		return queue, ErrClosesInPast
+	}
+
+	queue.IsActive = true
+	queue.ClosesAt = closesAt
+	if queue.OpensAt.After(now) {
+		queue.OpensAt = now
+	}
+	if err := s.Queues.Reopen(queueID, queue.OpensAt, queue.ClosesAt); err != nil {
+		return queue, err
+	}
+
+	status, err := s.status(queue)
+	if err != nil {
+		return queue, err
+	}
+	s.Events.Publish(queueID, "queue_update", status)
+	return queue, nil
+}
+
+// OpenForUpcomingEvents создаёт очереди для ещё не начавшихся событий ближайших 56 часов, у которых очереди нет.
+// Очередь открывается сразу и закрывается в момент начала события. Возвращает созданные очереди.
+func (s *QueueService) OpenForUpcomingEvents() ([]models.Queue, error) {
+	now := s.Now()
+	schedules, err := s.Schedules.ListStartingBetween(now, now.Add(upcomingEventsWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	var created []models.Queue
+	for _, sched := range schedules {
+		if sched.StartTime.Before(now) {
+			continue
+		}
+		if _, err := s.Queues.FindByScheduleID(sched.ID); err == nil {
+			continue
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return created, err
+		}
+
+		queue := models.Queue{
+			ScheduleID: sched.ID,
+			OpensAt:    now,
+			ClosesAt:   sched.StartTime,
+			IsActive:   true,
+		}
+		if err := s.Queues.Create(&queue); err != nil {
+			log.Println("Ошибка создания очереди для события", sched.Name, ":", err)
+			continue
+		}
+		created = append(created, queue)
+	}
+	return created, nil
+}
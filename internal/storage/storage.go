@@ -1,65 +1,29 @@
 package storage
 
 import (
-	"context"
 	"fmt"
-	"log"
-	"os"
 
 	"github.com/go-redis/redis/v8"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-var DB *gorm.DB
-
-func ConnectDatabase() {
-	host := os.Getenv("DB_HOST")
-	port := os.Getenv("DB_PORT")
-	user := os.Getenv("DB_USER")
-	password := os.Getenv("DB_PASSWORD")
-	dbname := os.Getenv("DB_NAME")
-
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		host, port, user, password, dbname)
-
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// Open подключается к базе данных по строке подключения, см. config.Database.DSN.
+func Open(dsn string) (*gorm.DB, error) {
+	// TranslateError заменяет ошибки драйвера на gorm.ErrDuplicatedKey и другие общие ошибки GORM.
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
-		log.Fatal("Ошибка подключения к базе данных:", err)
+		return nil, err
 	}
-
-	DB = db
 	fmt.Println("Подключение к базе данных успешно!")
+	return db, nil
 }
 
-var (
-	ctx         = context.Background()
-	RedisClient *redis.Client
-)
-
-func InitRedis() {
-	RedisClient = redis.NewClient(&redis.Options{
-		Addr:     os.Getenv("REDIS_ADDR"),
-		Password: os.Getenv("REDIS_PASS"),
-		DB:       0,
+// NewRedis создаёт клиент Redis. Подключение устанавливается при первом запросе.
+func NewRedis(addr, password string, db int) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
 	})
 }
-
-func ConnectTestingDatabase() {
-	host := os.Getenv("TEST_DB_HOST")
-	port := os.Getenv("TEST_DB_PORT")
-	user := os.Getenv("TEST_DB_USER")
-	password := os.Getenv("TEST_DB_PASSWORD")
-	dbname := os.Getenv("TEST_DB_NAME")
-
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		host, port, user, password, dbname)
-
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
-	if err != nil {
-		log.Fatal("Ошибка подключения к базе данных:", err)
-	}
-
-	DB = db
-	fmt.Println("Подключение к базе данных успешно!")
-}
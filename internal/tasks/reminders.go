@@ -0,0 +1,218 @@
+package tasks
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"test_hack/internal/models"
+	"test_hack/internal/notify"
+)
+
+// SendReminders отправляет напоминания об открытии очередей, о скором начале событий
+// и о приближении очереди пользователя. Возвращает количество отправленных напоминаний.
+func (p *Planner) SendReminders() (int64, error) {
+	var sent int64
+	var errs []error
+	for _, step := range []func() (int64, error){p.sendQueueOpenedReminders, p.sendQueueEntryReminders} {
+		n, err := step()
+		sent += n
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if sent > 0 {
+		log.Printf("Отправлено напоминаний: %d", sent)
+	}
+	return sent, errors.Join(errs...)
+}
+
+// sendQueueOpenedReminders уведомляет студентов групп события об открытых очередях,
+// в которые они ещё не встали.
+func (p *Planner) sendQueueOpenedReminders() (int64, error) {
+	now := time.Now()
+	var queues []models.Queue
+	if err := p.DB.Where("is_active = ? AND opens_at <= ? AND closes_at > ?", true, now, now).Find(&queues).Error; err != nil {
+		return 0, err
+	}
+	if len(queues) == 0 {
+		return 0, nil
+	}
+
+	schedules, err := p.loadSchedulesForQueues(queues)
+	if err != nil {
+		return 0, err
+	}
+	sentBefore, err := p.sentNotifications(notify.KindQueueOpened, queues)
+	if err != nil {
+		return 0, err
+	}
+
+	var sent int64
+	var errs []error
+	for _, queue := range queues {
+		schedule, ok := schedules[queue.ScheduleID]
+		if !ok {
+			continue
+		}
+
+		var groupIDs []string
+		for _, id := range strings.Split(schedule.GroupIDs, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				groupIDs = append(groupIDs, id)
+			}
+		}
+		if len(groupIDs) == 0 {
+			continue
+		}
+
+		var users []models.User
+		if err := p.DB.
+			Where("group_id IN ?", groupIDs).
+			Where("id NOT IN (?)", p.DB.Model(&models.QueueEntry{}).
+				Select("user_id").
+				Where("queue_id = ? AND exited_at IS NULL", queue.ID)).
+			Find(&users).Error; err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		var userIDs []uint
+		for _, u := range users {
+			if !sentBefore[notificationKey{u.ID, queue.ID}] {
+				userIDs = append(userIDs, u.ID)
+			}
+		}
+		settings, err := notify.SettingsForUsers(p.DB, userIDs)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		msg := notify.QueueOpenedMessage(schedule, queue)
+		for _, u := range users {
+			s, ok := settings[u.ID]
+			if !ok || !s.QueueOpened {
+				continue
+			}
+			ok, err := p.Handler.Notify.SendOnce(p.DB, u, s, msg)
+			if ok {
+				sent++
+			}
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// sendQueueEntryReminders напоминает участникам активных очередей о скором начале события
+// и о том, что их очередь приближается.
+func (p *Planner) sendQueueEntryReminders() (int64, error) {
+	var entries []models.QueueEntry
+	if err := p.DB.
+		Preload("User").
+		Joins("JOIN queues ON queues.id = queue_entries.queue_id AND queues.deleted_at IS NULL").
+		Where("queue_entries.exited_at IS NULL AND queues.is_active = ?", true).
+		Find(&entries).Error; err != nil {
+		return 0, err
+	}
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	queueIDs := make([]uint, 0, len(entries))
+	userIDs := make([]uint, 0, len(entries))
+	for _, e := range entries {
+		queueIDs = append(queueIDs, e.QueueID)
+		userIDs = append(userIDs, e.UserID)
+	}
+	var queues []models.Queue
+	if err := p.DB.Where("id IN ?", queueIDs).Find(&queues).Error; err != nil {
+		return 0, err
+	}
+	queueMap := make(map[uint]models.Queue, len(queues))
+	for _, q := range queues {
+		queueMap[q.ID] = q
+	}
+	schedules, err := p.loadSchedulesForQueues(queues)
+	if err != nil {
+		return 0, err
+	}
+	settings, err := notify.SettingsForUsers(p.DB, userIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	var sent int64
+	var errs []error
+	send := func(user models.User, s models.NotificationSettings, msg notify.Message) {
+		ok, err := p.Handler.Notify.SendOnce(p.DB, user, s, msg)
+		if ok {
+			sent++
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, entry := range entries {
+		queue := queueMap[entry.QueueID]
+		schedule, ok := schedules[queue.ScheduleID]
+		if !ok {
+			continue
+		}
+		s := settings[entry.UserID]
+
+		untilStart := schedule.StartTime.Sub(now)
+		if s.BeforeEvent && untilStart > 0 && untilStart <= time.Duration(s.BeforeEventMinutes)*time.Minute {
+			send(entry.User, s, notify.EventSoonMessage(schedule, queue, entry.Position))
+		}
+		if s.PositionReached && entry.Position <= s.PositionThreshold {
+			send(entry.User, s, notify.TurnNearMessage(schedule, queue, entry.Position))
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+func (p *Planner) loadSchedulesForQueues(queues []models.Queue) (map[uint]models.Schedule, error) {
+	ids := make([]uint, 0, len(queues))
+	for _, q := range queues {
+		ids = append(ids, q.ScheduleID)
+	}
+	var schedules []models.Schedule
+	if err := p.DB.Where("id IN ?", ids).Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[uint]models.Schedule, len(schedules))
+	for _, s := range schedules {
+		result[s.ID] = s
+	}
+	return result, nil
+}
+
+type notificationKey struct {
+	UserID  uint
+	QueueID uint
+}
+
+// sentNotifications возвращает множество уже отправленных напоминаний указанного типа по очередям,
+// чтобы не пытаться отправлять их повторно на каждом запуске задачи.
+func (p *Planner) sentNotifications(kind string, queues []models.Queue) (map[notificationKey]bool, error) {
+	ids := make([]uint, 0, len(queues))
+	for _, q := range queues {
+		ids = append(ids, q.ID)
+	}
+	var sent []models.Notification
+	if err := p.DB.Select("user_id", "queue_id").Where("kind = ? AND queue_id IN ?", kind, ids).Find(&sent).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[notificationKey]bool, len(sent))
+	for _, n := range sent {
+		result[notificationKey{n.UserID, n.QueueID}] = true
+	}
+	return result, nil
+}
@@ -0,0 +1,333 @@
+package telegram
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"test_hack/internal/audit"
+	"test_hack/internal/handlers"
+	"test_hack/internal/models"
+	"test_hack/internal/notify"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const helpText = `Команды бота:
+/schedule — расписание вашей группы на неделю и открытые очереди
+/queues — очереди, в которых вы стоите
+/join <ID очереди> — встать в очередь
+/leave <ID очереди> — выйти из очереди
+/unlink — отвязать Telegram от аккаунта
+
+Чтобы привязать аккаунт, получите код в профиле на сайте и отправьте /start <код>.`
+
+// Bot обрабатывает команды пользователей Telegram. Операции с очередями выполняются через
+// сервис очередей Handler.Queues() — так же, как в HTTP API.
+type Bot struct {
+	Client  *Client
+	DB      *gorm.DB
+	Handler *handlers.Handler
+	// WebhookURL — публичный адрес /telegram/webhook. Если пуст, бот работает через long polling.
+	WebhookURL string
+	// WebhookSecret проверяется в заголовке X-Telegram-Bot-Api-Secret-Token. Без него webhook не принимает обновления.
+	WebhookSecret string
+}
+
+// New создаёт бота по настройкам h.Config.Telegram. Возвращает nil, если токен бота не задан.
+func New(h *handlers.Handler) *Bot {
+	cfg := h.Config.Telegram
+	if cfg.BotToken == "" {
+		return nil
+	}
+	return &Bot{
+		Client:        NewClient(cfg.APIURL, cfg.BotToken),
+		DB:            h.DB,
+		Handler:       h,
+		WebhookURL:    cfg.WebhookURL,
+		WebhookSecret: cfg.WebhookSecret,
+	}
+}
+
+// Poll получает обновления через long polling до отмены контекста.
+func (b *Bot) Poll(ctx context.Context) {
+	if err := b.Client.DeleteWebhook(ctx); err != nil {
+		log.Println("Telegram: ошибка отключения webhook:", err)
+	}
+	log.Println("Telegram-бот запущен в режиме long polling.")
+
+	var offset int64
+	for {
+		updates, err := b.Client.GetUpdates(ctx, offset, 30)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Println("Telegram: ошибка получения обновлений:", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			b.HandleUpdate(ctx, u)
+		}
+	}
+}
+
+// WebhookHandler принимает обновления от Telegram в режиме webhook
+// @Summary		Webhook Telegram-бота
+// @Description	Принимает обновления от Telegram Bot API. Запрос должен содержать заголовок X-Telegram-Bot-Api-Secret-Token со значением TELEGRAM_WEBHOOK_SECRET
+// @Tags			telegram
+// @Accept			json
+// @Produce		json
+// @Success		200	{string}	string	"Обновление принято"
+// @Failure		400	{object}	response.ErrorResponse	"Некорректное обновление (VALIDATION_ERROR)"
+// @Failure		401	{object}	response.ErrorResponse	"Неверный секрет (INVALID_WEBHOOK_SECRET)"
+// @Router			/telegram/webhook [post]
+func (b *Bot) WebhookHandler(c *gin.Context) {
+	// Без секрета любой мог бы прислать поддельное обновление от имени привязанного чата.
+	got := c.GetHeader("X-Telegram-Bot-Api-Secret-Token")
+	if b.WebhookSecret == "" || subtle.ConstantTimeCompare([]byte(got), []byte(b.WebhookSecret)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": "INVALID_WEBHOOK_SECRET", "message": "Неверный секрет webhook"})
+		return
+	}
+
+	var u Update
+	if err := c.ShouldBindJSON(&u); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "VALIDATION_ERROR", "message": "Некорректное обновление", "details": err.Error()})
+		return
+	}
+	b.HandleUpdate(c.Request.Context(), u)
+	c.Status(http.StatusOK)
+}
+
+// HandleUpdate выполняет команду из сообщения и отвечает пользователю.
+func (b *Bot) HandleUpdate(ctx context.Context, u Update) {
+	if u.Message == nil || u.Message.Text == "" {
+		return
+	}
+	chatID := u.Message.Chat.ID
+	command, arg := parseCommand(u.Message.Text)
+
+	var reply string
+	switch command {
+	case "/start":
+		reply = b.start(chatID, arg)
+	case "/help":
+		reply = helpText
+	case "/schedule":
+		reply = b.withUser(chatID, b.schedule)
+	case "/queues":
+		reply = b.withUser(chatID, b.queues)
+	case "/join":
+		reply = b.withUser(chatID, func(user models.User) string { return b.join(user, arg) })
+	case "/leave":
+		reply = b.withUser(chatID, func(user models.User) string { return b.leave(user, arg) })
+	case "/unlink":
+		reply = b.withUser(chatID, b.unlink)
+	default:
+		reply = "Неизвестная команда.\n\n" + helpText
+	}
+
+	if err := b.Client.SendMessage(ctx, chatID, reply); err != nil {
+		log.Println("Telegram: ошибка отправки сообщения:", err)
+	}
+}
+
+// parseCommand разбирает "/join@queue_bot 15" на команду "/join" и аргумент "15".
+func parseCommand(text string) (string, string) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	command := strings.ToLower(fields[0])
+	if i := strings.Index(command, "@"); i >= 0 {
+		command = command[:i]
+	}
+	return command, strings.Join(fields[1:], " ")
+}
+
+func (b *Bot) withUser(chatID int64, fn func(user models.User) string) string {
+	var user models.User
+	if err := b.DB.Where("telegram_chat_id = ?", chatID).First(&user).Error; err != nil {
+		return "Аккаунт не привязан. Получите код в профиле на сайте и отправьте /start <код>."
+	}
+	return fn(user)
+}
+
+func (b *Bot) start(chatID int64, code string) string {
+	if code == "" {
+		return "Здравствуйте! Я помогу следить за очередями на сдачу практики.\n\n" + helpText
+	}
+	user, err := b.Handler.LinkTelegramChat(strings.ToUpper(code), chatID)
+	if err != nil {
+		if errors.Is(err, handlers.ErrInvalidLinkCode) {
+			return "Код недействителен или истёк. Получите новый код в профиле."
+		}
+		log.Println("Telegram: ошибка привязки:", err)
+		return "Не удалось привязать аккаунт, попробуйте позже."
+	}
+	return fmt.Sprintf("Аккаунт %s %s привязан. Теперь уведомления будут приходить сюда.\n\n%s", user.Name, user.Surname, helpText)
+}
+
+func (b *Bot) schedule(user models.User) string {
+	if user.GroupID == "" {
+		return "В профиле не указана группа."
+	}
+
+	now := time.Now()
+	var candidates []models.Schedule
+	if err := b.DB.
+		Where("start_time BETWEEN ? AND ? AND group_ids LIKE ?", now, now.AddDate(0, 0, 7), "%"+user.GroupID+"%").
+		Order("start_time ASC").
+		Find(&candidates).Error; err != nil {
+		return "Не удалось загрузить расписание."
+	}
+
+	var schedules []models.Schedule
+	var scheduleIDs []uint
+	for _, s := range candidates {
+		for _, id := range strings.Split(s.GroupIDs, ",") {
+			if strings.TrimSpace(id) == user.GroupID {
+				schedules = append(schedules, s)
+				scheduleIDs = append(scheduleIDs, s.ID)
+				break
+			}
+		}
+	}
+	if len(schedules) == 0 {
+		return "На ближайшую неделю событий нет."
+	}
+
+	var queues []models.Queue
+	b.DB.Where("schedule_id IN ?", scheduleIDs).Find(&queues)
+	queueBySchedule := make(map[uint]models.Queue)
+	for _, q := range queues {
+		queueBySchedule[q.ScheduleID] = q
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Расписание на неделю:\n")
+	for _, s := range schedules {
+		fmt.Fprintf(&sb, "\n%s — %s", s.StartTime.Format("02.01 15:04"), s.Name)
+		if q, ok := queueBySchedule[s.ID]; ok && q.IsActive {
+			fmt.Fprintf(&sb, "\n  очередь открыта: /join %d", q.ID)
+		}
+	}
+	return sb.String()
+}
+
+func (b *Bot) queues(user models.User) string {
+	var entries []models.QueueEntry
+	if err := b.DB.
+		Where("user_id = ? AND exited_at IS NULL", user.ID).
+		Order("created_at ASC").
+		Find(&entries).Error; err != nil {
+		return "Не удалось загрузить очереди."
+	}
+	if len(entries) == 0 {
+		return "Вы не стоите ни в одной очереди."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Ваши очереди:\n")
+	for _, e := range entries {
+		var queue models.Queue
+		var schedule models.Schedule
+		if err := b.DB.First(&queue, e.QueueID).Error; err != nil {
+			continue
+		}
+		b.DB.First(&schedule, queue.ScheduleID)
+		fmt.Fprintf(&sb, "\n#%d %s (%s) — позиция %d", queue.ID, schedule.Name, schedule.StartTime.Format("02.01 15:04"), e.Position)
+	}
+	return sb.String()
+}
+
+func (b *Bot) join(user models.User, arg string) string {
+	queueID, err := strconv.Atoi(arg)
+	if err != nil || queueID <= 0 {
+		return "Укажите ID очереди: /join 15"
+	}
+	position, err := b.Handler.Queues().Join(user.ID, uint(queueID))
+	switch {
+	case errors.Is(err, handlers.ErrAlreadyInQueue):
+		return "Вы уже стоите в этой очереди."
+	case errors.Is(err, handlers.ErrQueueNotFound):
+		return "Очередь не найдена."
+	case errors.Is(err, handlers.ErrQueueInactive):
+		return "Очередь не активна."
+	case errors.Is(err, handlers.ErrEmailNotVerified):
+		return "Сначала подтвердите email по ссылке из письма."
+	case err != nil:
+		log.Println("Telegram: ошибка вступления в очередь:", err)
+		return "Не удалось встать в очередь, попробуйте позже."
+	}
+	audit.Record(b.DB, audit.Event{
+		Action:     audit.ActionQueueJoined,
+		ActorID:    &user.ID,
+		UserID:     &user.ID,
+		TargetType: audit.TargetQueue,
+		TargetID:   queueID,
+		After:      map[string]interface{}{"position": position},
+		Details:    map[string]interface{}{"channel": "telegram"},
+	})
+	return fmt.Sprintf("Вы в очереди #%d, ваша позиция: %d.", queueID, position)
+}
+
+func (b *Bot) leave(user models.User, arg string) string {
+	queueID, err := strconv.Atoi(arg)
+	if err != nil || queueID <= 0 {
+		return "Укажите ID очереди: /leave 15"
+	}
+	position, err := b.Handler.Queues().Leave(user.ID, uint(queueID))
+	switch {
+	case errors.Is(err, handlers.ErrNotInQueue):
+		return "Вы не стоите в этой очереди."
+	case err != nil:
+		log.Println("Telegram: ошибка выхода из очереди:", err)
+		return "Не удалось выйти из очереди, попробуйте позже."
+	}
+	audit.Record(b.DB, audit.Event{
+		Action:     audit.ActionQueueLeft,
+		ActorID:    &user.ID,
+		UserID:     &user.ID,
+		TargetType: audit.TargetQueue,
+		TargetID:   queueID,
+		Before:     map[string]interface{}{"position": position},
+		Details:    map[string]interface{}{"channel": "telegram"},
+	})
+	return fmt.Sprintf("Вы вышли из очереди #%d.", queueID)
+}
+
+func (b *Bot) unlink(user models.User) string {
+	if err := b.DB.Model(&user).Update("telegram_chat_id", nil).Error; err != nil {
+		return "Не удалось отвязать аккаунт, попробуйте позже."
+	}
+	return "Telegram отвязан от аккаунта. Уведомления больше не будут приходить сюда."
+}
+
+// Channel доставляет напоминания в Telegram пользователям с привязанным чатом.
+type Channel struct {
+	Client *Client
+}
+
+func (Channel) Name() string { return "telegram" }
+
+func (ch Channel) Send(user models.User, msg notify.Message) error {
+	if user.TelegramChatID == nil {
+		return errors.New("Telegram не привязан")
+	}
+	title, body := msg.Localized(user.Language)
+	return ch.Client.SendMessage(context.Background(), *user.TelegramChatID, title+"\n\n"+body)
+}
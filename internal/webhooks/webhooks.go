@@ -0,0 +1,380 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"test_hack/internal/metrics"
+	"test_hack/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Events — типы событий, на которые можно подписать вебхук. Совпадают с event_type сообщений WebSocket.
+var Events = []string{"user_joined", "user_left", "user_served", "queue_closed", "queue_update"}
+
+const (
+	// MaxAttempts — число попыток доставки, после которого событие попадает в webhook_dead_letters.
+	MaxAttempts = 6
+	// claimTimeout — на это время доставка резервируется за одним обработчиком, чтобы её не отправили дважды.
+	claimTimeout = 2 * time.Minute
+)
+
+var (
+	ErrDeadLetterNotFound = errors.New("запись не найдена")
+	ErrAlreadyRetried     = errors.New("событие уже отправлено повторно")
+	// ErrShutdownTimeout — Shutdown не дождался отправки опубликованных событий; недоставленные
+	// события остаются в очереди доставки и будут отправлены задачей DeliverWebhooks.
+	ErrShutdownTimeout = errors.New("не все события отправлены до остановки")
+)
+
+// IsKnownEvent сообщает, существует ли тип события.
+func IsKnownEvent(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscribed сообщает, подписан ли вебхук на событие. Пустой список событий означает подписку на все.
+func Subscribed(hook models.Webhook, event string) bool {
+	if hook.Events == "" {
+		return true
+	}
+	for _, e := range strings.Split(hook.Events, ",") {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Backoff возвращает задержку перед следующей попыткой: 30 секунд, затем вдвое больше после каждой неудачи, но не более часа.
+func Backoff(attempts int) time.Duration {
+	delay := 30 * time.Second
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= time.Hour {
+			return time.Hour
+		}
+	}
+	return delay
+}
+
+// Sign вычисляет подпись запроса: hex(HMAC-SHA256(secret, "<timestamp>.<body>")).
+// Получатель должен проверить подпись и отклонять запросы со слишком старой меткой времени.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Post отправляет подписанный запрос через client и возвращает HTTP-код ответа.
+// Ответ с кодом вне диапазона 2xx считается ошибкой.
+func Post(ctx context.Context, client *http.Client, url, secret string, deliveryID uint, event string, payload []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "test-hack-webhooks/1.0")
+	req.Header.Set("X-Webhook-Event", event)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatUint(uint64(deliveryID), 10))
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", "sha256="+Sign(secret, timestamp, payload))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("получатель ответил кодом %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// UpdateThrottle ограничивает частоту событий queue_update для вебхуков с QueueUpdateInterval.
+// Нулевое значение готово к использованию.
+type UpdateThrottle struct {
+	mu   sync.Mutex
+	last map[throttleKey]time.Time // время последней отправки
+}
+
+type throttleKey struct {
+	hookID  uint
+	queueID string
+}
+
+// Allow сообщает, можно ли отправить вебхуку hookID событие queue_update очереди queueID в момент now,
+// и запоминает отправку.
+func (t *UpdateThrottle) Allow(hookID uint, queueID string, interval time.Duration, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := throttleKey{hookID, queueID}
+	if last, ok := t.last[key]; ok && now.Sub(last) < interval {
+		return false
+	}
+	if t.last == nil {
+		t.last = make(map[throttleKey]time.Time)
+	}
+	t.last[key] = now
+	return true
+}
+
+// Forget удаляет сведения об очереди queueID после её закрытия.
+func (t *UpdateThrottle) Forget(queueID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key := range t.last {
+		if key.queueID == queueID {
+			delete(t.last, key)
+		}
+	}
+}
+
+// Dispatcher ставит события в очередь доставки и отправляет их на вебхуки, сохраняя попытки в базе.
+type Dispatcher struct {
+	DB *gorm.DB
+	// Client отправляет запросы на вебхуки; его Timeout ограничивает одну попытку доставки.
+	Client *http.Client
+
+	throttle UpdateThrottle
+	mu       sync.Mutex
+	closed   bool
+	inflight sync.WaitGroup
+}
+
+// NewDispatcher создаёт диспетчер вебхуков. Время запросов к вебхукам учитывается в m.
+func NewDispatcher(db *gorm.DB, m *metrics.Metrics) *Dispatcher {
+	return &Dispatcher{
+		DB:     db,
+		Client: &http.Client{Timeout: 10 * time.Second, Transport: m.Transport("webhooks", nil)},
+	}
+}
+
+// Publish ставит событие очереди queueID в очередь доставки для всех активных вебхуков, подписанных на него,
+// и сразу пытается его отправить. Выполняется асинхронно, чтобы не задерживать рассылку по WebSocket;
+// Shutdown дожидается завершения. После Shutdown события не принимаются.
+func (d *Dispatcher) Publish(queueID, event string, payload []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		log.Printf("Событие %s очереди %s не передано вебхукам: диспетчер остановлен", event, queueID)
+		return
+	}
+	d.inflight.Add(1)
+	go func() {
+		defer d.inflight.Done()
+		for _, id := range d.Enqueue(queueID, event, payload) {
+			if err := d.Deliver(id); err != nil {
+				log.Printf("Ошибка доставки вебхука (delivery_id=%d): %v", id, err)
+			}
+		}
+	}()
+}
+
+// Shutdown перестаёт принимать события и ждёт, пока опубликованные события будут записаны и отправлены,
+// но не дольше, чем до отмены ctx.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	d.closed = true
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ErrShutdownTimeout
+	}
+}
+
+// Enqueue записывает событие очереди queueID в очередь доставки для всех активных вебхуков, подписанных на него,
+// не отправляя его. Записи отправляет Deliver или задача DeliverWebhooks. Возвращает ID созданных доставок.
+func (d *Dispatcher) Enqueue(queueID, event string, payload []byte) []uint {
+	var hooks []models.Webhook
+	if err := d.DB.Where("is_active = ?", true).Find(&hooks).Error; err != nil {
+		log.Println("Ошибка загрузки вебхуков:", err)
+		return nil
+	}
+	if event == "queue_closed" {
+		defer d.throttle.Forget(queueID)
+	}
+
+	now := time.Now()
+	var ids []uint
+	for _, hook := range hooks {
+		if !Subscribed(hook, event) {
+			continue
+		}
+		if event == "queue_update" && hook.QueueUpdateInterval > 0 &&
+			!d.throttle.Allow(hook.ID, queueID, time.Duration(hook.QueueUpdateInterval)*time.Second, now) {
+			continue
+		}
+		delivery := models.WebhookDelivery{
+			WebhookID:     hook.ID,
+			EventType:     event,
+			Payload:       string(payload),
+			Status:        models.DeliveryStatusPending,
+			NextAttemptAt: time.Now(),
+		}
+		if err := d.DB.Create(&delivery).Error; err != nil {
+			log.Printf("Ошибка записи доставки вебхука (webhook_id=%d): %v", hook.ID, err)
+			continue
+		}
+		ids = append(ids, delivery.ID)
+	}
+	return ids
+}
+
+// Deliver выполняет одну попытку доставки. Если доставка уже выполняется другим обработчиком
+// или ещё не наступило время повторной попытки, ничего не делает.
+func (d *Dispatcher) Deliver(id uint) error {
+	now := time.Now()
+	claim := d.DB.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", id, models.DeliveryStatusPending, now).
+		Update("next_attempt_at", now.Add(claimTimeout))
+	if claim.Error != nil {
+		return claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return nil
+	}
+
+	var delivery models.WebhookDelivery
+	if err := d.DB.First(&delivery, id).Error; err != nil {
+		return err
+	}
+
+	var hook models.Webhook
+	if err := d.DB.First(&hook, delivery.WebhookID).Error; err != nil {
+		delivery.Status = models.DeliveryStatusDead
+		delivery.LastError = "вебхук удалён"
+		return d.DB.Save(&delivery).Error
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.Client.Timeout)
+	defer cancel()
+	code, err := Post(ctx, d.Client, hook.URL, hook.Secret, delivery.ID, delivery.EventType, []byte(delivery.Payload))
+
+	delivery.Attempts++
+	delivery.LastStatusCode = code
+	if err == nil {
+		delivered := time.Now()
+		delivery.Status = models.DeliveryStatusSuccess
+		delivery.LastError = ""
+		delivery.DeliveredAt = &delivered
+		return d.DB.Save(&delivery).Error
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts < MaxAttempts {
+		delivery.NextAttemptAt = time.Now().Add(Backoff(delivery.Attempts))
+		return d.DB.Save(&delivery).Error
+	}
+
+	delivery.Status = models.DeliveryStatusDead
+	return d.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&delivery).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.WebhookDeadLetter{
+			WebhookID:  delivery.WebhookID,
+			DeliveryID: delivery.ID,
+			EventType:  delivery.EventType,
+			Payload:    delivery.Payload,
+			Attempts:   delivery.Attempts,
+			LastError:  delivery.LastError,
+		}).Error
+	})
+}
+
+// DeliverPending повторяет доставки, для которых наступило время следующей попытки.
+// Возвращает количество успешно доставленных событий.
+func (d *Dispatcher) DeliverPending() (int64, error) {
+	var ids []uint
+	if err := d.DB.Model(&models.WebhookDelivery{}).
+		Where("status = ? AND next_attempt_at <= ?", models.DeliveryStatusPending, time.Now()).
+		Order("next_attempt_at ASC").
+		Limit(100).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	var delivered int64
+	for _, id := range ids {
+		if err := d.Deliver(id); err != nil {
+			log.Printf("Ошибка доставки вебхука (delivery_id=%d): %v", id, err)
+			continue
+		}
+		var status string
+		d.DB.Model(&models.WebhookDelivery{}).Where("id = ?", id).Pluck("status", &status)
+		if status == models.DeliveryStatusSuccess {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// RetryDeadLetter создаёт новую доставку для недоставленного события и сразу пытается её отправить.
+func (d *Dispatcher) RetryDeadLetter(id uint) (models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	var letter models.WebhookDeadLetter
+	if err := d.DB.First(&letter, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return delivery, ErrDeadLetterNotFound
+		}
+		return delivery, err
+	}
+	if letter.RetriedAt != nil {
+		return delivery, ErrAlreadyRetried
+	}
+
+	now := time.Now()
+	delivery = models.WebhookDelivery{
+		WebhookID:     letter.WebhookID,
+		EventType:     letter.EventType,
+		Payload:       letter.Payload,
+		Status:        models.DeliveryStatusPending,
+		NextAttemptAt: now,
+	}
+	err := d.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&delivery).Error; err != nil {
+			return err
+		}
+		return tx.Model(&letter).Update("retried_at", now).Error
+	})
+	if err != nil {
+		return delivery, err
+	}
+
+	if err := d.Deliver(delivery.ID); err != nil {
+		return delivery, err
+	}
+	err = d.DB.First(&delivery, delivery.ID).Error
+	return delivery, err
+}
@@ -1,21 +1,15 @@
 package main
 
 import (
-	"fmt"
+	"context"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 	_ "test_hack/docs"
-	"test_hack/internal/auth"
-	"test_hack/internal/handlers"
-	"test_hack/internal/models"
-	"test_hack/internal/storage"
-	"test_hack/internal/tasks"
-
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
-	swaggerFiles "github.com/swaggo/files"
-	ginSwagger "github.com/swaggo/gin-swagger"
+	"test_hack/internal/app"
+	"test_hack/internal/config"
+	"test_hack/internal/migrations"
 )
 
 // @Title						Онлайн очередь для сдачи практики
@@ -23,66 +17,42 @@ import (
 // @in							header
 // @name						Authorization
 func main() {
-	key := os.Getenv("ENV_CHEK")
-	if key == "" {
-		fmt.Println("Подключение к .env")
-		err := godotenv.Load()
-		if err != nil {
-			log.Fatal("Ошибка получения .env")
-		}
+	// CONFIG_FILE — необязательный YAML-файл с настройками; переменные окружения и .env его переопределяют.
+	cfg, err := config.Load(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	storage.ConnectDatabase()
-
-	if err := storage.DB.AutoMigrate(&models.User{}, &models.Schedule{}, &models.Queue{}, &models.QueueEntry{}); err != nil {
-		log.Fatal("Ошибка при миграции... ", err.Error())
+	a, err := app.Open(cfg)
+	if err != nil {
+		log.Fatal("Ошибка подключения к базе данных:", err)
 	}
 
-	storage.InitRedis()
-	tasks.InitScheduler()
-
-	go handlers.HubInstance.Run()
-
-	r := gin.Default()
-
-	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Authorization", "Content-Type"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-	}))
-
-	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-
-	authGroup := r.Group("/auth")
-	{
-		authGroup.POST("/login", handlers.Login)
-		authGroup.POST("/register", handlers.Register)
-		authGroup.POST("/refresh", handlers.RefreshToken)
+	// go run . migrate up|down [N]|status — управление схемой базы данных без запуска сервера.
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		m, err := a.Migrator()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := migrations.Command(context.Background(), m, os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
 
-	profileGroup := r.Group("/profile", auth.AuthMiddleware())
-	{
-		profileGroup.GET("/", handlers.GetMyProfileHandler)
-		profileGroup.GET("/queues", handlers.GetUserQueuesHandler)
+	if cfg.Migrations.OnStart {
+		if err := a.Migrate(context.Background()); err != nil {
+			log.Fatal("Ошибка при миграции... ", err.Error())
+		}
 	}
 
-	apiGroup := r.Group("")
-	{
-		apiGroup.GET("/groups", handlers.GetGroupsHandler)
-		apiGroup.GET("/schedule", handlers.GetFullScheduleHandler)
-	}
+	a.InitIntegrations(context.Background())
 
-	r.GET("/api/queues/:id/status", handlers.GetQueueStatusHandler)
-	r.GET("/api/queues/:id/ws", handlers.QueueWebSocketHandler)
-	queues := r.Group("/api/queues", auth.AuthMiddleware())
-	{
-		queues.POST("/:id/join", handlers.JoinQueueHandler)
-		queues.POST("/:id/leave", handlers.LeaveQueueHandler)
-	}
+	// SIGINT и SIGTERM запускают корректную остановку: см. App.Shutdown.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	if err := r.Run(":8080"); err != nil {
+	if err := a.Run(ctx); err != nil {
 		log.Fatal("Ошибка запуска сервера...", err.Error())
 	}
 }
@@ -0,0 +1,91 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"test_hack/internal/models"
+	"test_hack/internal/requestid"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditEventsForQueueAndRoleChanges(t *testing.T) {
+	ts, a := setupTestServer(t)
+
+	now := time.Now()
+	schedule := models.Schedule{ExternalID: "9996", Name: "Химия", StartTime: now.Add(time.Hour), EndTime: now.Add(2 * time.Hour), GroupIDs: "1"}
+	assert.NoError(t, a.DB.Create(&schedule).Error)
+	queue := models.Queue{ScheduleID: schedule.ID, OpensAt: now, ClosesAt: schedule.StartTime, IsActive: true}
+	assert.NoError(t, a.DB.Create(&queue).Error)
+
+	var users [3]models.User
+	for i := range users {
+		users[i] = models.User{Name: "Студент", Surname: fmt.Sprint(i), Email: fmt.Sprintf("audit_%d_%d@example.com", i, now.UnixNano()), PasswordHash: "x"}
+		assert.NoError(t, a.DB.Create(&users[i]).Error)
+	}
+	admin := models.User{Name: "Админ", Surname: "Админов", Email: fmt.Sprintf("audit_admin_%d@example.com", now.UnixNano()), PasswordHash: "x", Role: models.RoleAdmin}
+	assert.NoError(t, a.DB.Create(&admin).Error)
+
+	queueURL := fmt.Sprintf("%s/api/queues/%d", ts.URL, queue.ID)
+	for _, u := range users {
+		code, _ := postJSONAs(t, a, queueURL+"/join", u.ID, nil)
+		assert.Equal(t, http.StatusOK, code)
+	}
+	code, _ := postJSONAs(t, a, queueURL+"/serve", admin.ID, map[string]uint{"user_id": users[0].ID})
+	assert.Equal(t, http.StatusOK, code)
+
+	// ID запроса из заголовка попадает в журнал.
+	req, _ := http.NewRequest(http.MethodPost, queueURL+"/leave", bytes.NewReader(nil))
+	req.Header.Set("Authorization", "Bearer "+accessToken(t, a, users[1].ID))
+	req.Header.Set(requestid.Header, "leave-request-1")
+	res, err := http.DefaultClient.Do(req)
+	if assert.NoError(t, err) {
+		res.Body.Close()
+		assert.Equal(t, "leave-request-1", res.Header.Get(requestid.Header))
+	}
+
+	code, _ = doJSONAs(t, a, http.MethodPut, fmt.Sprintf("%s/admin/users/%d/role", ts.URL, users[2].ID), admin.ID, map[string]string{"role": "teacher"})
+	assert.Equal(t, http.StatusOK, code)
+
+	list := func(query string) []map[string]interface{} {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/admin/audit-events?"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+accessToken(t, a, admin.ID))
+		res, err := http.DefaultClient.Do(req)
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		var events []map[string]interface{}
+		json.NewDecoder(res.Body).Decode(&events)
+		return events
+	}
+
+	events := list(fmt.Sprintf("target_type=queue&target_id=%d&action=queue.", queue.ID))
+	if assert.Len(t, events, 5) {
+		// От новых к старым: leave, serve, три join.
+		assert.Equal(t, "queue.left", events[0]["action"])
+		assert.Equal(t, "leave-request-1", events[0]["request_id"])
+		assert.Equal(t, float64(1), events[0]["before"].(map[string]interface{})["position"])
+		assert.Equal(t, "queue.served", events[1]["action"])
+		assert.Equal(t, float64(admin.ID), events[1]["actor_id"])
+		assert.Equal(t, float64(users[0].ID), events[1]["user_id"])
+		assert.Equal(t, "queue.joined", events[4]["action"])
+	}
+
+	events = list(fmt.Sprintf("action=admin.role_changed&user_id=%d", users[2].ID))
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "student", events[0]["before"].(map[string]interface{})["role"])
+		assert.Equal(t, "teacher", events[0]["after"].(map[string]interface{})["role"])
+	}
+
+	// Журнал только пополняется.
+	var event models.AuditEvent
+	assert.NoError(t, a.DB.Where("action = ?", "admin.role_changed").Last(&event).Error)
+	assert.ErrorIs(t, a.DB.Delete(&event).Error, models.ErrAuditImmutable)
+	assert.ErrorIs(t, a.DB.Model(&event).Update("action", "x").Error, models.ErrAuditImmutable)
+}
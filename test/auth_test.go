@@ -0,0 +1,340 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"test_hack/internal/app"
+	"test_hack/internal/models"
+	"test_hack/internal/notify"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// postJSON отправляет JSON-запрос и возвращает код ответа и разобранное тело.
+func postJSON(t *testing.T, url string, body interface{}) (int, map[string]interface{}) {
+	data, _ := json.Marshal(body)
+	res, err := http.Post(url, "application/json", bytes.NewReader(data))
+	if !assert.NoError(t, err) {
+		return 0, nil
+	}
+	defer res.Body.Close()
+	var result map[string]interface{}
+	json.NewDecoder(res.Body).Decode(&result)
+	return res.StatusCode, result
+}
+
+// postJSONAs отправляет JSON-запрос от имени пользователя с его access токеном.
+func postJSONAs(t *testing.T, a *app.App, url string, userID uint, body interface{}) (int, map[string]interface{}) {
+	return doJSONAs(t, a, http.MethodPost, url, userID, body)
+}
+
+// doJSONAs отправляет JSON-запрос с произвольным методом от имени пользователя.
+func doJSONAs(t *testing.T, a *app.App, method, url string, userID uint, body interface{}) (int, map[string]interface{}) {
+	data, _ := json.Marshal(body)
+	req, _ := http.NewRequest(method, url, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken(t, a, userID))
+	res, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return 0, nil
+	}
+	defer res.Body.Close()
+	var result map[string]interface{}
+	json.NewDecoder(res.Body).Decode(&result)
+	return res.StatusCode, result
+}
+
+func TestPasswordResetFlow(t *testing.T) {
+	ts, a := setupTestServer(t)
+
+	mailer := &notify.MemoryMailer{}
+	a.Notify.SetMailer(mailer)
+
+	email := fmt.Sprintf("reset_%d@example.com", time.Now().UnixNano())
+	hash, _ := bcrypt.GenerateFromPassword([]byte("oldpass1"), bcrypt.MinCost)
+	user := models.User{Name: "Анна", Surname: "Смирнова", Email: email, PasswordHash: string(hash), Language: notify.LangRU}
+	assert.NoError(t, a.DB.Create(&user).Error)
+
+	code, tokens := postJSON(t, ts.URL+"/auth/login", map[string]string{"email": email, "password": "oldpass1"})
+	assert.Equal(t, http.StatusOK, code)
+	oldRefresh := tokens["refresh_token"]
+
+	code, _ = postJSON(t, ts.URL+"/auth/forgot-password", map[string]string{"email": email})
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = postJSON(t, ts.URL+"/auth/forgot-password", map[string]string{"email": "nobody_" + email})
+	assert.Equal(t, http.StatusOK, code, "Ответ для незарегистрированного email не должен отличаться")
+
+	mails := mailer.Messages()
+	if !assert.Len(t, mails, 1) {
+		return
+	}
+	match := regexp.MustCompile(`token=([A-Za-z0-9_-]+)`).FindStringSubmatch(mails[0].Body)
+	if !assert.Len(t, match, 2, "В письме нет ссылки с токеном") {
+		return
+	}
+	token := match[1]
+
+	var stored models.PasswordResetToken
+	assert.NoError(t, a.DB.Where("user_id = ?", user.ID).First(&stored).Error)
+	assert.NotEqual(t, token, stored.TokenHash, "Токен должен храниться в виде хеша")
+
+	code, _ = postJSON(t, ts.URL+"/auth/reset-password", map[string]string{"token": token, "password": "newpass1"})
+	assert.Equal(t, http.StatusOK, code)
+	code, body := postJSON(t, ts.URL+"/auth/reset-password", map[string]string{"token": token, "password": "another1"})
+	assert.Equal(t, http.StatusBadRequest, code, "Токен одноразовый")
+	assert.Equal(t, "INVALID_RESET_TOKEN", body["code"])
+
+	code, _ = postJSON(t, ts.URL+"/auth/refresh", map[string]interface{}{"refresh_token": oldRefresh})
+	assert.Equal(t, http.StatusUnauthorized, code, "Старые refresh токены должны быть отозваны")
+
+	code, _ = postJSON(t, ts.URL+"/auth/login", map[string]string{"email": email, "password": "newpass1"})
+	assert.Equal(t, http.StatusOK, code)
+
+	// Лимит — 3 запроса на email в час.
+	postJSON(t, ts.URL+"/auth/forgot-password", map[string]string{"email": email})
+	postJSON(t, ts.URL+"/auth/forgot-password", map[string]string{"email": email})
+	code, body = postJSON(t, ts.URL+"/auth/forgot-password", map[string]string{"email": email})
+	assert.Equal(t, http.StatusTooManyRequests, code)
+	assert.Equal(t, "TOO_MANY_REQUESTS", body["code"])
+}
+
+func TestEmailVerificationFlow(t *testing.T) {
+	ts, a := setupTestServer(t)
+
+	mailer := &notify.MemoryMailer{}
+	a.Notify.SetMailer(mailer)
+	a.Handler.Config.Accounts.EmailAllowedDomains = []string{"university.ru"}
+	a.Handler.Config.Accounts.RequireEmailVerification = true
+
+	suffix := time.Now().UnixNano()
+	code, body := postJSON(t, ts.URL+"/auth/register", map[string]string{
+		"name": "Олег", "surname": "Кузнецов", "email": fmt.Sprintf("oleg_%d@example.com", suffix), "password": "secret1",
+	})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "EMAIL_DOMAIN_NOT_ALLOWED", body["code"])
+
+	email := fmt.Sprintf("oleg_%d@student.university.ru", suffix)
+	code, _ = postJSON(t, ts.URL+"/auth/register", map[string]string{
+		"name": "Олег", "surname": "Кузнецов", "email": email, "password": "secret1",
+	})
+	assert.Equal(t, http.StatusCreated, code)
+
+	var user models.User
+	assert.NoError(t, a.DB.Where("email = ?", email).First(&user).Error)
+	assert.False(t, user.EmailVerified)
+
+	now := time.Now()
+	schedule := models.Schedule{ExternalID: fmt.Sprint(suffix), Name: "Проверка email", StartTime: now.Add(time.Hour), EndTime: now.Add(2 * time.Hour), GroupIDs: "1"}
+	assert.NoError(t, a.DB.Create(&schedule).Error)
+	queue := models.Queue{ScheduleID: schedule.ID, OpensAt: now.Add(-time.Minute), ClosesAt: schedule.StartTime, IsActive: true}
+	assert.NoError(t, a.DB.Create(&queue).Error)
+
+	join := func() int {
+		req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/api/queues/%d/join", ts.URL, queue.ID), nil)
+		req.Header.Set("Authorization", "Bearer "+accessToken(t, a, user.ID))
+		res, err := http.DefaultClient.Do(req)
+		if !assert.NoError(t, err) {
+			return 0
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+	assert.Equal(t, http.StatusForbidden, join(), "Пользователь с неподтверждённым email не может встать в очередь")
+
+	mails := mailer.Messages()
+	if !assert.Len(t, mails, 1) {
+		return
+	}
+	match := regexp.MustCompile(`/auth/verify\?token=([A-Za-z0-9_-]+)`).FindStringSubmatch(mails[0].Body)
+	if !assert.Len(t, match, 2, "В письме нет ссылки подтверждения") {
+		return
+	}
+
+	res, err := http.Get(ts.URL + "/auth/verify?token=" + match[1])
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	res, err = http.Get(ts.URL + "/auth/verify?token=" + match[1])
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "Токен одноразовый")
+
+	assert.Equal(t, http.StatusOK, join())
+}
+
+func TestEmailIsCaseInsensitive(t *testing.T) {
+	ts, a := setupTestServer(t)
+
+	email := fmt.Sprintf("Case_%d@Example.com", time.Now().UnixNano())
+	register := map[string]string{"name": "Вера", "surname": "Соколова", "email": email, "password": "secret12", "group_id": "101"}
+	code, _ := postJSON(t, ts.URL+"/auth/register", register)
+	assert.Equal(t, http.StatusCreated, code)
+
+	var user models.User
+	assert.NoError(t, a.DB.Where("email = ?", strings.ToLower(email)).First(&user).Error, "email сохраняется в нижнем регистре")
+
+	register["email"] = strings.ToUpper(email)
+	code, body := postJSON(t, ts.URL+"/auth/register", register)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "EMAIL_EXISTS", body["code"])
+
+	code, _ = postJSON(t, ts.URL+"/auth/login", map[string]string{"email": strings.ToUpper(email), "password": "secret12"})
+	assert.Equal(t, http.StatusOK, code)
+
+	// Уникальный индекс по LOWER(email) защищает и от записей в обход регистрации.
+	err := a.DB.Create(&models.User{Name: "Вера", Surname: "Соколова", Email: strings.ToUpper(email), PasswordHash: "x"}).Error
+	assert.Error(t, err)
+}
+
+func TestLoginThrottling(t *testing.T) {
+	ts, a := setupTestServer(t)
+
+	email := fmt.Sprintf("brute_%d@example.com", time.Now().UnixNano())
+	hash, _ := bcrypt.GenerateFromPassword([]byte("correct1"), bcrypt.MinCost)
+	user := models.User{Name: "Ольга", Surname: "Орлова", Email: email, PasswordHash: string(hash)}
+	assert.NoError(t, a.DB.Create(&user).Error)
+	for _, prefix := range []string{"login_failures:", "login_lock:", "login_wait:"} {
+		a.Redis.Del(context.Background(), prefix+"ip:127.0.0.1")
+	}
+
+	wrong := map[string]string{"email": email, "password": "wrong"}
+	for i := 0; i < 3; i++ {
+		code, body := postJSON(t, ts.URL+"/auth/login", wrong)
+		assert.Equal(t, http.StatusUnauthorized, code)
+		assert.Equal(t, "INVALID_CREDENTIALS", body["code"])
+	}
+
+	// После трёх неудач следующая попытка возможна только после паузы — даже с верным паролем.
+	data, _ := json.Marshal(map[string]string{"email": email, "password": "correct1"})
+	res, err := http.Post(ts.URL+"/auth/login", "application/json", bytes.NewReader(data))
+	if assert.NoError(t, err) {
+		res.Body.Close()
+		assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+		assert.NotEmpty(t, res.Header.Get("Retry-After"))
+	}
+
+	var failed int64
+	a.DB.Model(&models.AuditEvent{}).Where("action = ? AND user_id = ?", "auth.login_failed", user.ID).Count(&failed)
+	assert.Equal(t, int64(3), failed, "Каждая неудачная попытка должна попадать в журнал аудита")
+}
+
+func TestLoginIPLockIgnoresSpoofedForwardedFor(t *testing.T) {
+	ts, _ := setupTestServer(t)
+
+	// Каждая попытка — с новым email и новым X-Forwarded-For: без доверенных прокси все они считаются для адреса соединения.
+	attempt := func(i int) *http.Response {
+		data, _ := json.Marshal(map[string]string{"email": fmt.Sprintf("spoof_%d_%d@example.com", time.Now().UnixNano(), i), "password": "wrong"})
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/auth/login", bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("203.0.113.%d", i+1))
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res
+	}
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, http.StatusUnauthorized, attempt(i).StatusCode)
+	}
+	res := attempt(20)
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	assert.NotEmpty(t, res.Header.Get("Retry-After"))
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	ts, a := setupTestServer(t)
+
+	email := fmt.Sprintf("mfa_%d@example.com", time.Now().UnixNano())
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret12"), bcrypt.MinCost)
+	user := models.User{Name: "Пётр", Surname: "Петров", Email: email, PasswordHash: string(hash), Role: models.RoleTeacher}
+	assert.NoError(t, a.DB.Create(&user).Error)
+
+	code, setup := postJSONAs(t, a, ts.URL+"/profile/2fa/setup", user.ID, nil)
+	if !assert.Equal(t, http.StatusOK, code) {
+		return
+	}
+	secret, _ := setup["secret"].(string)
+	assert.Contains(t, setup["otpauth_url"], "otpauth://totp/")
+
+	code, body := postJSONAs(t, a, ts.URL+"/profile/2fa/enable", user.ID, map[string]string{"code": "000000"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "INVALID_MFA_CODE", body["code"])
+
+	totpCode, _ := totp.GenerateCode(secret, time.Now())
+	code, body = postJSONAs(t, a, ts.URL+"/profile/2fa/enable", user.ID, map[string]string{"code": totpCode})
+	if !assert.Equal(t, http.StatusOK, code) {
+		return
+	}
+	recovery, _ := body["recovery_codes"].([]interface{})
+	if !assert.Len(t, recovery, 10) {
+		return
+	}
+
+	// Пароль верный, но вместо токенов приходит токен второго шага.
+	code, challenge := postJSON(t, ts.URL+"/auth/login", map[string]string{"email": email, "password": "secret12"})
+	assert.Equal(t, http.StatusAccepted, code)
+	assert.Equal(t, true, challenge["mfa_required"])
+	assert.Nil(t, challenge["access_token"])
+
+	code, body = postJSON(t, ts.URL+"/auth/login/mfa", map[string]interface{}{"mfa_token": challenge["mfa_token"], "code": "123456"})
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, "INVALID_MFA_CODE", body["code"])
+
+	// Код восстановления действует один раз.
+	code, tokens := postJSON(t, ts.URL+"/auth/login/mfa", map[string]interface{}{"mfa_token": challenge["mfa_token"], "code": recovery[0]})
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotEmpty(t, tokens["access_token"])
+
+	_, challenge = postJSON(t, ts.URL+"/auth/login", map[string]string{"email": email, "password": "secret12"})
+	code, _ = postJSON(t, ts.URL+"/auth/login/mfa", map[string]interface{}{"mfa_token": challenge["mfa_token"], "code": recovery[0]})
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// Токен второго шага не принимается вместо refresh токена.
+	code, _ = postJSON(t, ts.URL+"/auth/refresh", map[string]interface{}{"refresh_token": challenge["mfa_token"]})
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	a.Handler.Config.MFA.RequiredRoles = []string{"teacher", "admin"}
+	code, body = postJSONAs(t, a, ts.URL+"/profile/2fa/disable", user.ID, map[string]string{"password": "secret12", "code": recovery[1].(string)})
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Equal(t, "MFA_REQUIRED", body["code"])
+}
+
+func TestTwoFactorCodeFailuresCountTowardsLoginLimits(t *testing.T) {
+	ts, a := setupTestServer(t)
+
+	email := fmt.Sprintf("mfa_brute_%d@example.com", time.Now().UnixNano())
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret12"), bcrypt.MinCost)
+	user := models.User{Name: "Игорь", Surname: "Зайцев", Email: email, PasswordHash: string(hash), TOTPEnabled: true, TOTPSecret: "JBSWY3DPEHPK3PXP"}
+	require.NoError(t, a.DB.Create(&user).Error)
+	for _, prefix := range []string{"login_failures:", "login_lock:", "login_wait:"} {
+		a.Redis.Del(context.Background(), prefix+"ip:127.0.0.1", prefix+"email:"+email)
+	}
+
+	// Злоумышленник знает пароль и каждый раз получает новый токен второго шага.
+	for i := 0; i < 3; i++ {
+		code, challenge := postJSON(t, ts.URL+"/auth/login", map[string]string{"email": email, "password": "secret12"})
+		require.Equal(t, http.StatusAccepted, code, "попытка %d", i+1)
+		code, body := postJSON(t, ts.URL+"/auth/login/mfa", map[string]interface{}{"mfa_token": challenge["mfa_token"], "code": "000000"})
+		assert.Equal(t, http.StatusUnauthorized, code)
+		assert.Equal(t, "INVALID_MFA_CODE", body["code"])
+	}
+
+	// Верный пароль не сбрасывает неудачи второго шага: после трёх неверных кодов действует пауза.
+	code, body := postJSON(t, ts.URL+"/auth/login", map[string]string{"email": email, "password": "secret12"})
+	assert.Equal(t, http.StatusTooManyRequests, code)
+	assert.Equal(t, "TOO_MANY_ATTEMPTS", body["code"])
+
+	var failed int64
+	a.DB.Model(&models.AuditEvent{}).Where("action = ? AND user_id = ?", "auth.mfa_failed", user.ID).Count(&failed)
+	assert.Equal(t, int64(3), failed)
+}
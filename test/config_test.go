@@ -0,0 +1,132 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"test_hack/internal/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigYAMLAndEnvOverride(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+server:
+  port: 9090
+  cors_origins: ["https://queue.example.com"]
+database:
+  host: db
+  user: queue
+  name: queue
+redis:
+  addr: redis:6379
+jwt:
+  access_secret: access
+  refresh_secret: refresh
+  access_ttl: 5m
+cache:
+  groups_ttl: 1h
+`), 0o600))
+
+	t.Setenv("PORT", "9191")
+	t.Setenv("CORS_ORIGINS", "https://a.example.com, https://b.example.com")
+	t.Setenv("JWT_REFRESH_TTL", "48h")
+	t.Setenv("JWT_ACCESS_SECRET", "")
+	t.Setenv("JWT_REFRESH_SECRET", "")
+	t.Setenv("SMTP_HOST", "smtp.example.com")
+	t.Setenv("EMAIL_ALLOWED_DOMAINS", "university.ru, example.edu")
+	t.Setenv("MFA_REQUIRED_ROLES", "teacher,admin")
+	t.Setenv("RATE_LIMIT_QUEUE_JOIN", "20/1m")
+	t.Setenv("REDIS_DB", "2")
+	t.Setenv("METRICS_TOKEN", "scrape-secret")
+
+	_, err := config.Load(path, filepath.Join(dir, "missing.env"))
+	require.Error(t, err, "пустой ключ из окружения должен перекрыть YAML и не пройти проверку")
+	assert.Contains(t, err.Error(), "JWT_ACCESS_SECRET")
+	assert.Contains(t, err.Error(), "JWT_REFRESH_SECRET")
+
+	os.Unsetenv("JWT_ACCESS_SECRET")
+	os.Unsetenv("JWT_REFRESH_SECRET")
+	cfg, err := config.Load(path, filepath.Join(dir, "missing.env"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 9191, cfg.Server.Port)
+	assert.Equal(t, ":9191", cfg.Addr())
+	assert.Equal(t, []string{"https://a.example.com", "https://b.example.com"}, cfg.Server.CORSOrigins)
+	assert.Equal(t, "access", cfg.JWT.AccessSecret)
+	assert.Equal(t, 5*time.Minute, cfg.JWT.AccessTTL)
+	assert.Equal(t, 48*time.Hour, cfg.JWT.RefreshTTL)
+	assert.Equal(t, time.Hour, cfg.Cache.GroupsTTL)
+	// Значения, которых нет ни в YAML, ни в окружении, остаются по умолчанию.
+	assert.Equal(t, 15*time.Minute, cfg.Cache.EmptyScheduleTTL)
+	assert.Equal(t, "https://api.profcomff.com/timetable", cfg.Timetable.APIURL)
+
+	// Без MAIL_BACKEND письма отправляются через SMTP, если задан SMTP_HOST.
+	assert.Equal(t, "smtp", cfg.Mail.BackendName())
+	assert.Equal(t, "smtp.example.com", cfg.Mail.SMTP.Host)
+	assert.Equal(t, "587", cfg.Mail.SMTP.Port)
+	assert.Equal(t, 30*time.Second, cfg.Mail.SMTP.Timeout)
+	assert.Equal(t, []string{"university.ru", "example.edu"}, cfg.Accounts.EmailAllowedDomains)
+	assert.True(t, cfg.MFA.Required("admin"))
+	assert.False(t, cfg.MFA.Required("student"))
+	assert.Equal(t, map[string]string{"queue_join": "20/1m"}, cfg.RateLimit.Limits())
+	assert.True(t, cfg.RateLimit.Enabled)
+	assert.Equal(t, 500, cfg.Export.AsyncThreshold)
+	assert.Equal(t, 2, cfg.Redis.DB)
+	assert.Equal(t, "scrape-secret", cfg.Metrics.Token)
+	assert.Equal(t, 15, cfg.TestRedis.DB)
+}
+
+func TestConfigEnvFileDoesNotChangeProcessEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.env")
+	require.NoError(t, os.WriteFile(path, []byte("QUEUE_TEST_ENV_FILE_ONLY=1\nEXPORT_ASYNC_THRESHOLD=42\nMFA_ISSUER=FromFile\n"), 0o600))
+	t.Setenv("MFA_ISSUER", "FromEnv")
+
+	cfg, err := config.Read("", path)
+	require.NoError(t, err)
+	assert.Equal(t, 42, cfg.Export.AsyncThreshold)
+	assert.Equal(t, "FromEnv", cfg.MFA.Issuer, "переменные окружения важнее файла .env")
+
+	_, set := os.LookupEnv("QUEUE_TEST_ENV_FILE_ONLY")
+	assert.False(t, set, "чтение .env не должно менять окружение процесса")
+}
+
+func TestConfigValidate(t *testing.T) {
+	cfg := config.Default()
+	cfg.Database = config.Database{Host: "db", Port: "5432", User: "queue", Name: "queue"}
+	cfg.Redis.Addr = "redis:6379"
+	cfg.JWT.AccessSecret = "access"
+	cfg.JWT.RefreshSecret = "refresh"
+	require.NoError(t, cfg.Validate())
+
+	bad := cfg
+	bad.JWT.RefreshSecret = "access"
+	bad.Server.Port = 0
+	bad.Cache.GroupsTTL = 0
+	bad.Timetable.APIURL = "api.profcomff.com"
+	bad.Mail.Backend = "sendmail"
+	bad.OIDC.IssuerURL = "https://sso.example.com"
+	bad.WebPush.PublicKey = "key"
+	bad.RateLimit.QueueJoin = "10"
+	bad.Export.AsyncThreshold = -1
+	bad.Server.TrustedProxies = []string{"10.0.0.0/8", "proxy.local"}
+	bad.Telegram = config.Telegram{BotToken: "token", WebhookURL: "https://queue.example.com/telegram/webhook"}
+	err := bad.Validate()
+	require.Error(t, err)
+	for _, name := range []string{
+		"JWT_REFRESH_SECRET", "PORT", "CACHE_GROUPS_TTL", "TIMETABLE_API_URL", "MAIL_BACKEND",
+		"OIDC_CLIENT_ID", "VAPID_PUBLIC_KEY", "RATE_LIMIT_QUEUE_JOIN", "EXPORT_ASYNC_THRESHOLD",
+		"TELEGRAM_WEBHOOK_SECRET", "TRUSTED_PROXIES",
+	} {
+		assert.Contains(t, err.Error(), name)
+	}
+
+	t.Setenv("JWT_ACCESS_TTL", "пятнадцать минут")
+	_, err = config.Read("", filepath.Join(t.TempDir(), "missing.env"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "JWT_ACCESS_TTL")
+}
@@ -0,0 +1,93 @@
+package test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"test_hack/internal/app"
+	"test_hack/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// getAs выполняет GET от имени пользователя и возвращает ответ целиком.
+func getAs(t *testing.T, a *app.App, url string, userID uint) (*http.Response, []byte) {
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken(t, a, userID))
+	res, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return nil, nil
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	return res, body
+}
+
+func TestProfileDataExport(t *testing.T) {
+	ts, a := setupTestServer(t)
+
+	now := time.Now()
+	schedule := models.Schedule{ExternalID: "9997", Name: "Физика", StartTime: now.Add(time.Hour), EndTime: now.Add(2 * time.Hour), GroupIDs: "1"}
+	assert.NoError(t, a.DB.Create(&schedule).Error)
+	queue := models.Queue{ScheduleID: schedule.ID, OpensAt: now, ClosesAt: schedule.StartTime, IsActive: true}
+	assert.NoError(t, a.DB.Create(&queue).Error)
+
+	user := models.User{Name: "Вера", Surname: "Соколова", Email: fmt.Sprintf("export_%d@example.com", now.UnixNano()), PasswordHash: "x"}
+	assert.NoError(t, a.DB.Create(&user).Error)
+	assert.NoError(t, a.DB.Create(&models.QueueEntry{QueueID: queue.ID, UserID: user.ID, Position: 1}).Error)
+
+	// Небольшая выгрузка отдаётся сразу.
+	res, body := getAs(t, a, ts.URL+"/profile/export", user.ID)
+	if !assert.NotNil(t, res) {
+		return
+	}
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Contains(t, res.Header.Get("Content-Disposition"), "attachment")
+	var export map[string]interface{}
+	assert.NoError(t, json.Unmarshal(body, &export))
+	assert.Equal(t, user.Email, export["user"].(map[string]interface{})["email"])
+	history := export["queue_history"].([]interface{})
+	if assert.Len(t, history, 1) {
+		assert.Equal(t, "Физика", history[0].(map[string]interface{})["schedule_name"])
+	}
+
+	// Фоновая выгрузка в ZIP.
+	code, status := doJSONAs(t, a, http.MethodGet, ts.URL+"/profile/export?format=zip&async=true", user.ID, nil)
+	assert.Equal(t, http.StatusAccepted, code)
+	url, _ := status["download_url"].(string)
+	if !assert.NotEmpty(t, url) {
+		return
+	}
+
+	// Чужая выгрузка недоступна.
+	code, _ = doJSONAs(t, a, http.MethodGet, ts.URL+url, user.ID+1000000, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		res, body = getAs(t, a, ts.URL+url, user.ID)
+		if !assert.NotNil(t, res) {
+			return
+		}
+		if res.Header.Get("Content-Type") == "application/zip" || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	assert.Equal(t, "application/zip", res.Header.Get("Content-Type"))
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if !assert.NoError(t, err) {
+		return
+	}
+	files := map[string]bool{}
+	for _, f := range zr.File {
+		files[f.Name] = true
+	}
+	assert.True(t, files["user.json"])
+	assert.True(t, files["queue_history.json"])
+}
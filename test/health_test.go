@@ -0,0 +1,73 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"test_hack/internal/handlers"
+	"test_hack/internal/health"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthReadiness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hub := handlers.NewHub(nil, nil)
+
+	checker := health.New()
+	checker.Timeout = 100 * time.Millisecond
+	checker.Add("database", func(context.Context) error { return nil })
+	checker.Add("websocket_hub", func(context.Context) error {
+		if !hub.Alive() {
+			return errors.New("хаб WebSocket не запущен")
+		}
+		return nil
+	})
+
+	r := gin.New()
+	r.GET("/healthz", health.Liveness)
+	r.GET("/readyz", checker.Readiness)
+
+	ready := func() (int, health.Report) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var report health.Report
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		return w.Code, report
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Хаб ещё не запущен.
+	code, report := ready()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.StatusDown, report.Status)
+	assert.Equal(t, health.StatusUp, report.Components["database"].Status)
+	assert.Equal(t, health.StatusDown, report.Components["websocket_hub"].Status)
+	assert.NotEmpty(t, report.Components["websocket_hub"].Error)
+
+	go hub.Run()
+	require.Eventually(t, hub.Alive, time.Second, 10*time.Millisecond)
+	code, report = ready()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.StatusUp, report.Status)
+
+	// Зависшая проверка прерывается по таймауту.
+	block := make(chan struct{})
+	defer close(block)
+	checker.Add("redis", func(context.Context) error { <-block; return nil })
+	code, report = ready()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Contains(t, report.Components["redis"].Error, context.DeadlineExceeded.Error())
+
+	hub.Shutdown(context.Background(), 0)
+	require.Eventually(t, func() bool { return !hub.Alive() }, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,103 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"test_hack/internal/handlers"
+	"test_hack/internal/jobs"
+	"test_hack/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminJobsListAndManualRun(t *testing.T) {
+	ts, a := setupTestServer(t)
+	admin := models.User{Name: "Админ", Surname: "Задачи", Email: fmt.Sprintf("jobs_admin_%d@example.com", time.Now().UnixNano()), PasswordHash: "x", Role: models.RoleAdmin}
+	require.NoError(t, a.DB.Create(&admin).Error)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	a.Jobs.Register(jobs.Job{Name: "TestBlockingJob", Spec: "@yearly", Run: func() (int64, error) {
+		close(started)
+		<-release
+		return 3, nil
+	}})
+	a.Jobs.Register(jobs.Job{Name: "TestFailingJob", Spec: "@yearly", Run: func() (int64, error) {
+		return 0, errors.New("нет связи с API")
+	}})
+
+	listJobs := func() map[string]handlers.JobStatusResponse {
+		res, body := getAs(t, a, ts.URL+"/admin/jobs", admin.ID)
+		require.NotNil(t, res)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		var list []handlers.JobStatusResponse
+		require.NoError(t, json.Unmarshal(body, &list))
+		byName := map[string]handlers.JobStatusResponse{}
+		for _, job := range list {
+			byName[job.Name] = job
+		}
+		return byName
+	}
+
+	list := listJobs()
+	assert.Contains(t, list, "CloseExpiredQueues", "задачи планировщика зарегистрированы в реестре")
+	assert.Equal(t, "@yearly", list["TestBlockingJob"].Schedule)
+	assert.Nil(t, list["TestBlockingJob"].LastRun)
+
+	type result struct {
+		code int
+		body map[string]interface{}
+	}
+	first := make(chan result, 1)
+	go func() {
+		code, body := postJSONAs(t, a, ts.URL+"/admin/jobs/TestBlockingJob/run", admin.ID, nil)
+		first <- result{code, body}
+	}()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("задача не запустилась")
+	}
+
+	// Пока задача выполняется, повторный запуск отклоняется.
+	code, body := postJSONAs(t, a, ts.URL+"/admin/jobs/TestBlockingJob/run", admin.ID, nil)
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, "JOB_RUNNING", body["code"])
+	assert.True(t, listJobs()["TestBlockingJob"].Running)
+
+	close(release)
+	r := <-first
+	require.Equal(t, http.StatusOK, r.code)
+	assert.Equal(t, models.JobStatusSuccess, r.body["status"])
+	assert.Equal(t, models.JobTriggerManual, r.body["trigger"])
+	assert.EqualValues(t, 3, r.body["affected_rows"])
+
+	list = listJobs()
+	assert.False(t, list["TestBlockingJob"].Running)
+	if assert.NotNil(t, list["TestBlockingJob"].LastRun) {
+		assert.Equal(t, models.JobStatusSuccess, list["TestBlockingJob"].LastRun.Status)
+	}
+
+	code, body = postJSONAs(t, a, ts.URL+"/admin/jobs/TestFailingJob/run", admin.ID, nil)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, models.JobStatusFailed, body["status"])
+	assert.Equal(t, "нет связи с API", body["error"])
+
+	res, raw := getAs(t, a, ts.URL+"/admin/jobs/TestFailingJob/runs", admin.ID)
+	require.NotNil(t, res)
+	var runs []handlers.JobRunResponse
+	require.NoError(t, json.Unmarshal(raw, &runs))
+	if assert.Len(t, runs, 1) {
+		assert.Equal(t, models.JobStatusFailed, runs[0].Status)
+		assert.NotNil(t, runs[0].FinishedAt)
+	}
+
+	code, body = postJSONAs(t, a, ts.URL+"/admin/jobs/NoSuchJob/run", admin.ID, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, "JOB_NOT_FOUND", body["code"])
+}
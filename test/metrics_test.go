@@ -0,0 +1,133 @@
+package test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"test_hack/internal/handlers"
+	"test_hack/internal/metrics"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := metrics.New()
+	hub := handlers.NewHub(nil, m)
+	go hub.Run()
+
+	lengthsErr := error(nil)
+	lengths := func() (map[uint]int64, error) {
+		return map[uint]int64{7: 3}, lengthsErr
+	}
+
+	r := gin.New()
+	r.Use(m.Middleware())
+	r.GET("/metrics", metrics.RequireToken("scrape-secret"), m.Handler(metrics.QueueLengths(lengths)))
+	r.GET("/api/queues/:id/status", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+	r.GET("/api/queues/:id/ws", (&handlers.Handler{Hub: hub}).QueueWebSocketHandler)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	scrape := func() string {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/metrics", nil)
+		req.Header.Set("Authorization", "Bearer scrape-secret")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return string(body)
+	}
+
+	resp, err := http.Get(server.URL + "/api/queues/7/status")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/api/queues/metrics-test/ws", nil)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return hub.ClientCount("metrics-test") == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	m.CacheLookup("metrics-test", true)
+	m.CacheLookup("metrics-test", false)
+
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer external.Close()
+	client := &http.Client{Transport: m.Transport("metrics-test", nil)}
+	resp, err = client.Get(external.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	body := scrape()
+	assert.Contains(t, body, `http_request_duration_seconds_count{method="GET",route="/api/queues/:id/status",status="404"}`)
+	assert.Contains(t, body, `ws_connections{queue_id="metrics-test"} 1`)
+	assert.Contains(t, body, `queue_length{queue_id="7"} 3`)
+	assert.Contains(t, body, `cache_requests_total{cache="metrics-test",result="hit"} 1`)
+	assert.Contains(t, body, `cache_requests_total{cache="metrics-test",result="miss"} 1`)
+	assert.Contains(t, body, `external_request_duration_seconds_count{service="metrics-test",status="502"} 1`)
+	assert.NotContains(t, body, `route="/api/queues/:id/ws"`, "подключения WebSocket не попадают в метрики запросов")
+
+	// После отключения клиента очередь пропадает из ws_connections.
+	conn.Close()
+	require.Eventually(t, func() bool { return hub.ClientCount("metrics-test") == 0 }, 2*time.Second, 10*time.Millisecond)
+	assert.NotContains(t, scrape(), `ws_connections{queue_id="metrics-test"}`)
+
+	// Ошибка при подсчёте длин очередей не мешает отдать остальные метрики.
+	lengthsErr = errors.New("база данных недоступна")
+	body = scrape()
+	assert.NotContains(t, body, "queue_length{")
+	assert.Contains(t, body, "http_request_duration_seconds")
+	assert.Contains(t, body, "go_goroutines")
+
+	// Без токена метрики не отдаются.
+	for _, header := range []string{"", "Bearer wrong"} {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/metrics", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, header)
+	}
+}
+
+// TestMetricsInstancesAreIndependent проверяет, что у каждого экземпляра Metrics свой реестр, а nil-экземпляр
+// ничего не учитывает.
+func TestMetricsInstancesAreIndependent(t *testing.T) {
+	first, second := metrics.New(), metrics.New()
+	first.QueueJoined()
+	first.QueueJoined()
+	second.QueueJoined()
+
+	count := func(m *metrics.Metrics) float64 {
+		families, err := m.Registry.Gather()
+		require.NoError(t, err)
+		for _, f := range families {
+			if f.GetName() == "queue_joins_total" {
+				return f.GetMetric()[0].GetCounter().GetValue()
+			}
+		}
+		return 0
+	}
+	assert.Equal(t, 2.0, count(first))
+	assert.Equal(t, 1.0, count(second))
+
+	var disabled *metrics.Metrics
+	assert.NotPanics(t, func() {
+		disabled.QueueJoined()
+		disabled.CacheLookup("groups", true)
+		disabled.SetWSConnections("1", 1)
+	})
+	assert.Equal(t, http.DefaultTransport, disabled.Transport("timetable", nil))
+}
@@ -0,0 +1,183 @@
+package test
+
+import (
+	"context"
+	"test_hack/internal/migrations"
+	"test_hack/internal/models"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestMigrationsEmbedded(t *testing.T) {
+	list, err := migrations.Embedded()
+	require.NoError(t, err)
+	require.NotEmpty(t, list)
+	assert.Equal(t, "0001_init", list[0].String())
+}
+
+func TestMigrationsParse(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/0002_add_index.up.sql":   {Data: []byte("CREATE INDEX i ON t (a);")},
+		"sql/0002_add_index.down.sql": {Data: []byte("DROP INDEX i;")},
+		"sql/0001_init.up.sql":        {Data: []byte("CREATE TABLE t (a int);")},
+		"sql/0001_init.down.sql":      {Data: []byte("DROP TABLE t;")},
+	}
+	list, err := migrations.Parse(fsys, "sql")
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, int64(1), list[0].Version)
+	assert.Equal(t, "add_index", list[1].Name)
+	assert.Equal(t, "DROP INDEX i;", list[1].Down)
+
+	delete(fsys, "sql/0002_add_index.down.sql")
+	_, err = migrations.Parse(fsys, "sql")
+	assert.Error(t, err, "миграция без down-файла")
+
+	fsys["sql/0002_add_index.down.sql"] = &fstest.MapFile{Data: []byte("DROP INDEX i;")}
+	fsys["sql/0004_gap.up.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+	fsys["sql/0004_gap.down.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+	_, err = migrations.Parse(fsys, "sql")
+	assert.Error(t, err, "пропущенная версия")
+}
+
+// TestMigrationsUpDown применяет все миграции к пустой базе, откатывает их и применяет снова.
+func TestMigrationsUpDown(t *testing.T) {
+	_, db := testDatabase(t)
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	m, err := migrations.New(sqlDB)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	assert.Len(t, applied, len(m.Migrations))
+
+	for _, model := range []interface{}{&models.User{}, &models.Queue{}, &models.QueueEntry{}, &models.VAPIDKeys{}, &models.Session{}} {
+		assert.True(t, db.Migrator().HasTable(model))
+	}
+	assert.True(t, db.Migrator().HasIndex(&models.QueueEntry{}, "idx_queue_entries_active_user"))
+
+	// Повторный запуск ничего не применяет.
+	applied, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	status, err := m.Status(ctx)
+	require.NoError(t, err)
+	for _, s := range status {
+		assert.NotNil(t, s.AppliedAt, s.String())
+	}
+
+	reverted, err := m.Down(ctx, len(m.Migrations))
+	require.NoError(t, err)
+	assert.Len(t, reverted, len(m.Migrations))
+	assert.False(t, db.Migrator().HasTable(&models.User{}))
+
+	applied, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Len(t, applied, len(m.Migrations))
+}
+
+// Модели первой версии сервера, схему которых создавал AutoMigrate.
+type baselineUser struct {
+	gorm.Model
+	Name         string `gorm:"not null"`
+	Surname      string `gorm:"not null"`
+	Email        string `gorm:"uniqueIndex;not null"`
+	PasswordHash string `gorm:"not null"`
+}
+
+func (baselineUser) TableName() string { return "users" }
+
+type baselineSchedule struct {
+	gorm.Model
+	ExternalID string    `gorm:"uniqueIndex"`
+	Name       string    `gorm:"not null"`
+	StartTime  time.Time `gorm:"index;not null"`
+	EndTime    time.Time `gorm:"not null"`
+	GroupIDs   string    `gorm:"not null"`
+}
+
+func (baselineSchedule) TableName() string { return "schedules" }
+
+type baselineQueue struct {
+	gorm.Model
+	ScheduleID      uint      `gorm:"index;not null"`
+	OpensAt         time.Time `gorm:"index"`
+	ClosesAt        time.Time `gorm:"index"`
+	IsActive        bool      `gorm:"default:false"`
+	MaxParticipants int
+}
+
+func (baselineQueue) TableName() string { return "queues" }
+
+type baselineQueueEntry struct {
+	gorm.Model
+	UserID   uint         `gorm:"index;not null"`
+	User     baselineUser `gorm:"foreignKey:UserID"`
+	QueueID  uint         `gorm:"index;not null"`
+	Position int          `gorm:"index;not null"`
+	ExitedAt *time.Time
+}
+
+func (baselineQueueEntry) TableName() string { return "queue_entries" }
+
+// TestMigrationsFromBaseline применяет миграции к базе, созданной AutoMigrate первой версии сервера:
+// недостающие столбцы добавляются, а данные сохраняются.
+func TestMigrationsFromBaseline(t *testing.T) {
+	_, db := testDatabase(t)
+	require.NoError(t, db.AutoMigrate(&baselineUser{}, &baselineSchedule{}, &baselineQueue{}, &baselineQueueEntry{}))
+
+	user := baselineUser{Name: "Иван", Surname: "Иванов", Email: "baseline@example.com", PasswordHash: "x"}
+	require.NoError(t, db.Create(&user).Error)
+	schedule := baselineSchedule{ExternalID: "1", Name: "Физика", StartTime: time.Now(), EndTime: time.Now().Add(time.Hour), GroupIDs: "1"}
+	require.NoError(t, db.Create(&schedule).Error)
+	queue := baselineQueue{ScheduleID: schedule.ID, IsActive: true}
+	require.NoError(t, db.Create(&queue).Error)
+	exitedAt := time.Now()
+	waiting := baselineQueueEntry{UserID: user.ID, QueueID: queue.ID, Position: 1}
+	left := baselineQueueEntry{UserID: user.ID, QueueID: queue.ID, Position: 2, ExitedAt: &exitedAt}
+	require.NoError(t, db.Create(&waiting).Error)
+	require.NoError(t, db.Create(&left).Error)
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	m, err := migrations.New(sqlDB)
+	require.NoError(t, err)
+	applied, err := m.Up(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, applied, len(m.Migrations))
+
+	for _, column := range []string{"Role", "GroupID", "Language", "TelegramChatID", "TokenVersion", "EmailVerified", "EmailVerifiedAt", "TOTPSecret", "TOTPEnabled", "TOTPEnabledAt", "OIDCSubject"} {
+		assert.True(t, db.Migrator().HasColumn(&models.User{}, column), column)
+	}
+	for _, column := range []string{"Status", "ServedByID", "ServedAt"} {
+		assert.True(t, db.Migrator().HasColumn(&models.QueueEntry{}, column), column)
+	}
+	assert.True(t, db.Migrator().HasIndex(&models.User{}, "idx_users_group_id"))
+	assert.True(t, db.Migrator().HasConstraint(&models.QueueEntry{}, "fk_queue_entries_served_by"))
+
+	var migrated models.User
+	require.NoError(t, db.First(&migrated, user.ID).Error)
+	assert.Equal(t, "baseline@example.com", migrated.Email)
+	assert.Equal(t, models.RoleStudent, migrated.Role)
+	assert.Equal(t, "ru", migrated.Language)
+	// Зарегистрированные до появления подтверждения email считаются подтверждёнными с момента регистрации.
+	assert.True(t, migrated.EmailVerified)
+	if assert.NotNil(t, migrated.EmailVerifiedAt) {
+		assert.WithinDuration(t, user.CreatedAt, *migrated.EmailVerifiedAt, time.Millisecond)
+	}
+
+	var entries []models.QueueEntry
+	require.NoError(t, db.Order("position").Find(&entries).Error)
+	require.Len(t, entries, 2)
+	assert.Equal(t, models.EntryStatusWaiting, entries[0].Status)
+	assert.Equal(t, models.EntryStatusLeft, entries[1].Status)
+}
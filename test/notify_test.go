@@ -0,0 +1,94 @@
+package test
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"test_hack/internal/models"
+	"test_hack/internal/notify"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationTemplates(t *testing.T) {
+	schedule := models.Schedule{Name: "Практика по Go", StartTime: time.Date(2025, 3, 1, 10, 30, 0, 0, time.Local)}
+	queue := models.Queue{ClosesAt: schedule.StartTime}
+	queue.ID = 7
+
+	msg := notify.TurnNearMessage(schedule, queue, 1)
+	assert.Equal(t, notify.KindTurnNear, msg.Kind)
+	assert.Equal(t, uint(7), msg.QueueID)
+	assert.Contains(t, msg.Body, "Вы следующий", "Текст по умолчанию должен быть на русском")
+
+	title, body := msg.Localized(notify.LangEN)
+	assert.Equal(t, "Your turn is near", title)
+	assert.Contains(t, body, `"Практика по Go"`)
+
+	_, body = notify.TurnNearMessage(schedule, queue, 3).Localized(notify.LangEN)
+	assert.Contains(t, body, "number 3")
+
+	subject, body, err := notify.Render(notify.LangRU, notify.TemplatePasswordReset, map[string]interface{}{
+		"Name": "Иван", "URL": "http://localhost/reset?token=abc", "ExpiresIn": "1 час",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Восстановление пароля", subject)
+	assert.Contains(t, body, "http://localhost/reset?token=abc")
+
+	_, _, err = notify.Render(notify.LangEN, "unknown_template", nil)
+	assert.Error(t, err, "Неизвестный шаблон должен возвращать ошибку")
+}
+
+func TestEmailChannelWithCaptureMailers(t *testing.T) {
+	user := models.User{Email: "student@example.com", Language: notify.LangEN}
+	schedule := models.Schedule{Name: "Lab 1", StartTime: time.Now().Add(time.Hour)}
+	msg := notify.EventSoonMessage(schedule, models.Queue{}, 2)
+
+	memory := &notify.MemoryMailer{}
+	assert.NoError(t, notify.EmailChannel{Mailer: memory}.Send(user, msg))
+	sent := memory.Messages()
+	if assert.Len(t, sent, 1) {
+		assert.Equal(t, "student@example.com", sent[0].To)
+		assert.Equal(t, "Starting soon: Lab 1", sent[0].Subject)
+	}
+	memory.Reset()
+	assert.Empty(t, memory.Messages())
+
+	dir := t.TempDir()
+	files := &notify.FileMailer{Dir: dir, From: "noreply@example.com"}
+	assert.NoError(t, notify.EmailChannel{Mailer: files}.Send(user, msg))
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if assert.Len(t, matches, 1) {
+		content, err := os.ReadFile(matches[0])
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(content), "From: noreply@example.com\r\n"))
+		assert.Contains(t, string(content), "To: student@example.com")
+	}
+}
+
+func TestEmailChannelSMTPTimeout(t *testing.T) {
+	// Сервер принимает соединение, но не присылает приветствие.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	mailer := &notify.SMTPMailer{Host: host, Port: port, From: "noreply@example.com", Timeout: 200 * time.Millisecond}
+	start := time.Now()
+	err = mailer.Send(notify.Mail{To: "student@example.com", Subject: "s", Body: "b"})
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second, "Send должен прерваться по таймауту")
+}
@@ -0,0 +1,227 @@
+package test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"test_hack/internal/config"
+	"test_hack/internal/models"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockOIDCProvider — минимальный провайдер OpenID Connect: discovery, JWKS и token endpoint с проверкой PKCE.
+type mockOIDCProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu        sync.Mutex
+	challenge string // code_challenge из запроса авторизации
+	nonce     string
+	claims    jwt.MapClaims
+}
+
+const mockOIDCCode = "mock-auth-code"
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &mockOIDCProvider{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                p.server.URL,
+			"authorization_endpoint":                p.server.URL + "/authorize",
+			"token_endpoint":                        p.server.URL + "/token",
+			"jwks_uri":                              p.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test",
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if r.PostForm.Get("code") != mockOIDCCode || base64.RawURLEncoding.EncodeToString(sum[:]) != p.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		claims := jwt.MapClaims{
+			"iss":   p.server.URL,
+			"aud":   "queue-app",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"iat":   time.Now().Unix(),
+			"nonce": p.nonce,
+		}
+		for k, v := range p.claims {
+			claims[k] = v
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "test"
+		idToken, _ := token.SignedString(key)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "provider-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     idToken,
+		})
+	})
+	p.server = httptest.NewServer(mux)
+	return p
+}
+
+// authorize имитирует страницу входа провайдера: запоминает PKCE и nonce из URL авторизации
+// и возвращает state, с которым провайдер перенаправит пользователя обратно.
+func (p *mockOIDCProvider) authorize(t *testing.T, authURL string, claims jwt.MapClaims) string {
+	u, err := url.Parse(authURL)
+	if !assert.NoError(t, err) {
+		return ""
+	}
+	q := u.Query()
+	assert.Equal(t, "S256", q.Get("code_challenge_method"))
+	p.mu.Lock()
+	p.challenge = q.Get("code_challenge")
+	p.nonce = q.Get("nonce")
+	p.claims = claims
+	p.mu.Unlock()
+	return q.Get("state")
+}
+
+func TestOIDCLogin(t *testing.T) {
+	ts, a := setupTestServer(t)
+	provider := newMockOIDCProvider(t)
+	defer provider.server.Close()
+
+	a.Handler.Config.OIDC = config.OIDC{
+		IssuerURL:    provider.server.URL,
+		ClientID:     "queue-app",
+		ClientSecret: "secret",
+		RedirectURL:  ts.URL + "/auth/oidc/callback",
+		AllowSignup:  true,
+	}
+	if err := a.Handler.InitOIDC(context.Background()); !assert.NoError(t, err) {
+		return
+	}
+
+	// Неподтверждённый аккаунт с чужим email: у его автора есть пароль, 2FA и активная сессия.
+	email := fmt.Sprintf("sso_%d@example.com", time.Now().UnixNano())
+	user := models.User{Name: "Мария", Surname: "Кузнецова", Email: email, PasswordHash: "x", TOTPEnabled: true, TOTPSecret: "JBSWY3DPEHPK3PXP"}
+	assert.NoError(t, a.DB.Create(&user).Error)
+	session := models.Session{UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour), LastUsedAt: time.Now()}
+	assert.NoError(t, a.DB.Create(&session).Error)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	login := func(claims jwt.MapClaims) (string, *http.Response) {
+		res, err := client.Get(ts.URL + "/auth/oidc/login")
+		if !assert.NoError(t, err) {
+			return "", nil
+		}
+		res.Body.Close()
+		assert.Equal(t, http.StatusFound, res.StatusCode)
+		state := provider.authorize(t, res.Header.Get("Location"), claims)
+		res, err = client.Get(ts.URL + "/auth/oidc/callback?code=" + mockOIDCCode + "&state=" + url.QueryEscape(state))
+		if !assert.NoError(t, err) {
+			return state, nil
+		}
+		return state, res
+	}
+
+	// Существующий пользователь привязывается по подтверждённому email.
+	state, res := login(jwt.MapClaims{"sub": "sso-1", "email": email, "email_verified": true})
+	if !assert.NotNil(t, res) {
+		return
+	}
+	var tokens map[string]interface{}
+	json.NewDecoder(res.Body).Decode(&tokens)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.NotEmpty(t, tokens["access_token"])
+
+	var linked models.User
+	a.DB.First(&linked, user.ID)
+	if assert.NotNil(t, linked.OIDCSubject) {
+		assert.Equal(t, "sso-1", *linked.OIDCSubject)
+	}
+	assert.True(t, linked.EmailVerified)
+	// Всё, что задал автор неподтверждённой регистрации, сброшено.
+	assert.NotEqual(t, "x", linked.PasswordHash)
+	assert.False(t, linked.TOTPEnabled)
+	assert.Empty(t, linked.TOTPSecret)
+	assert.Equal(t, user.TokenVersion+1, linked.TokenVersion)
+	assert.NoError(t, a.DB.First(&session, session.ID).Error)
+	assert.NotNil(t, session.RevokedAt)
+
+	// Подтверждённый аккаунт привязывается без изменения пароля.
+	verifiedEmail := "verified_" + email
+	verified := models.User{Name: "Олег", Surname: "Орлов", Email: verifiedEmail, PasswordHash: "y", EmailVerified: true}
+	assert.NoError(t, a.DB.Create(&verified).Error)
+	_, res = login(jwt.MapClaims{"sub": "sso-4", "email": verifiedEmail, "email_verified": true})
+	if assert.NotNil(t, res) {
+		res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	}
+	assert.NoError(t, a.DB.First(&verified, verified.ID).Error)
+	assert.Equal(t, "y", verified.PasswordHash)
+	if assert.NotNil(t, verified.OIDCSubject) {
+		assert.Equal(t, "sso-4", *verified.OIDCSubject)
+	}
+
+	// state одноразовый.
+	res, err := client.Get(ts.URL + "/auth/oidc/callback?code=" + mockOIDCCode + "&state=" + url.QueryEscape(state))
+	if assert.NoError(t, err) {
+		res.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	}
+
+	// Неподтверждённый провайдером email не связывается с существующим пользователем.
+	_, res = login(jwt.MapClaims{"sub": "sso-2", "email": email, "email_verified": false})
+	if assert.NotNil(t, res) {
+		res.Body.Close()
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+	}
+
+	// Новый пользователь создаётся по данным из ID токена.
+	newEmail := "new_" + email
+	_, res = login(jwt.MapClaims{"sub": "sso-3", "email": newEmail, "email_verified": true, "given_name": "Иван", "family_name": "Сидоров"})
+	if assert.NotNil(t, res) {
+		res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	}
+	var created models.User
+	if assert.NoError(t, a.DB.Where("email = ?", newEmail).First(&created).Error) {
+		assert.Equal(t, "Иван", created.Name)
+		assert.Equal(t, models.RoleStudent, created.Role)
+	}
+}
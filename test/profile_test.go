@@ -0,0 +1,189 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"test_hack/internal/app"
+	"test_hack/internal/audit"
+	"test_hack/internal/models"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestProfileUpdateAndPasswordChange(t *testing.T) {
+	ts, a := setupTestServer(t)
+
+	email := fmt.Sprintf("profile_%d@example.com", time.Now().UnixNano())
+	hash, _ := bcrypt.GenerateFromPassword([]byte("oldpass1"), bcrypt.MinCost)
+	user := models.User{Name: "Анна", Surname: "Иванова", Email: email, PasswordHash: string(hash), GroupID: "67"}
+	assert.NoError(t, a.DB.Create(&user).Error)
+
+	code, profile := doJSONAs(t, a, http.MethodPut, ts.URL+"/profile/", user.ID, map[string]string{"surname": "Петрова", "group_id": "203"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Анна", profile["name"])
+	assert.Equal(t, "Петрова", profile["surname"])
+	assert.Equal(t, "203", profile["group_id"])
+
+	code, _ = doJSONAs(t, a, http.MethodPut, ts.URL+"/profile/", user.ID, map[string]string{"name": "  "})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	_, tokens := postJSON(t, ts.URL+"/auth/login", map[string]string{"email": email, "password": "oldpass1"})
+
+	code, body := postJSONAs(t, a, ts.URL+"/profile/password", user.ID, map[string]string{"old_password": "wrong", "new_password": "newpass1"})
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, "INVALID_CREDENTIALS", body["code"])
+
+	code, body = postJSONAs(t, a, ts.URL+"/profile/password", user.ID, map[string]string{"old_password": "oldpass1", "new_password": "newpass1"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotEmpty(t, body["refresh_token"])
+
+	code, _ = postJSON(t, ts.URL+"/auth/refresh", map[string]interface{}{"refresh_token": tokens["refresh_token"]})
+	assert.Equal(t, http.StatusUnauthorized, code, "Старые refresh токены должны быть отозваны")
+	code, _ = postJSON(t, ts.URL+"/auth/refresh", map[string]interface{}{"refresh_token": body["refresh_token"]})
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestDeleteProfileLeavesQueuesAndAnonymises(t *testing.T) {
+	ts, a := setupTestServer(t)
+
+	now := time.Now()
+	schedule := models.Schedule{ExternalID: "9998", Name: "Пара", StartTime: now.Add(time.Hour), EndTime: now.Add(2 * time.Hour), GroupIDs: "1"}
+	assert.NoError(t, a.DB.Create(&schedule).Error)
+	queue := models.Queue{ScheduleID: schedule.ID, OpensAt: now, ClosesAt: schedule.StartTime, IsActive: true}
+	assert.NoError(t, a.DB.Create(&queue).Error)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret12"), bcrypt.MinCost)
+	leaving := models.User{Name: "Олег", Surname: "Олегов", Email: fmt.Sprintf("leave_%d@example.com", now.UnixNano()), PasswordHash: string(hash)}
+	staying := models.User{Name: "Ирина", Surname: "Иринина", Email: fmt.Sprintf("stay_%d@example.com", now.UnixNano()), PasswordHash: string(hash)}
+	assert.NoError(t, a.DB.Create(&leaving).Error)
+	assert.NoError(t, a.DB.Create(&staying).Error)
+	assert.NoError(t, a.DB.Create(&models.QueueEntry{UserID: leaving.ID, QueueID: queue.ID, Position: 1}).Error)
+	assert.NoError(t, a.DB.Create(&models.QueueEntry{UserID: staying.ID, QueueID: queue.ID, Position: 2}).Error)
+	// Выгрузка персональных данных и история напоминаний удаляются вместе с аккаунтом.
+	assert.NoError(t, a.DB.Create(&models.DataExport{UserID: leaving.ID, Format: "json", Status: models.DataExportStatusReady, Data: []byte(`{"email":"x"}`), ExpiresAt: now.Add(time.Hour)}).Error)
+	assert.NoError(t, a.DB.Create(&models.Notification{UserID: leaving.ID, QueueID: queue.ID, Kind: "turn_near", Title: "t", Body: "b", SentAt: now}).Error)
+	// Неудачный вход оставляет в журнале email, IP и User-Agent пользователя.
+	code, _ := postJSON(t, ts.URL+"/auth/login", map[string]string{"email": leaving.Email, "password": "wrong"})
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, _ = doJSONAs(t, a, http.MethodDelete, ts.URL+"/profile/", leaving.ID, map[string]string{"password": "wrong"})
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, _ = doJSONAs(t, a, http.MethodDelete, ts.URL+"/profile/", leaving.ID, map[string]string{"password": "secret12"})
+	assert.Equal(t, http.StatusOK, code)
+
+	var entry models.QueueEntry
+	assert.NoError(t, a.DB.Where("user_id = ? AND queue_id = ?", staying.ID, queue.ID).First(&entry).Error)
+	assert.Equal(t, 1, entry.Position, "Позиции после удалённого пользователя должны сдвинуться")
+
+	var left models.QueueEntry
+	assert.NoError(t, a.DB.Where("user_id = ? AND queue_id = ?", leaving.ID, queue.ID).First(&left).Error)
+	assert.NotNil(t, left.ExitedAt)
+	assert.Equal(t, models.EntryStatusLeft, left.Status)
+
+	var anonymised models.User
+	assert.NoError(t, a.DB.First(&anonymised, leaving.ID).Error)
+	assert.NotEqual(t, leaving.Email, anonymised.Email)
+	assert.NotEqual(t, "Олег", anonymised.Name)
+	assert.Empty(t, anonymised.PasswordHash)
+	for _, model := range []interface{}{&models.DataExport{}, &models.Notification{}} {
+		var count int64
+		assert.NoError(t, a.DB.Unscoped().Model(model).Where("user_id = ?", leaving.ID).Count(&count).Error)
+		assert.Zero(t, count)
+	}
+
+	code, _ = postJSON(t, ts.URL+"/auth/login", map[string]string{"email": leaving.Email, "password": "secret12"})
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// В журнале аудита не остаётся персональных данных удалённого пользователя.
+	var events []models.AuditEvent
+	assert.NoError(t, a.DB.Where("user_id = ?", leaving.ID).Find(&events).Error)
+	assert.NotEmpty(t, events)
+	var deleted bool
+	for _, e := range events {
+		assert.Empty(t, e.Email, e.Action)
+		assert.Empty(t, e.IP, e.Action)
+		assert.Empty(t, e.UserAgent, e.Action)
+		deleted = deleted || e.Action == audit.ActionAccountDeleted
+	}
+	assert.True(t, deleted)
+	var mentions int64
+	assert.NoError(t, a.DB.Model(&models.AuditEvent{}).Where("LOWER(email) = LOWER(?)", leaving.Email).Count(&mentions).Error)
+	assert.Zero(t, mentions)
+}
+
+// doBearerJSON отправляет JSON с access токеном и возвращает код ответа и разобранное тело.
+func doBearerJSON(t *testing.T, method, url, accessToken string, body interface{}) (int, map[string]interface{}) {
+	data, _ := json.Marshal(body)
+	req, _ := http.NewRequest(method, url, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	res, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return 0, nil
+	}
+	defer res.Body.Close()
+	var result map[string]interface{}
+	json.NewDecoder(res.Body).Decode(&result)
+	return res.StatusCode, result
+}
+
+// sessionToken выпускает access токен, привязанный к сессии session.
+func sessionToken(t *testing.T, a *app.App, session models.Session) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": session.UserID,
+		"sid":     session.ID,
+		"exp":     time.Now().Add(time.Hour).Unix(),
+		"iat":     time.Now().Unix(),
+	}).SignedString([]byte(a.Config.JWT.AccessSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestDeleteProfileConfirmation(t *testing.T) {
+	ts, a := setupTestServer(t)
+	now := time.Now()
+
+	// Пользователь с 2FA подтверждает удаление кодом из приложения.
+	secret := "JBSWY3DPEHPK3PXP"
+	withMFA := models.User{Name: "Игорь", Surname: "Зайцев", Email: fmt.Sprintf("delete_mfa_%d@example.com", now.UnixNano()), PasswordHash: "x", TOTPEnabled: true, TOTPSecret: secret}
+	assert.NoError(t, a.DB.Create(&withMFA).Error)
+	code, body := doJSONAs(t, a, http.MethodDelete, ts.URL+"/profile/", withMFA.ID, map[string]string{"code": "000000"})
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, "INVALID_MFA_CODE", body["code"])
+	totpCode, _ := totp.GenerateCode(secret, time.Now())
+	code, _ = doJSONAs(t, a, http.MethodDelete, ts.URL+"/profile/", withMFA.ID, map[string]string{"code": totpCode})
+	assert.Equal(t, http.StatusOK, code)
+
+	// Пользователь, созданный через SSO, не знает своего пароля и подтверждает удаление свежим входом через SSO.
+	sso := models.User{Name: "Мария", Surname: "Кузнецова", Email: fmt.Sprintf("delete_sso_%d@example.com", now.UnixNano()), PasswordHash: "x"}
+	assert.NoError(t, a.DB.Create(&sso).Error)
+	stale := models.Session{UserID: sso.ID, Method: models.SessionMethodSSO, LastUsedAt: now, ExpiresAt: now.Add(time.Hour)}
+	stale.CreatedAt = now.Add(-time.Hour)
+	byPassword := models.Session{UserID: sso.ID, Method: models.SessionMethodPassword, LastUsedAt: now, ExpiresAt: now.Add(time.Hour)}
+	fresh := models.Session{UserID: sso.ID, Method: models.SessionMethodSSO, LastUsedAt: now, ExpiresAt: now.Add(time.Hour)}
+	for _, session := range []*models.Session{&stale, &byPassword, &fresh} {
+		assert.NoError(t, a.DB.Create(session).Error)
+	}
+
+	for _, session := range []models.Session{stale, byPassword} {
+		code, body = doBearerJSON(t, http.MethodDelete, ts.URL+"/profile/", sessionToken(t, a, session), map[string]string{})
+		assert.Equal(t, http.StatusUnauthorized, code)
+		assert.Equal(t, "REAUTHENTICATION_REQUIRED", body["code"])
+	}
+
+	code, _ = doBearerJSON(t, http.MethodDelete, ts.URL+"/profile/", sessionToken(t, a, fresh), map[string]string{})
+	assert.Equal(t, http.StatusOK, code)
+	var anonymised models.User
+	assert.NoError(t, a.DB.First(&anonymised, sso.ID).Error)
+	assert.NotEqual(t, sso.Email, anonymised.Email)
+}
@@ -0,0 +1,62 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+	"test_hack/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Одновременные выходы и приёмы не должны оставлять пропусков и повторов в позициях.
+func TestConcurrentLeaveAndServeKeepPositions(t *testing.T) {
+	_, a := setupTestServer(t)
+
+	now := time.Now()
+	schedule := models.Schedule{ExternalID: "9996", Name: "Химия", StartTime: now.Add(time.Hour), EndTime: now.Add(2 * time.Hour), GroupIDs: "1"}
+	require.NoError(t, a.DB.Create(&schedule).Error)
+	queue := models.Queue{ScheduleID: schedule.ID, OpensAt: now, ClosesAt: schedule.StartTime, IsActive: true}
+	require.NoError(t, a.DB.Create(&queue).Error)
+
+	teacher := models.User{Name: "Преподаватель", Surname: "Т", Email: fmt.Sprintf("exit_teacher_%d@example.com", now.UnixNano()), PasswordHash: "x", Role: models.RoleTeacher}
+	require.NoError(t, a.DB.Create(&teacher).Error)
+
+	svc := a.Handler.Queues()
+	var users []models.User
+	for i := 0; i < 8; i++ {
+		user := models.User{Name: "Участник", Surname: fmt.Sprint(i), Email: fmt.Sprintf("exit_%d_%d@example.com", i, now.UnixNano()), PasswordHash: "x", EmailVerified: true}
+		require.NoError(t, a.DB.Create(&user).Error)
+		_, err := svc.Join(user.ID, queue.ID)
+		require.NoError(t, err)
+		users = append(users, user)
+	}
+
+	var wg sync.WaitGroup
+	for _, user := range users[2:6] {
+		wg.Add(1)
+		go func(userID uint) {
+			defer wg.Done()
+			_, err := svc.Leave(userID, queue.ID)
+			assert.NoError(t, err)
+		}(user.ID)
+	}
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := svc.Serve(queue.ID, 0, teacher.ID)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	var entries []models.QueueEntry
+	require.NoError(t, a.DB.Where("queue_id = ? AND exited_at IS NULL", queue.ID).Order("position").Find(&entries).Error)
+	require.Len(t, entries, 2)
+	for i, e := range entries {
+		assert.Equal(t, i+1, e.Position)
+	}
+}
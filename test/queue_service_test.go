@@ -0,0 +1,478 @@
+package test
+
+import (
+	"errors"
+	"sort"
+	"test_hack/internal/models"
+	"test_hack/internal/repository"
+	"test_hack/internal/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// Реализации хранилищ в памяти для тестов сервиса очередей без базы данных.
+
+type fakeQueueRepo struct {
+	queues  map[uint]*models.Queue
+	entries []*models.QueueEntry
+	nextID  uint
+	err     error // Если задана, FindByID возвращает эту ошибку
+}
+
+func newFakeQueueRepo() *fakeQueueRepo {
+	return &fakeQueueRepo{queues: map[uint]*models.Queue{}}
+}
+
+func (r *fakeQueueRepo) id() uint {
+	r.nextID++
+	return r.nextID
+}
+
+func (r *fakeQueueRepo) FindByID(id uint) (models.Queue, error) {
+	if r.err != nil {
+		return models.Queue{}, r.err
+	}
+	if q, ok := r.queues[id]; ok {
+		return *q, nil
+	}
+	return models.Queue{}, repository.ErrNotFound
+}
+
+func (r *fakeQueueRepo) FindByScheduleID(scheduleID uint) (models.Queue, error) {
+	for _, q := range r.queues {
+		if q.ScheduleID == scheduleID {
+			return *q, nil
+		}
+	}
+	return models.Queue{}, repository.ErrNotFound
+}
+
+func (r *fakeQueueRepo) ListActive() ([]models.Queue, error) {
+	var result []models.Queue
+	for _, q := range r.queues {
+		if q.IsActive {
+			result = append(result, *q)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeQueueRepo) ActiveLengths() (map[uint]int64, error) {
+	lengths := map[uint]int64{}
+	for id, q := range r.queues {
+		if q.IsActive {
+			lengths[id] = 0
+		}
+	}
+	for _, e := range r.entries {
+		if _, ok := lengths[e.QueueID]; ok && e.ExitedAt == nil {
+			lengths[e.QueueID]++
+		}
+	}
+	return lengths, nil
+}
+
+func (r *fakeQueueRepo) ListExpired(now time.Time) ([]models.Queue, error) {
+	var result []models.Queue
+	for _, q := range r.queues {
+		if q.IsActive && !q.ClosesAt.After(now) {
+			result = append(result, *q)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeQueueRepo) Create(queue *models.Queue) error {
+	queue.ID = r.id()
+	q := *queue
+	r.queues[q.ID] = &q
+	return nil
+}
+
+func (r *fakeQueueRepo) Close(queueID uint) error {
+	r.queues[queueID].IsActive = false
+	for _, e := range r.entries {
+		if e.QueueID == queueID && e.ExitedAt == nil && e.Status == models.EntryStatusWaiting {
+			e.Status = models.EntryStatusNotServed
+		}
+	}
+	return nil
+}
+
+func (r *fakeQueueRepo) Reopen(queueID uint, opensAt, closesAt time.Time) error {
+	q := r.queues[queueID]
+	q.IsActive, q.OpensAt, q.ClosesAt = true, opensAt, closesAt
+	for _, e := range r.entries {
+		if e.QueueID == queueID && e.ExitedAt == nil && e.Status == models.EntryStatusNotServed {
+			e.Status = models.EntryStatusWaiting
+		}
+	}
+	return nil
+}
+
+func (r *fakeQueueRepo) DeleteClosingBefore(t time.Time) (int64, error) {
+	var n int64
+	for id, q := range r.queues {
+		if q.ClosesAt.Before(t) {
+			delete(r.queues, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *fakeQueueRepo) ActiveEntries(queueID uint) ([]models.QueueEntry, error) {
+	var result []models.QueueEntry
+	for _, e := range r.entries {
+		if e.QueueID == queueID && e.ExitedAt == nil {
+			result = append(result, *e)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Position < result[j].Position })
+	return result, nil
+}
+
+func (r *fakeQueueRepo) ActiveEntry(queueID, userID uint) (models.QueueEntry, error) {
+	for _, e := range r.entries {
+		if e.QueueID == queueID && e.UserID == userID && e.ExitedAt == nil {
+			return *e, nil
+		}
+	}
+	return models.QueueEntry{}, repository.ErrNotFound
+}
+
+func (r *fakeQueueRepo) FirstActiveEntry(queueID uint) (models.QueueEntry, error) {
+	entries, _ := r.ActiveEntries(queueID)
+	if len(entries) == 0 {
+		return models.QueueEntry{}, repository.ErrNotFound
+	}
+	return entries[0], nil
+}
+
+func (r *fakeQueueRepo) ActiveEntriesByUser(userID uint) ([]models.QueueEntry, error) {
+	var result []models.QueueEntry
+	for _, e := range r.entries {
+		if e.UserID == userID && e.ExitedAt == nil {
+			result = append(result, *e)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeQueueRepo) AppendEntry(entry *models.QueueEntry) error {
+	entries, _ := r.ActiveEntries(entry.QueueID)
+	for _, e := range entries {
+		if e.UserID == entry.UserID {
+			return repository.ErrDuplicate
+		}
+	}
+	entry.ID = r.id()
+	entry.Position = len(entries) + 1
+	e := *entry
+	r.entries = append(r.entries, &e)
+	return nil
+}
+
+func (r *fakeQueueRepo) ExitEntry(entry *models.QueueEntry) error {
+	for i, e := range r.entries {
+		if e.ID != entry.ID || e.ExitedAt != nil {
+			continue
+		}
+		entry.Position = e.Position
+		saved := *entry
+		r.entries[i] = &saved
+		for _, other := range r.entries {
+			if other.QueueID == entry.QueueID && other.ExitedAt == nil && other.Position > entry.Position {
+				other.Position--
+			}
+		}
+		return nil
+	}
+	return repository.ErrNotFound
+}
+
+type fakeUserRepo struct {
+	users map[uint]models.User
+}
+
+func (r *fakeUserRepo) FindByID(id uint) (models.User, error) {
+	if u, ok := r.users[id]; ok {
+		return u, nil
+	}
+	return models.User{}, repository.ErrNotFound
+}
+
+func (r *fakeUserRepo) FindByEmail(email string) (models.User, error) {
+	for _, u := range r.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return models.User{}, repository.ErrNotFound
+}
+
+func (r *fakeUserRepo) Create(user *models.User) error {
+	user.ID = uint(len(r.users) + 1)
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *fakeUserRepo) Update(id uint, fields map[string]interface{}) error {
+	if _, ok := r.users[id]; !ok {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+type fakeScheduleRepo struct {
+	schedules []models.Schedule
+}
+
+func (r *fakeScheduleRepo) FindByID(id uint) (models.Schedule, error) {
+	for _, s := range r.schedules {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return models.Schedule{}, repository.ErrNotFound
+}
+
+func (r *fakeScheduleRepo) ListStartingBetween(from, to time.Time) ([]models.Schedule, error) {
+	var result []models.Schedule
+	for _, s := range r.schedules {
+		if !s.StartTime.Before(from) && !s.StartTime.After(to) {
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeScheduleRepo) DeleteEndedBefore(t time.Time) (int64, error) {
+	return 0, nil
+}
+
+type publishedEvent struct {
+	QueueID   uint
+	EventType string
+	Data      interface{}
+}
+
+type recordingPublisher struct {
+	events []publishedEvent
+}
+
+func (p *recordingPublisher) Publish(queueID uint, eventType string, data interface{}) {
+	p.events = append(p.events, publishedEvent{queueID, eventType, data})
+}
+
+func (p *recordingPublisher) types() []string {
+	result := make([]string, 0, len(p.events))
+	for _, e := range p.events {
+		result = append(result, e.EventType)
+	}
+	return result
+}
+
+var serviceNow = time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+func newTestQueueService() (*service.QueueService, *fakeQueueRepo, *recordingPublisher) {
+	queues := newFakeQueueRepo()
+	users := &fakeUserRepo{users: map[uint]models.User{
+		1: {Name: "Иван", Surname: "Иванов", EmailVerified: true},
+		2: {Name: "Пётр", Surname: "Петров"},
+		3: {Name: "Анна", Surname: "Сидорова", EmailVerified: true},
+	}}
+	for id, u := range users.users {
+		u.ID = id
+		users.users[id] = u
+	}
+	schedules := &fakeScheduleRepo{}
+	events := &recordingPublisher{}
+
+	svc := service.NewQueueService(queues, users, schedules, events)
+	svc.Now = func() time.Time { return serviceNow }
+	return svc, queues, events
+}
+
+func openQueue(t *testing.T, queues *fakeQueueRepo) models.Queue {
+	queue := models.Queue{
+		ScheduleID: 100,
+		OpensAt:    serviceNow.Add(-time.Hour),
+		ClosesAt:   serviceNow.Add(time.Hour),
+		IsActive:   true,
+	}
+	require.NoError(t, queues.Create(&queue))
+	return queue
+}
+
+func TestQueueServiceJoinAssignsPositions(t *testing.T) {
+	svc, queues, events := newTestQueueService()
+	queue := openQueue(t, queues)
+
+	pos, err := svc.Join(1, queue.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, pos)
+
+	pos, err = svc.Join(2, queue.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, pos)
+
+	_, err = svc.Join(1, queue.ID)
+	assert.ErrorIs(t, err, service.ErrAlreadyInQueue)
+
+	assert.Equal(t, []string{"user_joined", "user_joined"}, events.types())
+}
+
+func TestQueueServiceJoinRejectsClosedQueue(t *testing.T) {
+	svc, queues, _ := newTestQueueService()
+	queue := openQueue(t, queues)
+	queues.queues[queue.ID].IsActive = false
+
+	_, err := svc.Join(1, queue.ID)
+	assert.ErrorIs(t, err, service.ErrQueueInactive)
+
+	_, err = svc.Join(1, 999)
+	assert.ErrorIs(t, err, service.ErrQueueNotFound)
+
+	// Ошибка хранилища не выдаётся за отсутствие очереди.
+	queues.err = errors.New("connection refused")
+	_, err = svc.Join(1, queue.ID)
+	assert.EqualError(t, err, "connection refused")
+}
+
+func TestQueueServiceJoinRequiresVerifiedEmail(t *testing.T) {
+	svc, queues, _ := newTestQueueService()
+	queue := openQueue(t, queues)
+	svc.RequireVerifiedEmail = func() bool { return true }
+
+	_, err := svc.Join(2, queue.ID)
+	assert.ErrorIs(t, err, service.ErrEmailNotVerified)
+
+	_, err = svc.Join(1, queue.ID)
+	assert.NoError(t, err)
+}
+
+func TestQueueServiceLeaveShiftsPositions(t *testing.T) {
+	svc, queues, events := newTestQueueService()
+	queue := openQueue(t, queues)
+	for _, userID := range []uint{1, 2, 3} {
+		_, err := svc.Join(userID, queue.ID)
+		require.NoError(t, err)
+	}
+
+	pos, err := svc.Leave(1, queue.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, pos)
+
+	status, err := svc.Status(queue.ID)
+	require.NoError(t, err)
+	require.Len(t, status.Participants, 2)
+	assert.Equal(t, uint(2), status.Participants[0].UserID)
+	assert.Equal(t, 1, status.Participants[0].Position)
+	assert.Equal(t, uint(3), status.Participants[1].UserID)
+	assert.Equal(t, 2, status.Participants[1].Position)
+
+	last := events.events[len(events.events)-1]
+	assert.Equal(t, "user_left", last.EventType)
+
+	_, err = svc.Leave(1, queue.ID)
+	assert.ErrorIs(t, err, service.ErrNotInQueue)
+}
+
+func TestQueueServiceServeTakesFirst(t *testing.T) {
+	svc, queues, events := newTestQueueService()
+	queue := openQueue(t, queues)
+
+	_, err := svc.Serve(queue.ID, 0, 10)
+	assert.ErrorIs(t, err, service.ErrQueueEmpty)
+
+	for _, userID := range []uint{1, 2} {
+		_, err := svc.Join(userID, queue.ID)
+		require.NoError(t, err)
+	}
+
+	entry, err := svc.Serve(queue.ID, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), entry.UserID)
+	assert.Equal(t, 1, entry.Position)
+	assert.Equal(t, "user_served", events.events[len(events.events)-1].EventType)
+
+	_, err = svc.Serve(queue.ID, 1, 10)
+	assert.ErrorIs(t, err, service.ErrNotInQueue)
+
+	next, err := queues.FirstActiveEntry(queue.ID)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), next.UserID)
+	assert.Equal(t, 1, next.Position)
+}
+
+func TestQueueServiceCloseExpired(t *testing.T) {
+	svc, queues, events := newTestQueueService()
+	queue := openQueue(t, queues)
+	_, err := svc.Join(1, queue.ID)
+	require.NoError(t, err)
+	queues.queues[queue.ID].ClosesAt = serviceNow.Add(-time.Minute)
+
+	closed, err := svc.CloseExpired()
+	require.NoError(t, err)
+	require.Len(t, closed, 1)
+	assert.False(t, queues.queues[queue.ID].IsActive)
+	assert.Equal(t, models.EntryStatusNotServed, queues.entries[0].Status)
+	assert.Equal(t, "queue_closed", events.events[len(events.events)-1].EventType)
+}
+
+func TestQueueServiceCloseAndReopen(t *testing.T) {
+	svc, queues, events := newTestQueueService()
+	queue := openQueue(t, queues)
+	_, err := svc.Join(1, queue.ID)
+	require.NoError(t, err)
+
+	_, err = svc.Reopen(queue.ID, time.Time{})
+	assert.ErrorIs(t, err, service.ErrQueueActive)
+
+	_, err = svc.Close(queue.ID)
+	require.NoError(t, err)
+	assert.False(t, queues.queues[queue.ID].IsActive)
+	assert.Equal(t, models.EntryStatusNotServed, queues.entries[0].Status)
+	_, err = svc.Close(queue.ID)
+	assert.ErrorIs(t, err, service.ErrQueueInactive)
+
+	_, err = svc.Reopen(queue.ID, serviceNow.Add(-time.Minute))
+	assert.ErrorIs(t, err, service.ErrClosesInPast)
+
+	until := serviceNow.Add(3 * time.Hour)
+	reopened, err := svc.Reopen(queue.ID, until)
+	require.NoError(t, err)
+	assert.True(t, reopened.IsActive)
+	assert.Equal(t, until, queues.queues[queue.ID].ClosesAt)
+	assert.Equal(t, []string{"user_joined", "queue_closed", "queue_update"}, events.types())
+
+	// Участник, которого не успели принять до закрытия, возвращается на своё место.
+	assert.Equal(t, models.EntryStatusWaiting, queues.entries[0].Status)
+	status := events.events[2].Data.(service.QueueStatus)
+	require.Len(t, status.Participants, 1)
+	assert.Equal(t, uint(1), status.Participants[0].UserID)
+}
+
+func TestQueueServiceOpenForUpcomingEvents(t *testing.T) {
+	svc, queues, _ := newTestQueueService()
+	schedules := svc.Schedules.(*fakeScheduleRepo)
+	schedules.schedules = []models.Schedule{
+		{Model: gorm.Model{ID: 100}, Name: "С очередью", StartTime: serviceNow.Add(2 * time.Hour)},
+		{Model: gorm.Model{ID: 101}, Name: "Завтра", StartTime: serviceNow.Add(24 * time.Hour)},
+		{Model: gorm.Model{ID: 102}, Name: "Через неделю", StartTime: serviceNow.Add(7 * 24 * time.Hour)},
+	}
+	openQueue(t, queues)
+
+	created, err := svc.OpenForUpcomingEvents()
+	require.NoError(t, err)
+	require.Len(t, created, 1)
+	assert.Equal(t, uint(101), created[0].ScheduleID)
+	assert.Equal(t, serviceNow, created[0].OpensAt)
+	assert.Equal(t, schedules.schedules[1].StartTime, created[0].ClosesAt)
+	assert.True(t, created[0].IsActive)
+}
@@ -1,100 +1,116 @@
 package test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"strconv"
-	"test_hack/internal/handlers"
+	"test_hack/internal/app"
+	"test_hack/internal/config"
 	"test_hack/internal/models"
 	"test_hack/internal/storage"
 	"test_hack/internal/tasks"
 	"testing"
 	"time"
 
-	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/websocket"
-	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
 )
 
-func AuthMiddlewareTest() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		userIDStr := c.Request.Header.Get("X-Test-UserID")
-		if userIDStr == "" {
-			// Значение по умолчанию
-			c.Set("userID", uint(1))
-		} else {
-			// Попытка сконвертировать строку в число
-			id, err := strconv.Atoi(userIDStr)
-			if err != nil {
-				c.Set("userID", uint(1))
-			} else {
-				c.Set("userID", uint(id))
-			}
+// testDatabase создаёт для теста отдельную схему в тестовой базе и удаляет её после теста, поэтому тесты
+// не мешают друг другу и не трогают чужие таблицы. Возвращает настройки, в которых Database указывает
+// на эту схему, а Redis — на тестовую базу Redis, очищенную перед тестом. Тесты делят тестовую базу Redis
+// (ключи ограничений запросов и блокировок входа), поэтому не помечаются t.Parallel.
+func testDatabase(t *testing.T) (config.Config, *gorm.DB) {
+	// Основная база тестам не нужна, поэтому настройки читаются без проверки Validate.
+	cfg, err := config.Read("", "../.env")
+	require.NoError(t, err)
+
+	admin, err := storage.Open(cfg.TestDatabase.DSN())
+	require.NoError(t, err, "Ошибка подключения к тестовой базе данных")
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	require.NoError(t, admin.Exec("CREATE SCHEMA "+schema).Error)
+	t.Cleanup(func() {
+		if err := admin.Exec("DROP SCHEMA " + schema + " CASCADE").Error; err != nil {
+			t.Errorf("Ошибка удаления схемы %s: %v", schema, err)
 		}
-		c.Next()
-	}
-}
-
-func setupTestServer() *httptest.Server {
-	key := os.Getenv("ENV_CHEK")
-	if key == "" {
-		fmt.Println("Подключение к .env")
-		err := godotenv.Load("../.env")
-		if err != nil {
-			log.Fatal("Ошибка получения .env")
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
 		}
-	}
-
-	storage.ConnectTestingDatabase()
-	storage.DB.Exec("TRUNCATE TABLE users, schedules, queues, queue_entries RESTART IDENTITY CASCADE;")
-
-	if err := storage.DB.AutoMigrate(&models.User{}, &models.Schedule{}, &models.Queue{}, &models.QueueEntry{}); err != nil {
-		log.Fatal("Ошибка при миграции... ", err.Error())
-	}
-
-	storage.InitRedis()
-	tasks.InitScheduler()
-
-	go handlers.HubInstance.Run()
-
-	r := gin.Default()
+	})
+
+	cfg.Database = cfg.TestDatabase
+	cfg.Database.Schema = schema
+	db, err := storage.Open(cfg.Database.DSN())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		// Обычно соединения уже закрыты в App.Shutdown; повторное закрытие ничего не делает.
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
 
-	authGroup := r.Group("/auth")
-	{
-		authGroup.POST("/login", handlers.Login)
-		authGroup.POST("/register", handlers.Register)
-		authGroup.POST("/refresh", handlers.RefreshToken)
+	redisCfg := cfg.TestRedis
+	if redisCfg.Addr == "" {
+		redisCfg.Addr, redisCfg.Password = cfg.Redis.Addr, cfg.Redis.Password
 	}
+	cfg.Redis = redisCfg
+	rdb := storage.NewRedis(cfg.Redis.Addr, cfg.Redis.Password, cfg.Redis.DB)
+	require.NoError(t, rdb.FlushDB(context.Background()).Err(), "Ошибка очистки тестовой базы Redis")
+	rdb.Close()
+	return cfg, db
+}
 
-	apiGroup := r.Group("")
-	{
-		apiGroup.GET("/groups", handlers.GetGroupsHandler)
-		apiGroup.GET("/schedule", handlers.GetFullScheduleHandler)
-	}
+// setupTestServer поднимает приложение на отдельной схеме тестовой базы и HTTP-сервер с его роутером
+// (a.Router): запросы проходят те же middleware, что и в работе, — авторизацию по токену, проверку ролей
+// и ограничения запросов. Приложение принимает события queuectl (ListenEvents); планировщик
+// не запускается: тесты вызывают задачи напрямую. После теста сервер закрывается, а приложение
+// останавливается через Shutdown.
+func setupTestServer(t *testing.T) (*httptest.Server, *app.App) {
+	cfg, db := testDatabase(t)
+	// Результат тестов не зависит от .env разработчика: тесты, которым нужны эти проверки, включают их сами.
+	cfg.Accounts.RequireEmailVerification = false
+	cfg.MFA.RequiredRoles = nil
+	a := app.New(cfg, db, storage.NewRedis(cfg.Redis.Addr, cfg.Redis.Password, cfg.Redis.DB))
+	require.NoError(t, a.Migrate(context.Background()), "Ошибка при миграции")
+	go a.Hub.Run()
+	require.NoError(t, a.ListenEvents(context.Background()), "Ошибка подписки на события queuectl")
+
+	ts := httptest.NewServer(a.Router)
+	t.Cleanup(func() {
+		ts.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := a.Shutdown(ctx); err != nil {
+			t.Errorf("Ошибка остановки приложения: %v", err)
+		}
+	})
+	return ts, a
+}
 
-	r.GET("/api/queues/:id/status", handlers.GetQueueStatusHandler)
-	queues := r.Group("/api/queues", AuthMiddlewareTest())
-	{
-		queues.POST("/:id/join", handlers.JoinQueueHandler)
-		queues.POST("/:id/leave", handlers.LeaveQueueHandler)
-		queues.GET("/:id/ws", handlers.QueueWebSocketHandler)
+// accessToken выпускает access токен пользователя userID, подписанный ключом тестового приложения.
+// Токен без sid не привязан к сессии, поэтому сессию создавать не нужно.
+func accessToken(t *testing.T, a *app.App, userID uint) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": userID,
+		"exp":     time.Now().Add(time.Hour).Unix(),
+		"iat":     time.Now().Unix(),
+	}).SignedString([]byte(a.Config.JWT.AccessSecret))
+	if err != nil {
+		t.Fatal(err)
 	}
-
-	return httptest.NewServer(r)
+	return token
 }
 
 func TestQueueFlow(t *testing.T) {
 	// Настройка сервера
-	ts := setupTestServer()
-	defer ts.Close()
-
-	log.Println("Тест: очистка базы перед запуском теста")
-	// (База очищается в setupTestServer())
+	ts, a := setupTestServer(t)
 
 	// 1. Создаем тестовое расписание и очередь вручную.
 	now := time.Now()
@@ -105,7 +121,7 @@ func TestQueueFlow(t *testing.T) {
 		EndTime:    now.Add(3 * time.Minute),
 		GroupIDs:   "1,2",
 	}
-	err := storage.DB.Create(&testSchedule).Error
+	err := a.DB.Create(&testSchedule).Error
 	assert.NoError(t, err, "Ошибка создания тестового расписания")
 	log.Println("Тестовое расписание создано, ID:", testSchedule.ID)
 
@@ -115,7 +131,7 @@ func TestQueueFlow(t *testing.T) {
 		ClosesAt:   testSchedule.StartTime,
 		IsActive:   true,
 	}
-	err = storage.DB.Create(&testQueue).Error
+	err = a.DB.Create(&testQueue).Error
 	assert.NoError(t, err, "Ошибка создания тестовой очереди")
 	log.Println("Тестовая очередь создана, ID:", testQueue.ID)
 
@@ -124,9 +140,9 @@ func TestQueueFlow(t *testing.T) {
 	user2Email := fmt.Sprintf("petr_%d@example.com", time.Now().UnixNano())
 	user1 := models.User{Name: "Иван", Surname: "Иванов", Email: user1Email, PasswordHash: "hashed123"}
 	user2 := models.User{Name: "Петр", Surname: "Петров", Email: user2Email, PasswordHash: "hashed456"}
-	err = storage.DB.Create(&user1).Error
+	err = a.DB.Create(&user1).Error
 	assert.NoError(t, err, "Ошибка создания пользователя 1")
-	err = storage.DB.Create(&user2).Error
+	err = a.DB.Create(&user2).Error
 	assert.NoError(t, err, "Ошибка создания пользователя 2")
 	log.Println("Тестовые пользователи созданы, ID1:", user1.ID, "ID2:", user2.ID)
 
@@ -135,7 +151,7 @@ func TestQueueFlow(t *testing.T) {
 
 	log.Println("Отправка запроса join для пользователя 1")
 	req1, _ := http.NewRequest("POST", joinURL, nil)
-	req1.Header.Set("X-Test-UserID", fmt.Sprintf("%d", user1.ID))
+	req1.Header.Set("Authorization", "Bearer "+accessToken(t, a, user1.ID))
 	res1, err := http.DefaultClient.Do(req1)
 	assert.NoError(t, err, "Ошибка запроса join для пользователя 1")
 	defer res1.Body.Close()
@@ -143,7 +159,7 @@ func TestQueueFlow(t *testing.T) {
 
 	log.Println("Отправка запроса join для пользователя 2")
 	req2, _ := http.NewRequest("POST", joinURL, nil)
-	req2.Header.Set("X-Test-UserID", fmt.Sprintf("%d", user2.ID))
+	req2.Header.Set("Authorization", "Bearer "+accessToken(t, a, user2.ID))
 	res2, err := http.DefaultClient.Do(req2)
 	assert.NoError(t, err, "Ошибка запроса join для пользователя 2")
 	defer res2.Body.Close()
@@ -151,10 +167,7 @@ func TestQueueFlow(t *testing.T) {
 
 	// 4. Проверка состояния очереди через HTTP GET /api/queues/:id/status
 	statusURL := ts.URL + "/api/queues/" + strconv.Itoa(int(testQueue.ID)) + "/status"
-	statusReq, _ := http.NewRequest("GET", statusURL, nil)
-	// Для тестовой авторизации можно установить X-Test-UserID, если требуется
-	statusReq.Header.Set("X-Test-UserID", fmt.Sprintf("%d", user1.ID))
-	statusRes, err := http.DefaultClient.Do(statusReq)
+	statusRes, err := http.Get(statusURL)
 	assert.NoError(t, err, "Ошибка запроса статуса очереди")
 	defer statusRes.Body.Close()
 	assert.Equal(t, http.StatusOK, statusRes.StatusCode, "Ошибка получения статуса очереди")
@@ -171,9 +184,7 @@ func TestQueueFlow(t *testing.T) {
 	// 5. Тестируем WS-соединение для очереди.
 	wsURL := "ws" + ts.URL[4:] + "/api/queues/" + strconv.Itoa(int(testQueue.ID)) + "/ws"
 	dialer := websocket.Dialer{}
-	wsHeaders := http.Header{}
-	wsHeaders.Set("X-Test-UserID", fmt.Sprintf("%d", user1.ID))
-	wsConn, _, err := dialer.Dial(wsURL, wsHeaders)
+	wsConn, _, err := dialer.Dial(wsURL, nil)
 	assert.NoError(t, err, "Ошибка подключения к WS")
 	defer wsConn.Close()
 
@@ -188,8 +199,8 @@ func TestQueueFlow(t *testing.T) {
 
 	// 7. Симулируем автоматическое закрытие очереди:
 	log.Println("Симуляция закрытия очереди: обновляем closes_at на прошлое время")
-	storage.DB.Model(&models.Queue{}).Where("id = ?", testQueue.ID).Update("closes_at", time.Now().Add(-1*time.Minute))
-	tasks.CloseExpiredQueues()
+	a.DB.Model(&models.Queue{}).Where("id = ?", testQueue.ID).Update("closes_at", time.Now().Add(-1*time.Minute))
+	tasks.NewPlanner(a.Handler).CloseExpiredQueues()
 
 	// WS: ожидаем сообщение с event_type "queue_closed"
 	_, msgClosed, err := wsConn.ReadMessage()
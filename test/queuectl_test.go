@@ -0,0 +1,263 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"test_hack/internal/app"
+	"test_hack/internal/jobs"
+	"test_hack/internal/models"
+	"test_hack/internal/queuectl"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// runQueuectl выполняет подкоманду queuectl над тестовым приложением и возвращает её вывод.
+func runQueuectl(a *app.App, stdin string, args ...string) (string, error) {
+	var out bytes.Buffer
+	cli := &queuectl.CLI{App: a, In: strings.NewReader(stdin), Out: &out, Err: &bytes.Buffer{}}
+	err := cli.Run(context.Background(), args[0], args[1:])
+	return out.String(), err
+}
+
+// queuectlApp открывает над базой тестового сервера отдельный экземпляр приложения так же, как queuectl:
+// без хаба WebSocket, с передачей событий серверу через Redis.
+func queuectlApp(t *testing.T, server *app.App) *app.App {
+	a, err := app.Open(server.Config)
+	require.NoError(t, err)
+	a.ForwardEvents()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		assert.NoError(t, a.Shutdown(ctx))
+	})
+	return a
+}
+
+func TestQueuectlUsageErrors(t *testing.T) {
+	_, a := setupTestServer(t)
+
+	for _, args := range [][]string{
+		{"unknown"},
+		{"create-admin"},
+		{"create-admin", "-email", "new@example.com"},
+		{"import-schedule", "-group", "67", "-from", "2025-03-16", "-to", "2025-03-10"},
+		{"queue", "close"},
+		{"queue", "close", "abc"},
+		{"queue", "rename", "1"},
+		{"queue", "reopen", "1", "-until", "завтра"},
+		{"run-job"},
+	} {
+		_, err := runQueuectl(a, "", args...)
+		assert.True(t, errors.Is(err, queuectl.ErrUsage), "%v: ожидается ErrUsage, получено %v", args, err)
+	}
+}
+
+func TestQueuectlMigrateStatus(t *testing.T) {
+	_, a := setupTestServer(t)
+
+	out, err := runQueuectl(a, "", "migrate", "status")
+	require.NoError(t, err)
+	assert.Regexp(t, `0001\s+init`, out)
+
+	out, err = runQueuectl(a, "", "migrate", "up")
+	require.NoError(t, err)
+	assert.Contains(t, out, "новых миграций нет")
+}
+
+func TestQueuectlCreateAdmin(t *testing.T) {
+	_, a := setupTestServer(t)
+
+	// Новый администратор: пароль читается из stdin.
+	email := fmt.Sprintf("admin_%d@example.com", time.Now().UnixNano())
+	out, err := runQueuectl(a, "secret123\n", "create-admin", "-email", email, "-name", "Иван", "-surname", "Иванов")
+	require.NoError(t, err)
+	assert.Contains(t, out, "создан")
+
+	var admin models.User
+	require.NoError(t, a.DB.Where("email = ?", email).First(&admin).Error)
+	assert.Equal(t, models.RoleAdmin, admin.Role)
+	assert.True(t, admin.EmailVerified)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(admin.PasswordHash), []byte("secret123")))
+
+	var created models.AuditEvent
+	require.NoError(t, a.DB.Where("action = ? AND target_id = ?", "admin.admin_created", strconv.Itoa(int(admin.ID))).First(&created).Error)
+	assert.Contains(t, created.Details, `"channel":"cli"`)
+
+	// Повторный вызов для администратора — ошибка, пользователь не дублируется.
+	_, err = runQueuectl(a, "", "create-admin", "-email", email, "-password", "secret123")
+	assert.Error(t, err)
+
+	// Короткий пароль отклоняется.
+	_, err = runQueuectl(a, "", "create-admin", "-email", "short_"+email, "-name", "A", "-surname", "B", "-password", "123")
+	assert.Error(t, err)
+	var count int64
+	a.DB.Model(&models.User{}).Where("email = ?", "short_"+email).Count(&count)
+	assert.Zero(t, count)
+
+	// Существующий студент получает роль admin без смены пароля.
+	student := models.User{Name: "Петр", Surname: "Петров", Email: "student_" + email, PasswordHash: "hashed", Role: models.RoleStudent}
+	require.NoError(t, a.DB.Create(&student).Error)
+	out, err = runQueuectl(a, "", "create-admin", "-email", student.Email)
+	require.NoError(t, err)
+	assert.Contains(t, out, "назначен администратором")
+
+	require.NoError(t, a.DB.First(&student, student.ID).Error)
+	assert.Equal(t, models.RoleAdmin, student.Role)
+	assert.Equal(t, "hashed", student.PasswordHash)
+
+	var changed models.AuditEvent
+	require.NoError(t, a.DB.Where("action = ? AND target_id = ?", "admin.role_changed", strconv.Itoa(int(student.ID))).First(&changed).Error)
+	assert.Contains(t, changed.Before, models.RoleStudent)
+	assert.Contains(t, changed.After, models.RoleAdmin)
+}
+
+func TestQueuectlImportSchedule(t *testing.T) {
+	_, a := setupTestServer(t)
+
+	var query string
+	timetable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"items": []map[string]interface{}{
+				{"id": 501, "name": "Матанализ", "start_ts": "2025-03-10T10:00:00", "end_ts": "2025-03-10T11:35:00", "group": []map[string]interface{}{{"id": 67}}},
+				{"id": 502, "name": "Физика", "start_ts": "2025-03-11T12:00:00", "end_ts": "2025-03-11T13:35:00", "group": []map[string]interface{}{{"id": 67}, {"id": 68}}},
+			},
+		})
+	}))
+	defer timetable.Close()
+	a.Handler.Config.Timetable.APIURL = timetable.URL
+
+	out, err := runQueuectl(a, "", "import-schedule", "-group", "67", "-from", "2025-03-10", "-to", "2025-03-16")
+	require.NoError(t, err)
+	assert.Contains(t, query, "start=2025-03-10")
+	assert.Contains(t, query, "end=2025-03-16")
+	assert.Contains(t, query, "group_id=67")
+	assert.Contains(t, out, "Матанализ")
+	assert.Contains(t, out, "Загружено новых событий: 2")
+
+	var physics models.Schedule
+	require.NoError(t, a.DB.Where("external_id = ?", "502").First(&physics).Error)
+	assert.Equal(t, "67,68", physics.GroupIDs)
+
+	// Уже загруженные события не дублируются.
+	out, err = runQueuectl(a, "", "import-schedule", "-group", "67", "-from", "2025-03-10", "-to", "2025-03-16")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Загружено новых событий: 0")
+}
+
+func TestQueuectlQueueCloseReopenAndList(t *testing.T) {
+	ts, server := setupTestServer(t)
+	a := queuectlApp(t, server)
+
+	now := time.Now()
+	schedule := models.Schedule{ExternalID: "queuectl", Name: "Пара", StartTime: now.Add(time.Hour), EndTime: now.Add(2 * time.Hour), GroupIDs: "1"}
+	require.NoError(t, a.DB.Create(&schedule).Error)
+	queue := models.Queue{ScheduleID: schedule.ID, OpensAt: now.Add(-time.Minute), ClosesAt: schedule.StartTime, IsActive: true}
+	require.NoError(t, a.DB.Create(&queue).Error)
+	user := models.User{Name: "Иван", Surname: "Иванов", Email: fmt.Sprintf("ctl_%d@example.com", now.UnixNano()), PasswordHash: "x"}
+	require.NoError(t, a.DB.Create(&user).Error)
+	require.NoError(t, a.DB.Create(&models.QueueEntry{QueueID: queue.ID, UserID: user.ID, Position: 1, Status: models.EntryStatusWaiting}).Error)
+	queueID := strconv.Itoa(int(queue.ID))
+	hook := models.Webhook{URL: "https://hooks.example.com/queue", Secret: "secret", Events: "queue_closed", IsActive: true}
+	require.NoError(t, a.DB.Create(&hook).Error)
+
+	// Клиент WebSocket подключён к серверу, а не к процессу queuectl.
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/api/queues/"+queueID+"/ws", nil)
+	require.NoError(t, err)
+	defer ws.Close()
+	require.Eventually(t, func() bool { return server.Hub.ClientCount(queueID) == 1 }, time.Second, 10*time.Millisecond)
+
+	out, err := runQueuectl(a, "", "queues")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Очередь "+queueID)
+	assert.Contains(t, out, "участников: 1")
+	assert.Contains(t, out, "Иванов Иван")
+
+	out, err = runQueuectl(a, "", "queue", "close", queueID)
+	require.NoError(t, err)
+	assert.Contains(t, out, "закрыта")
+	require.NoError(t, a.DB.First(&queue, queue.ID).Error)
+	assert.False(t, queue.IsActive)
+
+	// Событие закрытия доходит до клиентов сервера, а на вебхук ставится в очередь доставки.
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg map[string]interface{}
+	require.NoError(t, ws.ReadJSON(&msg))
+	assert.Equal(t, "queue_closed", msg["event_type"])
+	assert.Equal(t, queueID, msg["queue_id"])
+	var delivery models.WebhookDelivery
+	require.NoError(t, a.DB.Where("webhook_id = ?", hook.ID).First(&delivery).Error)
+	assert.Equal(t, "queue_closed", delivery.EventType)
+	assert.Equal(t, models.DeliveryStatusPending, delivery.Status)
+
+	out, err = runQueuectl(a, "", "queues")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Активных очередей нет")
+
+	// Время закрытия в прошлом отклоняется.
+	_, err = runQueuectl(a, "", "queue", "reopen", queueID, "-until", now.Add(-time.Hour).Format("2006-01-02T15:04"))
+	assert.Error(t, err)
+
+	until := now.Add(3 * time.Hour).Truncate(time.Minute)
+	out, err = runQueuectl(a, "", "queue", "reopen", queueID, "-until", until.Format("2006-01-02T15:04"))
+	require.NoError(t, err)
+	assert.Contains(t, out, "открыта до "+until.Format("2006-01-02 15:04"))
+	require.NoError(t, a.DB.First(&queue, queue.ID).Error)
+	assert.True(t, queue.IsActive)
+	assert.WithinDuration(t, until, queue.ClosesAt, time.Second)
+
+	var events []models.AuditEvent
+	a.DB.Where("target_type = ? AND target_id = ?", "queue", queueID).Order("id").Find(&events)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, "queue.closed", events[0].Action)
+		assert.Equal(t, "queue.reopened", events[1].Action)
+		assert.Contains(t, events[1].Details, `"channel":"cli"`)
+	}
+
+	_, err = runQueuectl(a, "", "queue", "close", "999999")
+	assert.Error(t, err)
+}
+
+func TestQueuectlJobs(t *testing.T) {
+	_, a := setupTestServer(t)
+
+	a.Jobs.Register(jobs.Job{Name: "TestCLIJob", Spec: "@yearly", Run: func() (int64, error) { return 4, nil }})
+	a.Jobs.Register(jobs.Job{Name: "TestCLIFailingJob", Spec: "@yearly", Run: func() (int64, error) {
+		return 0, errors.New("нет связи с API")
+	}})
+
+	out, err := runQueuectl(a, "", "jobs")
+	require.NoError(t, err)
+	assert.Contains(t, out, "CloseExpiredQueues")
+	assert.Regexp(t, `TestCLIJob\s+@yearly`, out)
+
+	out, err = runQueuectl(a, "", "run-job", "TestCLIJob")
+	require.NoError(t, err)
+	assert.Contains(t, out, "затронуто записей: 4")
+
+	var run models.JobRun
+	require.NoError(t, a.DB.Where("job_name = ?", "TestCLIJob").First(&run).Error)
+	assert.Equal(t, models.JobTriggerCLI, run.Trigger)
+	assert.Equal(t, models.JobStatusSuccess, run.Status)
+	var audited int64
+	a.DB.Model(&models.AuditEvent{}).Where("action = ? AND target_id = ?", "admin.job_run", "TestCLIJob").Count(&audited)
+	assert.EqualValues(t, 1, audited)
+
+	_, err = runQueuectl(a, "", "run-job", "TestCLIFailingJob")
+	assert.EqualError(t, err, "нет связи с API")
+
+	_, err = runQueuectl(a, "", "run-job", "NoSuchJob")
+	assert.ErrorIs(t, err, jobs.ErrJobNotFound)
+}
@@ -0,0 +1,128 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"test_hack/internal/models"
+	"test_hack/internal/ratelimit"
+	"test_hack/internal/response"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := ratelimit.NewMemoryStore()
+	store.Now = func() time.Time { return now }
+
+	r := gin.New()
+	r.POST("/join", func(c *gin.Context) {
+		if id := c.GetHeader("X-Test-User"); id == "1" {
+			c.Set("userID", uint(1))
+		}
+		c.Next()
+	}, ratelimit.New("queue_join", ratelimit.Per(2, time.Minute), store), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	do := func(user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/join", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		if user != "" {
+			req.Header.Set("X-Test-User", user)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, http.StatusOK, do("1").Code)
+
+	w = do("1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	var errResp response.ErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+	assert.Equal(t, "RATE_LIMITED", errResp.Code)
+
+	// Анонимные запросы с того же IP учитываются отдельно от пользователя.
+	assert.Equal(t, http.StatusOK, do("").Code)
+
+	// Через 30 секунд корзина пополняется на один токен.
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, http.StatusOK, do("1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, do("1").Code)
+}
+
+func TestParseRateLimit(t *testing.T) {
+	limit, err := ratelimit.ParseLimit("10/1m")
+	assert.NoError(t, err)
+	assert.Equal(t, 10, limit.Burst)
+	assert.InDelta(t, 10.0/60, limit.Rate, 1e-9)
+
+	for _, bad := range []string{"", "10", "0/1m", "10/abc", "x/1s"} {
+		_, err := ratelimit.ParseLimit(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestMemoryStoreCleanupKeepsSlowBuckets(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := ratelimit.NewMemoryStore()
+	store.Now = func() time.Time { return now }
+	ctx := context.Background()
+
+	export := ratelimit.Per(5, time.Hour)
+	for i := 0; i < 5; i++ {
+		res, _ := store.Take(ctx, "ratelimit:profile_export:user:1", export)
+		assert.True(t, res.Allowed)
+	}
+
+	// Через две минуты корзины лимита 10/мин уже полны, а корзина 5/ч — ещё нет.
+	// Новые корзины быстрого маршрута запускают очистку, которая не должна сбросить медленную корзину.
+	now = now.Add(2 * time.Minute)
+	for i := 0; i < 1023; i++ {
+		store.Take(ctx, fmt.Sprintf("ratelimit:queue_join:user:%d", i+2), ratelimit.Per(10, time.Minute))
+	}
+
+	res, _ := store.Take(ctx, "ratelimit:profile_export:user:1", export)
+	assert.False(t, res.Allowed, "исчерпанная корзина 5/ч не должна сбрасываться очисткой")
+}
+
+func TestQueueJoinAndLeaveHaveSeparateLimits(t *testing.T) {
+	ts, a := setupTestServer(t)
+
+	user := models.User{Name: "Анна", Surname: "Смирнова", Email: fmt.Sprintf("limits_%d@example.com", time.Now().UnixNano()), PasswordHash: "x"}
+	require.NoError(t, a.DB.Create(&user).Error)
+	token := accessToken(t, a, user.ID)
+
+	post := func(path string) int {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	// Очереди нет, поэтому вступление отклоняется, но каждая попытка расходует лимит.
+	for i := 0; i < 10; i++ {
+		assert.NotEqual(t, http.StatusTooManyRequests, post("/api/queues/999999/join"))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, post("/api/queues/999999/join"))
+	assert.NotEqual(t, http.StatusTooManyRequests, post("/api/queues/999999/leave"), "выход не должен зависеть от лимита вступлений")
+}
@@ -0,0 +1,137 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"test_hack/internal/models"
+	"test_hack/internal/notify"
+	"test_hack/internal/tasks"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingChannel запоминает доставленные напоминания.
+type recordingChannel struct {
+	mu   sync.Mutex
+	sent []notify.Message
+}
+
+func (c *recordingChannel) Name() string { return "test" }
+
+func (c *recordingChannel) Send(user models.User, msg notify.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, msg)
+	return nil
+}
+
+func (c *recordingChannel) kinds() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var kinds []string
+	for _, m := range c.sent {
+		kinds = append(kinds, m.Kind)
+	}
+	return kinds
+}
+
+// TestRemindersSentOnce проверяет, что каждое напоминание отправляется один раз: повторные запуски
+// задачи натыкаются на уникальную запись в notifications.
+func TestRemindersSentOnce(t *testing.T) {
+	_, a := setupTestServer(t)
+
+	channel := &recordingChannel{}
+	a.Notify.Register(channel)
+
+	now := time.Now()
+	schedule := models.Schedule{ExternalID: "9980", Name: "Базы данных", StartTime: now.Add(20 * time.Minute), EndTime: now.Add(2 * time.Hour), GroupIDs: "305"}
+	require.NoError(t, a.DB.Create(&schedule).Error)
+	queue := models.Queue{ScheduleID: schedule.ID, OpensAt: now.Add(-time.Minute), ClosesAt: schedule.StartTime, IsActive: true}
+	require.NoError(t, a.DB.Create(&queue).Error)
+
+	newStudent := func(name string) models.User {
+		u := models.User{Name: name, Surname: "Тестовый", Email: fmt.Sprintf("%s_%d@example.com", name, now.UnixNano()), PasswordHash: "x", GroupID: "305"}
+		require.NoError(t, a.DB.Create(&u).Error)
+		settings := notify.DefaultSettings(u.ID)
+		settings.Channels = channel.Name()
+		require.NoError(t, a.DB.Create(&settings).Error)
+		return u
+	}
+	waiting := newStudent("waiting")
+	inQueue := newStudent("inqueue")
+	require.NoError(t, a.DB.Create(&models.QueueEntry{QueueID: queue.ID, UserID: inQueue.ID, Position: 1, Status: models.EntryStatusWaiting}).Error)
+
+	planner := tasks.NewPlanner(a.Handler)
+	sent, err := planner.SendReminders()
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, sent)
+	assert.ElementsMatch(t, []string{notify.KindQueueOpened, notify.KindEventSoon, notify.KindTurnNear}, channel.kinds())
+
+	var opened models.Notification
+	require.NoError(t, a.DB.Where("kind = ?", notify.KindQueueOpened).First(&opened).Error)
+	assert.Equal(t, waiting.ID, opened.UserID, "об открытии очереди уведомляются только те, кто в неё не встал")
+
+	// Повторные запуски ничего не отправляют.
+	for i := 0; i < 2; i++ {
+		sent, err = planner.SendReminders()
+		require.NoError(t, err)
+		assert.Zero(t, sent)
+	}
+	assert.Len(t, channel.kinds(), 3)
+
+	var count int64
+	require.NoError(t, a.DB.Model(&models.Notification{}).Where("queue_id = ?", queue.ID).Count(&count).Error)
+	assert.EqualValues(t, 3, count)
+}
+
+// failingChannel отклоняет все сообщения, пока fail = true.
+type failingChannel struct {
+	recordingChannel
+	fail bool
+}
+
+func (c *failingChannel) Name() string { return "failing" }
+
+func (c *failingChannel) Send(user models.User, msg notify.Message) error {
+	if c.fail {
+		return errors.New("канал недоступен")
+	}
+	return c.recordingChannel.Send(user, msg)
+}
+
+// TestReminderRetriedAfterFailedDelivery проверяет, что напоминание, которое не принял ни один канал,
+// не помечается отправленным и уходит при следующем запуске.
+func TestReminderRetriedAfterFailedDelivery(t *testing.T) {
+	_, a := setupTestServer(t)
+
+	channel := &failingChannel{fail: true}
+	a.Notify.Register(channel)
+
+	now := time.Now()
+	user := models.User{Name: "retry", Surname: "Тестовый", Email: fmt.Sprintf("retry_%d@example.com", now.UnixNano()), PasswordHash: "x"}
+	require.NoError(t, a.DB.Create(&user).Error)
+	settings := notify.DefaultSettings(user.ID)
+	settings.Channels = channel.Name()
+	msg := notify.Message{QueueID: 1, Kind: notify.KindTurnNear, Title: "t", Body: "b"}
+
+	ok, err := a.Notify.SendOnce(a.DB, user, settings, msg)
+	assert.Error(t, err)
+	assert.False(t, ok)
+	var count int64
+	require.NoError(t, a.DB.Unscoped().Model(&models.Notification{}).Where("user_id = ?", user.ID).Count(&count).Error)
+	assert.Zero(t, count, "неотправленное напоминание не должно блокировать повтор")
+
+	channel.fail = false
+	ok, err = a.Notify.SendOnce(a.DB, user, settings, msg)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []string{notify.KindTurnNear}, channel.kinds())
+
+	ok, err = a.Notify.SendOnce(a.DB, user, settings, msg)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
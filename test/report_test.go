@@ -0,0 +1,109 @@
+package test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"test_hack/internal/handlers"
+	"test_hack/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestQueueHistoryAndAttendanceReport(t *testing.T) {
+	ts, a := setupTestServer(t)
+
+	now := time.Now()
+	teacher := models.User{Name: "Пётр", Surname: "Преподаватель", Email: fmt.Sprintf("teacher_%d@example.com", now.UnixNano()), PasswordHash: "x", Role: models.RoleTeacher}
+	require.NoError(t, a.DB.Create(&teacher).Error)
+	var students []models.User
+	for i, surname := range []string{"Антонов", "Борисов", "Васильев"} {
+		s := models.User{Name: "Студент", Surname: surname, Email: fmt.Sprintf("report_%d_%d@example.com", i, now.UnixNano()), PasswordHash: "x", GroupID: "203"}
+		require.NoError(t, a.DB.Create(&s).Error)
+		students = append(students, s)
+	}
+
+	schedule := models.Schedule{ExternalID: "9990", Name: "Операционные системы", StartTime: now.Add(time.Hour), EndTime: now.Add(2 * time.Hour), GroupIDs: "67,203"}
+	require.NoError(t, a.DB.Create(&schedule).Error)
+	queue := models.Queue{ScheduleID: schedule.ID, OpensAt: now.Add(-time.Minute), ClosesAt: schedule.StartTime, IsActive: true}
+	require.NoError(t, a.DB.Create(&queue).Error)
+
+	// Первый студент принят, второй вышел из очереди, третий в очередь не вставал.
+	for _, s := range students[:2] {
+		code, _ := postJSONAs(t, a, fmt.Sprintf("%s/api/queues/%d/join", ts.URL, queue.ID), s.ID, nil)
+		require.Equal(t, http.StatusOK, code)
+	}
+	code, _ := postJSONAs(t, a, fmt.Sprintf("%s/api/queues/%d/serve", ts.URL, queue.ID), teacher.ID, map[string]interface{}{"user_id": students[0].ID})
+	require.Equal(t, http.StatusOK, code)
+	code, _ = postJSONAs(t, a, fmt.Sprintf("%s/api/queues/%d/leave", ts.URL, queue.ID), students[1].ID, nil)
+	require.Equal(t, http.StatusOK, code)
+
+	res, body := getAs(t, a, fmt.Sprintf("%s/api/queues/%d/history", ts.URL, queue.ID), teacher.ID)
+	require.NotNil(t, res)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var history handlers.QueueHistoryResponse
+	require.NoError(t, json.Unmarshal(body, &history))
+	assert.Equal(t, "Операционные системы", history.ScheduleName)
+	if assert.Len(t, history.Entries, 2) {
+		assert.Equal(t, models.EntryStatusServed, history.Entries[0].Status)
+		assert.Equal(t, "Преподаватель", history.Entries[0].ServedBySurname)
+		assert.Equal(t, models.EntryStatusLeft, history.Entries[1].Status)
+		assert.NotNil(t, history.Entries[1].LeftAt)
+	}
+
+	res, _ = getAs(t, a, ts.URL+"/api/queues/999999/history", teacher.ID)
+	require.NotNil(t, res)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	reportURL := ts.URL + "/api/reports/attendance?group_id=203"
+	res, body = getAs(t, a, reportURL, teacher.ID)
+	require.NotNil(t, res)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var report handlers.AttendanceReport
+	require.NoError(t, json.Unmarshal(body, &report))
+	statuses := map[uint]string{}
+	for _, row := range report.Rows {
+		statuses[row.UserID] = row.Status
+	}
+	assert.Equal(t, map[uint]string{
+		students[0].ID: models.EntryStatusServed,
+		students[1].ID: models.EntryStatusLeft,
+		students[2].ID: "absent",
+	}, statuses)
+
+	// Группа 20 не должна находить событие групп 67 и 203 по подстроке.
+	res, body = getAs(t, a, ts.URL+"/api/reports/attendance?group_id=20", teacher.ID)
+	require.NotNil(t, res)
+	require.NoError(t, json.Unmarshal(body, &report))
+	assert.Empty(t, report.Rows)
+
+	res, body = getAs(t, a, reportURL+"&format=csv", teacher.ID)
+	require.NotNil(t, res)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Contains(t, res.Header.Get("Content-Type"), "text/csv")
+	records, err := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(body, []byte("\xEF\xBB\xBF")))).ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, records, 1+len(students), "заголовок и строка на каждого студента")
+
+	res, body = getAs(t, a, reportURL+"&format=xlsx", teacher.ID)
+	require.NotNil(t, res)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	file, err := excelize.OpenReader(bytes.NewReader(body))
+	require.NoError(t, err)
+	defer file.Close()
+	rows, err := file.GetRows("Посещаемость")
+	require.NoError(t, err)
+	assert.Len(t, rows, 1+len(students))
+
+	for _, query := range []string{"", "?group_id=203&from=2024-13-01", "?group_id=203&from=2024-03-02&to=2024-03-01", "?group_id=203&format=pdf", "?schedule_id=abc"} {
+		res, _ = getAs(t, a, ts.URL+"/api/reports/attendance"+query, teacher.ID)
+		require.NotNil(t, res)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, query)
+	}
+}
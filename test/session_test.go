@@ -0,0 +1,88 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"test_hack/internal/models"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// doBearer отправляет запрос с access токеном и возвращает код ответа и разобранное тело.
+func doBearer(t *testing.T, method, url, accessToken string) (int, interface{}) {
+	req, _ := http.NewRequest(method, url, nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	res, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return 0, nil
+	}
+	defer res.Body.Close()
+	var result interface{}
+	json.NewDecoder(res.Body).Decode(&result)
+	return res.StatusCode, result
+}
+
+func TestSessionsListAndRevoke(t *testing.T) {
+	ts, a := setupTestServer(t)
+
+	// Маршруты сессий проверяются с настоящим AuthMiddleware.
+	r := gin.New()
+	profile := r.Group("/profile", a.Auth.AuthMiddleware())
+	profile.GET("/sessions", a.Handler.ListSessionsHandler)
+	profile.DELETE("/sessions/:id", a.Handler.RevokeSessionHandler)
+	ps := httptest.NewServer(r)
+	defer ps.Close()
+
+	email := fmt.Sprintf("sessions_%d@example.com", time.Now().UnixNano())
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret12"), bcrypt.MinCost)
+	user := models.User{Name: "Лев", Surname: "Морозов", Email: email, PasswordHash: string(hash)}
+	assert.NoError(t, a.DB.Create(&user).Error)
+
+	credentials := map[string]string{"email": email, "password": "secret12"}
+	_, laptop := postJSON(t, ts.URL+"/auth/login", credentials)
+	_, phone := postJSON(t, ts.URL+"/auth/login", credentials)
+	laptopToken, _ := laptop["access_token"].(string)
+	phoneToken, _ := phone["access_token"].(string)
+
+	code, body := doBearer(t, http.MethodGet, ps.URL+"/profile/sessions", laptopToken)
+	assert.Equal(t, http.StatusOK, code)
+	sessions, _ := body.([]interface{})
+	if !assert.Len(t, sessions, 2) {
+		return
+	}
+	var phoneSession float64
+	for _, s := range sessions {
+		session := s.(map[string]interface{})
+		if current, _ := session["current"].(bool); !current {
+			phoneSession = session["id"].(float64)
+		}
+	}
+	assert.NotZero(t, phoneSession)
+
+	// Обновление токенов продлевает ту же сессию, а не открывает новую.
+	code, refreshed := postJSON(t, ts.URL+"/auth/refresh", map[string]interface{}{"refresh_token": phone["refresh_token"]})
+	assert.Equal(t, http.StatusOK, code)
+	var count int64
+	a.DB.Model(&models.Session{}).Where("user_id = ?", user.ID).Count(&count)
+	assert.Equal(t, int64(2), count)
+
+	code, _ = doBearer(t, http.MethodDelete, fmt.Sprintf("%s/profile/sessions/%d", ps.URL, int(phoneSession)), laptopToken)
+	assert.Equal(t, http.StatusOK, code)
+
+	code, body = doBearer(t, http.MethodGet, ps.URL+"/profile/sessions", phoneToken)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, "SESSION_REVOKED", body.(map[string]interface{})["code"])
+	code, _ = postJSON(t, ts.URL+"/auth/refresh", map[string]interface{}{"refresh_token": refreshed["refresh_token"]})
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, _ = doBearer(t, http.MethodGet, ps.URL+"/profile/sessions", laptopToken)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = doBearer(t, http.MethodDelete, fmt.Sprintf("%s/profile/sessions/%d", ps.URL, int(phoneSession)), laptopToken)
+	assert.Equal(t, http.StatusNotFound, code)
+}
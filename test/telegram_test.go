@@ -0,0 +1,107 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"test_hack/internal/models"
+	"test_hack/internal/notify"
+	"test_hack/internal/telegram"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTelegramAPI записывает вызовы sendMessage вместо обращения к Telegram.
+type fakeTelegramAPI struct {
+	mu       sync.Mutex
+	messages []map[string]interface{}
+}
+
+func (f *fakeTelegramAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var params map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&params)
+	if r.URL.Path == "/bottest-token/sendMessage" {
+		f.mu.Lock()
+		f.messages = append(f.messages, params)
+		f.mu.Unlock()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"ok":true,"result":true}`))
+}
+
+func (f *fakeTelegramAPI) sent() []map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]map[string]interface{}(nil), f.messages...)
+}
+
+func TestTelegramChannelSendsLocalizedMessage(t *testing.T) {
+	api := &fakeTelegramAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	client := telegram.NewClient(server.URL, "test-token")
+	chatID := int64(42)
+	user := models.User{Language: notify.LangEN, TelegramChatID: &chatID}
+	msg := notify.EventSoonMessage(models.Schedule{Name: "Lab 1", StartTime: time.Now().Add(time.Hour)}, models.Queue{}, 2)
+
+	assert.NoError(t, telegram.Channel{Client: client}.Send(user, msg))
+	assert.Error(t, telegram.Channel{Client: client}.Send(models.User{}, msg), "Без привязанного чата отправка невозможна")
+
+	sent := api.sent()
+	if assert.Len(t, sent, 1) {
+		assert.Equal(t, float64(42), sent[0]["chat_id"])
+		assert.Contains(t, sent[0]["text"], "Starting soon: Lab 1")
+	}
+}
+
+func TestTelegramWebhookChecksSecret(t *testing.T) {
+	api := &fakeTelegramAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	bot := &telegram.Bot{Client: telegram.NewClient(server.URL, "test-token"), WebhookSecret: "s3cret"}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/telegram/webhook", bot.WebhookHandler)
+
+	update := `{"update_id":1,"message":{"message_id":1,"chat":{"id":7},"text":"/help@queue_bot"}}`
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/telegram/webhook", strings.NewReader(update))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, api.sent())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/telegram/webhook", strings.NewReader(update))
+	req.Header.Set("X-Telegram-Bot-Api-Secret-Token", "s3cret")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	sent := api.sent()
+	if assert.Len(t, sent, 1) {
+		assert.Equal(t, float64(7), sent[0]["chat_id"])
+		assert.Contains(t, sent[0]["text"], "/join")
+	}
+
+	// Без настроенного секрета webhook не принимает обновления даже без заголовка.
+	noSecret := &telegram.Bot{Client: telegram.NewClient(server.URL, "test-token")}
+	r = gin.New()
+	r.POST("/telegram/webhook", noSecret.WebhookHandler)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/telegram/webhook", strings.NewReader(update))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Len(t, api.sent(), 1)
+
+	// Сообщения без текста игнорируются.
+	bot.HandleUpdate(context.Background(), telegram.Update{UpdateID: 2})
+	assert.Len(t, api.sent(), 1)
+}
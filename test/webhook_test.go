@@ -0,0 +1,94 @@
+package test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"test_hack/internal/models"
+	"test_hack/internal/webhooks"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookPostIsSigned(t *testing.T) {
+	payload := []byte(`{"event_type":"user_joined","queue_id":"3","timestamp":1700000000}`)
+
+	var headers http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	code, err := webhooks.Post(context.Background(), server.Client(), server.URL, "secret", 15, "user_joined", payload)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, code)
+	assert.Equal(t, payload, body)
+	assert.Equal(t, "user_joined", headers.Get("X-Webhook-Event"))
+	assert.Equal(t, "15", headers.Get("X-Webhook-Delivery"))
+
+	// Получатель проверяет подпись тем же способом.
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(headers.Get("X-Webhook-Timestamp") + "."))
+	mac.Write(body)
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), headers.Get("X-Webhook-Signature"))
+}
+
+func TestWebhookPostFailsOnNon2xx(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	code, err := webhooks.Post(context.Background(), server.Client(), server.URL, "secret", 1, "queue_closed", []byte(`{}`))
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadGateway, code)
+}
+
+func TestWebhookBackoffAndFilters(t *testing.T) {
+	assert.Equal(t, 30*time.Second, webhooks.Backoff(1))
+	assert.Equal(t, time.Minute, webhooks.Backoff(2))
+	assert.Equal(t, 4*time.Minute, webhooks.Backoff(4))
+	assert.Equal(t, time.Hour, webhooks.Backoff(20), "Задержка ограничена часом")
+
+	assert.True(t, webhooks.Subscribed(models.Webhook{}, "queue_update"), "Пустой фильтр — все события")
+	hook := models.Webhook{Events: "user_joined,queue_closed"}
+	assert.True(t, webhooks.Subscribed(hook, "queue_closed"))
+	assert.False(t, webhooks.Subscribed(hook, "user_left"))
+
+	assert.True(t, webhooks.IsKnownEvent("user_served"))
+	assert.False(t, webhooks.IsKnownEvent("user_deleted"))
+}
+
+func TestWebhookQueueUpdateThrottle(t *testing.T) {
+	var throttle webhooks.UpdateThrottle
+	now := time.Now()
+	assert.True(t, throttle.Allow(1, "7", 5*time.Minute, now))
+	assert.False(t, throttle.Allow(1, "7", 5*time.Minute, now.Add(time.Minute)), "повтор внутри интервала пропускается")
+	assert.True(t, throttle.Allow(2, "7", 5*time.Minute, now.Add(time.Minute)), "интервал считается для каждого вебхука")
+	assert.True(t, throttle.Allow(1, "8", 5*time.Minute, now.Add(time.Minute)), "и для каждой очереди")
+	assert.True(t, throttle.Allow(1, "7", 5*time.Minute, now.Add(5*time.Minute)))
+
+	throttle.Forget("7")
+	assert.True(t, throttle.Allow(1, "7", 5*time.Minute, now.Add(6*time.Minute)), "после закрытия очереди счёт начинается заново")
+}
+
+func TestWebhookDispatcherShutdown(t *testing.T) {
+	d := &webhooks.Dispatcher{}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, d.Shutdown(ctx))
+
+	// После остановки события не принимаются: без базы данных запущенная доставка завершилась бы паникой.
+	d.Publish("1", "queue_update", []byte(`{}`))
+	require.NoError(t, d.Shutdown(ctx))
+}
@@ -0,0 +1,103 @@
+package test
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"test_hack/internal/config"
+	"test_hack/internal/models"
+	"test_hack/internal/notify"
+	"testing"
+
+	"github.com/SherClockHolmes/webpush-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBrowserSubscription создаёт подписку с ключами, как их сгенерировал бы браузер.
+func newBrowserSubscription(t *testing.T, endpoint string) models.PushSubscription {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	auth := make([]byte, 16)
+	rand.Read(auth)
+	return models.PushSubscription{
+		Endpoint: endpoint,
+		P256dh:   base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()),
+		Auth:     base64.RawURLEncoding.EncodeToString(auth),
+	}
+}
+
+func TestPushSenderAgainstStubService(t *testing.T) {
+	privateKey, publicKey, err := webpush.GenerateVAPIDKeys()
+	assert.NoError(t, err)
+
+	var received *http.Request
+	var body []byte
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer stub.Close()
+
+	sender := &notify.PushSender{
+		PublicKey:  publicKey,
+		PrivateKey: privateKey,
+		Subject:    "mailto:admin@example.com",
+		HTTPClient: stub.Client(),
+		TTL:        60,
+	}
+
+	code, err := sender.Send(context.Background(), newBrowserSubscription(t, stub.URL+"/push/1"), []byte(`{"kind":"turn_near"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, code)
+	if assert.NotNil(t, received) {
+		assert.Equal(t, "aes128gcm", received.Header.Get("Content-Encoding"))
+		assert.Equal(t, "60", received.Header.Get("TTL"))
+		assert.True(t, strings.HasPrefix(received.Header.Get("Authorization"), "vapid t="))
+		assert.Contains(t, received.Header.Get("Authorization"), "k="+publicKey)
+		assert.NotContains(t, string(body), "turn_near", "Содержимое должно быть зашифровано")
+	}
+
+	_, err = sender.Send(context.Background(), newBrowserSubscription(t, stub.URL+"/gone"), []byte(`{}`))
+	assert.ErrorIs(t, err, notify.ErrSubscriptionGone)
+}
+
+// TestVAPIDKeysGeneratedOnce проверяет, что экземпляры, одновременно запущенные с пустой таблицей
+// vapid_keys, получают одну и ту же ключевую пару.
+func TestVAPIDKeysGeneratedOnce(t *testing.T) {
+	_, a := setupTestServer(t)
+	db := a.DB
+
+	const instances = 5
+	keys := make([]string, instances)
+	var wg sync.WaitGroup
+	for i := 0; i < instances; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sender, err := notify.NewPushSender(db, config.WebPush{}, "noreply@example.com", nil)
+			if assert.NoError(t, err) {
+				keys[i] = sender.PublicKey
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, key := range keys {
+		assert.Equal(t, keys[0], key)
+	}
+	var count int64
+	require.NoError(t, db.Model(&models.VAPIDKeys{}).Count(&count).Error)
+	assert.EqualValues(t, 1, count)
+}
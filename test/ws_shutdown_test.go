@@ -0,0 +1,108 @@
+package test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"test_hack/internal/handlers"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHubShutdownClosesClients проверяет, что при остановке хаба клиенты получают кадр закрытия
+// с кодом 1012 и подсказкой о переподключении, а публикация событий больше не блокируется.
+func TestHubShutdownClosesClients(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hub := handlers.NewHub(nil, nil)
+	go hub.Run()
+
+	r := gin.New()
+	r.GET("/api/queues/:id/ws", (&handlers.Handler{Hub: hub}).QueueWebSocketHandler)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/queues/1/ws"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Eventually(t, func() bool { return hub.ClientCount("1") == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	hub.Shutdown(ctx, 3*time.Second)
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err = conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, websocket.CloseServiceRestart, closeErr.Code)
+	assert.Contains(t, closeErr.Text, `"reconnect_after_ms":3000`)
+	assert.Zero(t, hub.ClientCount("1"))
+
+	done := make(chan struct{})
+	go func() {
+		hub.BroadcastWSMessage(handlers.WSMessage{EventType: "queue_update", QueueID: "1"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("BroadcastWSMessage заблокировался после остановки хаба")
+	}
+
+	// Новые подключения после остановки сразу закрываются.
+	conn2, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer conn2.Close()
+	conn2.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err = conn2.ReadMessage()
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, websocket.CloseServiceRestart, closeErr.Code)
+}
+
+// TestHubDropsSlowClient проверяет, что клиент, который не читает сообщения, отключается при переполнении
+// буфера, а счётчик подключений можно безопасно читать во время рассылки (запускайте с -race).
+func TestHubDropsSlowClient(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hub := handlers.NewHub(nil, nil)
+	go hub.Run()
+	defer hub.Shutdown(context.Background(), time.Second)
+
+	r := gin.New()
+	r.GET("/api/queues/:id/ws", (&handlers.Handler{Hub: hub}).QueueWebSocketHandler)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/api/queues/slow/ws", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Eventually(t, func() bool { return hub.ClientCount("slow") == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	// Счётчик подключений читается параллельно с рассылкой.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				hub.ClientCount("slow")
+			}
+		}
+	}()
+
+	// Клиент ничего не читает: сначала заполняются буферы TCP, затем буфер Send хаба.
+	payload := strings.Repeat("x", 64<<10)
+	require.Eventually(t, func() bool {
+		for i := 0; i < 64; i++ {
+			hub.BroadcastWSMessage(handlers.WSMessage{EventType: "queue_update", QueueID: "slow", Data: payload})
+		}
+		return hub.ClientCount("slow") == 0
+	}, 10*time.Second, time.Millisecond)
+}